@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The errors FindAWSCredentials returns, for callers to tell apart with
+// errors.Is.
+var (
+	// ErrNoAWSRegion means neither AWS_REGION, AWS_DEFAULT_REGION nor the
+	// profile sets a region.
+	ErrNoAWSRegion = errors.New("no AWS region: AWS_REGION is not set")
+	// ErrNoAWSCredentials means no source of the default credential chain
+	// has credentials, or one has them incompletely configured.
+	ErrNoAWSCredentials = errors.New("no AWS credentials found")
+)
+
+// metadataProbeTimeout is how long the checks wait for an instance
+// metadata service to answer before concluding there is none.
+const metadataProbeTimeout = time.Second
+
+// AWSResult describes what FindAWSCredentials found.
+type AWSResult struct {
+	Region  string
+	Profile string
+	// Source is where the default credential chain finds credentials:
+	// "environment", "web identity", "shared credentials file", "sso",
+	// "credential process", "assume role", "shared config file",
+	// "container" or "instance metadata".
+	Source string
+}
+
+// FindAWSCredentials finds the region and the source of credentials of the
+// AWS SDK's default credential chain, in the environment, the shared config
+// and credentials files and the container and instance metadata endpoints,
+// without the SDK. It only finds that credentials are present: it does not
+// call AWS, so keys that are wrong, revoked or lack permissions pass. An SSO
+// login is checked against its cached token's expiry, and the instance
+// metadata service is only probed, unless AWS_EC2_METADATA_DISABLED is true.
+// Its errors wrap ErrNoAWSRegion or ErrNoAWSCredentials where they apply.
+// The AWSResult describes as much as was found, even with an error.
+func FindAWSCredentials(ctx context.Context) (AWSResult, error) {
+	res := AWSResult{Profile: cmp.Or(os.Getenv("AWS_PROFILE"), os.Getenv("AWS_DEFAULT_PROFILE"), "default")}
+	home, _ := os.UserHomeDir()
+	config, err := readINIFile(cmp.Or(os.Getenv("AWS_CONFIG_FILE"), filepath.Join(home, ".aws", "config")))
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrNoAWSCredentials, err)
+	}
+	credentials, err := readINIFile(cmp.Or(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), filepath.Join(home, ".aws", "credentials")))
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrNoAWSCredentials, err)
+	}
+	profile := config[awsProfileSection(res.Profile)]
+	res.Region = cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), profile["region"])
+	if res.Region == "" {
+		return res, ErrNoAWSRegion
+	}
+	res.Source, err = awsCredentialsSource(ctx, res.Profile, profile, credentials[res.Profile], config, home)
+	return res, err
+}
+
+// awsProfileSection returns the name of the config file section of profile.
+func awsProfileSection(profile string) string {
+	if profile == "default" {
+		return profile
+	}
+	return "profile " + profile
+}
+
+// awsCredentialsSource returns the first source of the default credential
+// chain that has credentials for profile, whose settings in the config and
+// credentials files are profile and credentials.
+func awsCredentialsSource(ctx context.Context, name string, profile, credentials map[string]string, config map[string]map[string]string, home string) (string, error) {
+	keyID, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	switch {
+	case keyID != "" && secret != "":
+		return "environment", nil
+	case keyID != "" || secret != "":
+		return "", fmt.Errorf("%w: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must both be set", ErrNoAWSCredentials)
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "":
+		if os.Getenv("AWS_ROLE_ARN") == "" {
+			return "", fmt.Errorf("%w: AWS_WEB_IDENTITY_TOKEN_FILE is set without AWS_ROLE_ARN", ErrNoAWSCredentials)
+		}
+		if _, err := os.Stat(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")); err != nil {
+			return "", fmt.Errorf("%w: the web identity token file is missing: %w", ErrNoAWSCredentials, err)
+		}
+		return "web identity", nil
+	}
+
+	if credentials != nil || profile != nil {
+		switch {
+		case credentials["aws_access_key_id"] != "" && credentials["aws_secret_access_key"] != "":
+			return "shared credentials file", nil
+		case profile["sso_session"] != "" || profile["sso_start_url"] != "":
+			return "sso", checkAWSSSOToken(name, profile, config, home)
+		case profile["credential_process"] != "":
+			return "credential process", nil
+		case profile["role_arn"] != "":
+			if profile["source_profile"] == "" && profile["credential_source"] == "" && profile["web_identity_token_file"] == "" {
+				return "", fmt.Errorf("%w: profile %s has a role_arn without a source_profile or credential_source", ErrNoAWSCredentials, name)
+			}
+			return "assume role", nil
+		case profile["aws_access_key_id"] != "" && profile["aws_secret_access_key"] != "":
+			return "shared config file", nil
+		}
+	} else if name != "default" {
+		return "", fmt.Errorf("%w: profile %s is not in the shared config or credentials files", ErrNoAWSCredentials, name)
+	}
+
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return "container", nil
+	}
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") && awsInstanceMetadataAvailable(ctx) {
+		return "instance metadata", nil
+	}
+	return "", fmt.Errorf("%w: none found in the environment, the shared config and credentials files or instance metadata", ErrNoAWSCredentials)
+}
+
+// checkAWSSSOToken checks that the SSO login of profile, name, is cached and
+// has not expired, as "aws sso login" leaves it.
+func checkAWSSSOToken(name string, profile map[string]string, config map[string]map[string]string, home string) error {
+	// The cache is keyed by the session's name or, for legacy profiles, its
+	// start URL.
+	key := profile["sso_start_url"]
+	if session := profile["sso_session"]; session != "" {
+		key = session
+		if config["sso-session "+session] == nil {
+			return fmt.Errorf("%w: profile %s uses sso-session %s, which is not in the config file", ErrNoAWSCredentials, name, session)
+		}
+	}
+	sum := sha1.Sum([]byte(key))
+	data, err := os.ReadFile(filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json"))
+	if err != nil {
+		return fmt.Errorf("%w: no SSO login for profile %s: %w", ErrNoAWSCredentials, name, err)
+	}
+	var token struct {
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("%w: failed to read the SSO token of profile %s: %w", ErrNoAWSCredentials, name, err)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return fmt.Errorf("%w: the SSO login of profile %s expired at %s", ErrNoAWSCredentials, name, token.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// awsInstanceMetadataAvailable reports whether the EC2 instance metadata
+// service, at AWS_EC2_METADATA_SERVICE_ENDPOINT if it is set, answers.
+func awsInstanceMetadataAvailable(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, metadataProbeTimeout)
+	defer cancel()
+	endpoint := strings.TrimSuffix(cmp.Or(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), "http://169.254.169.254"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// readINIFile reads the sections of an INI file, such as the AWS config
+// file, by name. A missing file has none.
+func readINIFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	sections := make(map[string]map[string]string)
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			section = make(map[string]string)
+			sections[name] = section
+		case section != nil:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return sections, nil
+}
+
+// RequireAWSCredentials skips the test if no AWS region is set, fails it
+// with an actionable message if FindAWSCredentials finds no credentials, and
+// returns the region. Unlike CheckGCPAuth it does not obtain a token, so it
+// catches missing credentials, not invalid ones.
+func RequireAWSCredentials(t testing.TB) string {
+	t.Helper()
+	res, err := FindAWSCredentials(context.Background())
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoAWSRegion):
+		t.Skip("Skipping real integration test: AWS_REGION environment variable is not set")
+	case errors.Is(err, ErrNoAWSCredentials):
+		t.Fatalf("%s", formatAWSAuthError(res, err))
+	default:
+		t.Fatalf("An unexpected error occurred while finding AWS credentials: %v", err)
+	}
+	return res.Region
+}
+
+// formatAWSAuthError explains how to fix the credentials of res.
+func formatAWSAuthError(res AWSResult, err error) string {
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		NO AWS CREDENTIALS FOUND!
+		---------------------------------------------------------------------
+		The AWS default credential chain has no credentials for profile
+		%[1]s.
+
+		To fix this, please either:
+		   1. Log in with IAM Identity Center (SSO):
+		      aws sso login --profile %[1]s
+		   2. Or configure an access key:
+		      aws configure --profile %[1]s
+		   3. Or, in CI, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or
+		      AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE.
+
+		Original Error: %[2]v
+		---------------------------------------------------------------------
+		`, res.Profile, err)
+}
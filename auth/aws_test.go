@@ -0,0 +1,141 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isolateAWS hides the AWS configuration of the environment from the test,
+// and returns its new home directory.
+func isolateAWS(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range []string{
+		"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_CONFIG_FILE",
+		"AWS_SHARED_CREDENTIALS_FILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_ROLE_ARN", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_EC2_METADATA_SERVICE_ENDPOINT",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return home
+}
+
+// writeFile writes content to path, creating its directory.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestFindAWSCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no region", func(t *testing.T) {
+		isolateAWS(t)
+		_, err := auth.FindAWSCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAWSRegion)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		isolateAWS(t)
+		t.Setenv("AWS_REGION", "eu-west-1")
+		_, err := auth.FindAWSCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAWSCredentials)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		isolateAWS(t)
+		t.Setenv("AWS_REGION", "eu-west-1")
+		t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+		_, err := auth.FindAWSCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAWSCredentials, "the secret is missing")
+
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		res, err := auth.FindAWSCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.AWSResult{Region: "eu-west-1", Profile: "default", Source: "environment"}, res)
+	})
+
+	t.Run("shared files", func(t *testing.T) {
+		home := isolateAWS(t)
+		writeFile(t, filepath.Join(home, ".aws", "config"), "[default]\nregion = us-east-1\n\n[profile ci]\nregion = eu-central-1\nrole_arn = arn:aws:iam::123:role/ci\n")
+		writeFile(t, filepath.Join(home, ".aws", "credentials"), "[default]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n")
+		res, err := auth.FindAWSCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.AWSResult{Region: "us-east-1", Profile: "default", Source: "shared credentials file"}, res)
+
+		t.Setenv("AWS_PROFILE", "ci")
+		res, err = auth.FindAWSCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAWSCredentials, "the role has no source profile")
+		assert.Equal(t, "eu-central-1", res.Region)
+
+		t.Setenv("AWS_PROFILE", "missing")
+		t.Setenv("AWS_REGION", "eu-west-1")
+		_, err = auth.FindAWSCredentials(ctx)
+		assert.ErrorContains(t, err, "profile missing is not in the shared config")
+	})
+
+	t.Run("sso", func(t *testing.T) {
+		home := isolateAWS(t)
+		writeFile(t, filepath.Join(home, ".aws", "config"), "[profile dev]\nregion = us-west-2\nsso_session = corp\n\n[sso-session corp]\nsso_start_url = https://corp.awsapps.com/start\n")
+		t.Setenv("AWS_PROFILE", "dev")
+		_, err := auth.FindAWSCredentials(ctx)
+		assert.ErrorContains(t, err, "no SSO login for profile dev")
+
+		sum := sha1.Sum([]byte("corp"))
+		cache := filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json")
+		writeFile(t, cache, `{"expiresAt": "`+time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)+`"}`)
+		_, err = auth.FindAWSCredentials(ctx)
+		assert.ErrorContains(t, err, "SSO login of profile dev expired")
+
+		writeFile(t, cache, `{"expiresAt": "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}`)
+		res, err := auth.FindAWSCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "sso", res.Source)
+	})
+
+	t.Run("instance metadata", func(t *testing.T) {
+		isolateAWS(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut || r.URL.Path != "/latest/api/token" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte("token"))
+		}))
+		t.Cleanup(server.Close)
+		t.Setenv("AWS_REGION", "eu-west-1")
+		t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+		t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+		res, err := auth.FindAWSCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "instance metadata", res.Source)
+	})
+}
+
+func TestRequireAWSCredentials(t *testing.T) {
+	isolateAWS(t)
+	recorder := &skipRecorder{}
+	assert.Empty(t, auth.RequireAWSCredentials(recorder))
+	assert.True(t, recorder.skipped)
+
+	t.Setenv("AWS_REGION", "eu-west-1")
+	fatal := &fatalRecorder{}
+	auth.RequireAWSCredentials(fatal)
+	assert.Contains(t, fatal.fatal, "NO AWS CREDENTIALS FOUND!")
+	assert.Contains(t, fatal.fatal, "aws sso login --profile default")
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The errors FindAzureCredentials returns, for callers to tell apart with
+// errors.Is.
+var (
+	// ErrNoAzureSubscription means AZURE_SUBSCRIPTION_ID is not set and the
+	// Azure CLI has no default subscription.
+	ErrNoAzureSubscription = errors.New("no Azure subscription: AZURE_SUBSCRIPTION_ID is not set")
+	// ErrNoAzureCredentials means no source of DefaultAzureCredential's
+	// chain has credentials, or one has them incompletely configured.
+	ErrNoAzureCredentials = errors.New("no Azure credentials found")
+)
+
+// AzureResult describes what FindAzureCredentials found.
+type AzureResult struct {
+	SubscriptionID string
+	TenantID       string
+	// Source is where DefaultAzureCredential's chain finds credentials:
+	// "environment", "workload identity", "managed identity" or
+	// "azure cli".
+	Source string
+}
+
+// azureProfile is what FindAzureCredentials reads of the Azure CLI's
+// azureProfile.json.
+type azureProfile struct {
+	Subscriptions []struct {
+		ID        string `json:"id"`
+		TenantID  string `json:"tenantId"`
+		IsDefault bool   `json:"isDefault"`
+	} `json:"subscriptions"`
+}
+
+// FindAzureCredentials finds the subscription and the source of credentials
+// of the Azure SDK's DefaultAzureCredential chain, in the environment, the
+// Azure CLI's login and the managed identity endpoints, without the SDK. It
+// only finds that credentials are present: it does not call Azure, so a
+// secret that is wrong or expired, or an expired CLI login, passes. The
+// instance metadata service is only probed, at
+// AZURE_POD_IDENTITY_AUTHORITY_HOST if it is set. Its errors wrap
+// ErrNoAzureSubscription or ErrNoAzureCredentials where they apply. The
+// AzureResult describes as much as was found, even with an error.
+func FindAzureCredentials(ctx context.Context) (AzureResult, error) {
+	res := AzureResult{SubscriptionID: os.Getenv("AZURE_SUBSCRIPTION_ID"), TenantID: os.Getenv("AZURE_TENANT_ID")}
+	profile, err := readAzureProfile()
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrNoAzureCredentials, err)
+	}
+	for _, sub := range profile.Subscriptions {
+		if res.SubscriptionID == "" && sub.IsDefault || sub.ID == res.SubscriptionID {
+			res.SubscriptionID = sub.ID
+			res.TenantID = cmp.Or(res.TenantID, sub.TenantID)
+		}
+	}
+	if res.SubscriptionID == "" {
+		return res, ErrNoAzureSubscription
+	}
+	res.Source, err = azureCredentialsSource(ctx, len(profile.Subscriptions) > 0)
+	return res, err
+}
+
+// readAzureProfile reads the Azure CLI's profile, in AZURE_CONFIG_DIR or
+// ~/.azure. It is empty if the CLI has not logged in.
+func readAzureProfile() (azureProfile, error) {
+	dir := os.Getenv("AZURE_CONFIG_DIR")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".azure")
+	}
+	var profile azureProfile
+	data, err := os.ReadFile(filepath.Join(dir, "azureProfile.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return profile, nil
+	}
+	if err != nil {
+		return profile, fmt.Errorf("failed to read the Azure CLI profile: %w", err)
+	}
+	// The CLI writes the file with a byte order mark.
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &profile); err != nil {
+		return profile, fmt.Errorf("failed to decode the Azure CLI profile: %w", err)
+	}
+	return profile, nil
+}
+
+// azureCredentialsSource returns the first source of DefaultAzureCredential's
+// chain that has credentials. cliLoggedIn says the Azure CLI has logged in.
+func azureCredentialsSource(ctx context.Context, cliLoggedIn bool) (string, error) {
+	tenant, client := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+	secret, certificate := os.Getenv("AZURE_CLIENT_SECRET"), os.Getenv("AZURE_CLIENT_CERTIFICATE_PATH")
+	user := os.Getenv("AZURE_USERNAME")
+	if secret != "" || certificate != "" || user != "" {
+		switch {
+		case tenant == "" || client == "":
+			return "", fmt.Errorf("%w: AZURE_TENANT_ID and AZURE_CLIENT_ID must be set with the client secret, certificate or username", ErrNoAzureCredentials)
+		case secret == "" && certificate != "":
+			if _, err := os.Stat(certificate); err != nil {
+				return "", fmt.Errorf("%w: the client certificate is missing: %w", ErrNoAzureCredentials, err)
+			}
+		case secret == "" && os.Getenv("AZURE_PASSWORD") == "":
+			return "", fmt.Errorf("%w: AZURE_USERNAME is set without AZURE_PASSWORD", ErrNoAzureCredentials)
+		}
+		return "environment", nil
+	}
+	if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+		if tenant == "" || client == "" {
+			return "", fmt.Errorf("%w: AZURE_TENANT_ID and AZURE_CLIENT_ID must be set with AZURE_FEDERATED_TOKEN_FILE", ErrNoAzureCredentials)
+		}
+		if _, err := os.Stat(tokenFile); err != nil {
+			return "", fmt.Errorf("%w: the federated token file is missing: %w", ErrNoAzureCredentials, err)
+		}
+		return "workload identity", nil
+	}
+	// App Service, Functions and Arc set the endpoint of their managed
+	// identity.
+	if os.Getenv("IDENTITY_ENDPOINT") != "" || os.Getenv("MSI_ENDPOINT") != "" {
+		return "managed identity", nil
+	}
+	// The CLI is checked before probing the instance metadata service, so
+	// developer machines do not wait for the probe.
+	if cliLoggedIn {
+		return "azure cli", nil
+	}
+	if azureInstanceMetadataAvailable(ctx) {
+		return "managed identity", nil
+	}
+	return "", fmt.Errorf("%w: none found in the environment, the Azure CLI or managed identity", ErrNoAzureCredentials)
+}
+
+// azureInstanceMetadataAvailable reports whether the Azure instance
+// metadata service, at AZURE_POD_IDENTITY_AUTHORITY_HOST if it is set,
+// answers.
+func azureInstanceMetadataAvailable(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, metadataProbeTimeout)
+	defer cancel()
+	endpoint := strings.TrimSuffix(cmp.Or(os.Getenv("AZURE_POD_IDENTITY_AUTHORITY_HOST"), "http://169.254.169.254"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/metadata/instance?api-version=2021-02-01", nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// RequireAzureCredentials skips the test if there is no Azure subscription,
+// fails it with an actionable message if FindAzureCredentials finds no
+// credentials, and returns the subscription ID. Unlike CheckGCPAuth it does
+// not obtain a token, so it catches missing credentials, not invalid ones.
+func RequireAzureCredentials(t testing.TB) string {
+	t.Helper()
+	res, err := FindAzureCredentials(context.Background())
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoAzureSubscription):
+		t.Skip("Skipping real integration test: AZURE_SUBSCRIPTION_ID environment variable is not set")
+	case errors.Is(err, ErrNoAzureCredentials):
+		t.Fatalf("%s", formatAzureAuthError(err))
+	default:
+		t.Fatalf("An unexpected error occurred while finding Azure credentials: %v", err)
+	}
+	return res.SubscriptionID
+}
+
+// formatAzureAuthError wraps an error of the credential chain in a message
+// explaining how to fix it.
+func formatAzureAuthError(err error) string {
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		NO AZURE CREDENTIALS FOUND!
+		---------------------------------------------------------------------
+		DefaultAzureCredential's chain has no credentials.
+
+		To fix this, please either:
+		   1. Log in with the Azure CLI:
+		      az login
+		   2. Or, in CI, set AZURE_TENANT_ID, AZURE_CLIENT_ID and
+		      AZURE_CLIENT_SECRET, or AZURE_FEDERATED_TOKEN_FILE for workload
+		      identity federation.
+
+		Original Error: %v
+		---------------------------------------------------------------------
+		`, err)
+}
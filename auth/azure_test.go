@@ -0,0 +1,126 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isolateAzure hides the Azure configuration of the environment from the
+// test, and returns the Azure CLI's new configuration directory.
+func isolateAzure(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AZURE_CONFIG_DIR", dir)
+	for _, name := range []string{
+		"AZURE_SUBSCRIPTION_ID", "AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET",
+		"AZURE_CLIENT_CERTIFICATE_PATH", "AZURE_USERNAME", "AZURE_PASSWORD", "AZURE_FEDERATED_TOKEN_FILE",
+		"IDENTITY_ENDPOINT", "MSI_ENDPOINT",
+	} {
+		t.Setenv(name, "")
+	}
+	// No instance metadata service answers.
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	t.Setenv("AZURE_POD_IDENTITY_AUTHORITY_HOST", server.URL)
+	return dir
+}
+
+func TestFindAzureCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no subscription", func(t *testing.T) {
+		isolateAzure(t)
+		_, err := auth.FindAzureCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAzureSubscription)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		isolateAzure(t)
+		t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+		_, err := auth.FindAzureCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAzureCredentials)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		isolateAzure(t)
+		t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+		t.Setenv("AZURE_CLIENT_SECRET", "secret")
+		_, err := auth.FindAzureCredentials(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoAzureCredentials, "the tenant and client are missing")
+
+		t.Setenv("AZURE_TENANT_ID", "tenant-1")
+		t.Setenv("AZURE_CLIENT_ID", "client-1")
+		res, err := auth.FindAzureCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.AzureResult{SubscriptionID: "sub-1", TenantID: "tenant-1", Source: "environment"}, res)
+	})
+
+	t.Run("workload identity", func(t *testing.T) {
+		isolateAzure(t)
+		t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+		t.Setenv("AZURE_TENANT_ID", "tenant-1")
+		t.Setenv("AZURE_CLIENT_ID", "client-1")
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		t.Setenv("AZURE_FEDERATED_TOKEN_FILE", tokenFile)
+		_, err := auth.FindAzureCredentials(ctx)
+		assert.ErrorContains(t, err, "federated token file is missing")
+
+		writeFile(t, tokenFile, "token")
+		res, err := auth.FindAzureCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "workload identity", res.Source)
+	})
+
+	t.Run("azure cli", func(t *testing.T) {
+		dir := isolateAzure(t)
+		writeFile(t, filepath.Join(dir, "azureProfile.json"), "\xef\xbb\xbf"+`{"subscriptions": [
+			{"id": "sub-1", "tenantId": "tenant-1", "isDefault": false},
+			{"id": "sub-2", "tenantId": "tenant-2", "isDefault": true}
+		]}`)
+		res, err := auth.FindAzureCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.AzureResult{SubscriptionID: "sub-2", TenantID: "tenant-2", Source: "azure cli"}, res)
+
+		t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+		res, err = auth.FindAzureCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-1", res.TenantID)
+	})
+
+	t.Run("managed identity", func(t *testing.T) {
+		isolateAzure(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Metadata") != "true" {
+				http.Error(w, "missing header", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte("{}"))
+		}))
+		t.Cleanup(server.Close)
+		t.Setenv("AZURE_POD_IDENTITY_AUTHORITY_HOST", server.URL)
+		t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+		res, err := auth.FindAzureCredentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "managed identity", res.Source)
+	})
+}
+
+func TestRequireAzureCredentials(t *testing.T) {
+	isolateAzure(t)
+	recorder := &skipRecorder{}
+	assert.Empty(t, auth.RequireAzureCredentials(recorder))
+	assert.True(t, recorder.skipped)
+
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "sub-1")
+	fatal := &fatalRecorder{}
+	auth.RequireAzureCredentials(fatal)
+	assert.Contains(t, fatal.fatal, "NO AZURE CREDENTIALS FOUND!")
+	assert.Contains(t, fatal.fatal, "az login")
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkCache holds the successful outcomes of the checks of CheckGCPAuth
+// and CheckGCPAdvancedAuth by checkKey, so each check runs once per process
+// rather than once per test. Failures are not kept, so a transient one fails
+// only the tests that waited on it.
+var checkCache sync.Map
+
+// checkEnv are the environment variables that change the outcome of a
+// check, so tests that set them with t.Setenv are checked again.
+var checkEnv = []string{
+	"GCP_PROJECT_ID", "GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_CLOUD_QUOTA_PROJECT",
+	"GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES", "CLOUDSDK_CONFIG", "GCE_METADATA_HOST", "HOME",
+}
+
+// checkExpiryMargin is how long before the token a check obtained expires
+// that the check runs again.
+const checkExpiryMargin = time.Minute
+
+// cachedCheck is the outcome of a check, made once.
+type cachedCheck struct {
+	once sync.Once
+	res  Result
+	err  error
+}
+
+// cachedCheckAuth returns the outcome of CheckAuth for opts, running it only
+// if it has not succeeded for the same options and environment, or the token
+// it obtained is about to expire. The check does not stop when ctx is
+// cancelled, as other callers may be waiting on it.
+func cachedCheckAuth(ctx context.Context, opts Options) (Result, error) {
+	key := fmt.Sprintf("%+v", opts)
+	for _, name := range checkEnv {
+		key += fmt.Sprintf(" %s=%q", name, os.Getenv(name))
+	}
+	ctx = context.WithoutCancel(ctx)
+	if v, ok := checkCache.Load(key); ok {
+		check := v.(*cachedCheck)
+		if check.run(ctx, opts); !check.stale() {
+			return check.result(key)
+		}
+		checkCache.CompareAndDelete(key, check)
+	}
+	v, _ := checkCache.LoadOrStore(key, &cachedCheck{})
+	check := v.(*cachedCheck)
+	check.run(ctx, opts)
+	return check.result(key)
+}
+
+// run runs the check, unless it has run.
+func (c *cachedCheck) run(ctx context.Context, opts Options) {
+	c.once.Do(func() { c.res, c.err = CheckAuth(ctx, opts) })
+}
+
+// result returns the outcome of the check stored under key, and forgets it
+// if it failed, so the next caller checks again.
+func (c *cachedCheck) result(key string) (Result, error) {
+	if c.err != nil {
+		checkCache.CompareAndDelete(key, c)
+	}
+	return c.res, c.err
+}
+
+// stale reports whether the token the check obtained is about to expire.
+func (c *cachedCheck) stale() bool {
+	return c.err == nil && !c.res.TokenExpiry.IsZero() && time.Now().Add(checkExpiryMargin).After(c.res.TokenExpiry)
+}
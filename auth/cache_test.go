@@ -0,0 +1,86 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingTokenServer starts a token endpoint that grants access tokens
+// expiring in expiresIn seconds and counts its requests, and returns its URL.
+func newCountingTokenServer(t *testing.T, expiresIn int, requests *atomic.Int32) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access-token", "expires_in": expiresIn})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCheckGCPAuth_Cached(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	var requests atomic.Int32
+	writeServiceAccountKey(t, key, newCountingTokenServer(t, 3600, &requests))
+
+	for range 5 {
+		assert.Equal(t, "test-project", auth.CheckGCPAuth(t))
+	}
+	assert.Equal(t, int32(1), requests.Load(), "the check runs once")
+
+	// Other credentials are checked again.
+	writeServiceAccountKey(t, key, newCountingTokenServer(t, 3600, &requests))
+	auth.CheckGCPAuth(t)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestCheckGCPAuth_CachedUntilTokenExpires(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	var requests atomic.Int32
+	// The check runs again within a minute of the token expiring.
+	writeServiceAccountKey(t, key, newCountingTokenServer(t, 30, &requests))
+
+	auth.CheckGCPAuth(t)
+	auth.CheckGCPAuth(t)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestCheckGCPAuth_FailureNotCached(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	// The token endpoint fails once, as on a transient network error.
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access-token", "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+	writeServiceAccountKey(t, key, server.URL)
+
+	recorder := &fatalRecorder{}
+	auth.CheckGCPAuth(recorder)
+	assert.NotEmpty(t, recorder.fatal, "the first check fails")
+
+	// The failure is not cached, but the success that follows is.
+	assert.Equal(t, "test-project", auth.CheckGCPAuth(t))
+	auth.CheckGCPAuth(t)
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/idtoken"
+)
+
+// The errors CheckAuth and IDTokenClient return, wrapping the underlying
+// error, for callers to tell apart with errors.Is.
+var (
+	// ErrNoProject means no project was given and GCP_PROJECT_ID is not set.
+	ErrNoProject = errors.New("no GCP project: GCP_PROJECT_ID is not set")
+	// ErrNoADC means the Application Default Credentials are missing,
+	// expired or unusable.
+	ErrNoADC = errors.New("no valid Application Default Credentials")
+	// ErrUserCredentials means the Application Default Credentials are user
+	// credentials, which cannot mint ID tokens.
+	ErrUserCredentials = errors.New("user credentials cannot mint ID tokens")
+	// ErrTokenExpired means a token could not be obtained from the
+	// Application Default Credentials because their refresh token has expired
+	// or been revoked, or reauthentication is required.
+	ErrTokenExpired = errors.New("credentials need refreshing: refresh token expired or revoked")
+	// ErrQuotaProjectMismatch means the Application Default Credentials
+	// bill a quota project other than the project checked, which is not
+	// allowed to differ.
+	ErrQuotaProjectMismatch = errors.New("quota project does not match the project")
+	// ErrWorkloadIdentity means the Application Default Credentials are
+	// workload identity federation credentials, e.g. from GitHub Actions
+	// OIDC, that cannot be used as they are configured.
+	ErrWorkloadIdentity = errors.New("workload identity federation credentials cannot be used")
+)
+
+// cloudPlatformScope is the scope of the token CheckAuth obtains.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Options select what CheckAuth verifies.
+type Options struct {
+	// ProjectID is the project to check against. Empty means the
+	// GCP_PROJECT_ID environment variable.
+	ProjectID string
+	// IDTokens also checks that the credentials can mint ID tokens, as
+	// invoking secure Cloud Run services needs.
+	IDTokens bool
+	// Token also obtains an access token, so credentials whose refresh token
+	// has expired or been revoked fail the check rather than the first real
+	// API call. It makes a network call.
+	Token bool
+	// AllowedQuotaProjects are the quota projects the credentials may set
+	// other than ProjectID, or "*" for any. A mismatched quota project makes
+	// APIs such as BigQuery and Firestore answer with confusing 403s.
+	AllowedQuotaProjects []string
+}
+
+// Result describes the credentials CheckAuth found.
+type Result struct {
+	// ProjectID is the project checked against.
+	ProjectID string
+	// Principal is the identity of the credentials, as far as it can be told
+	// without a network call: WhoAmI also looks up the email of users.
+	Principal Principal
+	// QuotaProject is the project the credentials bill API usage to, from
+	// GOOGLE_CLOUD_QUOTA_PROJECT or the credentials file, or "" if they set
+	// none.
+	QuotaProject string
+	// TokenExpiry is when the access token obtained under Options.Token
+	// expires, or zero.
+	TokenExpiry time.Time
+}
+
+// CheckAuth verifies that the Application Default Credentials can be used
+// against GCP, without a testing.T, e.g. for a developer CLI. Its errors wrap
+// ErrNoProject, ErrNoADC, ErrQuotaProjectMismatch, ErrWorkloadIdentity,
+// ErrUserCredentials or ErrTokenExpired where they apply. The Result
+// describes as much of the credentials as was found, even with an error.
+// CheckGCPAuth and CheckGCPAdvancedAuth are the test helpers built on it.
+func CheckAuth(ctx context.Context, opts Options) (Result, error) {
+	res := Result{ProjectID: opts.ProjectID}
+	if res.ProjectID == "" {
+		res.ProjectID = os.Getenv("GCP_PROJECT_ID")
+	}
+	if res.ProjectID == "" {
+		return res, ErrNoProject
+	}
+
+	creds, err := google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	if err != nil {
+		return res, fmt.Errorf("%w: %w", ErrNoADC, err)
+	}
+	res.Principal = principalOf(creds)
+	var file struct {
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if json.Unmarshal(creds.JSON, &file) == nil {
+		res.QuotaProject = file.QuotaProjectID
+	}
+	if quota := os.Getenv("GOOGLE_CLOUD_QUOTA_PROJECT"); quota != "" {
+		res.QuotaProject = quota
+	}
+	if res.QuotaProject != "" && res.QuotaProject != res.ProjectID &&
+		!slices.Contains(opts.AllowedQuotaProjects, res.QuotaProject) && !slices.Contains(opts.AllowedQuotaProjects, "*") {
+		return res, fmt.Errorf("%w: quota project %s, project %s", ErrQuotaProjectMismatch, res.QuotaProject, res.ProjectID)
+	}
+	if res.Principal.Type == PrincipalWorkloadIdentity {
+		if err := checkWorkloadIdentity(creds.JSON, opts.IDTokens); err != nil {
+			return res, err
+		}
+	}
+
+	// Check basic connectivity and authentication for resource management.
+	client, err := pubsub.NewClient(ctx, res.ProjectID)
+	if err != nil {
+		errStr := err.Error()
+		if strings.Contains(errStr, "dialing") || strings.Contains(errStr, "default credentials") {
+			return res, fmt.Errorf("%w: %w", ErrNoADC, err)
+		}
+		return res, fmt.Errorf("failed to connect to GCP: %w", err)
+	}
+	_ = client.Close()
+
+	if opts.IDTokens {
+		// This validates the credential type without making a network call
+		// for user credentials.
+		if _, err := idtoken.NewTokenSource(ctx, "https://example.com"); err != nil {
+			return res, idTokenError(res.Principal, err)
+		}
+	}
+	if opts.Token {
+		token, err := creds.TokenSource.Token()
+		if err != nil {
+			return res, tokenError(res.Principal, err)
+		}
+		res.TokenExpiry = token.Expiry
+	}
+	return res, nil
+}
+
+// IDTokenClient returns an HTTP client whose requests carry a Google-signed
+// ID token for audience, as NewIDTokenClient does, without a testing.T. Its
+// errors wrap ErrNoADC, ErrWorkloadIdentity or ErrUserCredentials where they
+// apply.
+func IDTokenClient(ctx context.Context, audience string) (*http.Client, error) {
+	var principal Principal
+	if creds, err := google.FindDefaultCredentials(ctx, cloudPlatformScope); err == nil {
+		principal = principalOf(creds)
+		if principal.Type == PrincipalWorkloadIdentity {
+			if err := checkWorkloadIdentity(creds.JSON, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	client, err := idtoken.NewClient(ctx, audience)
+	if err != nil {
+		return nil, idTokenError(principal, err)
+	}
+	return client, nil
+}
+
+// tokenError classifies an error of obtaining a token for p.
+func tokenError(p Principal, err error) error {
+	if p.Type == PrincipalWorkloadIdentity {
+		return fmt.Errorf("%w: %w", ErrWorkloadIdentity, err)
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" ||
+		strings.Contains(err.Error(), "invalid_grant") || strings.Contains(err.Error(), "reauth") {
+		return fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	}
+	return fmt.Errorf("failed to obtain a token: %w", err)
+}
+
+// idTokenError classifies an error of creating an ID token source for p.
+func idTokenError(p Principal, err error) error {
+	errStr := err.Error()
+	switch {
+	case p.Type == PrincipalWorkloadIdentity:
+		return fmt.Errorf("%w: %w", ErrWorkloadIdentity, err)
+	case strings.Contains(errStr, "unsupported credentials type"):
+		return fmt.Errorf("%w: %w", ErrUserCredentials, err)
+	case strings.Contains(errStr, "couldn't find any credentials") || strings.Contains(errStr, "default credentials"):
+		return fmt.Errorf("%w: %w", ErrNoADC, err)
+	}
+	return fmt.Errorf("failed to create an ID token source: %w", err)
+}
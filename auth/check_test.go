@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAuth(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no project", func(t *testing.T) {
+		t.Setenv("GCP_PROJECT_ID", "")
+		_, err := auth.CheckAuth(ctx, auth.Options{})
+		assert.ErrorIs(t, err, auth.ErrNoProject)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+		res, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project"})
+		assert.ErrorIs(t, err, auth.ErrNoADC)
+		assert.Equal(t, "test-project", res.ProjectID)
+	})
+
+	t.Run("user credentials", func(t *testing.T) {
+		writeUserCredentials(t, "")
+		t.Setenv("GCP_PROJECT_ID", "env-project")
+		res, err := auth.CheckAuth(ctx, auth.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, "env-project", res.ProjectID)
+		assert.Equal(t, "authorized_user", res.Principal.CredentialsType)
+		assert.Equal(t, auth.PrincipalUser, res.Principal.Type)
+
+		res, err = auth.CheckAuth(ctx, auth.Options{IDTokens: true})
+		assert.ErrorIs(t, err, auth.ErrUserCredentials)
+		assert.Equal(t, "authorized_user", res.Principal.CredentialsType)
+	})
+
+	t.Run("quota project", func(t *testing.T) {
+		writeUserCredentials(t, "billing-project")
+		res, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project"})
+		assert.ErrorIs(t, err, auth.ErrQuotaProjectMismatch)
+		assert.Equal(t, "billing-project", res.QuotaProject)
+
+		_, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project", AllowedQuotaProjects: []string{"billing-project"}})
+		assert.NoError(t, err)
+		_, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project", AllowedQuotaProjects: []string{"*"}})
+		assert.NoError(t, err)
+		_, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "billing-project"})
+		assert.NoError(t, err)
+
+		t.Setenv("GOOGLE_CLOUD_QUOTA_PROJECT", "test-project")
+		res, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project"})
+		assert.NoError(t, err, "the environment overrides the credentials file")
+		assert.Equal(t, "test-project", res.QuotaProject)
+	})
+
+	t.Run("service account", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		writeServiceAccountKey(t, key, newTokenServer(t, key))
+		res, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project", IDTokens: true, Token: true})
+		require.NoError(t, err)
+		assert.Equal(t, "service_account", res.Principal.CredentialsType)
+		assert.Equal(t, "invoker@test-project.iam.gserviceaccount.com", res.Principal.Email)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), res.TokenExpiry, time.Minute)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		writeServiceAccountKey(t, key, newRevokedTokenServer(t))
+		res, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "test-project", Token: true})
+		assert.ErrorIs(t, err, auth.ErrTokenExpired)
+		assert.Equal(t, "service_account", res.Principal.CredentialsType)
+		assert.Zero(t, res.TokenExpiry)
+	})
+}
+
+// newRevokedTokenServer starts a token endpoint that refuses every grant as
+// Google does when reauthentication is required, and returns its URL.
+func newRevokedTokenServer(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "reauth related error (invalid_rapt)"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCheckGCPAuth_TokenExpired(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeServiceAccountKey(t, key, newRevokedTokenServer(t))
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	recorder := &fatalRecorder{}
+	auth.CheckGCPAuth(recorder)
+	assert.Contains(t, recorder.fatal, "GCP CREDENTIALS EXPIRED!")
+	assert.Contains(t, recorder.fatal, "invalid_rapt")
+}
+
+func TestIDTokenClient_UserCredentials(t *testing.T) {
+	writeUserCredentials(t, "")
+	client, err := auth.IDTokenClient(context.Background(), "https://service.example.com")
+	assert.Nil(t, client)
+	assert.ErrorIs(t, err, auth.ErrUserCredentials)
+}
+
+func TestCheckGCPAuth_SkipsWithoutProject(t *testing.T) {
+	t.Setenv("GCP_PROJECT_ID", "")
+	recorder := &skipRecorder{}
+	assert.Empty(t, auth.CheckGCPAuth(recorder))
+	assert.True(t, recorder.skipped)
+}
+
+func TestCheckGCPAdvancedAuth_UserCredentials(t *testing.T) {
+	writeUserCredentials(t, "")
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	recorder := &fatalRecorder{}
+	assert.Equal(t, "test-project", auth.CheckGCPAdvancedAuth(recorder, false))
+	assert.Contains(t, recorder.fatal, "GCP INVOCATION AUTHENTICATION FAILED!")
+}
+
+// skipRecorder is a testing.TB that records a call of Skip instead of
+// skipping the test.
+type skipRecorder struct {
+	testing.TB
+	skipped bool
+}
+
+func (r *skipRecorder) Helper() {}
+
+func (r *skipRecorder) Skip(...any) { r.skipped = true }
+
+func TestCheckGCPAuth_QuotaProjectMismatch(t *testing.T) {
+	writeUserCredentials(t, "billing-project")
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+	recorder := &fatalRecorder{}
+	auth.CheckGCPAuth(recorder)
+	assert.Contains(t, recorder.fatal, "GCP QUOTA PROJECT MISMATCH!")
+	assert.Contains(t, recorder.fatal, "gcloud auth application-default set-quota-project test-project")
+	assert.Contains(t, recorder.fatal, "GCP_ALLOWED_QUOTA_PROJECTS=billing-project")
+}
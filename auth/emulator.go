@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// emulatorToken is the access token the emulator credentials carry. The
+// Firebase emulators treat "owner" as an admin, so security rules do not
+// apply; the other emulators ignore it.
+const emulatorToken = "owner"
+
+// EmulatorTokenSource returns a token source of a fake access token, for
+// clients of emulators that need a token. Google APIs reject the token, so a
+// client pointed at a real service by mistake fails rather than making
+// billable calls.
+func EmulatorTokenSource() oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: emulatorToken, TokenType: "Bearer"})
+}
+
+// EmulatorCredentials returns the client options that authenticate a Google
+// Cloud client with EmulatorTokenSource, so it never looks up the
+// Application Default Credentials.
+func EmulatorCredentials() []option.ClientOption {
+	return []option.ClientOption{option.WithTokenSource(EmulatorTokenSource())}
+}
+
+// ScrubCredentials hides the Application Default Credentials from the rest
+// of the test with t.Setenv, so a test against emulators cannot use real
+// credentials even where a client falls back to them: looking them up fails,
+// as WhoAmI does with ErrNoADC, rather than finding a credentials file,
+// gcloud's configuration or the metadata server. Like t.Setenv, it cannot be used in
+// parallel tests.
+func ScrubCredentials(t testing.TB) {
+	t.Helper()
+	dir := t.TempDir()
+	// A missing credentials file stops the lookup before it reaches gcloud's
+	// well-known file or the metadata server.
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(dir, "no-credentials.json"))
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	t.Setenv("GOOGLE_CLOUD_QUOTA_PROJECT", "")
+}
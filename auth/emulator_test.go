@@ -0,0 +1,35 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func TestEmulatorTokenSource(t *testing.T) {
+	token, err := auth.EmulatorTokenSource().Token()
+	require.NoError(t, err)
+	assert.Equal(t, "owner", token.AccessToken)
+	assert.True(t, token.Valid())
+}
+
+func TestScrubCredentials(t *testing.T) {
+	ctx := context.Background()
+	writeUserCredentials(t, "dev-project")
+	auth.ScrubCredentials(t)
+
+	_, err := google.FindDefaultCredentials(ctx)
+	assert.Error(t, err)
+	_, err = auth.WhoAmI(ctx)
+	assert.ErrorIs(t, err, auth.ErrNoADC)
+
+	// Clients with the emulator credentials do not need the ADC.
+	client, err := pubsub.NewClient(ctx, "test-project", auth.EmulatorCredentials()...)
+	require.NoError(t, err)
+	assert.NoError(t, client.Close())
+}
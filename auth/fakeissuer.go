@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// ErrInvalidToken means a token was not issued by a FakeIssuer for the
+// audience, or has expired.
+var ErrInvalidToken = errors.New("invalid token")
+
+// FakeIssuer is an in-process OpenID Connect issuer for unit tests of
+// handlers that validate JWTs, without a container or a real identity
+// provider. It serves its discovery document and JWKS over httptest, so
+// handlers configured with its URL verify its tokens as they would those of
+// a real issuer.
+type FakeIssuer struct {
+	// URL is the issuer, the iss claim of its tokens. It serves
+	// /.well-known/openid-configuration and the JWKS at JWKSURL.
+	URL    string
+	key    *rsa.PrivateKey
+	signer jose.Signer
+}
+
+// fakeIssuerKeyID is the kid of a FakeIssuer's signing key.
+const fakeIssuerKeyID = "fake-issuer-key"
+
+// NewFakeIssuer starts a FakeIssuer with a new RS256 signing key. It stops
+// when the test ends.
+func NewFakeIssuer(t testing.TB) *FakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate the fake issuer's key: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", fakeIssuerKeyID))
+	if err != nil {
+		t.Fatalf("Failed to create the fake issuer's signer: %v", err)
+	}
+	issuer := &FakeIssuer{key: key, signer: signer}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                issuer.URL,
+			"jwks_uri":                              issuer.JWKSURL(),
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"response_types_supported":              []string{"id_token"},
+			"subject_types_supported":               []string{"public"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key: &key.PublicKey, KeyID: fakeIssuerKeyID, Algorithm: string(jose.RS256), Use: "sig",
+		}}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer.URL = server.URL
+	return issuer
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// JWKSURL returns the URL of the issuer's JSON Web Key Set.
+func (i *FakeIssuer) JWKSURL() string {
+	return i.URL + "/jwks"
+}
+
+// Mint returns a JWT signed by the issuer with claims. The token's iss is
+// the issuer, iat now and exp an hour from now unless claims set them; a
+// nil claim leaves the claim out. Times may be given as time.Time.
+func (i *FakeIssuer) Mint(t testing.TB, claims map[string]any) string {
+	t.Helper()
+	now := time.Now()
+	all := map[string]any{"iss": i.URL, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+	for name, value := range claims {
+		switch v := value.(type) {
+		case nil:
+			delete(all, name)
+		case time.Time:
+			all[name] = v.Unix()
+		default:
+			all[name] = v
+		}
+	}
+	token, err := jwt.Signed(i.signer).Claims(all).Serialize()
+	if err != nil {
+		t.Fatalf("Failed to mint a token: %v", err)
+	}
+	return token
+}
+
+// Verify checks that token was signed by the issuer, for audience, and has
+// not expired, as a token-validating handler would, and returns its claims.
+// Its errors wrap ErrInvalidToken.
+func (i *FakeIssuer) Verify(token, audience string) (map[string]any, error) {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{jose.RS256})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	var registered jwt.Claims
+	var claims map[string]any
+	if err := parsed.Claims(&i.key.PublicKey, &registered, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	expected := jwt.Expected{Issuer: i.URL, AnyAudience: jwt.Audience{audience}, Time: time.Now()}
+	if err := registered.Validate(expected); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// claimsKey is the context key of the claims the middleware admitted a
+// request with.
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the token the middleware of a
+// FakeIssuer, or InjectClaims, admitted a request with.
+func ClaimsFromContext(ctx context.Context) (map[string]any, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(map[string]any)
+	return claims, ok
+}
+
+// Middleware returns verification middleware that admits requests with an
+// "Authorization: Bearer" token that Verify accepts for audience, with the
+// token's claims in their context, and rejects any other with 401
+// Unauthorized.
+func (i *FakeIssuer) Middleware(audience string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+			claims, err := i.Verify(token, audience)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// InjectClaims returns a middleware test double that admits every request
+// with claims in its context, without a token, for unit tests of handlers
+// that sit behind verification middleware.
+func InjectClaims(claims map[string]any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, maps.Clone(claims))))
+		})
+	}
+}
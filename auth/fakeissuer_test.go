@@ -0,0 +1,96 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeIssuer_Verify(t *testing.T) {
+	issuer := auth.NewFakeIssuer(t)
+	token := issuer.Mint(t, map[string]any{"aud": "my-api", "sub": "user-1", "roles": []string{"admin"}})
+
+	claims, err := issuer.Verify(token, "my-api")
+	require.NoError(t, err)
+	assert.Equal(t, issuer.URL, claims["iss"])
+	assert.Equal(t, "user-1", claims["sub"])
+	assert.Equal(t, []any{"admin"}, claims["roles"])
+
+	_, err = issuer.Verify(token, "other-api")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	expired := issuer.Mint(t, map[string]any{"aud": "my-api", "exp": time.Now().Add(-time.Hour)})
+	_, err = issuer.Verify(expired, "my-api")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	_, err = auth.NewFakeIssuer(t).Verify(token, "my-api")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken, "another issuer's key does not verify the token")
+}
+
+func TestFakeIssuer_Discovery(t *testing.T) {
+	issuer := auth.NewFakeIssuer(t)
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	getJSON(t, issuer.URL+"/.well-known/openid-configuration", &discovery)
+	assert.Equal(t, issuer.URL, discovery.Issuer)
+	assert.Equal(t, issuer.JWKSURL(), discovery.JWKSURI)
+
+	// A handler verifying with the published keys accepts the tokens.
+	var keys jose.JSONWebKeySet
+	getJSON(t, discovery.JWKSURI, &keys)
+	token, err := jwt.ParseSigned(issuer.Mint(t, map[string]any{"sub": "user-1"}), []jose.SignatureAlgorithm{jose.RS256})
+	require.NoError(t, err)
+	var claims jwt.Claims
+	require.NoError(t, token.Claims(keys, &claims))
+	assert.Equal(t, "user-1", claims.Subject)
+}
+
+// getJSON decodes the JSON response to a GET of url into v.
+func getJSON(t *testing.T, url string, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func TestFakeIssuer_Middleware(t *testing.T) {
+	issuer := auth.NewFakeIssuer(t)
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(claims["sub"].(string)))
+	})
+	handler := issuer.Middleware("my-api")(whoami)
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve("Bearer " + issuer.Mint(t, map[string]any{"aud": "my-api", "sub": "user-1"}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user-1", rec.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, serve("").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer "+issuer.Mint(t, map[string]any{"aud": "other-api"})).Code)
+
+	rec = httptest.NewRecorder()
+	auth.InjectClaims(map[string]any{"sub": "user-2"})(whoami).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "user-2", rec.Body.String())
+}
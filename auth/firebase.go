@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// firebaseCustomTokenAudience is the aud claim of Firebase custom tokens.
+const firebaseCustomTokenAudience = "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit"
+
+// firebaseEmulatorServiceAccount is the issuer of the custom tokens minted
+// for the Auth emulator, which does not check it.
+const firebaseEmulatorServiceAccount = "firebase-auth-emulator@example.com"
+
+// firebaseReservedClaims are the claim names Firebase custom tokens cannot
+// carry as developer claims.
+var firebaseReservedClaims = []string{
+	"acr", "amr", "at_hash", "aud", "auth_time", "azp", "cnf", "c_hash",
+	"exp", "firebase", "iat", "iss", "jti", "nbf", "nonce", "sub",
+}
+
+// MintFirebaseCustomToken returns a Firebase custom token for uid with the
+// developer claims, which become custom claims of the user's ID tokens and
+// request.auth.token in security rules. The token is unsigned, which only
+// the Firebase Auth emulator accepts.
+func MintFirebaseCustomToken(uid string, claims map[string]any) (string, error) {
+	if uid == "" || len(uid) > 128 {
+		return "", fmt.Errorf("invalid uid %q: it must be 1 to 128 characters", uid)
+	}
+	for name := range claims {
+		if slices.Contains(firebaseReservedClaims, name) {
+			return "", fmt.Errorf("developer claim %q is reserved", name)
+		}
+	}
+	now := time.Now()
+	payload := map[string]any{
+		"iss": firebaseEmulatorServiceAccount,
+		"sub": firebaseEmulatorServiceAccount,
+		"aud": firebaseCustomTokenAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+		"uid": uid,
+	}
+	if len(claims) > 0 {
+		payload["claims"] = claims
+	}
+	header, err := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode custom token header: %w", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode custom token claims: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body) + ".", nil
+}
+
+// FirebaseTokens are the tokens of a user signed in to the Firebase Auth
+// emulator.
+type FirebaseTokens struct {
+	// IDToken authenticates the user to Firestore and other emulators, as
+	// an "Authorization: Bearer" header.
+	IDToken      string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// SignInWithCustomToken exchanges customToken for the user's tokens at the
+// Firebase Auth emulator at emulatorHost, host:port, or
+// FIREBASE_AUTH_EMULATOR_HOST if it is empty.
+func SignInWithCustomToken(ctx context.Context, emulatorHost, customToken string) (FirebaseTokens, error) {
+	if emulatorHost == "" {
+		emulatorHost = os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")
+	}
+	if emulatorHost == "" {
+		return FirebaseTokens{}, errors.New("no Firebase Auth emulator: FIREBASE_AUTH_EMULATOR_HOST is not set")
+	}
+	reqBody, err := json.Marshal(map[string]any{"token": customToken, "returnSecureToken": true})
+	if err != nil {
+		return FirebaseTokens{}, fmt.Errorf("failed to encode sign-in request: %w", err)
+	}
+	// The emulator accepts any API key.
+	url := "http://" + emulatorHost + "/identitytoolkit.googleapis.com/v1/accounts:signInWithCustomToken?key=fake-api-key"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return FirebaseTokens{}, fmt.Errorf("failed to create sign-in request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return FirebaseTokens{}, fmt.Errorf("failed to sign in to the Firebase Auth emulator: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result struct {
+		IDToken      string `json:"idToken"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    string `json:"expiresIn"`
+		Error        struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return FirebaseTokens{}, fmt.Errorf("failed to decode sign-in response (status %s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return FirebaseTokens{}, fmt.Errorf("failed to sign in to the Firebase Auth emulator: %s: %s", resp.Status, result.Error.Message)
+	}
+	seconds, _ := strconv.Atoi(result.ExpiresIn)
+	return FirebaseTokens{
+		IDToken:      result.IDToken,
+		RefreshToken: result.RefreshToken,
+		ExpiresIn:    time.Duration(seconds) * time.Second,
+	}, nil
+}
+
+// NewFirebaseIDToken signs uid in to the Firebase Auth emulator at
+// FIREBASE_AUTH_EMULATOR_HOST with the developer claims and returns an ID
+// token for it, for Firestore security rules tests. It fails the test if it
+// cannot.
+func NewFirebaseIDToken(t testing.TB, ctx context.Context, uid string, claims map[string]any) string {
+	t.Helper()
+	customToken, err := MintFirebaseCustomToken(uid, claims)
+	if err != nil {
+		t.Fatalf("Failed to mint a Firebase custom token: %v", err)
+	}
+	tokens, err := SignInWithCustomToken(ctx, "", customToken)
+	if err != nil {
+		t.Fatalf("Failed to get a Firebase ID token for %s: %v", uid, err)
+	}
+	return tokens.IDToken
+}
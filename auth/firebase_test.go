@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeJWTClaims returns the claims of a JWT without verifying it.
+func decodeJWTClaims(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+// newFakeAuthEmulator starts a fake of the Firebase Auth emulator's
+// signInWithCustomToken, which, like the emulator, issues unsigned ID
+// tokens with the custom token's uid and claims, and returns its host.
+func newFakeAuthEmulator(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		var custom map[string]any
+		if r.URL.Path == "/identitytoolkit.googleapis.com/v1/accounts:signInWithCustomToken" && json.NewDecoder(r.Body).Decode(&req) == nil {
+			if parts := strings.Split(req.Token, "."); len(parts) == 3 {
+				payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+				_ = json.Unmarshal(payload, &custom)
+			}
+		}
+		if custom["uid"] == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "INVALID_CUSTOM_TOKEN"}}`))
+			return
+		}
+		idClaims := map[string]any{"user_id": custom["uid"], "sub": custom["uid"]}
+		if claims, ok := custom["claims"].(map[string]any); ok {
+			for name, value := range claims {
+				idClaims[name] = value
+			}
+		}
+		payload, _ := json.Marshal(idClaims)
+		idToken := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		_ = json.NewEncoder(w).Encode(map[string]any{"idToken": idToken, "refreshToken": "refresh", "expiresIn": "3600"})
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestMintFirebaseCustomToken(t *testing.T) {
+	token, err := auth.MintFirebaseCustomToken("user-1", map[string]any{"admin": true})
+	require.NoError(t, err)
+	claims := decodeJWTClaims(t, token)
+	assert.Equal(t, "user-1", claims["uid"])
+	assert.Equal(t, map[string]any{"admin": true}, claims["claims"])
+	assert.Equal(t, "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit", claims["aud"])
+
+	_, err = auth.MintFirebaseCustomToken("", nil)
+	assert.Error(t, err)
+	_, err = auth.MintFirebaseCustomToken("user-1", map[string]any{"sub": "someone-else"})
+	assert.ErrorContains(t, err, "reserved")
+}
+
+func TestSignInWithCustomToken(t *testing.T) {
+	ctx := context.Background()
+	host := newFakeAuthEmulator(t)
+	customToken, err := auth.MintFirebaseCustomToken("user-1", map[string]any{"role": "editor"})
+	require.NoError(t, err)
+
+	tokens, err := auth.SignInWithCustomToken(ctx, host, customToken)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, tokens.ExpiresIn)
+	assert.Equal(t, "refresh", tokens.RefreshToken)
+	claims := decodeJWTClaims(t, tokens.IDToken)
+	assert.Equal(t, "user-1", claims["user_id"])
+	assert.Equal(t, "editor", claims["role"])
+
+	_, err = auth.SignInWithCustomToken(ctx, host, "not-a-token")
+	assert.ErrorContains(t, err, "INVALID_CUSTOM_TOKEN")
+
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", "")
+	_, err = auth.SignInWithCustomToken(ctx, "", customToken)
+	assert.ErrorContains(t, err, "FIREBASE_AUTH_EMULATOR_HOST")
+}
+
+func TestNewFirebaseIDToken(t *testing.T) {
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", newFakeAuthEmulator(t))
+	idToken := auth.NewFirebaseIDToken(t, context.Background(), "user-2", nil)
+	assert.Equal(t, "user-2", decodeJWTClaims(t, idToken)["user_id"])
+}
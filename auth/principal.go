@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2/google"
+)
+
+// PrincipalType is the kind of identity Application Default Credentials
+// belong to.
+type PrincipalType string
+
+const (
+	// PrincipalUser is a person, signed in with gcloud auth
+	// application-default login or through workforce identity federation.
+	PrincipalUser PrincipalType = "user"
+	// PrincipalServiceAccount is a service account, from a key file or the
+	// metadata server.
+	PrincipalServiceAccount PrincipalType = "service_account"
+	// PrincipalImpersonated is a service account impersonated by other
+	// credentials.
+	PrincipalImpersonated PrincipalType = "impersonated_service_account"
+	// PrincipalWorkloadIdentity is an external workload, e.g. a CI job,
+	// through workload identity federation.
+	PrincipalWorkloadIdentity PrincipalType = "workload_identity"
+)
+
+// metadataServerSource is the Source of credentials from the metadata
+// server.
+const metadataServerSource = "metadata server"
+
+// tokenInfoURL is the endpoint WhoAmI looks up the email of user
+// credentials at.
+const tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// Principal describes the identity of Application Default Credentials.
+type Principal struct {
+	Type PrincipalType
+	// CredentialsType is the type of the credentials file, e.g.
+	// "authorized_user" or "external_account", or "" for credentials from
+	// the metadata server.
+	CredentialsType string
+	// Email is the principal's email: the service account's, including the
+	// one impersonated by impersonated or workload identity credentials, or
+	// the user's. It is "" when it cannot be told.
+	Email string
+	// ProjectID is the project of the credentials, e.g. a service account
+	// key's, or else their quota project, or "".
+	ProjectID string
+	// Source is the path of the credentials file, or "metadata server".
+	Source string
+	// Provider is the workload identity provider of PrincipalWorkloadIdentity
+	// credentials, e.g.
+	// projects/123/locations/global/workloadIdentityPools/ci/providers/github.
+	Provider string
+}
+
+// String describes p for logs, e.g. "service account
+// runner@my-project.iam.gserviceaccount.com (project my-project, from
+// /secrets/key.json)".
+func (p Principal) String() string {
+	var b strings.Builder
+	b.WriteString(strings.ReplaceAll(string(p.Type), "_", " "))
+	if p.Email != "" {
+		b.WriteString(" " + p.Email)
+	}
+	var details []string
+	if p.ProjectID != "" {
+		details = append(details, "project "+p.ProjectID)
+	}
+	if p.Source != "" {
+		details = append(details, "from "+p.Source)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+	return b.String()
+}
+
+// WhoAmI describes the identity of the Application Default Credentials, for
+// tests and CLIs to log or assert on. Looking up the email of user or
+// metadata server credentials makes a network call, and leaves Email empty
+// if it fails. Its error wraps ErrNoADC if there are no credentials.
+func WhoAmI(ctx context.Context) (Principal, error) {
+	creds, err := google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrNoADC, err)
+	}
+	p := principalOf(creds)
+	if p.Email == "" {
+		p.Email = lookUpEmail(ctx, p, creds)
+	}
+	return p, nil
+}
+
+// principalOf describes the identity of creds as far as can be told without
+// a network call.
+func principalOf(creds *google.Credentials) Principal {
+	p := Principal{ProjectID: creds.ProjectID, Source: credentialsSource(creds)}
+	var file struct {
+		Type                           string `json:"type"`
+		ClientEmail                    string `json:"client_email"`
+		QuotaProjectID                 string `json:"quota_project_id"`
+		ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+		Audience                       string `json:"audience"`
+	}
+	if len(creds.JSON) == 0 || json.Unmarshal(creds.JSON, &file) != nil {
+		p.Type = PrincipalServiceAccount
+		return p
+	}
+	p.CredentialsType = file.Type
+	switch file.Type {
+	case "authorized_user", "external_account_authorized_user":
+		p.Type = PrincipalUser
+	case "service_account":
+		p.Type, p.Email = PrincipalServiceAccount, file.ClientEmail
+	case "impersonated_service_account":
+		p.Type, p.Email = PrincipalImpersonated, impersonatedEmail(file.ServiceAccountImpersonationURL)
+	case "external_account":
+		p.Type, p.Email = PrincipalWorkloadIdentity, impersonatedEmail(file.ServiceAccountImpersonationURL)
+		p.Provider = strings.TrimPrefix(file.Audience, "//iam.googleapis.com/")
+	default:
+		p.Type = PrincipalType(file.Type)
+	}
+	if p.ProjectID == "" {
+		p.ProjectID = file.QuotaProjectID
+	}
+	return p
+}
+
+// credentialsSource returns where creds were found: the credentials file
+// Application Default Credentials read, or the metadata server.
+func credentialsSource(creds *google.Credentials) string {
+	if len(creds.JSON) == 0 {
+		return metadataServerSource
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	return wellKnownCredentialsFile()
+}
+
+// wellKnownCredentialsFile returns the path gcloud auth application-default
+// login writes its credentials to.
+func wellKnownCredentialsFile() string {
+	const f = "application_default_credentials.json"
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", f)
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", f)
+}
+
+// impersonatedEmail returns the email of the service account a service
+// account impersonation URL impersonates, or "".
+func impersonatedEmail(impersonationURL string) string {
+	// The URL ends .../serviceAccounts/{email}:generateAccessToken.
+	_, account, ok := strings.Cut(impersonationURL, "/serviceAccounts/")
+	if !ok {
+		return ""
+	}
+	account, _, _ = strings.Cut(account, ":")
+	return account
+}
+
+// lookUpEmail returns the email of user or metadata server credentials, or
+// "" if it cannot be looked up.
+func lookUpEmail(ctx context.Context, p Principal, creds *google.Credentials) string {
+	switch {
+	case p.Source == metadataServerSource:
+		email, _ := metadata.EmailWithContext(ctx, "default")
+		return email
+	case p.CredentialsType != "authorized_user":
+		return ""
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return ""
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?access_token="+url.QueryEscape(token.AccessToken), nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	var info struct {
+		Email string `json:"email"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&info) != nil {
+		return ""
+	}
+	return info.Email
+}
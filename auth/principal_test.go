@@ -0,0 +1,99 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCredentials writes a credentials file and sets it as the Application
+// Default Credentials, returning its path.
+func writeCredentials(t *testing.T, creds string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "creds.json")
+	require.NoError(t, os.WriteFile(path, []byte(creds), 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	return path
+}
+
+func TestWhoAmI(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("service account", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		writeServiceAccountKey(t, key, "https://oauth2.example.com/token")
+		p, err := auth.WhoAmI(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.PrincipalServiceAccount, p.Type)
+		assert.Equal(t, "service_account", p.CredentialsType)
+		assert.Equal(t, "invoker@test-project.iam.gserviceaccount.com", p.Email)
+		assert.Equal(t, "test-project", p.ProjectID)
+		assert.Equal(t, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), p.Source)
+	})
+
+	t.Run("impersonated", func(t *testing.T) {
+		path := writeCredentials(t, `{
+			"type": "impersonated_service_account",
+			"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/deployer@prod.iam.gserviceaccount.com:generateAccessToken",
+			"source_credentials": {"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token"}
+		}`)
+		p, err := auth.WhoAmI(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.Principal{
+			Type:            auth.PrincipalImpersonated,
+			CredentialsType: "impersonated_service_account",
+			Email:           "deployer@prod.iam.gserviceaccount.com",
+			Source:          path,
+		}, p)
+	})
+
+	t.Run("workload identity", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("oidc-token"), 0600))
+		writeCredentials(t, `{
+			"type": "external_account",
+			"audience": "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/ci/providers/github",
+			"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+			"token_url": "https://sts.googleapis.com/v1/token",
+			"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/ci@build.iam.gserviceaccount.com:generateAccessToken",
+			"credential_source": {"file": "`+tokenFile+`"},
+			"quota_project_id": "build"
+		}`)
+		p, err := auth.WhoAmI(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.PrincipalWorkloadIdentity, p.Type)
+		assert.Equal(t, "ci@build.iam.gserviceaccount.com", p.Email)
+		assert.Equal(t, "build", p.ProjectID)
+		assert.Equal(t, "projects/123/locations/global/workloadIdentityPools/ci/providers/github", p.Provider)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		writeUserCredentials(t, "dev-project")
+		p, err := auth.WhoAmI(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, auth.PrincipalUser, p.Type)
+		assert.Equal(t, "authorized_user", p.CredentialsType)
+		assert.Equal(t, "dev-project", p.ProjectID)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+		_, err := auth.WhoAmI(ctx)
+		assert.ErrorIs(t, err, auth.ErrNoADC)
+	})
+}
+
+func TestPrincipal_String(t *testing.T) {
+	p := auth.Principal{Type: auth.PrincipalServiceAccount, Email: "runner@p.iam.gserviceaccount.com", ProjectID: "p", Source: "/secrets/key.json"}
+	assert.Equal(t, "service account runner@p.iam.gserviceaccount.com (project p, from /secrets/key.json)", p.String())
+	assert.Equal(t, "user (from metadata server)", auth.Principal{Type: auth.PrincipalUser, Source: "metadata server"}.String())
+	assert.Equal(t, "user", auth.Principal{Type: auth.PrincipalUser}.String())
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ErrMissingScopes means the Application Default Credentials' token was not
+// granted the scopes an API needs.
+var ErrMissingScopes = errors.New("credentials are missing OAuth scopes")
+
+// scopePrefix is the prefix of Google's OAuth scopes, which may be left out
+// of the scopes given to CheckScopes and RequireScopes.
+const scopePrefix = "https://www.googleapis.com/auth/"
+
+// impliedScopes are the scopes granted along with a scope.
+var impliedScopes = map[string][]string{
+	scopePrefix + "bigquery":                {scopePrefix + "bigquery.readonly"},
+	scopePrefix + "devstorage.full_control": {scopePrefix + "devstorage.read_write", scopePrefix + "devstorage.read_only"},
+	scopePrefix + "devstorage.read_write":   {scopePrefix + "devstorage.read_only"},
+}
+
+// nonCloudScopes are the prefixes of the scopes of Google Workspace APIs,
+// which the cloud-platform scope does not imply.
+var nonCloudScopes = []string{
+	scopePrefix + "drive", scopePrefix + "spreadsheets", scopePrefix + "documents",
+	scopePrefix + "gmail", scopePrefix + "calendar",
+}
+
+// CheckScopes checks that the Application Default Credentials' token was
+// granted scopes, e.g. "bigquery" or
+// "https://www.googleapis.com/auth/devstorage.read_write", and returns the
+// credentials' Principal and the scopes missing. User credentials are
+// granted the scopes of their gcloud login, and credentials on the metadata
+// server the access scopes of their VM, whatever scopes a client asks for.
+// The cloud-platform scope implies those of all Google Cloud APIs. It
+// obtains a token and, unless the token response lists its scopes, looks
+// them up over the network. Its errors wrap ErrNoADC, ErrTokenExpired or
+// ErrMissingScopes where they apply.
+func CheckScopes(ctx context.Context, scopes ...string) (Principal, []string, error) {
+	scopes = slices.Clone(scopes)
+	for i, scope := range scopes {
+		if !strings.Contains(scope, "://") {
+			scopes[i] = scopePrefix + scope
+		}
+	}
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return Principal{}, nil, fmt.Errorf("%w: %w", ErrNoADC, err)
+	}
+	principal := principalOf(creds)
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return principal, nil, tokenError(principal, err)
+	}
+	granted, err := grantedScopes(ctx, token)
+	if err != nil {
+		return principal, nil, err
+	}
+	for _, scope := range granted {
+		granted = append(granted, impliedScopes[scope]...)
+	}
+	cloudPlatform := slices.Contains(granted, cloudPlatformScope)
+	var missing []string
+	for _, scope := range scopes {
+		if slices.Contains(granted, scope) || cloudPlatform && !isNonCloudScope(scope) {
+			continue
+		}
+		missing = append(missing, scope)
+	}
+	if len(missing) > 0 {
+		return principal, missing, fmt.Errorf("%w: %s", ErrMissingScopes, strings.Join(missing, " "))
+	}
+	return principal, nil, nil
+}
+
+// isNonCloudScope reports whether scope is of a Google Workspace API.
+func isNonCloudScope(scope string) bool {
+	return slices.ContainsFunc(nonCloudScopes, func(prefix string) bool { return strings.HasPrefix(scope, prefix) })
+}
+
+// grantedScopes returns the scopes of token, from the token response or the
+// token info endpoint.
+func grantedScopes(ctx context.Context, token *oauth2.Token) ([]string, error) {
+	if scope, ok := token.Extra("scope").(string); ok && scope != "" {
+		return strings.Fields(scope), nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?access_token="+url.QueryEscape(token.AccessToken), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token info request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the token's scopes: %w", err)
+	}
+	defer resp.Body.Close()
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up the token's scopes: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode token info: %w", err)
+	}
+	return strings.Fields(info.Scope), nil
+}
+
+// RequireScopes fails the test, with an actionable message, unless the
+// Application Default Credentials' token was granted scopes, as CheckScopes
+// checks, e.g. before tests of BigQuery or Cloud Storage with user
+// credentials whose gcloud login did not ask for them.
+func RequireScopes(t testing.TB, ctx context.Context, scopes ...string) {
+	t.Helper()
+	principal, missing, err := CheckScopes(ctx, scopes...)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrMissingScopes):
+		t.Fatalf("%s", formatMissingScopesError(principal, missing, err))
+	case errors.Is(err, ErrNoADC):
+		t.Fatalf("%s", FormatGCPAuthError(err))
+	case errors.Is(err, ErrTokenExpired):
+		t.Fatalf("%s", formatTokenError(err))
+	default:
+		t.Fatalf("Failed to check the scopes of your GCP credentials: %v", err)
+	}
+}
+
+// formatMissingScopesError explains how to grant the credentials of p the
+// missing scopes.
+func formatMissingScopesError(p Principal, missing []string, err error) string {
+	var fix string
+	switch {
+	case p.Type == PrincipalUser:
+		// gcloud needs the cloud-platform scope for the credentials' own use.
+		scopes := []string{"openid", scopePrefix + "userinfo.email", cloudPlatformScope}
+		for _, scope := range missing {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		fix = fmt.Sprintf(`To fix this, please log in again asking for the scopes:
+		gcloud auth application-default login --scopes=%s`, strings.Join(scopes, ","))
+	case p.Source == metadataServerSource:
+		fix = `The scopes of the metadata server's credentials are the access
+		scopes of the VM. To fix this, stop the VM and give it the
+		cloud-platform scope, then control access with IAM roles:
+		gcloud compute instances set-service-account [INSTANCE] --scopes=cloud-platform`
+	default:
+		fix = "To fix this, please ask for the scopes when creating the credentials."
+	}
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		GCP CREDENTIALS MISSING SCOPES!
+		---------------------------------------------------------------------
+		Your Application Default Credentials (ADC), %s, were not
+		granted the OAuth scopes the tests need, so the APIs will reject
+		them with 403 errors.
+
+		%s
+
+		Original Error: %v
+		---------------------------------------------------------------------
+		`, p, fix, err)
+}
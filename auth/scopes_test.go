@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScopedUserCredentials writes user credentials whose token endpoint
+// grants scope, as Google's does on refresh, and sets them as the
+// Application Default Credentials.
+func writeScopedUserCredentials(t *testing.T, scope string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access-token", "expires_in": 3600, "scope": scope})
+	}))
+	t.Cleanup(server.Close)
+	creds, err := json.Marshal(map[string]string{
+		"type":          "authorized_user",
+		"client_id":     "id",
+		"client_secret": "secret",
+		"refresh_token": "token",
+		"token_uri":     server.URL,
+	})
+	require.NoError(t, err)
+	writeCredentials(t, string(creds))
+}
+
+func TestCheckScopes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing", func(t *testing.T) {
+		writeScopedUserCredentials(t, "openid https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/devstorage.read_write")
+		p, missing, err := auth.CheckScopes(ctx, "bigquery", "devstorage.read_only", "https://www.googleapis.com/auth/devstorage.read_write")
+		assert.ErrorIs(t, err, auth.ErrMissingScopes)
+		assert.Equal(t, []string{"https://www.googleapis.com/auth/bigquery"}, missing)
+		assert.Equal(t, auth.PrincipalUser, p.Type)
+	})
+
+	t.Run("cloud platform", func(t *testing.T) {
+		writeScopedUserCredentials(t, "https://www.googleapis.com/auth/cloud-platform")
+		_, missing, err := auth.CheckScopes(ctx, "bigquery", "devstorage.full_control")
+		require.NoError(t, err)
+		assert.Empty(t, missing)
+
+		_, missing, err = auth.CheckScopes(ctx, "bigquery", "drive.readonly")
+		assert.ErrorIs(t, err, auth.ErrMissingScopes)
+		assert.Equal(t, []string{"https://www.googleapis.com/auth/drive.readonly"}, missing, "cloud-platform does not imply Workspace scopes")
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		creds, err := json.Marshal(map[string]string{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token", "token_uri": newRevokedTokenServer(t)})
+		require.NoError(t, err)
+		writeCredentials(t, string(creds))
+		_, _, err = auth.CheckScopes(ctx, "bigquery")
+		assert.ErrorIs(t, err, auth.ErrTokenExpired)
+	})
+}
+
+func TestRequireScopes(t *testing.T) {
+	writeScopedUserCredentials(t, "openid https://www.googleapis.com/auth/userinfo.email")
+	recorder := &fatalRecorder{}
+	auth.RequireScopes(recorder, context.Background(), "bigquery", "drive")
+	assert.Contains(t, recorder.fatal, "GCP CREDENTIALS MISSING SCOPES!")
+	assert.Contains(t, recorder.fatal, "gcloud auth application-default login --scopes=openid,https://www.googleapis.com/auth/userinfo.email,https://www.googleapis.com/auth/cloud-platform,https://www.googleapis.com/auth/bigquery,https://www.googleapis.com/auth/drive")
+}
@@ -2,15 +2,12 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
-
-	"cloud.google.com/go/pubsub/v2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/idtoken"
 )
 
 // FormatGCPAuthError takes a GCP client error and wraps it in a user-friendly
@@ -34,72 +31,151 @@ func FormatGCPAuthError(err error) string {
 
 // CheckGCPAuth is a helper that fails fast if the test is not configured to run
 // with valid Application Default Credentials (ADC). It now provides a more
-// user-friendly error message for common authentication failures.
-func CheckGCPAuth(t *testing.T) string {
+// user-friendly error message for common authentication failures. It
+// obtains a token, so credentials whose refresh token has expired or been
+// revoked fail here rather than mid-test. The credentials' quota project
+// must be the test project, or listed in the comma-separated
+// GCP_ALLOWED_QUOTA_PROJECTS environment variable. The check runs once per
+// process for the same environment, until the token it obtained expires,
+// so calling it in every test of a large suite stays cheap. A failed check
+// runs again for the next test.
+func CheckGCPAuth(t testing.TB) string {
 	t.Helper()
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	if projectID == "" {
-		t.Skip("Skipping real integration test: GCP_PROJECT_ID environment variable is not set")
-	}
-	ctx := context.Background()
+	res, err := cachedCheckAuth(context.Background(), Options{Token: true, AllowedQuotaProjects: allowedQuotaProjects()})
+	failOnAuthError(t, res, err)
+	return res.ProjectID
+}
 
-	// Check basic connectivity and authentication for resource management.
-	_, err := pubsub.NewClient(ctx, projectID)
-	if err != nil {
-		errStr := err.Error()
-		if strings.Contains(errStr, "dialing") || strings.Contains(errStr, "default credentials") {
-			// BUG FIX: Use a constant format string to satisfy the vet tool.
-			t.Fatalf("%s", FormatGCPAuthError(err))
+// CheckGCPAdvancedAuth is CheckGCPAuth, and also checks that the credentials
+// can mint the ID tokens invoking secure Cloud Run services needs. If
+// logCredentials is set, it logs the principal in use, as WhoAmI describes
+// it.
+func CheckGCPAdvancedAuth(t testing.TB, logCredentials bool) string {
+	t.Helper()
+	ctx := context.Background()
+	res, err := cachedCheckAuth(ctx, Options{IDTokens: true, Token: true, AllowedQuotaProjects: allowedQuotaProjects()})
+	// Log the principal associated with the Application Default Credentials.
+	if logCredentials {
+		if principal, err := WhoAmI(ctx); err == nil {
+			t.Logf("--- Using GCP credentials: %s", principal)
 		}
-		// For other errors, fail with the original message.
-		t.Fatalf("An unexpected error occurred while connecting to GCP: %v", err)
 	}
+	failOnAuthError(t, res, err)
+	return res.ProjectID
+}
 
-	return projectID
+// allowedQuotaProjects returns the quota projects listed in
+// GCP_ALLOWED_QUOTA_PROJECTS.
+func allowedQuotaProjects() []string {
+	var allowed []string
+	for _, project := range strings.Split(os.Getenv("GCP_ALLOWED_QUOTA_PROJECTS"), ",") {
+		if project = strings.TrimSpace(project); project != "" {
+			allowed = append(allowed, project)
+		}
+	}
+	return allowed
 }
 
-func CheckGCPAdvancedAuth(t *testing.T, logCredentials bool) string {
+// failOnAuthError skips the test if err says there is no project, and fails
+// it with an actionable message for any other error of CheckAuth.
+func failOnAuthError(t testing.TB, res Result, err error) {
 	t.Helper()
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	if projectID == "" {
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoProject):
 		t.Skip("Skipping real integration test: GCP_PROJECT_ID environment variable is not set")
+	case errors.Is(err, ErrNoADC):
+		// BUG FIX: Use a constant format string to satisfy the vet tool.
+		t.Fatalf("%s", FormatGCPAuthError(err))
+	case errors.Is(err, ErrTokenExpired):
+		t.Fatalf("%s", formatTokenError(err))
+	case errors.Is(err, ErrQuotaProjectMismatch):
+		t.Fatalf("%s", formatQuotaProjectError(res))
+	case errors.Is(err, ErrWorkloadIdentity):
+		t.Fatalf("%s", formatWorkloadIdentityError(res.Principal, res.ProjectID, err))
+	case errors.Is(err, ErrUserCredentials):
+		// This is the specific error the user is seeing. Provide a detailed, actionable fix.
+		t.Fatalf("%s", formatIDTokenError(res.ProjectID, err))
+	default:
+		t.Fatalf("An unexpected error occurred while checking GCP auth: %v", err)
 	}
-	ctx := context.Background()
+}
 
-	// 1. Check basic connectivity and authentication for resource management.
-	_, err := pubsub.NewClient(ctx, projectID)
-	if err != nil {
-		errStr := err.Error()
-		if strings.Contains(errStr, "dialing") || strings.Contains(errStr, "default credentials") {
-			// BUG FIX: Use a constant format string to satisfy the vet tool.
-			t.Fatalf("%s", FormatGCPAuthError(err))
-		}
-		t.Fatalf("An unexpected error occurred while connecting to GCP: %v", err)
-	}
+// formatTokenError wraps the error of obtaining a token from credentials
+// that need refreshing in a message explaining how to fix it.
+func formatTokenError(err error) string {
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		GCP CREDENTIALS EXPIRED!
+		---------------------------------------------------------------------
+		Your Application Default Credentials (ADC) could not be refreshed:
+		their refresh token has expired or been revoked, or your
+		organization requires you to sign in again.
 
-	// Log the principal associated with the Application Default Credentials.
-	if logCredentials {
-		creds, err := google.FindDefaultCredentials(ctx)
-		if err == nil {
-			// Attempt to unmarshal the JSON to find the principal.
-			var credsMap map[string]interface{}
-			if json.Unmarshal(creds.JSON, &credsMap) == nil {
-				if clientEmail, ok := credsMap["client_email"]; ok {
-					t.Logf("--- Using GCP Service Account: %s", clientEmail)
-				} else {
-					// For user credentials, the file path is the most reliable identifier.
-					t.Logf("--- Using GCP User Credentials from file: %s", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
-				}
-			}
-		}
+		To fix this, please run:
+		gcloud auth application-default login
+
+		Original Error: %v
+		---------------------------------------------------------------------
+		`, err)
+}
+
+// formatQuotaProjectError explains how to fix the mismatched quota project
+// of res.
+func formatQuotaProjectError(res Result) string {
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		GCP QUOTA PROJECT MISMATCH!
+		---------------------------------------------------------------------
+		Your Application Default Credentials (ADC) bill API usage to the
+		quota project %[1]s, but the tests run against %[2]s. APIs such
+		as BigQuery and Firestore then fail with confusing 403 errors.
+
+		To fix this, please run:
+		gcloud auth application-default set-quota-project %[2]s
+
+		Or, if the quota project is meant to differ, allow it:
+		export GCP_ALLOWED_QUOTA_PROJECTS=%[1]s
+		---------------------------------------------------------------------
+		`, res.QuotaProject, res.ProjectID)
+}
+
+// formatWorkloadIdentityError explains how to fix the workload identity
+// federation credentials of p, as a GitHub Actions workflow sets them up.
+func formatWorkloadIdentityError(p Principal, projectID string, err error) string {
+	email := p.Email
+	if email == "" {
+		email = "[YOUR_SERVICE_ACCOUNT]"
 	}
+	return fmt.Sprintf(`
+		---------------------------------------------------------------------
+		GCP WORKLOAD IDENTITY FEDERATION FAILED!
+		---------------------------------------------------------------------
+		Your Application Default Credentials (ADC) are workload identity
+		federation credentials (%[1]s) that could not be used.
 
-	// 2. Check for the specific ability to create ID tokens, which is needed to invoke Cloud Run.
-	// This check validates the credential type without making a network call.
-	_, err = idtoken.NewTokenSource(ctx, "https://example.com")
-	if err != nil && strings.Contains(err.Error(), "unsupported credentials type") {
-		// This is the specific error the user is seeing. Provide a detailed, actionable fix.
-		t.Fatalf(`
+		If the tests run in GitHub Actions, please check that:
+		   1. The job has the permission to request an OIDC token:
+		      permissions:
+		        id-token: write
+		   2. The google-github-actions/auth step runs in the same job,
+		      before the tests, with service_account set: ID tokens for
+		      Cloud Run need service account impersonation.
+		   3. The provider's attribute condition accepts this repository
+		      and branch.
+		   4. The repository may impersonate the service account (replace
+		      OWNER/REPO):
+		      gcloud iam service-accounts add-iam-policy-binding %[2]s --project=%[3]s --role="roles/iam.workloadIdentityUser" --member="principalSet://iam.googleapis.com/%[4]s/attribute.repository/OWNER/REPO"
+
+		Original Error: %[5]v
+		---------------------------------------------------------------------
+		`, p.Source, email, projectID, workloadIdentityPool(p.Provider), err)
+}
+
+// formatIDTokenError wraps the error of creating an ID token source from
+// user credentials in a message explaining how to fix it.
+func formatIDTokenError(projectID string, err error) string {
+	return fmt.Sprintf(`
 		---------------------------------------------------------------------
 		GCP INVOCATION AUTHENTICATION FAILED!
 		---------------------------------------------------------------------
@@ -122,10 +198,36 @@ func CheckGCPAdvancedAuth(t *testing.T, logCredentials bool) string {
 		Original Error: %v
 		---------------------------------------------------------------------
 		`, projectID, err)
-	} else if err != nil {
-		// A different, unexpected token-related error occurred.
-		t.Fatalf("Failed to create an ID token source, please check your GCP auth: %v", err)
-	}
+}
 
-	return projectID
+// NewIDTokenClient returns an HTTP client whose requests carry a
+// Google-signed ID token for audience, for tests that invoke secure Cloud
+// Run services or IAP-protected endpoints. audience is the service's URL, or
+// the OAuth client ID of an IAP-protected resource. The token comes from the
+// Application Default Credentials, which must be a service account key, an
+// impersonated or external account, or the metadata server: user
+// credentials cannot mint ID tokens. The first token is fetched straight
+// away, so the test fails fast, with an actionable message, if they cannot.
+func NewIDTokenClient(t testing.TB, ctx context.Context, audience string) *http.Client {
+	t.Helper()
+	client, err := IDTokenClient(ctx, audience)
+	if err == nil {
+		return client
+	}
+	projectID := os.Getenv("GCP_PROJECT_ID")
+	if projectID == "" {
+		projectID = "[YOUR_PROJECT]"
+	}
+	switch {
+	case errors.Is(err, ErrUserCredentials):
+		t.Fatalf("%s", formatIDTokenError(projectID, err))
+	case errors.Is(err, ErrNoADC):
+		t.Fatalf("%s", FormatGCPAuthError(err))
+	case errors.Is(err, ErrWorkloadIdentity):
+		principal, _ := WhoAmI(ctx)
+		t.Fatalf("%s", formatWorkloadIdentityError(principal, projectID, err))
+	default:
+		t.Fatalf("Failed to create an ID token client for %s, please check your GCP auth: %v", audience, err)
+	}
+	return nil
 }
@@ -1,11 +1,28 @@
 package auth_test
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/illmade-knight/go-test/auth"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/jws"
 )
 
 func TestFormatGCPAuthError(t *testing.T) {
@@ -25,3 +42,134 @@ func TestFormatGCPAuthError(t *testing.T) {
 	assert.Contains(t, formattedMessage, "gcloud auth application-default login", "Should contain the exact command to run")
 	assert.Contains(t, formattedMessage, "Original Error: google: could not find default credentials", "Should include the original error for debugging")
 }
+
+// fatalRecorder is a testing.TB that records the message of Fatalf instead
+// of stopping the test.
+type fatalRecorder struct {
+	testing.TB
+	fatal string
+}
+
+func (r *fatalRecorder) Helper() {}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.fatal = fmt.Sprintf(format, args...)
+}
+
+// writeServiceAccountKey writes a service account key whose token endpoint
+// is tokenURL and sets it as the Application Default Credentials.
+func writeServiceAccountKey(t *testing.T, key *rsa.PrivateKey, tokenURL string) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyJSON, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "invoker@test-project.iam.gserviceaccount.com",
+		"client_id":      "1234",
+		"token_uri":      tokenURL,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+}
+
+// writeUserCredentials writes the credentials of gcloud auth
+// application-default login, with quotaProject unless it is empty, and sets
+// them as the Application Default Credentials.
+func writeUserCredentials(t *testing.T, quotaProject string) {
+	t.Helper()
+	creds, err := json.Marshal(map[string]string{
+		"type":             "authorized_user",
+		"client_id":        "id",
+		"client_secret":    "secret",
+		"refresh_token":    "token",
+		"quota_project_id": quotaProject,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "user.json")
+	require.NoError(t, os.WriteFile(path, creds, 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+}
+
+// newTokenServer starts a token endpoint that exchanges the signed
+// assertions of a service account key for access tokens and ID tokens
+// signed with key, for the audiences they ask for, and returns its URL.
+func newTokenServer(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// jws.Decode leaves out the private claims.
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		var claims struct {
+			Iss            string `json:"iss"`
+			TargetAudience string `json:"target_audience"`
+		}
+		if len(parts) != 3 {
+			http.Error(w, "malformed assertion", http.StatusBadRequest)
+			return
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err == nil {
+			err = json.Unmarshal(payload, &claims)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		idToken, err := jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT"}, &jws.ClaimSet{
+			Iss: "https://accounts.google.com",
+			Aud: claims.TargetAudience,
+			Sub: claims.Iss,
+			Iat: time.Now().Unix(),
+			Exp: time.Now().Add(time.Hour).Unix(),
+		}, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"id_token": idToken, "access_token": "access-token", "expires_in": 3600})
+	}))
+	t.Cleanup(tokenServer.Close)
+	return tokenServer.URL
+}
+
+func TestNewIDTokenClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeServiceAccountKey(t, key, newTokenServer(t, key))
+
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims, err := jws.Decode(token)
+		if !ok || err != nil || jws.Verify(token, &key.PublicKey) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, claims.Aud)
+	}))
+	t.Cleanup(service.Close)
+
+	client := auth.NewIDTokenClient(t, context.Background(), service.URL)
+	resp, err := client.Get(service.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, service.URL, string(body), "the token should be for the audience")
+}
+
+func TestNewIDTokenClient_UserCredentials(t *testing.T) {
+	writeUserCredentials(t, "")
+	t.Setenv("GCP_PROJECT_ID", "test-project")
+
+	recorder := &fatalRecorder{}
+	client := auth.NewIDTokenClient(recorder, context.Background(), "https://service.example.com")
+	assert.Nil(t, client)
+	assert.Contains(t, recorder.fatal, "GCP INVOCATION AUTHENTICATION FAILED!")
+	assert.Contains(t, recorder.fatal, "gcloud projects add-iam-policy-binding test-project")
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCA is a throwaway certificate authority with a server and a client
+// certificate signed by it, for tests of TLS and mutual TLS, such as an
+// emulator serving TLS and a load generator connecting to it.
+type TestCA struct {
+	// Cert is the CA certificate and CertPEM the same, PEM encoded.
+	Cert    *x509.Certificate
+	CertPEM []byte
+	// Server is valid for localhost, the loopback addresses and the hosts
+	// the CA was created with; Client for client authentication as
+	// "test-client".
+	Server Certificate
+	Client Certificate
+	key    *ecdsa.PrivateKey
+	serial atomic.Int64
+}
+
+// Certificate is a certificate signed by a TestCA and its private key.
+type Certificate struct {
+	// CertPEM and KeyPEM are the certificate and key, PEM encoded, for
+	// servers configured with files.
+	CertPEM []byte
+	KeyPEM  []byte
+	// TLS is the certificate and key for a tls.Config.
+	TLS tls.Certificate
+}
+
+// testCertLifetime is how long the certificates of a TestCA are valid, from
+// an hour before they were issued to allow for clock skew.
+const testCertLifetime = 24 * time.Hour
+
+// NewTestCA returns a new TestCA whose server certificate also covers
+// hosts, host names or IP addresses, failing the test if it cannot.
+func NewTestCA(t testing.TB, hosts ...string) *TestCA {
+	t.Helper()
+	ca, err := GenerateTestCA(hosts...)
+	if err != nil {
+		t.Fatalf("Failed to generate a test CA: %v", err)
+	}
+	return ca
+}
+
+// GenerateTestCA is NewTestCA without a testing.TB.
+func GenerateTestCA(hosts ...string) (*TestCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	ca := &TestCA{key: key}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(ca.serial.Add(1)),
+		Subject:               pkix.Name{CommonName: "go-test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(testCertLifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	if ca.Cert, err = x509.ParseCertificate(der); err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	ca.CertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	if ca.Server, err = ca.IssueServer(hosts...); err != nil {
+		return nil, err
+	}
+	if ca.Client, err = ca.IssueClient("test-client"); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// IssueServer issues a server certificate valid for localhost, the loopback
+// addresses and hosts, host names or IP addresses.
+func (ca *TestCA) IssueServer(hosts ...string) (Certificate, error) {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	return ca.issue(template, "server")
+}
+
+// IssueClient issues a client certificate for commonName, for servers that
+// require mutual TLS.
+func (ca *TestCA) IssueClient(commonName string) (Certificate, error) {
+	return ca.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, "client")
+}
+
+// issue signs a certificate from template, of the given kind, with a new
+// key.
+func (ca *TestCA) issue(template *x509.Certificate, kind string) (Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("failed to generate %s key: %w", kind, err)
+	}
+	template.SerialNumber = big.NewInt(ca.serial.Add(1))
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(testCertLifetime)
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("failed to create %s certificate: %w", kind, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("failed to marshal %s key: %w", kind, err)
+	}
+	cert := Certificate{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	if cert.TLS, err = tls.X509KeyPair(cert.CertPEM, cert.KeyPEM); err != nil {
+		return Certificate{}, fmt.Errorf("failed to load %s certificate: %w", kind, err)
+	}
+	return cert, nil
+}
+
+// CertPool returns a pool holding only the CA certificate.
+func (ca *TestCA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	return pool
+}
+
+// ServerTLSConfig returns a server TLS configuration presenting the server
+// certificate. If mutual is set, it requires clients to present a
+// certificate signed by the CA.
+func (ca *TestCA) ServerTLSConfig(mutual bool) *tls.Config {
+	config := &tls.Config{Certificates: []tls.Certificate{ca.Server.TLS}, MinVersion: tls.VersionTLS12}
+	if mutual {
+		config.ClientCAs = ca.CertPool()
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// ClientTLSConfig returns a client TLS configuration that trusts only the
+// CA and presents the client certificate to servers that ask for one.
+func (ca *TestCA) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:      ca.CertPool(),
+		Certificates: []tls.Certificate{ca.Client.TLS},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
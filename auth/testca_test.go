@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestCA(t *testing.T) {
+	ca := auth.NewTestCA(t, "broker.example", "10.0.0.5")
+	assert.True(t, ca.Cert.IsCA)
+	assert.Contains(t, string(ca.CertPEM), "BEGIN CERTIFICATE")
+
+	server, err := x509.ParseCertificate(ca.Server.TLS.Certificate[0])
+	require.NoError(t, err)
+	for _, host := range []string{"localhost", "127.0.0.1", "broker.example", "10.0.0.5"} {
+		_, err := server.Verify(x509.VerifyOptions{Roots: ca.CertPool(), DNSName: host})
+		assert.NoError(t, err, host)
+	}
+	_, err = tls.X509KeyPair(ca.Client.CertPEM, ca.Client.KeyPEM)
+	assert.NoError(t, err)
+}
+
+func TestTestCA_MutualTLS(t *testing.T) {
+	ca := auth.NewTestCA(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = ca.ServerTLSConfig(true)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	get := func(config *tls.Config) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		return client.Get(server.URL)
+	}
+	resp, err := get(ca.ClientTLSConfig())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	// Without a client certificate, or one of another CA, the handshake fails.
+	_, err = get(&tls.Config{RootCAs: ca.CertPool()})
+	assert.Error(t, err)
+	other := auth.NewTestCA(t)
+	_, err = get(&tls.Config{RootCAs: ca.CertPool(), Certificates: []tls.Certificate{other.Client.TLS}})
+	assert.Error(t, err)
+
+	// Further clients may be issued, with their own names.
+	device, err := ca.IssueClient("device-1")
+	require.NoError(t, err)
+	config := ca.ClientTLSConfig()
+	config.Certificates = []tls.Certificate{device.TLS}
+	resp, err = get(config)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "device-1", string(body))
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// externalAccount is what the checks read of workload identity federation
+// credentials.
+type externalAccount struct {
+	ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	CredentialSource               struct {
+		File       string          `json:"file"`
+		Executable json.RawMessage `json:"executable"`
+	} `json:"credential_source"`
+}
+
+// checkWorkloadIdentity validates workload identity federation credentials
+// without a network call. idTokens says the credentials must mint ID tokens.
+func checkWorkloadIdentity(credsJSON []byte, idTokens bool) error {
+	var account externalAccount
+	if err := json.Unmarshal(credsJSON, &account); err != nil {
+		return fmt.Errorf("%w: %w", ErrWorkloadIdentity, err)
+	}
+	source := account.CredentialSource
+	if source.File != "" {
+		if _, err := os.Stat(source.File); err != nil {
+			return fmt.Errorf("%w: the subject token file is missing: %w", ErrWorkloadIdentity, err)
+		}
+	}
+	if len(source.Executable) > 0 && os.Getenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES") != "1" {
+		return fmt.Errorf("%w: executable-sourced credentials need GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES=1", ErrWorkloadIdentity)
+	}
+	if idTokens && account.ServiceAccountImpersonationURL == "" {
+		// ID tokens are minted by impersonating a service account.
+		return fmt.Errorf("%w: credentials without service account impersonation cannot mint ID tokens", ErrWorkloadIdentity)
+	}
+	return nil
+}
+
+// workloadIdentityPool returns the pool of a workload identity provider, or
+// "[POOL]" if it is not known.
+func workloadIdentityPool(provider string) string {
+	pool, _, ok := strings.Cut(provider, "/providers/")
+	if !ok || pool == "" {
+		return "[POOL]"
+	}
+	return pool
+}
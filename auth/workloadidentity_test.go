@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeWorkloadIdentityCredentials writes workload identity federation
+// credentials, as google-github-actions/auth does, with the credential source
+// source, and sets them as the Application Default Credentials. The
+// credentials impersonate ci@build.iam.gserviceaccount.com if impersonate is
+// set.
+func writeWorkloadIdentityCredentials(t *testing.T, source map[string]any, tokenURL string, impersonate bool) {
+	t.Helper()
+	creds := map[string]any{
+		"type":               "external_account",
+		"audience":           "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/ci/providers/github",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url":          tokenURL,
+		"credential_source":  source,
+		"quota_project_id":   "build",
+	}
+	if impersonate {
+		creds["service_account_impersonation_url"] = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/ci@build.iam.gserviceaccount.com:generateAccessToken"
+	}
+	credsJSON, err := json.Marshal(creds)
+	require.NoError(t, err)
+	writeCredentials(t, string(credsJSON))
+}
+
+// tokenFile writes a subject token and returns its credential source.
+func tokenFile(t *testing.T) map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("oidc-token"), 0600))
+	return map[string]any{"file": path}
+}
+
+func TestCheckAuth_WorkloadIdentity(t *testing.T) {
+	ctx := context.Background()
+	const sts = "https://sts.googleapis.com/v1/token"
+
+	t.Run("valid", func(t *testing.T) {
+		writeWorkloadIdentityCredentials(t, tokenFile(t), sts, true)
+		res, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "build"})
+		require.NoError(t, err)
+		assert.Equal(t, auth.PrincipalWorkloadIdentity, res.Principal.Type)
+		assert.Equal(t, "projects/123/locations/global/workloadIdentityPools/ci/providers/github", res.Principal.Provider)
+	})
+
+	t.Run("missing token file", func(t *testing.T) {
+		writeWorkloadIdentityCredentials(t, map[string]any{"file": filepath.Join(t.TempDir(), "token")}, sts, true)
+		_, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "build"})
+		assert.ErrorIs(t, err, auth.ErrWorkloadIdentity)
+		assert.ErrorContains(t, err, "subject token file is missing")
+	})
+
+	t.Run("executable", func(t *testing.T) {
+		writeWorkloadIdentityCredentials(t, map[string]any{"executable": map[string]any{"command": "/bin/oidc-token"}}, sts, true)
+		t.Setenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES", "")
+		_, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "build"})
+		assert.ErrorIs(t, err, auth.ErrWorkloadIdentity)
+
+		t.Setenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES", "1")
+		_, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "build"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ID tokens without impersonation", func(t *testing.T) {
+		writeWorkloadIdentityCredentials(t, tokenFile(t), sts, false)
+		_, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "build"})
+		require.NoError(t, err)
+		_, err = auth.CheckAuth(ctx, auth.Options{ProjectID: "build", IDTokens: true})
+		assert.ErrorIs(t, err, auth.ErrWorkloadIdentity)
+
+		_, err = auth.IDTokenClient(ctx, "https://service.example.com")
+		assert.ErrorIs(t, err, auth.ErrWorkloadIdentity)
+	})
+
+	t.Run("token exchange refused", func(t *testing.T) {
+		writeWorkloadIdentityCredentials(t, tokenFile(t), newRevokedTokenServer(t), true)
+		_, err := auth.CheckAuth(ctx, auth.Options{ProjectID: "build", Token: true})
+		assert.ErrorIs(t, err, auth.ErrWorkloadIdentity)
+		assert.NotErrorIs(t, err, auth.ErrTokenExpired)
+	})
+}
+
+func TestCheckGCPAuth_WorkloadIdentity(t *testing.T) {
+	writeWorkloadIdentityCredentials(t, map[string]any{"file": filepath.Join(t.TempDir(), "token")}, "https://sts.googleapis.com/v1/token", true)
+	t.Setenv("GCP_PROJECT_ID", "build")
+	recorder := &fatalRecorder{}
+	auth.CheckGCPAuth(recorder)
+	assert.Contains(t, recorder.fatal, "GCP WORKLOAD IDENTITY FEDERATION FAILED!")
+	assert.Contains(t, recorder.fatal, "id-token: write")
+	assert.Contains(t, recorder.fatal, "add-iam-policy-binding ci@build.iam.gserviceaccount.com --project=build")
+	assert.Contains(t, recorder.fatal, "principalSet://iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/ci/attribute.repository/OWNER/REPO")
+}
@@ -3,6 +3,7 @@ package emulators
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,11 +18,21 @@ import (
 type BigQueryConfig struct {
 	GCImageContainer
 	// DatasetTables holds a map of dataset names to table names.
-	// This is used by the *test* to know what to create, not by the setup function.
+	// This is used by BootstrapBigQueryResources (or the test itself) to know
+	// what to create, not by the setup function.
 	DatasetTables map[string]string
 	// Schemas holds a map of table names to their Go struct schema.
-	// This is used by the *test* to infer and create the table schema.
+	// This is used to infer and create the table schema.
 	Schemas map[string]interface{}
+	// SeedRows holds an optional map of table names to rows that
+	// BootstrapBigQueryResources inserts after creating the table.
+	// Rows are typically values of the table's schema struct.
+	SeedRows map[string][]interface{}
+	// SeedFile is an optional path to a YAML fixture on the host. It is mounted
+	// into the container and loaded with the emulator's --data-from-yaml flag,
+	// which is much faster than the insert API for large seed datasets.
+	// The project ID in the file must match ProjectID.
+	SeedFile string
 }
 
 const (
@@ -31,6 +42,8 @@ const (
 	testBigQueryGRPCPort = "9060"
 	// testBigQueryRestPort is the default REST port for the emulator.
 	testBigQueryRestPort = "9050"
+	// bigQuerySeedContainerPath is where the seed file is mounted in the container.
+	bigQuerySeedContainerPath = "/data/seed.yaml"
 )
 
 // GetDefaultBigQueryConfig provides a default configuration for the BigQuery emulator.
@@ -57,8 +70,30 @@ func GetDefaultBigQueryConfig(projectID string, datasetTables map[string]string,
 // This function *only* starts the emulator. It does NOT create any datasets or
 // tables. The test calling this function is responsible for creating its own
 // resources using the returned EmulatorConnectionInfo.
-func SetupBigQueryEmulator(t *testing.T, ctx context.Context, cfg BigQueryConfig) EmulatorConnectionInfo {
+func SetupBigQueryEmulator(t testing.TB, ctx context.Context, cfg BigQueryConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
+
+	connInfo, terminate, err := StartBigQueryEmulator(ctx, cfg, withTestLogs(t, "BigQuery", opts)...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate BigQuery container: %v", err)
+		}
+	})
+
+	t.Logf("BigQuery emulator container started. HTTP: %s, gRPC: %s", connInfo.HTTPEndpoint.Endpoint, connInfo.GRPCEndpoint.Endpoint)
+	if cfg.SetEnvVariables {
+		connInfo.SetEnv(t)
+	}
+	return connInfo
+}
+
+// StartBigQueryEmulator starts a BigQuery emulator container and verifies a
+// client can be created for it. It is SetupBigQueryEmulator without a
+// testing.TB: it does not bootstrap datasets or set environment variables,
+// and the caller must call the returned TerminateFunc.
+func StartBigQueryEmulator(ctx context.Context, cfg BigQueryConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
 	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
 	grpcPort := fmt.Sprintf("%s/tcp", cfg.EmulatorGRPCPort)
 	req := testcontainers.ContainerRequest{
@@ -74,37 +109,47 @@ func SetupBigQueryEmulator(t *testing.T, ctx context.Context, cfg BigQueryConfig
 			wait.ForListeningPort(nat.Port(grpcPort)).WithStartupTimeout(60*time.Second),
 		),
 	}
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
-	require.NoError(t, err)
-
-	t.Cleanup(func() {
-		if err := container.Terminate(context.Background()); err != nil {
-			t.Logf("Failed to terminate BigQuery container: %v", err)
+	if cfg.SeedFile != "" {
+		req.Files = []testcontainers.ContainerFile{{HostFilePath: cfg.SeedFile, ContainerFilePath: bigQuerySeedContainerPath, FileMode: 0644}}
+		req.Cmd = append(req.Cmd, "--data-from-yaml="+bigQuerySeedContainerPath)
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, "", opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to start BigQuery emulator: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
 		}
-	})
+	}()
 
-	host, err := container.Host(ctx)
-	require.NoError(t, err)
-	mappedGrpcPort, err := container.MappedPort(ctx, nat.Port(grpcPort))
-	require.NoError(t, err)
-	mappedRestPort, err := container.MappedPort(ctx, nat.Port(httpPort))
-	require.NoError(t, err)
+	grpcAddress, err := mappedAddress(ctx, container, grpcPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	restAddress, err := mappedAddress(ctx, container, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
 
-	endpointGRPC := fmt.Sprintf("grpc://%s:%s", host, mappedGrpcPort.Port())
-	endpointHTTP := fmt.Sprintf("http://%s:%s", host, mappedRestPort.Port())
-	opts := getEmulatorOptions(endpointHTTP)
+	endpointGRPC := "grpc://" + grpcAddress
+	endpointHTTP := "http://" + restAddress
+	clientOpts := getEmulatorOptions(endpointHTTP)
 
 	// --- Key Refactor ---
 	// Removed the resource creation loop.
 	// We verify connectivity by creating a client, but we don't
 	// modify state.
-	client, err := bigquery.NewClient(ctx, cfg.ProjectID, opts...)
-	require.NoError(t, err)
+	client, err := bigquery.NewClient(ctx, cfg.ProjectID, clientOpts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to connect to BigQuery emulator: %w", err)
+	}
 	_ = client.Close() // Close the temporary client immediately.
 
-	t.Logf("BigQuery emulator container started. HTTP: %s, gRPC: %s", endpointHTTP, endpointGRPC)
-
-	return EmulatorConnectionInfo{
+	connInfo = EmulatorConnectionInfo{
+		Service:   ServiceBigQuery,
+		ProjectID: cfg.ProjectID,
 		HTTPEndpoint: Endpoint{
 			Port:     httpPort,
 			Endpoint: endpointHTTP,
@@ -113,6 +158,44 @@ func SetupBigQueryEmulator(t *testing.T, ctx context.Context, cfg BigQueryConfig
 			Port:     grpcPort,
 			Endpoint: endpointGRPC,
 		},
-		ClientOptions: opts,
+		ClientOptions: clientOpts,
+	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if err = runReadinessProbe(ctx, cfg.ReadinessProbe, connInfo); err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("BigQuery emulator is not ready: %w", err)
+	}
+	return connInfo, terminate, nil
+}
+
+// BootstrapBigQueryResources creates the datasets and tables described by
+// cfg.DatasetTables and cfg.Schemas, then inserts any rows in cfg.SeedRows.
+// It is opt-in: SetupBigQueryEmulator never creates resources itself.
+// Datasets and tables that already exist are left in place.
+func BootstrapBigQueryResources(t testing.TB, ctx context.Context, client *bigquery.Client, cfg BigQueryConfig) {
+	t.Helper()
+
+	for datasetName, tableName := range cfg.DatasetTables {
+		err := client.Dataset(datasetName).Create(ctx, &bigquery.DatasetMetadata{Name: datasetName})
+		if err != nil && !strings.Contains(err.Error(), "Already Exists") {
+			require.NoError(t, err, "Failed to create dataset %s", datasetName)
+		}
+
+		schemaType, ok := cfg.Schemas[tableName]
+		require.True(t, ok, "Schema not found for table %s", tableName)
+		schema, err := bigquery.InferSchema(schemaType)
+		require.NoError(t, err, "Failed to infer schema for table %s", tableName)
+
+		table := client.Dataset(datasetName).Table(tableName)
+		err = table.Create(ctx, &bigquery.TableMetadata{Name: tableName, Schema: schema})
+		if err != nil && !strings.Contains(err.Error(), "Already Exists") {
+			require.NoError(t, err, "Failed to create table %s", tableName)
+		}
+
+		if rows := cfg.SeedRows[tableName]; len(rows) > 0 {
+			err = table.Inserter().Put(ctx, rows)
+			require.NoError(t, err, "Failed to seed table %s", tableName)
+		}
 	}
 }
@@ -2,8 +2,9 @@ package emulators
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"reflect" // Added for reflect.DeepEqual in TestGetDefaultBigQueryConfig
-	"strings" // Added for checking "Already Exists" errors
 	"testing"
 	"time"
 
@@ -46,26 +47,8 @@ func TestSetupBigQueryEmulator(t *testing.T) {
 		_ = client.Close()
 	})
 
-	// --- REFACTOR ---
-	// This logic is now part of the test, not the setup function.
 	t.Log("Creating test dataset and table...")
-	for k, v := range cfg.DatasetTables {
-		err = client.Dataset(k).Create(testCtx, &bigquery.DatasetMetadata{Name: k})
-		if err != nil && !strings.Contains(err.Error(), "Already Exists") {
-			require.NoError(t, err, "Failed to create dataset")
-		}
-
-		table := client.Dataset(k).Table(v)
-		schemaType, ok := cfg.Schemas[v]
-		require.True(t, ok, "Schema not found for table %s", v)
-		schema, err := bigquery.InferSchema(schemaType)
-		require.NoError(t, err, "Failed to infer schema")
-		err = table.Create(testCtx, &bigquery.TableMetadata{Name: v, Schema: schema})
-		if err != nil && !strings.Contains(err.Error(), "Already Exists") {
-			require.NoError(t, err, "Failed to create table")
-		}
-	}
-	// --- End Refactor ---
+	BootstrapBigQueryResources(t, testCtx, client, cfg)
 
 	// Verify dataset and table exist
 	ds := client.Dataset(datasetName)
@@ -76,9 +59,69 @@ func TestSetupBigQueryEmulator(t *testing.T) {
 	_, err = table.Metadata(testCtx) // Use testCtx for metadata operations
 	require.NoError(t, err, "Failed to get table %q metadata", tableName)
 
+	// Seeding inserts rows into the bootstrapped table.
+	seedCfg := cfg
+	seedCfg.SeedRows = map[string][]interface{}{tableName: {
+		TestData{ID: "1", Name: "alpha"},
+		TestData{ID: "2", Name: "beta"},
+	}}
+	BootstrapBigQueryResources(t, testCtx, client, seedCfg)
+
+	it, err := client.Query("SELECT COUNT(*) FROM " + datasetName + "." + tableName).Read(testCtx)
+	require.NoError(t, err, "Failed to query seeded table")
+	var row []bigquery.Value
+	require.NoError(t, it.Next(&row))
+	require.EqualValues(t, 2, row[0], "Seed rows should be inserted")
+
 	t.Logf("BigQuery emulator test passed. Connected to HTTP: %s, gRPC: %s", connInfo.HTTPEndpoint.Endpoint, connInfo.GRPCEndpoint.Endpoint)
 }
 
+func TestSetupBigQueryEmulatorWithSeedFile(t *testing.T) {
+	t.Parallel()
+
+	testCtx, testCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(testCancel)
+
+	projectID := "test-project-seedfile"
+	seed := `projects:
+- id: test-project-seedfile
+  datasets:
+    - id: seeded
+      tables:
+        - id: readings
+          columns:
+            - name: id
+              type: STRING
+            - name: value
+              type: INTEGER
+          data:
+            - id: a
+              value: 1
+            - id: b
+              value: 2
+            - id: c
+              value: 3
+`
+	seedFile := filepath.Join(t.TempDir(), "seed.yaml")
+	require.NoError(t, os.WriteFile(seedFile, []byte(seed), 0644))
+
+	cfg := GetDefaultBigQueryConfig(projectID, nil, nil)
+	cfg.SeedFile = seedFile
+	connInfo := SetupBigQueryEmulator(t, context.Background(), cfg)
+
+	client, err := bigquery.NewClient(testCtx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	it, err := client.Query("SELECT COUNT(*) FROM seeded.readings").Read(testCtx)
+	require.NoError(t, err, "Failed to query table loaded from the seed file")
+	var row []bigquery.Value
+	require.NoError(t, it.Next(&row))
+	require.EqualValues(t, 3, row[0], "All rows in the seed file should be loaded")
+}
+
 func TestGetDefaultBigQueryConfig(t *testing.T) {
 	projectID := "test-proj-defaults"
 	datasetTables := map[string]string{"ds1": "tbl1"}
@@ -0,0 +1,65 @@
+package emulators
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// TLSCertificates holds a throwaway CA and a server certificate signed by it,
+// all PEM encoded, for emulators that serve TLS. They come from
+// auth.GenerateTestCA.
+type TLSCertificates struct {
+	CACert     []byte
+	ServerCert []byte
+	ServerKey  []byte
+}
+
+// generateTestCertificates creates a throwaway CA and a server certificate
+// valid for localhost, the loopback addresses and any extra hosts given.
+func generateTestCertificates(hosts ...string) (TLSCertificates, error) {
+	ca, err := auth.GenerateTestCA(hosts...)
+	if err != nil {
+		return TLSCertificates{}, err
+	}
+	return TLSCertificates{CACert: ca.CertPEM, ServerCert: ca.Server.CertPEM, ServerKey: ca.Server.KeyPEM}, nil
+}
+
+// dockerDaemonHost returns the host name clients use to reach mapped container
+// ports, so server certificates can be issued for it before the container starts.
+func dockerDaemonHost(ctx context.Context) (string, error) {
+	provider, err := testcontainers.NewDockerProvider()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker provider: %w", err)
+	}
+	defer func() { _ = provider.Close() }()
+	return provider.DaemonHost(ctx)
+}
+
+// generateDaemonCertificates generates test certificates whose server
+// certificate also covers the Docker daemon host clients will dial.
+func generateDaemonCertificates(ctx context.Context) (*TLSCertificates, error) {
+	daemonHost, err := dockerDaemonHost(ctx)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := generateTestCertificates(daemonHost)
+	if err != nil {
+		return nil, err
+	}
+	return &certs, nil
+}
+
+// TLSConfigFromCA returns a client TLS configuration that trusts the given
+// PEM-encoded CA certificate, such as EmulatorConnectionInfo.CACert.
+func TLSConfigFromCA(caPEM []byte) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no valid certificates found in CA PEM")
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
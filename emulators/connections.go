@@ -1,6 +1,11 @@
 package emulators
 
 import (
+	"net/http"
+	"os"
+	"sort"
+	"testing"
+
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -9,24 +14,135 @@ import (
 // Endpoint holds the port and full endpoint string for a service.
 type Endpoint struct {
 	// Port is the *internal* port of the service (e.g., "9050").
-	Port string
+	Port string `json:"port,omitempty"`
 	// Endpoint is the full, *external*, mapped endpoint (e.g., "http://localhost:32768").
-	Endpoint string
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
+// Service identifies the kind of emulator an EmulatorConnectionInfo belongs to.
+type Service string
+
+// The services the package can start emulators for.
+const (
+	ServicePubsub    Service = "pubsub"
+	ServiceFirestore Service = "firestore"
+	ServiceGCS       Service = "gcs"
+	ServiceBigQuery  Service = "bigquery"
+	ServiceRedis     Service = "redis"
+	ServiceMQTT      Service = "mqtt"
+
+	ServicePrometheus    Service = "prometheus"
+	ServiceOTelCollector Service = "otel-collector"
+	ServiceJaeger        Service = "jaeger"
+
+	ServiceTemporal Service = "temporal"
+)
+
 // EmulatorConnectionInfo holds all connection details for a test emulator.
 // Different fields are populated depending on the service.
 type EmulatorConnectionInfo struct {
+	// Service is the kind of emulator this connection info belongs to.
+	Service Service
+	// ProjectID is the Google Cloud project the emulator was started with.
+	// It is empty for non-Google services.
+	ProjectID string
+	// Namespace is the Temporal namespace clients should use.
+	Namespace string
 	// HTTPEndpoint is the HTTP/REST endpoint, used by GCS, BigQuery, Pub/Sub, etc.
 	HTTPEndpoint Endpoint
 	// GRPCEndpoint is the gRPC endpoint, primarily used by BigQuery.
 	GRPCEndpoint Endpoint
+	// InternalEndpoint is the "host:port" address of the emulator's main port
+	// (ImageContainer.EmulatorPort) as seen from other containers, to hand to
+	// a service container: the network alias or container name when
+	// ImageContainer.Network is set, else the container IP.
+	InternalEndpoint string
 	// EmulatorAddress is a generic address string for non-HTTP services
 	// like MQTT ("tcp://localhost:1883") or Redis ("localhost:6379").
 	EmulatorAddress string
+	// TLSAddress is the address of an optional TLS listener for non-HTTP
+	// services (e.g., "tls://localhost:54322" for MQTT).
+	TLSAddress string
+	// WebSocketAddress is the address of an optional WebSocket listener
+	// (e.g., "ws://localhost:54323" for MQTT over WebSockets).
+	WebSocketAddress string
+	// MetricsEndpoint is the URL of a Prometheus-format metrics endpoint
+	// exposed by the service (e.g., the OpenTelemetry collector's Prometheus
+	// exporter, "http://localhost:54324/metrics").
+	MetricsEndpoint string
+	// QueryEndpoint is the base URL of a query API exposed by the service
+	// (e.g., Jaeger's, "http://localhost:54325").
+	QueryEndpoint string
+	// CACert is the PEM-encoded CA certificate that signed the emulator's
+	// TLS certificate, for clients to trust. It is empty when TLS is disabled.
+	CACert []byte
+	// Username and Password are the credentials for emulators started with
+	// authentication enabled (e.g., MQTT). They are empty otherwise.
+	Username string
+	Password string
 	// ClientOptions are pre-configured Google Cloud client options
 	// for connecting to the emulator (e.g., WithEndpoint, WithoutAuthentication).
 	ClientOptions []option.ClientOption
+	// HTTPClient is a pre-configured HTTP client for emulators that serve
+	// self-signed TLS (e.g., GCS in https mode). It skips certificate
+	// verification and is nil for plain-text emulators.
+	HTTPClient *http.Client
+}
+
+// EnvVars returns the canonical environment variables that point client
+// libraries at the emulator: PUBSUB_EMULATOR_HOST, FIRESTORE_EMULATOR_HOST,
+// STORAGE_EMULATOR_HOST, BIGQUERY_API_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT
+// and OTEL_EXPORTER_OTLP_PROTOCOL for OTLP receivers (the OpenTelemetry
+// collector and Jaeger), or TEMPORAL_ADDRESS and TEMPORAL_NAMESPACE. It is
+// empty for services without a canonical variable, such as Redis and MQTT.
+func (c EmulatorConnectionInfo) EnvVars() map[string]string {
+	switch c.Service {
+	case ServicePubsub:
+		return map[string]string{"PUBSUB_EMULATOR_HOST": c.HTTPEndpoint.Endpoint}
+	case ServiceFirestore:
+		return map[string]string{"FIRESTORE_EMULATOR_HOST": c.HTTPEndpoint.Endpoint}
+	case ServiceGCS:
+		return map[string]string{"STORAGE_EMULATOR_HOST": gcsEmulatorHost(c)}
+	case ServiceBigQuery:
+		return map[string]string{"BIGQUERY_API_ENDPOINT": c.HTTPEndpoint.Endpoint}
+	case ServiceOTelCollector, ServiceJaeger:
+		// http/protobuf is the default protocol in the OpenTelemetry spec.
+		return map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": c.HTTPEndpoint.Endpoint,
+			"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+		}
+	case ServiceTemporal:
+		return map[string]string{"TEMPORAL_ADDRESS": c.GRPCEndpoint.Endpoint, "TEMPORAL_NAMESPACE": c.Namespace}
+	default:
+		return nil
+	}
+}
+
+// SetEnv sets the variables from EnvVars with t.Setenv, so they are restored
+// when the test ends. Like t.Setenv, it cannot be used in parallel tests.
+func (c EmulatorConnectionInfo) SetEnv(t testing.TB) {
+	t.Helper()
+	for k, v := range c.EnvVars() {
+		t.Setenv(k, v)
+	}
+}
+
+// Environ returns the current process environment with the variables from
+// EnvVars added, in "KEY=value" form for exec.Cmd.Env. Use it to point a
+// subprocess at the emulator without changing the test's own environment.
+func (c EmulatorConnectionInfo) Environ() []string {
+	vars := c.EnvVars()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := os.Environ()
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
 }
 
 // getEmulatorOptions returns a standard set of gRPC client options
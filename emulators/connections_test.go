@@ -1,6 +1,7 @@
 package emulators
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -27,3 +28,52 @@ func Test_getEmulatorOptions(t *testing.T) {
 	t.Log("Direct comparison of google.golang.org/api/option.ClientOption types is not practical due to unexported internals.")
 	t.Log("Functionality of getEmulatorOptions is implicitly tested via successful client connections in other emulator setup tests.")
 }
+
+func TestEmulatorConnectionInfoEnvVars(t *testing.T) {
+	tests := []struct {
+		connInfo EmulatorConnectionInfo
+		want     map[string]string
+	}{
+		{EmulatorConnectionInfo{Service: ServicePubsub, HTTPEndpoint: Endpoint{Endpoint: "localhost:1001"}}, map[string]string{"PUBSUB_EMULATOR_HOST": "localhost:1001"}},
+		{EmulatorConnectionInfo{Service: ServiceFirestore, HTTPEndpoint: Endpoint{Endpoint: "localhost:1002"}}, map[string]string{"FIRESTORE_EMULATOR_HOST": "localhost:1002"}},
+		{EmulatorConnectionInfo{Service: ServiceGCS, HTTPEndpoint: Endpoint{Endpoint: "localhost:1003"}}, map[string]string{"STORAGE_EMULATOR_HOST": "localhost:1003"}},
+		{EmulatorConnectionInfo{Service: ServiceGCS, HTTPEndpoint: Endpoint{Endpoint: "localhost:1004"}, HTTPClient: newInsecureHTTPClient()}, map[string]string{"STORAGE_EMULATOR_HOST": "https://localhost:1004"}},
+		{EmulatorConnectionInfo{Service: ServiceBigQuery, HTTPEndpoint: Endpoint{Endpoint: "http://localhost:1005"}}, map[string]string{"BIGQUERY_API_ENDPOINT": "http://localhost:1005"}},
+		{EmulatorConnectionInfo{Service: ServiceRedis, EmulatorAddress: "localhost:6379"}, nil},
+		{EmulatorConnectionInfo{Service: ServiceOTelCollector, HTTPEndpoint: Endpoint{Endpoint: "http://localhost:1006"}}, map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:1006",
+			"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+		}},
+		{EmulatorConnectionInfo{Service: ServiceTemporal, GRPCEndpoint: Endpoint{Endpoint: "localhost:7233"}, Namespace: "orders"}, map[string]string{
+			"TEMPORAL_ADDRESS":   "localhost:7233",
+			"TEMPORAL_NAMESPACE": "orders",
+		}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.connInfo.EnvVars(), string(tt.connInfo.Service))
+	}
+}
+
+func TestEmulatorConnectionInfoSetEnv(t *testing.T) {
+	connInfo := EmulatorConnectionInfo{Service: ServicePubsub, HTTPEndpoint: Endpoint{Endpoint: "localhost:8085"}}
+	before := os.Getenv("PUBSUB_EMULATOR_HOST")
+
+	t.Run("SetEnv", func(t *testing.T) {
+		connInfo.SetEnv(t)
+		require.Equal(t, "localhost:8085", os.Getenv("PUBSUB_EMULATOR_HOST"))
+	})
+
+	// The subtest's t.Setenv is restored, and Environ leaves the process alone.
+	require.Equal(t, before, os.Getenv("PUBSUB_EMULATOR_HOST"))
+	env := connInfo.Environ()
+	require.Equal(t, "PUBSUB_EMULATOR_HOST=localhost:8085", env[len(env)-1])
+	require.Equal(t, before, os.Getenv("PUBSUB_EMULATOR_HOST"))
+}
+
+func BenchmarkEmulatorConnectionInfoSetEnv(b *testing.B) {
+	connInfo := EmulatorConnectionInfo{Service: ServicePubsub, HTTPEndpoint: Endpoint{Endpoint: "localhost:8085"}}
+	connInfo.SetEnv(b)
+	for b.Loop() {
+		_ = connInfo.EnvVars()
+	}
+}
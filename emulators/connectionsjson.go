@@ -0,0 +1,122 @@
+package emulators
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"google.golang.org/api/option"
+)
+
+// connectionInfoJSON is the serialized form of EmulatorConnectionInfo.
+// ClientOptions and HTTPClient cannot be serialized; they are rebuilt from
+// Service and InsecureTLS when the connection info is read back.
+type connectionInfoJSON struct {
+	Service          Service  `json:"service,omitempty"`
+	ProjectID        string   `json:"projectID,omitempty"`
+	Namespace        string   `json:"namespace,omitempty"`
+	HTTPEndpoint     Endpoint `json:"httpEndpoint"`
+	GRPCEndpoint     Endpoint `json:"grpcEndpoint"`
+	InternalEndpoint string   `json:"internalEndpoint,omitempty"`
+	EmulatorAddress  string   `json:"emulatorAddress,omitempty"`
+	TLSAddress       string   `json:"tlsAddress,omitempty"`
+	WebSocketAddress string   `json:"webSocketAddress,omitempty"`
+	MetricsEndpoint  string   `json:"metricsEndpoint,omitempty"`
+	QueryEndpoint    string   `json:"queryEndpoint,omitempty"`
+	CACert           string   `json:"caCert,omitempty"`
+	Username         string   `json:"username,omitempty"`
+	Password         string   `json:"password,omitempty"`
+	// InsecureTLS records that HTTPClient skips certificate verification.
+	InsecureTLS bool `json:"insecureTLS,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The CA certificate is written as
+// PEM text. ClientOptions and HTTPClient are not written; UnmarshalJSON
+// rebuilds them for the Google Cloud emulators.
+func (c EmulatorConnectionInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(connectionInfoJSON{
+		Service:          c.Service,
+		ProjectID:        c.ProjectID,
+		Namespace:        c.Namespace,
+		HTTPEndpoint:     c.HTTPEndpoint,
+		GRPCEndpoint:     c.GRPCEndpoint,
+		InternalEndpoint: c.InternalEndpoint,
+		EmulatorAddress:  c.EmulatorAddress,
+		TLSAddress:       c.TLSAddress,
+		WebSocketAddress: c.WebSocketAddress,
+		MetricsEndpoint:  c.MetricsEndpoint,
+		QueryEndpoint:    c.QueryEndpoint,
+		CACert:           string(c.CACert),
+		Username:         c.Username,
+		Password:         c.Password,
+		InsecureTLS:      c.HTTPClient != nil,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *EmulatorConnectionInfo) UnmarshalJSON(data []byte) error {
+	var j connectionInfoJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*c = EmulatorConnectionInfo{
+		Service:          j.Service,
+		ProjectID:        j.ProjectID,
+		Namespace:        j.Namespace,
+		HTTPEndpoint:     j.HTTPEndpoint,
+		GRPCEndpoint:     j.GRPCEndpoint,
+		InternalEndpoint: j.InternalEndpoint,
+		EmulatorAddress:  j.EmulatorAddress,
+		TLSAddress:       j.TLSAddress,
+		WebSocketAddress: j.WebSocketAddress,
+		MetricsEndpoint:  j.MetricsEndpoint,
+		QueryEndpoint:    j.QueryEndpoint,
+		Username:         j.Username,
+		Password:         j.Password,
+	}
+	if j.CACert != "" {
+		c.CACert = []byte(j.CACert)
+	}
+	if j.InsecureTLS {
+		c.HTTPClient = newInsecureHTTPClient()
+	}
+
+	switch c.Service {
+	case ServicePubsub, ServiceFirestore, ServiceBigQuery:
+		c.ClientOptions = getEmulatorOptions(c.HTTPEndpoint.Endpoint)
+	case ServiceGCS:
+		// As in SetupGCSEmulator, GCS clients find the emulator through
+		// STORAGE_EMULATOR_HOST (see SetEnv).
+		c.ClientOptions = []option.ClientOption{option.WithoutAuthentication()}
+		if c.HTTPClient != nil {
+			c.ClientOptions = append(c.ClientOptions, option.WithHTTPClient(c.HTTPClient))
+		}
+	}
+	return nil
+}
+
+// WriteToFile writes the connection info as JSON to path, so a test can hand
+// it to a binary it spawns (see ReadFromFile). The file is only readable by
+// the current user, since it may contain credentials.
+func (c EmulatorConnectionInfo) WriteToFile(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection info: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write connection info: %w", err)
+	}
+	return nil
+}
+
+// ReadFromFile replaces c with the connection info stored at path by WriteToFile.
+func (c *EmulatorConnectionInfo) ReadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read connection info: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse connection info %s: %w", path, err)
+	}
+	return nil
+}
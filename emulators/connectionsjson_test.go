@@ -0,0 +1,71 @@
+package emulators
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmulatorConnectionInfoJSON(t *testing.T) {
+	certs, err := generateTestCertificates()
+	require.NoError(t, err)
+	original := EmulatorConnectionInfo{
+		Service:         ServiceMQTT,
+		EmulatorAddress: "tcp://localhost:1883",
+		TLSAddress:      "tls://localhost:8883",
+		CACert:          certs.CACert,
+		Username:        "device",
+		Password:        "s3cret",
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"caCert":"-----BEGIN CERTIFICATE-----`, "CA should be written as PEM text")
+
+	var decoded EmulatorConnectionInfo
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, original, decoded)
+}
+
+func TestEmulatorConnectionInfoJSONRebuildsClientOptions(t *testing.T) {
+	tests := []struct {
+		connInfo    EmulatorConnectionInfo
+		wantOptions int
+	}{
+		{EmulatorConnectionInfo{Service: ServicePubsub, HTTPEndpoint: Endpoint{Port: "8085", Endpoint: "localhost:1001"}}, 3},
+		{EmulatorConnectionInfo{Service: ServiceBigQuery, HTTPEndpoint: Endpoint{Port: "9050/tcp", Endpoint: "http://localhost:1002"}}, 3},
+		{EmulatorConnectionInfo{Service: ServiceGCS, HTTPEndpoint: Endpoint{Endpoint: "localhost:1003"}, HTTPClient: newInsecureHTTPClient()}, 2},
+		{EmulatorConnectionInfo{Service: ServiceRedis, EmulatorAddress: "localhost:6379"}, 0},
+		{EmulatorConnectionInfo{Service: ServiceTemporal, GRPCEndpoint: Endpoint{Port: "7233", Endpoint: "localhost:7233"}, Namespace: "orders"}, 0},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.connInfo)
+		require.NoError(t, err)
+
+		var decoded EmulatorConnectionInfo
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, tt.connInfo.HTTPEndpoint, decoded.HTTPEndpoint)
+		require.Len(t, decoded.ClientOptions, tt.wantOptions, string(tt.connInfo.Service))
+		require.Equal(t, tt.connInfo.HTTPClient != nil, decoded.HTTPClient != nil)
+	}
+}
+
+func TestEmulatorConnectionInfoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pubsub.json")
+	original := EmulatorConnectionInfo{Service: ServicePubsub, HTTPEndpoint: Endpoint{Port: "8085", Endpoint: "localhost:1001"}}
+	require.NoError(t, original.WriteToFile(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	var read EmulatorConnectionInfo
+	require.NoError(t, read.ReadFromFile(path))
+	require.Equal(t, original.HTTPEndpoint, read.HTTPEndpoint)
+	require.Equal(t, original.EnvVars(), read.EnvVars())
+
+	require.Error(t, read.ReadFromFile(filepath.Join(t.TempDir(), "missing.json")))
+}
@@ -1,8 +1,13 @@
 package emulators
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -24,17 +29,30 @@ const (
 
 	// testFirestoreEmulatorPort is the default port for the Firestore emulator.
 	testFirestoreEmulatorPort = "8080"
+
+	// firestoreRulesContainerPath is where security rules are mounted in the container.
+	firestoreRulesContainerPath = "/firestore/firestore.rules"
 )
 
 // PubsubConfig holds configuration specific to the Pub/Sub emulator.
 type PubsubConfig struct {
 	GCImageContainer
 	// Note: TopicSubs map is no longer needed as the v2 emulator auto-creates resources.
+
+	// HostAccessPorts are ports on the host that the emulator must be able to
+	// reach, such as endpoints started with StartPushEndpoint.
+	HostAccessPorts []int
 }
 
 // FirestoreConfig holds configuration specific to the Firestore emulator.
 type FirestoreConfig struct {
 	GCImageContainer
+	// RulesFile is the path to a security rules file on the host. It is
+	// mounted into the container and loaded by the emulator at startup.
+	RulesFile string
+	// Rules is the security rules source. It takes precedence over RulesFile.
+	// When neither is set the emulator runs in open-access mode.
+	Rules string
 }
 
 // GetDefaultPubsubConfig provides a default configuration for the Pub/Sub emulator.
@@ -66,41 +84,61 @@ func GetDefaultFirestoreConfig(projectID string) FirestoreConfig {
 // SetupPubsubEmulator starts a Pub/Sub emulator container and configures it.
 // It automatically handles container startup and teardown via t.Cleanup.
 // The v2 emulator will create topics and subscriptions on first use.
-func SetupPubsubEmulator(t *testing.T, ctx context.Context, cfg PubsubConfig) EmulatorConnectionInfo {
+func SetupPubsubEmulator(t testing.TB, ctx context.Context, cfg PubsubConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
 
-	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
-	cmd := []string{
-		"gcloud", "beta", "emulators", "pubsub", "start",
-		fmt.Sprintf("--project=%s", cfg.ProjectID),
-		fmt.Sprintf("--host-port=0.0.0.0:%s", cfg.EmulatorPort),
-	}
-	req := testcontainers.ContainerRequest{
-		Image:        cfg.EmulatorImage,
-		ExposedPorts: []string{httpPort},
-		Cmd:          cmd,
-		WaitingFor:   wait.ForListeningPort(nat.Port(cfg.EmulatorPort)),
-	}
-
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+	connInfo, terminate, err := StartPubsubEmulator(ctx, cfg, withTestLogs(t, "Pub/Sub", opts)...)
 	require.NoError(t, err)
 
 	t.Cleanup(func() {
 		termCtx, termCancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer termCancel()
-		if err := container.Terminate(termCtx); err != nil {
+		if err := terminate(termCtx); err != nil {
 			log.Warn().Err(err).Msg("Failed to terminate Pub/Sub emulator container")
 		}
 	})
 
-	host, err := container.Host(ctx)
-	require.NoError(t, err)
-	port, err := container.MappedPort(ctx, nat.Port(cfg.EmulatorPort))
-	require.NoError(t, err)
-	emulatorHost := fmt.Sprintf("%s:%s", host, port.Port())
+	t.Logf("Pub/Sub emulator container started, listening on: %s", connInfo.HTTPEndpoint.Endpoint)
+	if cfg.SetEnvVariables {
+		connInfo.SetEnv(t)
+	}
+	return connInfo
+}
 
-	t.Logf("Pub/Sub emulator container started, listening on: %s", emulatorHost)
+// StartPubsubEmulator starts a Pub/Sub emulator container and waits for its
+// gRPC service to accept clients. Unlike SetupPubsubEmulator it needs no
+// testing.TB and sets no environment variables (see EmulatorConnectionInfo.EnvVars);
+// the caller must call the returned TerminateFunc.
+func StartPubsubEmulator(ctx context.Context, cfg PubsubConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	cmd := []string{
+		"gcloud", "beta", "emulators", "pubsub", "start",
+		fmt.Sprintf("--project=%s", cfg.ProjectID),
+		fmt.Sprintf("--host-port=0.0.0.0:%s", cfg.EmulatorPort),
+	}
+	req := testcontainers.ContainerRequest{
+		Image:           cfg.EmulatorImage,
+		ExposedPorts:    []string{httpPort},
+		Cmd:             cmd,
+		WaitingFor:      wait.ForListeningPort(nat.Port(cfg.EmulatorPort)),
+		HostAccessPorts: cfg.HostAccessPorts,
+	}
+
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, "", opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to start Pub/Sub emulator: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
 
+	emulatorHost, err := mappedAddress(ctx, container, cfg.EmulatorPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
 	clientOptions := getEmulatorOptions(emulatorHost)
 
 	// Verify connectivity by creating and immediately closing a client.
@@ -109,23 +147,57 @@ func SetupPubsubEmulator(t *testing.T, ctx context.Context, cfg PubsubConfig) Em
 	verifyCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	adminClient, err := pubsub.NewClient(verifyCtx, cfg.ProjectID, clientOptions...)
-	require.NoError(t, err)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to connect to Pub/Sub emulator: %w", err)
+	}
 	_ = adminClient.Close() // Close the temporary client.
 
-	return EmulatorConnectionInfo{
+	connInfo = EmulatorConnectionInfo{
+		Service:   ServicePubsub,
+		ProjectID: cfg.ProjectID,
 		HTTPEndpoint: Endpoint{
 			Port:     cfg.EmulatorPort,
 			Endpoint: emulatorHost,
 		},
 		ClientOptions: clientOptions,
 	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if err = runReadinessProbe(ctx, cfg.ReadinessProbe, connInfo); err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("Pub/Sub emulator is not ready: %w", err)
+	}
+	return connInfo, terminate, nil
 }
 
 // SetupFirestoreEmulator starts a Firestore emulator container and configures it.
 // It automatically handles container startup and teardown via t.Cleanup.
-func SetupFirestoreEmulator(t *testing.T, ctx context.Context, cfg FirestoreConfig) EmulatorConnectionInfo {
+func SetupFirestoreEmulator(t testing.TB, ctx context.Context, cfg FirestoreConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
 
+	connInfo, terminate, err := StartFirestoreEmulator(ctx, cfg, withTestLogs(t, "Firestore", opts)...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		termCtx, termCancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer termCancel()
+		if err := terminate(termCtx); err != nil {
+			log.Warn().Err(err).Msg("Failed to terminate Firestore emulator container")
+		}
+	})
+
+	t.Logf("Firestore emulator container started, listening on: %s", connInfo.HTTPEndpoint.Endpoint)
+	if cfg.SetEnvVariables {
+		connInfo.SetEnv(t)
+	}
+	return connInfo
+}
+
+// StartFirestoreEmulator starts a Firestore emulator container and waits for
+// its gRPC service to accept clients. Unlike SetupFirestoreEmulator it needs
+// no testing.TB and sets no environment variables; the caller must call the
+// returned TerminateFunc, which also removes the rules file.
+func StartFirestoreEmulator(ctx context.Context, cfg FirestoreConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
 	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
 	cmd := []string{
 		"gcloud", "beta", "emulators", "firestore", "start",
@@ -139,25 +211,38 @@ func SetupFirestoreEmulator(t *testing.T, ctx context.Context, cfg FirestoreConf
 		WaitingFor:   wait.ForListeningPort(nat.Port(cfg.EmulatorPort)),
 	}
 
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
-	require.NoError(t, err)
-
-	t.Cleanup(func() {
-		termCtx, termCancel := context.WithTimeout(context.Background(), 60*time.Second)
-		defer termCancel()
-		if err := container.Terminate(termCtx); err != nil {
-			log.Warn().Err(err).Msg("Failed to terminate Firestore emulator container")
+	var dir string
+	rulesFile := cfg.RulesFile
+	if cfg.Rules != "" {
+		if dir, err = os.MkdirTemp("", "firestore-rules-"); err != nil {
+			return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to create rules directory: %w", err)
 		}
-	})
-
-	host, err := container.Host(ctx)
-	require.NoError(t, err)
-	port, err := container.MappedPort(ctx, nat.Port(cfg.EmulatorPort))
-	require.NoError(t, err)
-	emulatorHost := fmt.Sprintf("%s:%s", host, port.Port())
+		rulesFile = filepath.Join(dir, "firestore.rules")
+		if err := os.WriteFile(rulesFile, []byte(cfg.Rules), 0644); err != nil {
+			_ = os.RemoveAll(dir)
+			return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to write rules file: %w", err)
+		}
+	}
+	if rulesFile != "" {
+		req.Files = []testcontainers.ContainerFile{{HostFilePath: rulesFile, ContainerFilePath: firestoreRulesContainerPath, FileMode: 0644}}
+		req.Cmd = append(req.Cmd, "--rules="+firestoreRulesContainerPath)
+	}
 
-	t.Logf("Firestore emulator container started, listening on: %s", emulatorHost)
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, dir, opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to start Firestore emulator: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
 
+	emulatorHost, err := mappedAddress(ctx, container, cfg.EmulatorPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
 	clientOptions := getEmulatorOptions(emulatorHost)
 
 	// We mirror the Pub/Sub setup to prove gRPC is ready.
@@ -165,14 +250,51 @@ func SetupFirestoreEmulator(t *testing.T, ctx context.Context, cfg FirestoreConf
 	verifyCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	fsClient, err := firestore.NewClient(verifyCtx, cfg.ProjectID, clientOptions...)
-	require.NoError(t, err)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to connect to Firestore emulator: %w", err)
+	}
 	_ = fsClient.Close() // Close the temporary client.
 
-	return EmulatorConnectionInfo{
+	connInfo = EmulatorConnectionInfo{
+		Service:   ServiceFirestore,
+		ProjectID: cfg.ProjectID,
 		HTTPEndpoint: Endpoint{
 			Port:     cfg.EmulatorPort,
 			Endpoint: emulatorHost,
 		},
 		ClientOptions: clientOptions,
 	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if err = runReadinessProbe(ctx, cfg.ReadinessProbe, connInfo); err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("Firestore emulator is not ready: %w", err)
+	}
+	return connInfo, terminate, nil
+}
+
+// ReloadRules replaces the security rules of a running Firestore emulator.
+// It lets a single emulator be reused across tests that need different rules.
+//
+// Note that rules only apply to unauthenticated or end-user requests; clients
+// built from the emulator's ClientOptions are not treated as admin.
+func ReloadRules(t testing.TB, ctx context.Context, connInfo EmulatorConnectionInfo, projectID, rules string) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"rules": map[string]interface{}{
+			"files": []map[string]string{{"content": rules}},
+		},
+	})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s:securityRules", connInfo.HTTPEndpoint.Endpoint, projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "Failed to reload Firestore rules")
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Firestore emulator rejected the rules")
 }
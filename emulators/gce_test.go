@@ -12,33 +12,12 @@ import (
 	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
 	"github.com/google/uuid"
 	"github.com/illmade-knight/go-test/emulators"
+	"github.com/illmade-knight/go-test/poll"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// createPubsubResources helper remains the same.
-func createPubsubResources(t *testing.T, ctx context.Context, client *pubsub.Client, projectID, topicID, subID string) {
-	t.Helper()
-	topicAdmin := client.TopicAdminClient
-	subAdmin := client.SubscriptionAdminClient
-	topicName := fmt.Sprintf("projects/%s/topics/%s", projectID, topicID)
-	_, err := topicAdmin.CreateTopic(ctx, &pubsubpb.Topic{Name: topicName})
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		_ = topicAdmin.DeleteTopic(context.Background(), &pubsubpb.DeleteTopicRequest{Topic: topicName})
-	})
-	subName := fmt.Sprintf("projects/%s/subscriptions/%s", projectID, subID)
-	_, err = subAdmin.CreateSubscription(ctx, &pubsubpb.Subscription{
-		Name:  subName,
-		Topic: topicName,
-	})
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		_ = subAdmin.DeleteSubscription(context.Background(), &pubsubpb.DeleteSubscriptionRequest{Subscription: subName})
-	})
-}
-
 func TestSetupPubsubEmulator(t *testing.T) {
 	t.Parallel()
 
@@ -64,32 +43,21 @@ func TestSetupPubsubEmulator(t *testing.T) {
 		_ = client.Close()
 	})
 
-	createPubsubResources(t, ctx, client, projectID, topicID, subID)
+	emulators.CreatePubsubResources(t, ctx, client, emulators.ResourceSpec{
+		Subscriptions: map[string]string{subID: topicID},
+	})
 
-	// Polling loop remains the same.
 	t.Logf("Polling for subscription %s to exist...", subID)
-	subAdmin := client.SubscriptionAdminClient
 	subName := fmt.Sprintf("projects/%s/subscriptions/%s", projectID, subID)
 	req := &pubsubpb.GetSubscriptionRequest{Subscription: subName}
-	pollCtx, pollCancel := context.WithTimeout(ctx, 30*time.Second)
-	defer pollCancel()
-
-	for {
-		_, err := subAdmin.GetSubscription(pollCtx, req)
-		if err == nil {
-			t.Logf("Subscription %s confirmed to exist.", subID)
-			break
+	poll.Until(t, ctx, 200*time.Millisecond, 30*time.Second, func() (bool, error) {
+		_, err := client.SubscriptionAdminClient.GetSubscription(ctx, req)
+		if status.Code(err) == codes.NotFound {
+			return false, err
 		}
-		if s, ok := status.FromError(err); ok && s.Code() == codes.NotFound {
-			select {
-			case <-time.After(200 * time.Millisecond):
-				continue
-			case <-pollCtx.Done():
-				t.Fatalf("Timed out waiting for subscription %s to exist. Last error: %v", subID, err)
-			}
-		}
-		t.Fatalf("Error while polling for subscription %s: %v", subID, err)
-	}
+		return err == nil, poll.Permanent(err)
+	})
+	t.Logf("Subscription %s confirmed to exist.", subID)
 
 	received := make(chan []byte, 1)
 
@@ -166,6 +134,24 @@ func TestSetupFirestoreEmulator(t *testing.T) {
 	t.Logf("Firestore emulator test passed. Connected to: %s", connInfo.HTTPEndpoint.Endpoint)
 }
 
+func TestSetupFirestoreEmulatorWithReadinessProbe(t *testing.T) {
+	t.Parallel()
+
+	projectID := "test-project-firestore-probe"
+	cfg := emulators.GetDefaultFirestoreConfig(projectID)
+	cfg.ReadinessProbe = emulators.FirestoreWriteProbe(projectID)
+	connInfo := emulators.SetupFirestoreEmulator(t, context.Background(), cfg)
+
+	// The probe already proved writes succeed, so the first one must too.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	client, err := firestore.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	_, _, err = client.Collection("testCollection").Add(ctx, map[string]interface{}{"field1": "value1"})
+	require.NoError(t, err)
+}
+
 // --- NEW DUAL EMULATOR TEST ---
 
 // TestSetupDualEmulators verifies that both emulators can be started and
@@ -217,3 +203,43 @@ func TestSetupDualEmulators(t *testing.T) {
 
 	t.Log("✅ Dual emulator test passed.")
 }
+
+func TestSetupFirestoreEmulatorWithRules(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-firestore-rules"
+	cfg := emulators.GetDefaultFirestoreConfig(projectID)
+	cfg.Rules = `rules_version = '2';
+service cloud.firestore {
+  match /databases/{database}/documents {
+    match /{document=**} {
+      allow read, write: if false;
+    }
+  }
+}`
+	connInfo := emulators.SetupFirestoreEmulator(t, context.Background(), cfg)
+
+	client, err := firestore.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	_, err = client.Collection("locked").Doc("doc").Set(ctx, map[string]interface{}{"field": "value"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err), "Write should be denied by the loaded rules")
+
+	emulators.ReloadRules(t, ctx, connInfo, projectID, `rules_version = '2';
+service cloud.firestore {
+  match /databases/{database}/documents {
+    match /{document=**} {
+      allow read, write: if true;
+    }
+  }
+}`)
+
+	_, err = client.Collection("locked").Doc("doc").Set(ctx, map[string]interface{}{"field": "value"})
+	require.NoError(t, err, "Write should be allowed after reloading the rules")
+}
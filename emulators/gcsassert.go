@@ -0,0 +1,125 @@
+package emulators
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+// RequireObjectExists fails the test unless object exists in bucket, and
+// returns its attributes.
+func RequireObjectExists(t testing.TB, ctx context.Context, client *storage.Client, bucket, object string) *storage.ObjectAttrs {
+	t.Helper()
+	attrs, err := client.Bucket(bucket).Object(object).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		require.FailNow(t, "Object does not exist", "gs://%s/%s", bucket, object)
+	}
+	require.NoError(t, err, "Failed to get attributes of gs://%s/%s", bucket, object)
+	return attrs
+}
+
+// RequireObjectContent fails the test unless object in bucket exists and
+// holds exactly want. Text content is compared as strings, so the failure
+// shows a line diff.
+func RequireObjectContent(t testing.TB, ctx context.Context, client *storage.Client, bucket, object string, want []byte) {
+	t.Helper()
+	got, err := readGCSObject(ctx, client, bucket, object)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		require.FailNow(t, "Object does not exist", "gs://%s/%s", bucket, object)
+	}
+	require.NoError(t, err, "Failed to read gs://%s/%s", bucket, object)
+	if utf8.Valid(got) && utf8.Valid(want) {
+		require.Equal(t, string(want), string(got), "Unexpected content of gs://%s/%s", bucket, object)
+		return
+	}
+	require.Equal(t, want, got, "Unexpected content of gs://%s/%s", bucket, object)
+}
+
+// DiffBucketAgainstDir compares the objects in bucket with the regular files
+// under the golden directory dir, named as SeedGCSDirectory names them, and
+// fails the test listing every object that is missing, unexpected or
+// differs from its file.
+func DiffBucketAgainstDir(t testing.TB, ctx context.Context, client *storage.Client, bucket, dir string) {
+	t.Helper()
+
+	want, err := readDirFiles(dir)
+	require.NoError(t, err, "Failed to read golden directory %s", dir)
+
+	got := make(map[string][]byte)
+	it := client.Bucket(bucket).Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		require.NoError(t, err, "Failed to list objects in %s", bucket)
+		content, err := readGCSObject(ctx, client, bucket, attrs.Name)
+		require.NoError(t, err, "Failed to read gs://%s/%s", bucket, attrs.Name)
+		got[attrs.Name] = content
+	}
+
+	if diffs := diffObjects(got, want); len(diffs) > 0 {
+		require.FailNow(t, fmt.Sprintf("Bucket %s differs from %s", bucket, dir), strings.Join(diffs, "\n"))
+	}
+}
+
+// readGCSObject returns the content of object in bucket.
+func readGCSObject(ctx context.Context, client *storage.Client, bucket, object string) ([]byte, error) {
+	r, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
+
+// diffObjects describes how the objects got differ from want, by name, one
+// line per object in name order.
+func diffObjects(got, want map[string][]byte) []string {
+	names := make([]string, 0, len(got)+len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []string
+	for _, name := range names {
+		g, inGot := got[name]
+		w, inWant := want[name]
+		switch {
+		case !inGot:
+			diffs = append(diffs, "missing:    "+name)
+		case !inWant:
+			diffs = append(diffs, "unexpected: "+name)
+		case !bytes.Equal(g, w):
+			diffs = append(diffs, fmt.Sprintf("differs:    %s (%d bytes, want %d; first difference at byte %d)", name, len(g), len(w), firstDifference(g, w)))
+		}
+	}
+	return diffs
+}
+
+// firstDifference returns the offset of the first byte at which a and b
+// differ.
+func firstDifference(a, b []byte) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
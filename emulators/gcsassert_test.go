@@ -0,0 +1,52 @@
+package emulators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffObjects(t *testing.T) {
+	got := map[string][]byte{
+		"same.txt":  []byte("same"),
+		"extra.txt": []byte("x"),
+		"diff.txt":  []byte("hello world"),
+	}
+	want := map[string][]byte{
+		"same.txt":    []byte("same"),
+		"missing.txt": []byte("m"),
+		"diff.txt":    []byte("hello there"),
+	}
+	require.Equal(t, []string{
+		"differs:    diff.txt (11 bytes, want 11; first difference at byte 6)",
+		"unexpected: extra.txt",
+		"missing:    missing.txt",
+	}, diffObjects(got, want))
+	require.Empty(t, diffObjects(want, want))
+	require.Equal(t, 2, firstDifference([]byte("ab"), []byte("abc")))
+}
+
+func TestGCSAssertionsIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-gcs-assert"
+	bucket := "output-bucket"
+	connInfo := SetupGCSEmulator(t, context.Background(), GetDefaultGCSConfig(projectID, bucket))
+	client := NewStorageClient(t, ctx, connInfo.ClientOptions)
+	require.NoError(t, client.Bucket(bucket).Create(ctx, projectID, nil))
+
+	golden := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(golden, "reports"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(golden, "reports", "daily.csv"), []byte("a,b\n1,2\n"), 0644))
+	SeedGCSDirectory(t, ctx, client, bucket, golden)
+
+	attrs := RequireObjectExists(t, ctx, client, bucket, "reports/daily.csv")
+	require.EqualValues(t, 8, attrs.Size)
+	RequireObjectContent(t, ctx, client, bucket, "reports/daily.csv", []byte("a,b\n1,2\n"))
+	DiffBucketAgainstDir(t, ctx, client, bucket, golden)
+}
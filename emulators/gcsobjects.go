@@ -0,0 +1,154 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+// SeedGCSObjects writes each entry of objects (object name to content) into
+// bucket and registers a t.Cleanup hook that deletes them again.
+// The bucket must already exist.
+func SeedGCSObjects(t testing.TB, ctx context.Context, client *storage.Client, bucket string, objects map[string][]byte) {
+	t.Helper()
+
+	// Write in a stable order so failures are reproducible.
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		obj := client.Bucket(bucket).Object(name)
+		w := obj.NewWriter(ctx)
+		_, err := w.Write(objects[name])
+		require.NoError(t, err, "Failed to write object %s", name)
+		require.NoError(t, w.Close(), "Failed to finalize object %s", name)
+
+		t.Cleanup(func() {
+			_ = obj.Delete(context.Background())
+		})
+	}
+}
+
+// SeedGCSDirectory mirrors every regular file under dir into bucket, using the
+// slash-separated path relative to dir as the object name (so "dir/a/b.json"
+// becomes object "a/b.json"). The objects are deleted via t.Cleanup.
+// The bucket must already exist.
+func SeedGCSDirectory(t testing.TB, ctx context.Context, client *storage.Client, bucket, dir string) {
+	t.Helper()
+
+	objects, err := readDirFiles(dir)
+	require.NoError(t, err, "Failed to read seed directory %s", dir)
+
+	SeedGCSObjects(t, ctx, client, bucket, objects)
+}
+
+// readDirFiles returns the content of every regular file under dir by its
+// slash-separated path relative to dir.
+func readDirFiles(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return files, err
+}
+
+// ApplyGCSLifecycle runs the Delete rules of lifecycle against the objects in
+// bucket as if it were now, and returns the names of the deleted objects.
+// fake-gcs-server does not run lifecycle rules, so tests call it to simulate
+// the passing of time; e.g. now = time.Now().AddDate(0, 0, 31) expires
+// objects under a 30-day AgeInDays rule.
+//
+// Only the AllObjects, AgeInDays, CreatedBefore, MatchesPrefix, MatchesSuffix
+// and MatchesStorageClasses conditions are supported; other rules fail the test.
+func ApplyGCSLifecycle(t testing.TB, ctx context.Context, client *storage.Client, bucket string, lifecycle storage.Lifecycle, now time.Time) []string {
+	t.Helper()
+
+	for _, rule := range lifecycle.Rules {
+		require.NoError(t, checkLifecycleRule(rule), "Unsupported lifecycle rule")
+	}
+
+	var deleted []string
+	it := client.Bucket(bucket).Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		require.NoError(t, err, "Failed to list objects in %s", bucket)
+		for _, rule := range lifecycle.Rules {
+			if lifecycleRuleMatches(rule.Condition, attrs, now) {
+				err := client.Bucket(bucket).Object(attrs.Name).Delete(ctx)
+				require.NoError(t, err, "Failed to delete expired object %s", attrs.Name)
+				deleted = append(deleted, attrs.Name)
+				break
+			}
+		}
+	}
+	return deleted
+}
+
+// checkLifecycleRule returns an error if ApplyGCSLifecycle cannot run rule.
+func checkLifecycleRule(rule storage.LifecycleRule) error {
+	if rule.Action.Type != storage.DeleteAction {
+		return fmt.Errorf("action %q is not supported", rule.Action.Type)
+	}
+	c := rule.Condition
+	if !c.CustomTimeBefore.IsZero() || c.DaysSinceCustomTime != 0 || c.DaysSinceNoncurrentTime != 0 ||
+		!c.NoncurrentTimeBefore.IsZero() || c.NumNewerVersions != 0 || c.Liveness == storage.Archived {
+		return errors.New("conditions on custom time or object versions are not supported")
+	}
+	return nil
+}
+
+// lifecycleRuleMatches reports whether an object meets every condition of a
+// lifecycle rule at now.
+func lifecycleRuleMatches(c storage.LifecycleCondition, attrs *storage.ObjectAttrs, now time.Time) bool {
+	if c.AgeInDays > 0 && now.Sub(attrs.Created) < time.Duration(c.AgeInDays)*24*time.Hour {
+		return false
+	}
+	if !c.CreatedBefore.IsZero() {
+		y, m, d := c.CreatedBefore.Date()
+		if !attrs.Created.Before(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)) {
+			return false
+		}
+	}
+	if len(c.MatchesPrefix) > 0 && !slices.ContainsFunc(c.MatchesPrefix, func(p string) bool { return strings.HasPrefix(attrs.Name, p) }) {
+		return false
+	}
+	if len(c.MatchesSuffix) > 0 && !slices.ContainsFunc(c.MatchesSuffix, func(s string) bool { return strings.HasSuffix(attrs.Name, s) }) {
+		return false
+	}
+	if len(c.MatchesStorageClasses) > 0 && !slices.Contains(c.MatchesStorageClasses, attrs.StorageClass) {
+		return false
+	}
+	// A rule without conditions never matches; AllObjects makes it match everything.
+	return c.AllObjects || c.AgeInDays > 0 || !c.CreatedBefore.IsZero() ||
+		len(c.MatchesPrefix) > 0 || len(c.MatchesSuffix) > 0 || len(c.MatchesStorageClasses) > 0
+}
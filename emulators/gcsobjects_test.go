@@ -0,0 +1,119 @@
+package emulators
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+func TestSetupGCSEmulatorWithSeededObjects(t *testing.T) {
+	testCtx, testCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(testCancel)
+
+	projectID := "test-project-gcs-seed"
+	bucket := "seed-bucket"
+	connInfo := SetupGCSEmulator(t, context.Background(), GetDefaultGCSConfig(projectID, bucket))
+	client := NewStorageClient(t, testCtx, connInfo.ClientOptions)
+	require.NoError(t, client.Bucket(bucket).Create(testCtx, projectID, nil))
+
+	SeedGCSObjects(t, testCtx, client, bucket, map[string][]byte{
+		"inline/one.txt": []byte("one"),
+		"inline/two.txt": []byte("two"),
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "top.json"), []byte(`{"a":1}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "deep.json"), []byte(`{"b":2}`), 0644))
+	SeedGCSDirectory(t, testCtx, client, bucket, dir)
+
+	var names []string
+	it := client.Bucket(bucket).Objects(testCtx, nil)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, attrs.Name)
+	}
+	require.ElementsMatch(t, []string{"inline/one.txt", "inline/two.txt", "top.json", "nested/deep.json"}, names)
+
+	r, err := client.Bucket(bucket).Object("nested/deep.json").NewReader(testCtx)
+	require.NoError(t, err)
+	defer func(r *storage.Reader) { _ = r.Close() }(r)
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, `{"b":2}`, string(content))
+}
+
+func TestLifecycleRuleMatches(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	attrs := &storage.ObjectAttrs{Name: "logs/app.log", Created: now.AddDate(0, 0, -10), StorageClass: "STANDARD"}
+
+	tests := []struct {
+		name string
+		cond storage.LifecycleCondition
+		want bool
+	}{
+		{"no conditions", storage.LifecycleCondition{}, false},
+		{"all objects", storage.LifecycleCondition{AllObjects: true}, true},
+		{"old enough", storage.LifecycleCondition{AgeInDays: 7}, true},
+		{"too young", storage.LifecycleCondition{AgeInDays: 30}, false},
+		{"created before", storage.LifecycleCondition{CreatedBefore: time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)}, true},
+		{"created after", storage.LifecycleCondition{CreatedBefore: time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)}, false},
+		{"prefix", storage.LifecycleCondition{MatchesPrefix: []string{"tmp/", "logs/"}}, true},
+		{"other prefix", storage.LifecycleCondition{MatchesPrefix: []string{"tmp/"}}, false},
+		{"suffix and age", storage.LifecycleCondition{MatchesSuffix: []string{".log"}, AgeInDays: 7}, true},
+		{"suffix but too young", storage.LifecycleCondition{MatchesSuffix: []string{".log"}, AgeInDays: 30}, false},
+		{"storage class", storage.LifecycleCondition{MatchesStorageClasses: []string{"NEARLINE"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, lifecycleRuleMatches(tt.cond, attrs, now))
+		})
+	}
+}
+
+func TestCheckLifecycleRule(t *testing.T) {
+	require.NoError(t, checkLifecycleRule(storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 30},
+	}))
+	require.Error(t, checkLifecycleRule(storage.LifecycleRule{
+		Action: storage.LifecycleAction{Type: storage.SetStorageClassAction, StorageClass: "NEARLINE"},
+	}))
+	require.Error(t, checkLifecycleRule(storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{NumNewerVersions: 2},
+	}))
+}
+
+func TestSetupGCSEmulatorWithLifecycle(t *testing.T) {
+	testCtx, testCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(testCancel)
+
+	lifecycle := storage.Lifecycle{Rules: []storage.LifecycleRule{{
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 30, MatchesPrefix: []string{"tmp/"}},
+	}}}
+	cfg := GetDefaultGCSConfig("test-project-gcs-lifecycle", "")
+	cfg.Buckets = []GCSBucket{{Name: "expiring", Lifecycle: lifecycle}}
+	connInfo := SetupGCSEmulator(t, context.Background(), cfg)
+	client := NewStorageClient(t, testCtx, connInfo.ClientOptions)
+	SeedGCSObjects(t, testCtx, client, "expiring", map[string][]byte{
+		"tmp/scratch.txt": []byte("scratch"),
+		"keep/data.txt":   []byte("data"),
+	})
+
+	require.Empty(t, ApplyGCSLifecycle(t, testCtx, client, "expiring", lifecycle, time.Now()))
+	deleted := ApplyGCSLifecycle(t, testCtx, client, "expiring", lifecycle, time.Now().AddDate(0, 0, 31))
+	require.Equal(t, []string{"tmp/scratch.txt"}, deleted)
+}
@@ -2,7 +2,10 @@ package emulators
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +32,88 @@ type GCSConfig struct {
 	BaseBucket string
 	// BaseStorage is the internal health check path for the emulator.
 	BaseStorage string
+	// Scheme is the protocol the emulator serves: "http" (the default) or
+	// "https". In https mode the emulator uses a self-signed certificate and
+	// the returned connection info carries a TLS-skipping HTTP client.
+	Scheme string
+	// PublicHost is the host name the emulator uses in generated URLs, such as
+	// media links and signed URLs. It is left to the emulator default when empty.
+	PublicHost string
+	// Buckets are created as soon as the emulator is ready, unlike BaseBucket.
+	Buckets []GCSBucket
+	// Notifications, when set, makes the emulator publish object change
+	// notifications to a Pub/Sub emulator.
+	Notifications *GCSNotificationConfig
+}
+
+// GCSBucket is a bucket the GCS emulator creates at startup.
+type GCSBucket struct {
+	Name string
+	// Lifecycle is sent with the bucket. fake-gcs-server does not run
+	// lifecycle rules by itself; use ApplyGCSLifecycle to run them as of a
+	// given time.
+	Lifecycle storage.Lifecycle
+}
+
+// GCSNotificationConfig configures the object change notifications of the
+// GCS emulator, so upload-triggered pipelines can be tested end to end.
+// fake-gcs-server sends the same attributes (eventType, bucketId, objectId)
+// and JSON payload as Cloud Storage, but supports a single topic only.
+type GCSNotificationConfig struct {
+	// PubsubEmulatorHost is the address of the Pub/Sub emulator as seen from
+	// the GCS container, usually the InternalEndpoint of its connection info.
+	PubsubEmulatorHost string
+	// ProjectID is the project of TopicID. It defaults to the GCS project.
+	ProjectID string
+	// TopicID is the topic notifications are published to. It must exist
+	// before the first event.
+	TopicID string
+	// Bucket restricts notifications to one bucket. All buckets notify when
+	// it is empty.
+	Bucket string
+	// ObjectPrefix restricts notifications to objects with this name prefix.
+	ObjectPrefix string
+	// EventTypes are the events to publish, such as storage.ObjectFinalizeEvent
+	// and storage.ObjectDeleteEvent. Only finalize events are published when
+	// it is empty.
+	EventTypes []string
+}
+
+// gcsEventNames maps Cloud Storage event types to fake-gcs-server event names.
+var gcsEventNames = map[string]string{
+	storage.ObjectFinalizeEvent:       "finalize",
+	storage.ObjectDeleteEvent:         "delete",
+	storage.ObjectMetadataUpdateEvent: "metadataUpdate",
+	storage.ObjectArchiveEvent:        "archive",
+}
+
+// args returns the fake-gcs-server flags that enable the notifications.
+func (n GCSNotificationConfig) args(projectID string) ([]string, error) {
+	if n.PubsubEmulatorHost == "" || n.TopicID == "" {
+		return nil, fmt.Errorf("GCS notifications need PubsubEmulatorHost and TopicID")
+	}
+	if n.ProjectID != "" {
+		projectID = n.ProjectID
+	}
+	args := []string{"-event.pubsub-project-id", projectID, "-event.pubsub-topic", n.TopicID}
+	if n.Bucket != "" {
+		args = append(args, "-event.bucket", n.Bucket)
+	}
+	if n.ObjectPrefix != "" {
+		args = append(args, "-event.object-prefix", n.ObjectPrefix)
+	}
+	if len(n.EventTypes) > 0 {
+		events := make([]string, 0, len(n.EventTypes))
+		for _, eventType := range n.EventTypes {
+			event, ok := gcsEventNames[eventType]
+			if !ok {
+				return nil, fmt.Errorf("unsupported GCS notification event type %q", eventType)
+			}
+			events = append(events, event)
+		}
+		args = append(args, "-event.list", strings.Join(events, ","))
+	}
+	return args, nil
 }
 
 // GetDefaultGCSConfig provides a default configuration for the GCS emulator.
@@ -43,6 +128,7 @@ func GetDefaultGCSConfig(projectID, baseBucket string) GCSConfig {
 		},
 		BaseBucket:  baseBucket,
 		BaseStorage: "/storage/v1/b",
+		Scheme:      "http",
 	}
 }
 
@@ -50,7 +136,7 @@ func GetDefaultGCSConfig(projectID, baseBucket string) GCSConfig {
 // It automatically registers a t.Cleanup hook to close the client.
 //
 // This function *only* creates a client. It does NOT create any buckets.
-func NewStorageClient(t *testing.T, ctx context.Context, opts []option.ClientOption) *storage.Client {
+func NewStorageClient(t testing.TB, ctx context.Context, opts []option.ClientOption) *storage.Client {
 	t.Helper()
 	gcsClient, err := storage.NewClient(ctx, opts...)
 	require.NoError(t, err)
@@ -73,55 +159,176 @@ func NewStorageClient(t *testing.T, ctx context.Context, opts []option.ClientOpt
 // SetupGCSEmulator starts a GCS emulator (fake-gcs-server) container.
 // It automatically handles container startup and teardown via t.Cleanup.
 // It returns connection info for creating a client.
-func SetupGCSEmulator(t *testing.T, ctx context.Context, cfg GCSConfig) EmulatorConnectionInfo {
+func SetupGCSEmulator(t testing.TB, ctx context.Context, cfg GCSConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
 
-	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
-	req := testcontainers.ContainerRequest{
-		Image:        cfg.EmulatorImage,
-		ExposedPorts: []string{httpPort},
-		Cmd:          []string{"-scheme", "http"}, // Explicitly tell fake-gcs-server to use http
-		WaitingFor: wait.ForHTTP(cfg.BaseStorage).WithPort(nat.Port(httpPort)).WithStatusCodeMatcher(
-			func(status int) bool {
-				// The fake-gcs-server returns 400 for an empty listing, which is healthy.
-				return status > 0
-			}).WithStartupTimeout(20 * time.Second),
-	}
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+	connInfo, terminate, err := StartGCSEmulator(ctx, cfg, withTestLogs(t, "GCS", opts)...)
 	require.NoError(t, err)
 
 	t.Cleanup(func() {
-		if err := container.Terminate(context.Background()); err != nil {
+		if err := terminate(context.Background()); err != nil {
 			t.Logf("Failed to terminate GCS container: %v", err)
 		}
 	})
 
-	// The endpoint must be retrieved and set as an environment variable
-	// for the GCS client library to work correctly without https.
-	emulatorEndpoint, err := container.Endpoint(ctx, "") // Returns "host:port"
+	useGCSEmulator(t, ctx, connInfo)
+	return connInfo
+}
+
+// useGCSEmulator points the storage client library at a started emulator and
+// verifies connectivity.
+//
+// The endpoint must be set as an environment variable for the GCS client
+// library to work correctly without https, so this cannot run in parallel tests.
+func useGCSEmulator(t testing.TB, ctx context.Context, connInfo EmulatorConnectionInfo) {
+	t.Helper()
+
+	connInfo.SetEnv(t)
+	t.Logf("GCS emulator container started at: %s", gcsEmulatorHost(connInfo))
+
+	// We must also create a client here to verify connectivity
+	// before the env var (t.Setenv) goes out of scope.
+	client, err := storage.NewClient(ctx, connInfo.ClientOptions...)
 	require.NoError(t, err)
-	t.Setenv("STORAGE_EMULATOR_HOST", emulatorEndpoint)
-	t.Logf("GCS emulator container started at: %s", emulatorEndpoint)
+	_ = client.Close()
+}
+
+// gcsEmulatorHost returns the STORAGE_EMULATOR_HOST value for a started emulator.
+func gcsEmulatorHost(connInfo EmulatorConnectionInfo) string {
+	if connInfo.HTTPClient != nil {
+		// The client library assumes http unless the scheme is given explicitly.
+		return "https://" + connInfo.HTTPEndpoint.Endpoint
+	}
+	return connInfo.HTTPEndpoint.Endpoint
+}
+
+// newGCSClient returns a storage client that addresses the emulator directly
+// rather than through STORAGE_EMULATOR_HOST, which is only set for the test
+// that started it.
+func newGCSClient(ctx context.Context, connInfo EmulatorConnectionInfo) (*storage.Client, error) {
+	host := gcsEmulatorHost(connInfo)
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	opts := append([]option.ClientOption{option.WithEndpoint(host + "/storage/v1/")}, connInfo.ClientOptions...)
+	return storage.NewClient(ctx, opts...)
+}
+
+// createGCSBuckets creates buckets in the project of connInfo.
+func createGCSBuckets(ctx context.Context, connInfo EmulatorConnectionInfo, buckets []GCSBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	client, err := newGCSClient(ctx, connInfo)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	for _, b := range buckets {
+		if err := client.Bucket(b.Name).Create(ctx, connInfo.ProjectID, &storage.BucketAttrs{Lifecycle: b.Lifecycle}); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// StartGCSEmulator starts a fake-gcs-server container and waits for it to
+// serve requests, without needing a testing.TB. The caller must call the
+// returned TerminateFunc, and must set STORAGE_EMULATOR_HOST (see
+// EmulatorConnectionInfo.EnvVars) before creating storage clients.
+func StartGCSEmulator(ctx context.Context, cfg GCSConfig, opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	cmd := []string{"-scheme", scheme} // Explicitly tell fake-gcs-server which scheme to use
+	if cfg.PublicHost != "" {
+		cmd = append(cmd, "-public-host", cfg.PublicHost)
+	}
+	var env map[string]string
+	if cfg.Notifications != nil {
+		args, err := cfg.Notifications.args(cfg.ProjectID)
+		if err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+		cmd = append(cmd, args...)
+		env = map[string]string{"PUBSUB_EMULATOR_HOST": cfg.Notifications.PubsubEmulatorHost}
+	}
+
+	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	waitStrategy := wait.ForHTTP(cfg.BaseStorage).WithPort(nat.Port(httpPort)).WithStatusCodeMatcher(
+		func(status int) bool {
+			// The fake-gcs-server returns 400 for an empty listing, which is healthy.
+			return status > 0
+		}).WithStartupTimeout(20 * time.Second)
+	if scheme == "https" {
+		waitStrategy = waitStrategy.WithTLS(true, &tls.Config{InsecureSkipVerify: true})
+	}
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: []string{httpPort},
+		Cmd:          cmd,
+		Env:          env,
+		WaitingFor:   waitStrategy,
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, "", opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to start GCS emulator: %w", err)
+	}
+
+	emulatorEndpoint, err := container.Endpoint(ctx, "") // Returns "host:port"
+	if err != nil {
+		_ = terminate(context.Background())
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to get GCS emulator endpoint: %w", err)
+	}
 
 	// Note: The GCS client options are special. They rely on the
 	// STORAGE_EMULATOR_HOST env var and do not use getEmulatorOptions().
 	// We return a "clean" set of options (no endpoint, no insecure credentials)
 	// and let the Google client library automatically detect the env var.
-	opts := []option.ClientOption{
+	clientOpts := []option.ClientOption{
 		option.WithoutAuthentication(),
 	}
 
-	// We must also create a client here to verify connectivity
-	// before the env var (t.Setenv) goes out of scope.
-	client, err := storage.NewClient(ctx, opts...)
-	require.NoError(t, err)
-	_ = client.Close()
+	// In https mode the emulator's certificate is self-signed, so clients
+	// must skip verification.
+	var httpClient *http.Client
+	if scheme == "https" {
+		httpClient = newInsecureHTTPClient()
+		clientOpts = append(clientOpts, option.WithHTTPClient(httpClient))
+	}
 
-	return EmulatorConnectionInfo{
+	connInfo := EmulatorConnectionInfo{
+		Service:   ServiceGCS,
+		ProjectID: cfg.ProjectID,
 		HTTPEndpoint: Endpoint{
 			Port:     cfg.EmulatorPort,
 			Endpoint: emulatorEndpoint, // This is just "host:port"
 		},
-		ClientOptions: opts,
+		ClientOptions: clientOpts,
+		HTTPClient:    httpClient,
+	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		_ = terminate(context.Background())
+		return EmulatorConnectionInfo{}, nil, err
 	}
+	if err := createGCSBuckets(ctx, connInfo, cfg.Buckets); err != nil {
+		_ = terminate(context.Background())
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	// STORAGE_EMULATOR_HOST is not set yet, so a probe must address the
+	// emulator through connInfo.HTTPEndpoint itself.
+	if err := runReadinessProbe(ctx, cfg.ReadinessProbe, connInfo); err != nil {
+		_ = terminate(context.Background())
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("GCS emulator is not ready: %w", err)
+	}
+	return connInfo, terminate, nil
+}
+
+// newInsecureHTTPClient returns an HTTP client that skips TLS certificate
+// verification, for talking to emulators that use self-signed certificates.
+func newInsecureHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Transport: transport}
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/storage"
 	"github.com/stretchr/testify/require" // Using require for fatal assertions
 )
 
@@ -68,4 +70,86 @@ func TestGetDefaultGCSConfig(t *testing.T) {
 	if cfg.BaseStorage != "/storage/v1/b" {
 		t.Errorf("Expected BaseStorage %q, got %q", "/storage/v1/b", cfg.BaseStorage)
 	}
+	if cfg.Scheme != "http" {
+		t.Errorf("Expected Scheme %q, got %q", "http", cfg.Scheme)
+	}
+}
+
+func TestSetupGCSEmulatorHTTPS(t *testing.T) {
+	testCtx, testCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(testCancel)
+
+	projectID := "test-project-gcs-https"
+	bucket := "https-bucket"
+
+	cfg := GetDefaultGCSConfig(projectID, bucket)
+	cfg.Scheme = "https"
+	cfg.PublicHost = "storage.test.local"
+	connInfo := SetupGCSEmulator(t, context.Background(), cfg)
+	require.NotNil(t, connInfo.HTTPClient, "https mode should return a TLS-skipping HTTP client")
+
+	gcsClient := NewStorageClient(t, testCtx, connInfo.ClientOptions)
+	require.NoError(t, gcsClient.Bucket(bucket).Create(testCtx, projectID, nil))
+
+	w := gcsClient.Bucket(bucket).Object("hello.txt").NewWriter(testCtx)
+	_, err := w.Write([]byte("hello over https"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// The raw HTTP client can talk to the emulator's JSON API directly.
+	resp, err := connInfo.HTTPClient.Get("https://" + connInfo.HTTPEndpoint.Endpoint + "/storage/v1/b/" + bucket + "/o")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+}
+
+func TestGCSNotificationConfigArgs(t *testing.T) {
+	args, err := GCSNotificationConfig{
+		PubsubEmulatorHost: "pubsub:8085",
+		TopicID:            "uploads",
+		Bucket:             "inbox",
+		ObjectPrefix:       "incoming/",
+		EventTypes:         []string{storage.ObjectFinalizeEvent, storage.ObjectDeleteEvent},
+	}.args("gcs-project")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-event.pubsub-project-id", "gcs-project",
+		"-event.pubsub-topic", "uploads",
+		"-event.bucket", "inbox",
+		"-event.object-prefix", "incoming/",
+		"-event.list", "finalize,delete",
+	}, args)
+
+	args, err = GCSNotificationConfig{PubsubEmulatorHost: "pubsub:8085", ProjectID: "pubsub-project", TopicID: "uploads"}.args("gcs-project")
+	require.NoError(t, err)
+	require.Equal(t, []string{"-event.pubsub-project-id", "pubsub-project", "-event.pubsub-topic", "uploads"}, args)
+
+	_, err = GCSNotificationConfig{PubsubEmulatorHost: "pubsub:8085", TopicID: "uploads", EventTypes: []string{"OBJECT_RESTORE"}}.args("gcs-project")
+	require.Error(t, err)
+	_, err = GCSNotificationConfig{TopicID: "uploads"}.args("gcs-project")
+	require.Error(t, err)
+}
+
+func TestSetupGCSEmulatorNotifications(t *testing.T) {
+	testCtx, testCancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	t.Cleanup(testCancel)
+
+	projectID := "test-project-gcs-events"
+	pubsubInfo := SetupPubsubEmulator(t, context.Background(), GetDefaultPubsubConfig(projectID))
+	psClient, err := pubsub.NewClient(testCtx, projectID, pubsubInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = psClient.Close() })
+	CreatePubsubResources(t, testCtx, psClient, ResourceSpec{Subscriptions: map[string]string{"uploads-sub": "uploads"}})
+
+	cfg := GetDefaultGCSConfig(projectID, "")
+	cfg.Buckets = []GCSBucket{{Name: "inbox"}}
+	cfg.Notifications = &GCSNotificationConfig{PubsubEmulatorHost: pubsubInfo.InternalEndpoint, TopicID: "uploads"}
+	connInfo := SetupGCSEmulator(t, context.Background(), cfg)
+	client := NewStorageClient(t, testCtx, connInfo.ClientOptions)
+	SeedGCSObjects(t, testCtx, client, "inbox", map[string][]byte{"report.csv": []byte("a,b")})
+
+	attrs := ConsumeMessages(t, testCtx, psClient, "uploads-sub", 1, time.Minute)[0].Attributes
+	require.Equal(t, storage.ObjectFinalizeEvent, attrs["eventType"])
+	require.Equal(t, "inbox", attrs["bucketId"])
+	require.Equal(t, "report.csv", attrs["objectId"])
 }
@@ -0,0 +1,63 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// DefaultImages returns the image of every default emulator configuration,
+// before any SetImageRegistry rewrite.
+func DefaultImages() []string {
+	return []string{
+		testEmulatorImage, // Pub/Sub and Firestore
+		testGCSImage,
+		testBigQueryEmulatorImage,
+		cloudTestRedisImage,
+		mosquitoImage,
+		emqxImage,
+		hivemqImage,
+		prometheusImage,
+		otelCollectorImage,
+		jaegerImage,
+		temporalImage,
+	}
+}
+
+// PullImages pulls images concurrently, skipping any already present, so a
+// CI setup step can warm the image cache and keep pulls out of test timeouts.
+// Images are rewritten by SetImageRegistry and pulled with the credentials
+// set by SetImageRegistryAuth, exactly as the Setup functions would. It
+// returns the errors of all failed pulls.
+func PullImages(ctx context.Context, images ...string) error {
+	provider, err := testcontainers.NewDockerProvider()
+	if err != nil {
+		return fmt.Errorf("failed to create docker provider: %w", err)
+	}
+	defer func() { _ = provider.Close() }()
+
+	errs := make([]error, len(images))
+	var wg sync.WaitGroup
+	for i, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = pullImage(ctx, provider, registryImage(image))
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// PrewarmDefaults pulls every image in DefaultImages. Run it in a CI setup
+// step or from TestMain before the first Setup function:
+//
+//	if err := emulators.PrewarmDefaults(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+func PrewarmDefaults(ctx context.Context) error {
+	return PullImages(ctx, DefaultImages()...)
+}
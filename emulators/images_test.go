@@ -0,0 +1,40 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultImages(t *testing.T) {
+	// Every default config must be covered, so PrewarmDefaults warms them all.
+	configured := []string{
+		GetDefaultPubsubConfig("p").EmulatorImage,
+		GetDefaultFirestoreConfig("p").EmulatorImage,
+		GetDefaultGCSConfig("p", "b").EmulatorImage,
+		GetDefaultBigQueryConfig("p", nil, nil).EmulatorImage,
+		GetDefaultRedisImageContainer().EmulatorImage,
+		GetDefaultMqttImageContainer().EmulatorImage,
+		GetDefaultEMQXConfig().EmulatorImage,
+		GetDefaultHiveMQConfig().EmulatorImage,
+		GetDefaultPrometheusConfig().EmulatorImage,
+		GetDefaultOTelCollectorConfig().EmulatorImage,
+		GetDefaultJaegerConfig().EmulatorImage,
+	}
+	images := DefaultImages()
+	for _, image := range configured {
+		require.Contains(t, images, image)
+	}
+}
+
+func TestPullImagesIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	require.NoError(t, PullImages(ctx, cloudTestRedisImage))
+	// A second pull finds the image locally.
+	require.NoError(t, PullImages(ctx, cloudTestRedisImage))
+	require.Error(t, PullImages(ctx, "emulators-test/does-not-exist:never"))
+}
@@ -0,0 +1,243 @@
+package emulators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// jaegerImage is the default Jaeger all-in-one image to use.
+	jaegerImage = "jaegertracing/all-in-one:1.70.0"
+	// jaegerQueryPort is the internal port of the Jaeger UI and query API.
+	jaegerQueryPort = "16686"
+	// jaegerAdminPort is the internal port of the health check.
+	jaegerAdminPort = "14269"
+)
+
+// JaegerConfig holds configuration for a Jaeger all-in-one container.
+type JaegerConfig struct {
+	ImageContainer
+	// QueryPort is the internal port of the Jaeger query API, returned as
+	// EmulatorConnectionInfo.QueryEndpoint.
+	QueryPort string
+}
+
+// GetDefaultJaegerConfig returns a default configuration for a Jaeger
+// all-in-one container, which receives OTLP and keeps traces in memory.
+func GetDefaultJaegerConfig() JaegerConfig {
+	return JaegerConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage:    jaegerImage,
+			EmulatorPort:     otelCollectorHTTPPort,
+			EmulatorGRPCPort: otelCollectorGRPCPort,
+		},
+		QueryPort: jaegerQueryPort,
+	}
+}
+
+// SetupJaegerContainer starts a Jaeger all-in-one container. It automatically
+// handles container startup and teardown via t.Cleanup. The returned
+// connection info has the OTLP/HTTP endpoint as HTTPEndpoint, the OTLP/gRPC
+// endpoint as GRPCEndpoint, and the query API as QueryEndpoint; use
+// NewJaegerQueryClient to look up the traces that arrived.
+func SetupJaegerContainer(t testing.TB, ctx context.Context, cfg JaegerConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
+
+	connInfo, terminate, err := StartJaegerContainer(ctx, cfg, withTestLogs(t, "Jaeger", opts)...)
+	require.NoError(t, err, "Failed to start Jaeger container")
+
+	t.Cleanup(func() {
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate Jaeger container: %v", err)
+		}
+	})
+
+	t.Logf("Jaeger container started with OTLP/gRPC at %s and query API at %s", connInfo.GRPCEndpoint.Endpoint, connInfo.QueryEndpoint)
+	return connInfo
+}
+
+// StartJaegerContainer starts a Jaeger all-in-one container and waits for its
+// health check to pass. Unlike SetupJaegerContainer it needs no testing.TB;
+// the caller must call the returned TerminateFunc.
+func StartJaegerContainer(ctx context.Context, cfg JaegerConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	grpcPort := fmt.Sprintf("%s/tcp", cfg.EmulatorGRPCPort)
+	queryPort := fmt.Sprintf("%s/tcp", cfg.QueryPort)
+	adminPort := jaegerAdminPort + "/tcp"
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: []string{httpPort, grpcPort, queryPort, adminPort},
+		Env:          map[string]string{"COLLECTOR_OTLP_ENABLED": "true"},
+		WaitingFor: wait.ForAll(
+			wait.ForHTTP("/").WithPort(nat.Port(adminPort)),
+			wait.ForListeningPort(nat.Port(grpcPort)),
+			wait.ForListeningPort(nat.Port(httpPort)),
+		).WithDeadline(60 * time.Second),
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, "", opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	httpAddr, err := mappedAddress(ctx, container, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	grpcAddr, err := mappedAddress(ctx, container, grpcPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	queryAddr, err := mappedAddress(ctx, container, queryPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	internal, err := internalAddress(ctx, container, cfg.ImageContainer, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	return EmulatorConnectionInfo{
+		Service:          ServiceJaeger,
+		HTTPEndpoint:     Endpoint{Port: cfg.EmulatorPort, Endpoint: "http://" + httpAddr},
+		GRPCEndpoint:     Endpoint{Port: cfg.EmulatorGRPCPort, Endpoint: grpcAddr},
+		InternalEndpoint: internal,
+		QueryEndpoint:    "http://" + queryAddr,
+	}, terminate, nil
+}
+
+// JaegerTrace is a trace returned by the Jaeger query API.
+type JaegerTrace struct {
+	TraceID string
+	Spans   []JaegerSpan
+}
+
+// Span returns the first span of the trace with the given operation name.
+func (tr JaegerTrace) Span(operation string) (JaegerSpan, bool) {
+	for _, span := range tr.Spans {
+		if span.OperationName == operation {
+			return span, true
+		}
+	}
+	return JaegerSpan{}, false
+}
+
+// JaegerSpan is a span of a JaegerTrace.
+type JaegerSpan struct {
+	TraceID       string
+	SpanID        string
+	OperationName string
+	// ServiceName is the service.name resource attribute of the span.
+	ServiceName string
+	StartTime   time.Time
+	Duration    time.Duration
+	// Tags holds the span attributes. Values are strings, bools or, for
+	// numeric attributes, float64.
+	Tags map[string]any
+}
+
+// JaegerQueryClient looks up traces through the Jaeger query API.
+type JaegerQueryClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewJaegerQueryClient returns a query client for a Jaeger container started
+// by SetupJaegerContainer.
+func NewJaegerQueryClient(connInfo EmulatorConnectionInfo) *JaegerQueryClient {
+	return &JaegerQueryClient{endpoint: connInfo.QueryEndpoint, httpClient: http.DefaultClient}
+}
+
+// FindTraces returns the traces of service that contain a span named
+// operation, or all traces of service when operation is empty. Spans are
+// indexed asynchronously, so wrap it in require.Eventually when waiting for
+// new traces.
+func (c *JaegerQueryClient) FindTraces(ctx context.Context, service, operation string) ([]JaegerTrace, error) {
+	if service == "" {
+		return nil, errors.New("service is required to find traces")
+	}
+	query := url.Values{"service": {service}, "limit": {"1000"}}
+	if operation != "" {
+		query.Set("operation", operation)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/api/traces?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Data []struct {
+			TraceID string `json:"traceID"`
+			Spans   []struct {
+				TraceID       string `json:"traceID"`
+				SpanID        string `json:"spanID"`
+				OperationName string `json:"operationName"`
+				ProcessID     string `json:"processID"`
+				// StartTime and Duration are in microseconds.
+				StartTime int64 `json:"startTime"`
+				Duration  int64 `json:"duration"`
+				Tags      []struct {
+					Key   string `json:"key"`
+					Value any    `json:"value"`
+				} `json:"tags"`
+			} `json:"spans"`
+			Processes map[string]struct {
+				ServiceName string `json:"serviceName"`
+			} `json:"processes"`
+		} `json:"data"`
+		Errors []struct {
+			Msg string `json:"msg"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode traces (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if len(body.Errors) > 0 {
+		return nil, fmt.Errorf("failed to query traces: %s", body.Errors[0].Msg)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query traces: HTTP %d", resp.StatusCode)
+	}
+
+	traces := make([]JaegerTrace, 0, len(body.Data))
+	for _, data := range body.Data {
+		trace := JaegerTrace{TraceID: data.TraceID}
+		for _, s := range data.Spans {
+			span := JaegerSpan{
+				TraceID:       s.TraceID,
+				SpanID:        s.SpanID,
+				OperationName: s.OperationName,
+				ServiceName:   data.Processes[s.ProcessID].ServiceName,
+				StartTime:     time.UnixMicro(s.StartTime),
+				Duration:      time.Duration(s.Duration) * time.Microsecond,
+				Tags:          make(map[string]any, len(s.Tags)),
+			}
+			for _, tag := range s.Tags {
+				span.Tags[tag.Key] = tag.Value
+			}
+			trace.Spans = append(trace.Spans, span)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
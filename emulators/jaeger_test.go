@@ -0,0 +1,87 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJaegerQueryClientFindTraces(t *testing.T) {
+	var lastQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastQuery = r.URL.Path + "?" + r.URL.RawQuery
+		if r.URL.Query().Get("service") != "checkout" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"data":null,"errors":[{"code":404,"msg":"service not found"}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"data":[{"traceID":"abc","spans":[
+			{"traceID":"abc","spanID":"1","operationName":"charge","processID":"p1","startTime":1700000000000000,"duration":1500,
+			 "tags":[{"key":"order.id","type":"string","value":"o-42"},{"key":"retry","type":"bool","value":false},{"key":"amount","type":"int64","value":1999}]}],
+			"processes":{"p1":{"serviceName":"checkout","tags":[]}}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	client := NewJaegerQueryClient(EmulatorConnectionInfo{QueryEndpoint: server.URL})
+	ctx := context.Background()
+
+	traces, err := client.FindTraces(ctx, "checkout", "charge")
+	require.NoError(t, err)
+	require.Equal(t, "/api/traces?limit=1000&operation=charge&service=checkout", lastQuery)
+	require.Len(t, traces, 1)
+	span, ok := traces[0].Span("charge")
+	require.True(t, ok)
+	require.Equal(t, JaegerSpan{
+		TraceID:       "abc",
+		SpanID:        "1",
+		OperationName: "charge",
+		ServiceName:   "checkout",
+		StartTime:     time.UnixMicro(1700000000000000),
+		Duration:      1500 * time.Microsecond,
+		Tags:          map[string]any{"order.id": "o-42", "retry": false, "amount": float64(1999)},
+	}, span)
+	_, ok = traces[0].Span("refund")
+	require.False(t, ok)
+
+	_, err = client.FindTraces(ctx, "unknown", "")
+	require.ErrorContains(t, err, "service not found")
+	_, err = client.FindTraces(ctx, "", "")
+	require.Error(t, err)
+}
+
+// otlpSpanJSON is an OTLP/JSON export request with a single span.
+const otlpSpanJSON = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"emulators-test"}}]},
+"scopeSpans":[{"spans":[{"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","name":"process-order","kind":2,
+"startTimeUnixNano":"%d","endTimeUnixNano":"%d","attributes":[{"key":"order.id","value":{"stringValue":"o-42"}}]}]}]}]}`
+
+func TestSetupJaegerContainer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	connInfo := SetupJaegerContainer(t, ctx, GetDefaultJaegerConfig())
+	require.NotEmpty(t, connInfo.QueryEndpoint)
+
+	now := time.Now().UnixNano()
+	body := strings.Replace(otlpSpanJSON, "%d", strconv.FormatInt(now, 10), 1)
+	body = strings.Replace(body, "%d", strconv.FormatInt(now+int64(time.Millisecond), 10), 1)
+	resp, err := http.Post(connInfo.HTTPEndpoint.Endpoint+"/v1/traces", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client := NewJaegerQueryClient(connInfo)
+	var traces []JaegerTrace
+	require.Eventually(t, func() bool {
+		traces, err = client.FindTraces(ctx, "emulators-test", "process-order")
+		return err == nil && len(traces) == 1
+	}, 30*time.Second, 500*time.Millisecond, "Span did not arrive in Jaeger")
+	span, ok := traces[0].Span("process-order")
+	require.True(t, ok)
+	require.Equal(t, "o-42", span.Tags["order.id"])
+}
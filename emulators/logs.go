@@ -0,0 +1,72 @@
+package emulators
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// containerLogConsumer writes each container log line to logf with a name
+// prefix, either as it arrives or, in buffered mode, only when flushed.
+// It drops lines after stop, because testing.T must not be logged to once
+// the test has finished and reused containers keep producing logs.
+type containerLogConsumer struct {
+	name   string
+	logf   func(format string, args ...any)
+	buffer bool
+
+	mu      sync.Mutex
+	stopped bool
+	lines   []string
+}
+
+// newContainerLogConsumer returns a consumer writing to logf, or to the
+// package logger when logf is nil. With buffer set, lines are held until flush.
+func newContainerLogConsumer(name string, logf func(format string, args ...any), buffer bool) *containerLogConsumer {
+	if logf == nil {
+		logf = func(format string, args ...any) {
+			log.Info().Msgf(format, args...)
+		}
+	}
+	return &containerLogConsumer{name: name, logf: logf, buffer: buffer}
+}
+
+// Accept implements testcontainers.LogConsumer.
+func (c *containerLogConsumer) Accept(l testcontainers.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(l.Content), "\n"), "\n") {
+		if c.buffer {
+			c.lines = append(c.lines, line)
+		} else {
+			c.logf("[%s] %s", c.name, line)
+		}
+	}
+}
+
+// flush writes and discards the buffered lines.
+func (c *containerLogConsumer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.lines) == 0 {
+		return
+	}
+	c.logf("[%s] --- container logs ---", c.name)
+	for _, line := range c.lines {
+		c.logf("[%s] %s", c.name, line)
+	}
+	c.lines = nil
+}
+
+// stop makes the consumer drop all further lines, and any buffered ones.
+func (c *containerLogConsumer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.lines = nil
+}
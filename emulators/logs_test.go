@@ -0,0 +1,48 @@
+package emulators
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestContainerLogConsumer(t *testing.T) {
+	var lines []string
+	consumer := newContainerLogConsumer("Redis", func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}, false)
+
+	consumer.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte("Server initialized\nReady to accept connections tcp\n")})
+	require.Equal(t, []string{"[Redis] Server initialized", "[Redis] Ready to accept connections tcp"}, lines)
+
+	consumer.stop()
+	consumer.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte("shutting down\n")})
+	require.Len(t, lines, 2, "lines after stop should be dropped")
+}
+
+func TestContainerLogConsumerBuffered(t *testing.T) {
+	var lines []string
+	consumer := newContainerLogConsumer("Pub/Sub", func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}, true)
+
+	consumer.Accept(testcontainers.Log{LogType: testcontainers.StderrLog, Content: []byte("[pubsub] starting\n")})
+	require.Empty(t, lines, "buffered lines should not be written before flush")
+
+	consumer.flush()
+	require.Equal(t, []string{"[Pub/Sub] --- container logs ---", "[Pub/Sub] [pubsub] starting"}, lines)
+
+	consumer.flush()
+	require.Len(t, lines, 2, "flush should discard written lines")
+}
+
+func TestWithTestLogs(t *testing.T) {
+	var req testcontainers.ContainerRequest
+	o := applySetupOptions(&req, withTestLogs(t, "Pub/Sub", []SetupOption{WithTag("latest")}))
+	require.Equal(t, "Pub/Sub", o.logName)
+	require.NotNil(t, o.logf)
+	require.NotNil(t, o.failed)
+	require.False(t, o.failed())
+}
@@ -0,0 +1,26 @@
+package emulators
+
+import (
+	"testing"
+)
+
+// SetupFunc starts an emulator with opts, usually by calling a Setup function
+// with a fixed config:
+//
+//	func(t testing.TB, opts ...emulators.SetupOption) emulators.EmulatorConnectionInfo {
+//		return emulators.SetupBigQueryEmulator(t, ctx, cfg, opts...)
+//	}
+type SetupFunc func(t testing.TB, opts ...SetupOption) EmulatorConnectionInfo
+
+// ForEachImageTag runs fn in a subtest per tag, named after the tag, against
+// an emulator started by setup with WithTag(tag). Use it to verify that a
+// library works across emulator versions, e.g. bigquery-emulator 0.4, 0.5 and
+// 0.6. Each emulator is terminated when its subtest ends.
+func ForEachImageTag(t *testing.T, tags []string, setup SetupFunc, fn func(t *testing.T, connInfo EmulatorConnectionInfo)) {
+	t.Helper()
+	for _, tag := range tags {
+		t.Run(tag, func(t *testing.T) {
+			fn(t, setup(t, WithTag(tag)))
+		})
+	}
+}
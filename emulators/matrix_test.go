@@ -0,0 +1,26 @@
+package emulators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestForEachImageTag(t *testing.T) {
+	var images, ran []string
+	setup := func(t testing.TB, opts ...SetupOption) EmulatorConnectionInfo {
+		req := testcontainers.ContainerRequest{Image: testBigQueryEmulatorImage}
+		applySetupOptions(&req, opts)
+		images = append(images, req.Image)
+		return EmulatorConnectionInfo{Service: ServiceBigQuery, HTTPEndpoint: Endpoint{Endpoint: req.Image}}
+	}
+
+	ForEachImageTag(t, []string{"0.4.4", "0.6.6"}, setup, func(t *testing.T, connInfo EmulatorConnectionInfo) {
+		ran = append(ran, t.Name())
+		require.Equal(t, ServiceBigQuery, connInfo.Service)
+	})
+
+	require.Equal(t, []string{"ghcr.io/goccy/bigquery-emulator:0.4.4", "ghcr.io/goccy/bigquery-emulator:0.6.6"}, images)
+	require.Equal(t, []string{"TestForEachImageTag/0.4.4", "TestForEachImageTag/0.6.6"}, ran)
+}
@@ -2,17 +2,19 @@ package emulators
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
-	"github.com/docker/go-connections/nat" // Added for ForListeningPort
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 const (
@@ -20,57 +22,252 @@ const (
 	mosquitoImage = "eclipse-mosquitto:2.0"
 	// mosquitoPort is the default internal port for the Mosquitto broker.
 	mosquitoPort = "1883"
+	// mosquittoTLSPort is the internal port of the optional TLS listener.
+	mosquittoTLSPort = "8883"
+	// mosquittoWebSocketPort is the internal port of the optional WebSocket listener.
+	mosquittoWebSocketPort = "9001"
+
+	// mosquittoConfigDir is where configuration files are mounted in the container.
+	mosquittoConfigDir = "/mosquitto/config"
 )
 
+// MqttConfig holds configuration for an MQTT broker container.
+// With no credentials set, the broker allows anonymous access.
+type MqttConfig struct {
+	ImageContainer
+	// Broker selects the broker implementation. Nil means Mosquitto.
+	Broker MqttBroker
+	// Username and Password, when set, are written to a password file mounted
+	// into the container and anonymous access is disabled. They are returned
+	// in the EmulatorConnectionInfo for clients to use.
+	Username string
+	Password string
+	// ACLFile is the path to a Mosquitto ACL file on the host.
+	ACLFile string
+	// ACL is the ACL file content. It takes precedence over ACLFile.
+	ACL string
+	// EnableTLS adds a TLS listener on port 8883 alongside the plain listener.
+	// A throwaway CA and server certificate are generated; the CA is returned
+	// as EmulatorConnectionInfo.CACert and the listener as TLSAddress.
+	EnableTLS bool
+	// EnableWebSockets adds an MQTT-over-WebSockets listener on port 9001,
+	// returned as EmulatorConnectionInfo.WebSocketAddress ("ws://...").
+	EnableWebSockets bool
+}
+
 // GetDefaultMqttImageContainer returns a default configuration for the Mosquitto container.
-func GetDefaultMqttImageContainer() ImageContainer {
-	return ImageContainer{
-		EmulatorImage: mosquitoImage,
-		EmulatorPort:  mosquitoPort,
+func GetDefaultMqttImageContainer() MqttConfig {
+	return MqttConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: mosquitoImage,
+			EmulatorPort:  mosquitoPort,
+		},
 	}
 }
 
-// SetupMosquittoContainer starts an MQTT (Mosquitto) emulator container.
+// SetupMosquittoContainer starts an MQTT emulator container. Despite its
+// name it honours cfg.Broker, so it is equivalent to SetupMqttBroker.
 // It automatically handles container startup, configuration, and teardown via t.Cleanup.
 // It returns an EmulatorConnectionInfo struct with the EmulatorAddress field populated
-// (e.g., "tcp://localhost:54321").
-func SetupMosquittoContainer(t *testing.T, ctx context.Context, cfg ImageContainer) EmulatorConnectionInfo {
+// (e.g., "tcp://localhost:54321"), plus Username and Password if authentication is enabled.
+func SetupMosquittoContainer(t testing.TB, ctx context.Context, cfg MqttConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
+	return SetupMqttBroker(t, ctx, cfg, opts...)
+}
 
-	// Mosquitto requires a config file to allow anonymous access.
-	confPath := filepath.Join(t.TempDir(), "mosquitto.conf")
-	err := os.WriteFile(confPath, []byte("listener 1883\nallow_anonymous true\n"), 0644)
-	require.NoError(t, err)
-
-	port := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+// SetupMqttBroker starts the MQTT broker selected by cfg.Broker (Mosquitto when nil).
+// It automatically handles container startup, configuration, and teardown via t.Cleanup.
+// It fails the test if cfg asks for a feature the broker implementation does not support.
+func SetupMqttBroker(t testing.TB, ctx context.Context, cfg MqttConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
 
-	req := testcontainers.ContainerRequest{
-		Image:        cfg.EmulatorImage,
-		ExposedPorts: []string{port},
-		// REFACTOR: Changed from brittle ForLog to robust ForListeningPort.
-		WaitingFor: wait.ForListeningPort(nat.Port(port)).WithStartupTimeout(60 * time.Second),
-		Files:        []testcontainers.ContainerFile{{HostFilePath: confPath, ContainerFilePath: "/mosquitto/config/mosquitto.conf"}},
-	}
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+	broker := mqttBrokerOrDefault(cfg)
+	connInfo, terminate, err := StartMqttBroker(ctx, cfg, withTestLogs(t, broker.Name(), opts)...)
 	require.NoError(t, err)
 
 	t.Cleanup(func() {
-		if err := container.Terminate(context.Background()); err != nil {
-			t.Logf("Failed to terminate Mosquitto container: %v", err)
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate %s container: %v", broker.Name(), err)
 		}
 	})
 
-	host, err := container.Host(ctx)
-	require.NoError(t, err)
-	mappedPort, err := container.MappedPort(ctx, nat.Port(port)) // Use nat.Port(port) for consistency
-	require.NoError(t, err)
-	brokerURL := fmt.Sprintf("tcp://%s:%s", host, mappedPort.Port())
+	t.Logf("%s emulator container started, listening on: %s", broker.Name(), connInfo.EmulatorAddress)
+	if connInfo.TLSAddress != "" {
+		t.Logf("%s TLS listener available on: %s", broker.Name(), connInfo.TLSAddress)
+	}
+	if connInfo.WebSocketAddress != "" {
+		t.Logf("%s WebSocket listener available on: %s", broker.Name(), connInfo.WebSocketAddress)
+	}
+	return connInfo
+}
+
+// mqttBrokerOrDefault returns cfg.Broker, or Mosquitto when it is nil.
+func mqttBrokerOrDefault(cfg MqttConfig) MqttBroker {
+	if cfg.Broker == nil {
+		return MosquittoBroker{}
+	}
+	return cfg.Broker
+}
+
+// StartMqttBroker starts the MQTT broker selected by cfg.Broker. It returns an
+// error if cfg asks for a feature the broker implementation does not support.
+// Unlike SetupMqttBroker it needs no testing.TB; the caller must call the
+// returned TerminateFunc.
+func StartMqttBroker(ctx context.Context, cfg MqttConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	broker := mqttBrokerOrDefault(cfg)
+	listeners := broker.Listeners()
+	if cfg.EnableTLS && listeners.TLSPort == "" {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("%s broker does not support the TLS listener", broker.Name())
+	}
+	if cfg.EnableWebSockets && listeners.WebSocketPort == "" {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("%s broker does not support the WebSocket listener", broker.Name())
+	}
+
+	var certs *TLSCertificates
+	if cfg.EnableTLS {
+		// Certificates are generated per start, so a reused container would
+		// present one that clients no longer trust.
+		if cfg.Reuse {
+			return EmulatorConnectionInfo{}, nil, errors.New("MQTT TLS listener cannot be used with a reused container")
+		}
+		if certs, err = generateDaemonCertificates(ctx); err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "mqtt-")
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to create %s config directory: %w", broker.Name(), err)
+	}
+	req, err := broker.ContainerRequest(dir, cfg, certs)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return EmulatorConnectionInfo{}, nil, err
+	}
+
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, dir, opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to start %s broker: %w", broker.Name(), err)
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	address, err := mappedAddress(ctx, container, cfg.EmulatorPort+"/tcp")
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	connInfo = EmulatorConnectionInfo{
+		Service:         ServiceMQTT,
+		EmulatorAddress: "tcp://" + address,
+		Username:        cfg.Username,
+		Password:        cfg.Password,
+	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if certs != nil {
+		tlsAddress, err := mappedAddress(ctx, container, listeners.TLSPort+"/tcp")
+		if err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+		connInfo.TLSAddress = "tls://" + tlsAddress
+		connInfo.CACert = certs.CACert
+	}
+	if cfg.EnableWebSockets {
+		wsAddress, err := mappedAddress(ctx, container, listeners.WebSocketPort+"/tcp")
+		if err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+		connInfo.WebSocketAddress = "ws://" + wsAddress + listeners.WebSocketPath
+	}
+	return connInfo, terminate, nil
+}
+
+// writeMosquittoFiles renders mosquitto.conf and any password, ACL or
+// certificate files into dir, returning the container files to mount.
+// certs is nil unless the TLS listener is enabled.
+func writeMosquittoFiles(dir string, cfg MqttConfig, certs *TLSCertificates) ([]testcontainers.ContainerFile, error) {
+	var conf strings.Builder
+	var files []testcontainers.ContainerFile
+
+	addFile := func(name string, content []byte) error {
+		file, err := writeContainerFile(dir, name, mosquittoConfigDir+"/"+name, content)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	}
+
+	fmt.Fprintf(&conf, "listener %s\n", cfg.EmulatorPort)
+	if cfg.Username != "" {
+		entry, err := mosquittoPasswordEntry(cfg.Username, cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+		if err := addFile("passwd", []byte(entry+"\n")); err != nil {
+			return nil, err
+		}
+		conf.WriteString("allow_anonymous false\n")
+		fmt.Fprintf(&conf, "password_file %s/passwd\n", mosquittoConfigDir)
+	} else {
+		conf.WriteString("allow_anonymous true\n")
+	}
+
+	acl := []byte(cfg.ACL)
+	if cfg.ACL == "" && cfg.ACLFile != "" {
+		var err error
+		if acl, err = os.ReadFile(cfg.ACLFile); err != nil {
+			return nil, fmt.Errorf("failed to read mosquitto ACL file: %w", err)
+		}
+	}
+	if len(acl) > 0 {
+		if err := addFile("acl", acl); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&conf, "acl_file %s/acl\n", mosquittoConfigDir)
+	}
 
-	t.Logf("Mosquitto emulator container started, listening on: %s", brokerURL)
+	if certs != nil {
+		for name, content := range map[string][]byte{"ca.crt": certs.CACert, "server.crt": certs.ServerCert, "server.key": certs.ServerKey} {
+			if err := addFile(name, content); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Fprintf(&conf, "listener %s\n", mosquittoTLSPort)
+		fmt.Fprintf(&conf, "cafile %s/ca.crt\n", mosquittoConfigDir)
+		fmt.Fprintf(&conf, "certfile %s/server.crt\n", mosquittoConfigDir)
+		fmt.Fprintf(&conf, "keyfile %s/server.key\n", mosquittoConfigDir)
+	}
+
+	if cfg.EnableWebSockets {
+		fmt.Fprintf(&conf, "listener %s\n", mosquittoWebSocketPort)
+		conf.WriteString("protocol websockets\n")
+	}
 
-	return EmulatorConnectionInfo{
-		EmulatorAddress: brokerURL,
+	if err := addFile("mosquitto.conf", []byte(conf.String())); err != nil {
+		return nil, err
 	}
+	return files, nil
+}
+
+// mosquittoPasswordEntry returns a password file line in Mosquitto's salted
+// SHA-512 format: "user:$6$<base64 salt>$<base64 sha512(password+salt)>".
+func mosquittoPasswordEntry(username, password string) (string, error) {
+	salt := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate password salt: %w", err)
+	}
+	h := sha512.New()
+	h.Write([]byte(password))
+	h.Write(salt)
+	return fmt.Sprintf("%s:$6$%s$%s", username,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(h.Sum(nil))), nil
 }
 
 // CreateTestMqttPublisher is a helper function that creates and connects an
@@ -78,9 +275,34 @@ func SetupMosquittoContainer(t *testing.T, ctx context.Context, cfg ImageContain
 // It waits up to 10 seconds to connect.
 func CreateTestMqttPublisher(brokerURL, clientID string) (mqtt.Client, error) {
 	opts := mqtt.NewClientOptions().AddBroker(brokerURL).SetClientID(clientID)
+	return connectTestMqttClient(opts)
+}
+
+// CreateTestMqttClient creates and connects an MQTT client using everything
+// in connInfo, including credentials when authentication is enabled. When the
+// broker has a TLS listener, the client connects over TLS and trusts connInfo.CACert.
+// It waits up to 10 seconds to connect.
+func CreateTestMqttClient(connInfo EmulatorConnectionInfo, clientID string) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().SetClientID(clientID)
+	if connInfo.TLSAddress != "" {
+		tlsConfig, err := TLSConfigFromCA(connInfo.CACert)
+		if err != nil {
+			return nil, err
+		}
+		opts.AddBroker(connInfo.TLSAddress).SetTLSConfig(tlsConfig)
+	} else {
+		opts.AddBroker(connInfo.EmulatorAddress)
+	}
+	if connInfo.Username != "" {
+		opts.SetUsername(connInfo.Username).SetPassword(connInfo.Password)
+	}
+	return connectTestMqttClient(opts)
+}
+
+func connectTestMqttClient(opts *mqtt.ClientOptions) (mqtt.Client, error) {
 	client := mqtt.NewClient(opts)
 	if token := client.Connect(); token.WaitTimeout(10*time.Second) && token.Error() != nil {
 		return nil, fmt.Errorf("test mqtt publisher connect error: %w", token.Error())
 	}
 	return client, nil
-}
\ No newline at end of file
+}
@@ -2,11 +2,9 @@ package emulators
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,44 +23,10 @@ func TestMqttPublishSubscribeIntegration(t *testing.T) {
 
 	testTopic := "test/messages"
 	testMessage := "Hello MQTT from Testcontainers!"
-	receivedMessage := make(chan string, 1) // Channel to receive the message
 
-	var wg sync.WaitGroup
-	wg.Add(1) // Wait for the subscriber to receive a message
-
-	// 2. Create and connect MQTT Subscriber
-	subscriberClientID := "test-subscriber-client"
-	subOpts := mqtt.NewClientOptions().AddBroker(brokerURL).SetClientID(subscriberClientID).SetAutoReconnect(false)
-	subscriber := mqtt.NewClient(subOpts)
-
-	t.Log("Connecting MQTT subscriber...")
-	connectToken := subscriber.Connect()
-	// Wait for connection or timeout
-	if !connectToken.WaitTimeout(10 * time.Second) {
-		t.Fatal("MQTT subscriber connection timed out")
-	}
-	require.NoError(t, connectToken.Error(), "Failed to connect MQTT subscriber")
-
-	t.Cleanup(func() {
-		subscriber.Disconnect(250)
-	})
-	require.True(t, subscriber.IsConnected(), "Subscriber should be connected")
-
-	t.Logf("Subscriber connected. Subscribing to topic: %s", testTopic)
-	subscribeToken := subscriber.Subscribe(testTopic, 0, func(client mqtt.Client, msg mqtt.Message) {
-		t.Logf("Subscriber received message: %s on topic: %s", msg.Payload(), msg.Topic())
-		// Send to channel in a non-blocking way in case test already timed out
-		select {
-		case receivedMessage <- string(msg.Payload()):
-		default:
-			t.Log("Message received, but channel was full or closed.")
-		}
-		wg.Done() // Signal that the message was received
-	})
-	if !subscribeToken.WaitTimeout(5 * time.Second) {
-		t.Fatal("MQTT subscriber subscribe timed out")
-	}
-	require.NoError(t, subscribeToken.Error(), "Failed to subscribe")
+	// 2. Capture what a subscriber receives
+	t.Logf("Capturing messages on topic: %s", testTopic)
+	capture := CaptureMQTTMessages(t, brokerURL, testTopic)
 
 	// 3. Create and connect MQTT Publisher
 	publisherClientID := "test-publisher-client"
@@ -85,14 +49,9 @@ func TestMqttPublishSubscribeIntegration(t *testing.T) {
 	t.Log("Message published.")
 
 	// 5. Wait for the message to be received by the subscriber
-	select {
-	case msg := <-receivedMessage:
-		require.Equal(t, testMessage, msg, "Received message content mismatch")
-	case <-time.After(15 * time.Second): // Give some time for message propagation
-		t.Fatal("Timed out waiting for message to be received by subscriber")
-	}
-
-	wg.Wait() // Ensure the handler has completed
+	messages := capture.WaitForCount(1, 15*time.Second)
+	require.Equal(t, testMessage, string(messages[0].Payload), "Received message content mismatch")
+	require.Equal(t, testTopic, messages[0].Topic)
 
 	t.Log("MQTT publish/subscribe integration test completed successfully.")
 }
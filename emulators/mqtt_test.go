@@ -2,6 +2,13 @@ package emulators
 
 import (
 	"context"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require" // Using require for fatal assertions
@@ -45,3 +52,147 @@ func TestGetDefaultMqttImageContainer(t *testing.T) {
 func TestCreateTestMqttPublisher(t *testing.T) {
 	t.Skip("Skipping TestCreateTestMqttPublisher as it relies on a running broker, tested in TestSetupMosquittoContainer")
 }
+
+func TestSetupMosquittoContainerWithAuth(t *testing.T) {
+	t.Parallel()
+
+	cfg := GetDefaultMqttImageContainer()
+	cfg.Username = "device"
+	cfg.Password = "s3cret"
+	cfg.ACL = "user device\ntopic readwrite devices/#\n"
+	connInfo := SetupMosquittoContainer(t, context.Background(), cfg)
+	require.Equal(t, "device", connInfo.Username)
+	require.Equal(t, "s3cret", connInfo.Password)
+
+	// Anonymous clients are rejected.
+	_, err := CreateTestMqttPublisher(connInfo.EmulatorAddress, "anonymous-client")
+	require.Error(t, err, "Anonymous connection should be refused")
+
+	client, err := CreateTestMqttClient(connInfo, "authenticated-client")
+	require.NoError(t, err, "Authenticated connection should succeed")
+	t.Cleanup(func() {
+		client.Disconnect(250)
+	})
+	require.True(t, client.IsConnected())
+}
+
+func TestWriteMosquittoFiles(t *testing.T) {
+	t.Run("anonymous", func(t *testing.T) {
+		dir := t.TempDir()
+		files, err := writeMosquittoFiles(dir, GetDefaultMqttImageContainer(), nil)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		conf, err := os.ReadFile(filepath.Join(dir, "mosquitto.conf"))
+		require.NoError(t, err)
+		require.Equal(t, "listener 1883\nallow_anonymous true\n", string(conf))
+	})
+
+	t.Run("credentials and ACL", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := GetDefaultMqttImageContainer()
+		cfg.Username = "user"
+		cfg.Password = "pass"
+		cfg.ACL = "user user\ntopic read #\n"
+		files, err := writeMosquittoFiles(dir, cfg, nil)
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+
+		conf, err := os.ReadFile(filepath.Join(dir, "mosquitto.conf"))
+		require.NoError(t, err)
+		require.Contains(t, string(conf), "allow_anonymous false\n")
+		require.Contains(t, string(conf), "password_file /mosquitto/config/passwd\n")
+		require.Contains(t, string(conf), "acl_file /mosquitto/config/acl\n")
+
+		passwd, err := os.ReadFile(filepath.Join(dir, "passwd"))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(passwd), "user:$6$"), "password file should use the salted SHA-512 format")
+	})
+}
+
+func TestSetupMosquittoContainerWithTLS(t *testing.T) {
+	t.Parallel()
+
+	cfg := GetDefaultMqttImageContainer()
+	cfg.EnableTLS = true
+	connInfo := SetupMosquittoContainer(t, context.Background(), cfg)
+	require.True(t, strings.HasPrefix(connInfo.TLSAddress, "tls://"), "TLSAddress should use the tls scheme")
+	require.NotEmpty(t, connInfo.CACert, "CA certificate should be returned")
+
+	client, err := CreateTestMqttClient(connInfo, "tls-client")
+	require.NoError(t, err, "TLS connection should succeed with the returned CA")
+	t.Cleanup(func() {
+		client.Disconnect(250)
+	})
+	require.True(t, client.IsConnected())
+}
+
+func TestWriteMosquittoFilesWithTLS(t *testing.T) {
+	certs, err := generateTestCertificates("docker.example")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	files, err := writeMosquittoFiles(dir, GetDefaultMqttImageContainer(), &certs)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	conf, err := os.ReadFile(filepath.Join(dir, "mosquitto.conf"))
+	require.NoError(t, err)
+	require.Contains(t, string(conf), "listener 8883\n")
+	require.Contains(t, string(conf), "certfile /mosquitto/config/server.crt\n")
+
+	// The server certificate must verify against the CA for the extra host.
+	tlsConfig, err := TLSConfigFromCA(certs.CACert)
+	require.NoError(t, err)
+	block, _ := pem.Decode(certs.ServerCert)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs, DNSName: "docker.example"})
+	require.NoError(t, err)
+}
+
+func TestSetupMosquittoContainerWithWebSockets(t *testing.T) {
+	t.Parallel()
+
+	cfg := GetDefaultMqttImageContainer()
+	cfg.EnableWebSockets = true
+	connInfo := SetupMosquittoContainer(t, context.Background(), cfg)
+	require.True(t, strings.HasPrefix(connInfo.WebSocketAddress, "ws://"), "WebSocketAddress should use the ws scheme")
+
+	client, err := CreateTestMqttPublisher(connInfo.WebSocketAddress, "ws-client")
+	require.NoError(t, err, "WebSocket connection should succeed")
+	t.Cleanup(func() {
+		client.Disconnect(250)
+	})
+	require.True(t, client.IsConnected())
+}
+
+func TestWriteMosquittoFilesWithWebSockets(t *testing.T) {
+	dir := t.TempDir()
+	cfg := GetDefaultMqttImageContainer()
+	cfg.EnableWebSockets = true
+	_, err := writeMosquittoFiles(dir, cfg, nil)
+	require.NoError(t, err)
+
+	conf, err := os.ReadFile(filepath.Join(dir, "mosquitto.conf"))
+	require.NoError(t, err)
+	require.Equal(t, "listener 1883\nallow_anonymous true\nlistener 9001\nprotocol websockets\n", string(conf))
+}
+
+func TestMosquittoPasswordEntry(t *testing.T) {
+	entry, err := mosquittoPasswordEntry("alice", "wonderland")
+	require.NoError(t, err)
+
+	parts := strings.Split(entry, "$")
+	require.Len(t, parts, 4)
+	require.Equal(t, "alice:", parts[0])
+	require.Equal(t, "6", parts[1])
+
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	h := sha512.New()
+	h.Write([]byte("wonderland"))
+	h.Write(salt)
+	require.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), parts[3])
+}
@@ -0,0 +1,232 @@
+package emulators
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// emqxImage is the default EMQX open-source image to use.
+	emqxImage = "emqx/emqx:5.8.6"
+	// emqxCertDir is where TLS material is mounted in the EMQX container.
+	emqxCertDir = "/opt/emqx/etc/certs/test"
+
+	// hivemqImage is the default HiveMQ Community Edition image to use.
+	hivemqImage = "hivemq/hivemq-ce:2024.9"
+	// hivemqWebSocketPort is the internal port of the HiveMQ WebSocket listener.
+	hivemqWebSocketPort = "8000"
+)
+
+// MqttListeners describes the internal ports a broker implementation exposes.
+// An empty port means the implementation does not support that listener.
+type MqttListeners struct {
+	// TLSPort is the internal port of the TLS listener.
+	TLSPort string
+	// WebSocketPort is the internal port of the MQTT-over-WebSockets listener.
+	WebSocketPort string
+	// WebSocketPath is the HTTP path of the WebSocket listener (e.g., "/mqtt").
+	WebSocketPath string
+}
+
+// MqttBroker renders the container configuration for one MQTT broker
+// implementation. Implementations decide how MqttConfig features are
+// configured and how readiness is detected, since each broker differs.
+type MqttBroker interface {
+	// Name identifies the broker in logs and failure messages.
+	Name() string
+	// Listeners returns the optional listener ports the broker supports.
+	Listeners() MqttListeners
+	// ContainerRequest builds the container request for cfg, rendering any
+	// configuration files into dir. certs is non-nil when cfg.EnableTLS is set.
+	ContainerRequest(dir string, cfg MqttConfig, certs *TLSCertificates) (testcontainers.ContainerRequest, error)
+}
+
+// MosquittoBroker is the Eclipse Mosquitto broker. It supports every
+// MqttConfig feature and is the default when MqttConfig.Broker is nil.
+type MosquittoBroker struct{}
+
+// Name implements MqttBroker.
+func (MosquittoBroker) Name() string { return "Mosquitto" }
+
+// Listeners implements MqttBroker.
+func (MosquittoBroker) Listeners() MqttListeners {
+	return MqttListeners{TLSPort: mosquittoTLSPort, WebSocketPort: mosquittoWebSocketPort}
+}
+
+// ContainerRequest implements MqttBroker.
+func (b MosquittoBroker) ContainerRequest(dir string, cfg MqttConfig, certs *TLSCertificates) (testcontainers.ContainerRequest, error) {
+	files, err := writeMosquittoFiles(dir, cfg, certs)
+	if err != nil {
+		return testcontainers.ContainerRequest{}, err
+	}
+
+	ports := []string{cfg.EmulatorPort + "/tcp"}
+	if certs != nil {
+		ports = append(ports, mosquittoTLSPort+"/tcp")
+	}
+	if cfg.EnableWebSockets {
+		ports = append(ports, mosquittoWebSocketPort+"/tcp")
+	}
+	// Mosquitto starts in well under a second, so listening ports are a
+	// sufficient readiness check.
+	waits := make([]wait.Strategy, 0, len(ports))
+	for _, p := range ports {
+		waits = append(waits, wait.ForListeningPort(nat.Port(p)).WithStartupTimeout(60*time.Second))
+	}
+
+	return testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: ports,
+		WaitingFor:   wait.ForAll(waits...),
+		Files:        files,
+	}, nil
+}
+
+// EMQXBroker is the EMQX open-source broker. It supports the TLS and
+// WebSocket listeners, but not credentials or ACLs.
+type EMQXBroker struct{}
+
+// GetDefaultEMQXConfig returns a default configuration for an EMQX container.
+func GetDefaultEMQXConfig() MqttConfig {
+	return MqttConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: emqxImage,
+			EmulatorPort:  mosquitoPort,
+		},
+		Broker: EMQXBroker{},
+	}
+}
+
+// Name implements MqttBroker.
+func (EMQXBroker) Name() string { return "EMQX" }
+
+// Listeners implements MqttBroker.
+func (EMQXBroker) Listeners() MqttListeners {
+	return MqttListeners{TLSPort: "8883", WebSocketPort: "8083", WebSocketPath: "/mqtt"}
+}
+
+// ContainerRequest implements MqttBroker.
+func (b EMQXBroker) ContainerRequest(dir string, cfg MqttConfig, certs *TLSCertificates) (testcontainers.ContainerRequest, error) {
+	if err := rejectMqttAuth(b, cfg); err != nil {
+		return testcontainers.ContainerRequest{}, err
+	}
+
+	listeners := b.Listeners()
+	ports := []string{cfg.EmulatorPort + "/tcp"}
+	env := map[string]string{
+		"EMQX_LISTENERS__TCP__DEFAULT__BIND": "0.0.0.0:" + cfg.EmulatorPort,
+	}
+	var files []testcontainers.ContainerFile
+	if certs != nil {
+		ports = append(ports, listeners.TLSPort+"/tcp")
+		for name, content := range map[string][]byte{"ca.pem": certs.CACert, "server.pem": certs.ServerCert, "server.key": certs.ServerKey} {
+			file, err := writeContainerFile(dir, name, emqxCertDir+"/"+name, content)
+			if err != nil {
+				return testcontainers.ContainerRequest{}, err
+			}
+			files = append(files, file)
+		}
+		env["EMQX_LISTENERS__SSL__DEFAULT__SSL_OPTIONS__CACERTFILE"] = emqxCertDir + "/ca.pem"
+		env["EMQX_LISTENERS__SSL__DEFAULT__SSL_OPTIONS__CERTFILE"] = emqxCertDir + "/server.pem"
+		env["EMQX_LISTENERS__SSL__DEFAULT__SSL_OPTIONS__KEYFILE"] = emqxCertDir + "/server.key"
+	}
+	if cfg.EnableWebSockets {
+		ports = append(ports, listeners.WebSocketPort+"/tcp")
+	}
+
+	// EMQX opens its listeners before the broker is fully booted, so wait
+	// for the startup banner as well.
+	return testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: ports,
+		Env:          env,
+		Files:        files,
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort(nat.Port(cfg.EmulatorPort+"/tcp")),
+			wait.ForLog("is running now"),
+		).WithDeadline(2 * time.Minute),
+	}, nil
+}
+
+// HiveMQBroker is the HiveMQ Community Edition broker. It supports the
+// WebSocket listener, but not TLS, credentials or ACLs.
+type HiveMQBroker struct{}
+
+// GetDefaultHiveMQConfig returns a default configuration for a HiveMQ CE container.
+func GetDefaultHiveMQConfig() MqttConfig {
+	return MqttConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: hivemqImage,
+			EmulatorPort:  mosquitoPort,
+		},
+		Broker: HiveMQBroker{},
+	}
+}
+
+// Name implements MqttBroker.
+func (HiveMQBroker) Name() string { return "HiveMQ" }
+
+// Listeners implements MqttBroker.
+func (HiveMQBroker) Listeners() MqttListeners {
+	return MqttListeners{WebSocketPort: hivemqWebSocketPort, WebSocketPath: "/mqtt"}
+}
+
+// ContainerRequest implements MqttBroker.
+func (b HiveMQBroker) ContainerRequest(dir string, cfg MqttConfig, certs *TLSCertificates) (testcontainers.ContainerRequest, error) {
+	if err := rejectMqttAuth(b, cfg); err != nil {
+		return testcontainers.ContainerRequest{}, err
+	}
+	if certs != nil {
+		return testcontainers.ContainerRequest{}, fmt.Errorf("%s broker does not support the TLS listener", b.Name())
+	}
+
+	var conf strings.Builder
+	conf.WriteString("<?xml version=\"1.0\"?>\n<hivemq>\n  <listeners>\n")
+	fmt.Fprintf(&conf, "    <tcp-listener>\n      <port>%s</port>\n      <bind-address>0.0.0.0</bind-address>\n    </tcp-listener>\n", cfg.EmulatorPort)
+	ports := []string{cfg.EmulatorPort + "/tcp"}
+	if cfg.EnableWebSockets {
+		fmt.Fprintf(&conf, "    <websocket-listener>\n      <port>%s</port>\n      <bind-address>0.0.0.0</bind-address>\n      <path>/mqtt</path>\n      <subprotocols>\n        <subprotocol>mqttv3.1</subprotocol>\n        <subprotocol>mqtt</subprotocol>\n      </subprotocols>\n    </websocket-listener>\n", hivemqWebSocketPort)
+		ports = append(ports, hivemqWebSocketPort+"/tcp")
+	}
+	conf.WriteString("  </listeners>\n</hivemq>\n")
+
+	file, err := writeContainerFile(dir, "config.xml", "/opt/hivemq/conf/config.xml", []byte(conf.String()))
+	if err != nil {
+		return testcontainers.ContainerRequest{}, err
+	}
+
+	// HiveMQ runs on the JVM and binds its listeners during startup, so the
+	// startup log line is the reliable readiness signal.
+	return testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: ports,
+		Files:        []testcontainers.ContainerFile{file},
+		WaitingFor:   wait.ForLog("Started HiveMQ in").WithStartupTimeout(2 * time.Minute),
+	}, nil
+}
+
+// rejectMqttAuth returns an error if cfg asks for credentials or ACLs, for
+// broker implementations that only support anonymous access.
+func rejectMqttAuth(b MqttBroker, cfg MqttConfig) error {
+	if cfg.Username != "" || cfg.ACL != "" || cfg.ACLFile != "" {
+		return fmt.Errorf("%s broker does not support credentials or ACLs", b.Name())
+	}
+	return nil
+}
+
+// writeContainerFile writes content to dir/name and returns the container
+// file that mounts it at containerPath.
+func writeContainerFile(dir, name, containerPath string, content []byte) (testcontainers.ContainerFile, error) {
+	hostPath := filepath.Join(dir, name)
+	if err := os.WriteFile(hostPath, content, 0644); err != nil {
+		return testcontainers.ContainerFile{}, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return testcontainers.ContainerFile{HostFilePath: hostPath, ContainerFilePath: containerPath, FileMode: 0644}, nil
+}
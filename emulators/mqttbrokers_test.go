@@ -0,0 +1,142 @@
+package emulators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupMqttBrokerEMQXSharedSubscription(t *testing.T) {
+	t.Parallel()
+
+	connInfo := SetupMqttBroker(t, context.Background(), GetDefaultEMQXConfig())
+
+	// Two members of the same share group should split the messages between them.
+	received := make(chan string, 10)
+	for _, id := range []string{"shared-a", "shared-b"} {
+		client, err := CreateTestMqttClient(connInfo, id)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Disconnect(250) })
+		token := client.Subscribe("$share/group/devices/+", 1, func(_ mqtt.Client, msg mqtt.Message) {
+			received <- string(msg.Payload())
+		})
+		require.True(t, token.WaitTimeout(10*time.Second))
+		require.NoError(t, token.Error())
+	}
+
+	publisher, err := CreateTestMqttClient(connInfo, "shared-publisher")
+	require.NoError(t, err)
+	t.Cleanup(func() { publisher.Disconnect(250) })
+	for _, payload := range []string{"one", "two", "three", "four"} {
+		token := publisher.Publish("devices/sensor", 1, false, payload)
+		require.True(t, token.WaitTimeout(10*time.Second))
+		require.NoError(t, token.Error())
+	}
+
+	// Each message is delivered to exactly one group member.
+	var got []string
+	for len(got) < 4 {
+		select {
+		case payload := <-received:
+			got = append(got, payload)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for shared messages, got %v", got)
+		}
+	}
+	select {
+	case payload := <-received:
+		t.Fatalf("Unexpected duplicate delivery of %q", payload)
+	case <-time.After(500 * time.Millisecond):
+	}
+	require.ElementsMatch(t, []string{"one", "two", "three", "four"}, got)
+}
+
+func TestSetupMqttBrokerHiveMQ(t *testing.T) {
+	t.Parallel()
+
+	cfg := GetDefaultHiveMQConfig()
+	cfg.EnableWebSockets = true
+	connInfo := SetupMqttBroker(t, context.Background(), cfg)
+	require.True(t, strings.HasSuffix(connInfo.WebSocketAddress, "/mqtt"), "WebSocketAddress should include the listener path")
+
+	for id, address := range map[string]string{"hivemq-tcp": connInfo.EmulatorAddress, "hivemq-ws": connInfo.WebSocketAddress} {
+		client, err := CreateTestMqttPublisher(address, id)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Disconnect(250) })
+		require.True(t, client.IsConnected())
+	}
+}
+
+func TestEMQXBrokerContainerRequest(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		req, err := EMQXBroker{}.ContainerRequest(t.TempDir(), GetDefaultEMQXConfig(), nil)
+		require.NoError(t, err)
+		require.Equal(t, emqxImage, req.Image)
+		require.Equal(t, []string{"1883/tcp"}, req.ExposedPorts)
+		require.Empty(t, req.Files)
+	})
+
+	t.Run("TLS and WebSockets", func(t *testing.T) {
+		certs, err := generateTestCertificates()
+		require.NoError(t, err)
+		cfg := GetDefaultEMQXConfig()
+		cfg.EnableWebSockets = true
+
+		req, err := EMQXBroker{}.ContainerRequest(t.TempDir(), cfg, &certs)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1883/tcp", "8883/tcp", "8083/tcp"}, req.ExposedPorts)
+		require.Len(t, req.Files, 3)
+		require.Equal(t, emqxCertDir+"/server.pem", req.Env["EMQX_LISTENERS__SSL__DEFAULT__SSL_OPTIONS__CERTFILE"])
+	})
+
+	t.Run("credentials rejected", func(t *testing.T) {
+		cfg := GetDefaultEMQXConfig()
+		cfg.Username = "device"
+		_, err := EMQXBroker{}.ContainerRequest(t.TempDir(), cfg, nil)
+		require.ErrorContains(t, err, "EMQX broker does not support credentials")
+	})
+}
+
+func TestHiveMQBrokerContainerRequest(t *testing.T) {
+	t.Run("WebSockets", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := GetDefaultHiveMQConfig()
+		cfg.EnableWebSockets = true
+
+		req, err := HiveMQBroker{}.ContainerRequest(dir, cfg, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1883/tcp", "8000/tcp"}, req.ExposedPorts)
+		require.Len(t, req.Files, 1)
+		require.Equal(t, "/opt/hivemq/conf/config.xml", req.Files[0].ContainerFilePath)
+
+		conf, err := os.ReadFile(filepath.Join(dir, "config.xml"))
+		require.NoError(t, err)
+		require.Contains(t, string(conf), "<port>1883</port>")
+		require.Contains(t, string(conf), "<websocket-listener>")
+		require.Contains(t, string(conf), "<path>/mqtt</path>")
+	})
+
+	t.Run("TLS rejected", func(t *testing.T) {
+		certs, err := generateTestCertificates()
+		require.NoError(t, err)
+		_, err = HiveMQBroker{}.ContainerRequest(t.TempDir(), GetDefaultHiveMQConfig(), &certs)
+		require.ErrorContains(t, err, "HiveMQ broker does not support the TLS listener")
+	})
+}
+
+func TestMosquittoBrokerContainerRequest(t *testing.T) {
+	cfg := GetDefaultMqttImageContainer()
+	cfg.EnableWebSockets = true
+
+	req, err := MosquittoBroker{}.ContainerRequest(t.TempDir(), cfg, nil)
+	require.NoError(t, err)
+	require.Equal(t, mosquitoImage, req.Image)
+	require.Equal(t, []string{"1883/tcp", "9001/tcp"}, req.ExposedPorts)
+	require.Len(t, req.Files, 1)
+}
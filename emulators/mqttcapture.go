@@ -0,0 +1,105 @@
+package emulators
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// CapturedMessage is an MQTT message received by a Capture.
+type CapturedMessage struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retained bool
+	// ReceivedAt is when the capturing client received the message.
+	ReceivedAt time.Time
+}
+
+// Capture buffers the messages an MQTT subscription receives, in the order
+// they arrive, for a test to wait on and assert against.
+type Capture struct {
+	t        testing.TB
+	mu       sync.Mutex
+	messages []CapturedMessage
+	// arrived is closed, and replaced, whenever a message is recorded.
+	arrived chan struct{}
+}
+
+// CaptureMQTTMessages connects its own client to the broker at brokerURL and
+// subscribes to topicFilter, which may contain wildcards, before it returns,
+// so no message published afterwards is missed. It subscribes at QoS 2, so
+// each message keeps the QoS it was published with. The client is
+// disconnected via t.Cleanup, and the test fails if it cannot connect or
+// subscribe within 10 seconds.
+func CaptureMQTTMessages(t testing.TB, brokerURL, topicFilter string) *Capture {
+	t.Helper()
+	opts := mqtt.NewClientOptions().AddBroker(brokerURL).
+		SetClientID("capture-" + uuid.NewString()[:8]).
+		SetAutoReconnect(false)
+	client, err := connectTestMqttClient(opts)
+	require.NoError(t, err)
+	require.True(t, client.IsConnected(), "MQTT capture client failed to connect to %s", brokerURL)
+	t.Cleanup(func() { client.Disconnect(250) })
+
+	c := newCapture(t)
+	token := client.Subscribe(topicFilter, 2, func(_ mqtt.Client, msg mqtt.Message) {
+		c.record(CapturedMessage{
+			Topic:      msg.Topic(),
+			Payload:    msg.Payload(),
+			QoS:        msg.Qos(),
+			Retained:   msg.Retained(),
+			ReceivedAt: time.Now(),
+		})
+	})
+	require.True(t, token.WaitTimeout(10*time.Second), "MQTT capture subscribe to %q timed out", topicFilter)
+	require.NoError(t, token.Error(), "MQTT capture failed to subscribe to %q", topicFilter)
+	return c
+}
+
+func newCapture(t testing.TB) *Capture {
+	return &Capture{t: t, arrived: make(chan struct{})}
+}
+
+// record adds msg to the capture and wakes any waiters.
+func (c *Capture) record(msg CapturedMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+	close(c.arrived)
+	c.arrived = make(chan struct{})
+}
+
+// Messages returns a copy of the messages captured so far.
+func (c *Capture) Messages() []CapturedMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedMessage(nil), c.messages...)
+}
+
+// WaitForCount waits until at least n messages have been captured and
+// returns all of them. It fails the test if fewer have arrived within
+// timeout.
+func (c *Capture) WaitForCount(n int, timeout time.Duration) []CapturedMessage {
+	c.t.Helper()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.mu.Lock()
+		got, arrived := len(c.messages), c.arrived
+		c.mu.Unlock()
+		if got >= n {
+			return c.Messages()
+		}
+		select {
+		case <-arrived:
+		case <-deadline.C:
+			c.t.Fatalf("timed out after %s waiting for %d MQTT messages, captured %d", timeout, n, got)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package emulators
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureWaitForCount(t *testing.T) {
+	c := newCapture(t)
+	go func() {
+		for i := range 3 {
+			time.Sleep(10 * time.Millisecond)
+			c.record(CapturedMessage{Topic: fmt.Sprintf("devices/%d", i), ReceivedAt: time.Now()})
+		}
+	}()
+
+	messages := c.WaitForCount(3, 5*time.Second)
+	require.Len(t, messages, 3)
+	for i, msg := range messages {
+		require.Equal(t, fmt.Sprintf("devices/%d", i), msg.Topic)
+	}
+	require.False(t, messages[2].ReceivedAt.Before(messages[0].ReceivedAt))
+
+	// Messages returns a copy.
+	messages[0].Topic = "changed"
+	require.Equal(t, "devices/0", c.Messages()[0].Topic)
+}
+
+func TestCaptureWaitForCountTimesOut(t *testing.T) {
+	recorder := &failRecorder{TB: t}
+	c := newCapture(recorder)
+	c.record(CapturedMessage{Topic: "devices/0"})
+
+	done := make(chan struct{})
+	// FailNow ends the goroutine, as it would a test.
+	go func() {
+		defer close(done)
+		c.WaitForCount(2, 50*time.Millisecond)
+	}()
+	<-done
+	require.True(t, recorder.failed, "capturing too few messages fails the test")
+	require.Contains(t, recorder.message, "captured 1")
+}
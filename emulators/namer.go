@@ -0,0 +1,228 @@
+package emulators
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resourceKind is a kind of Google Cloud resource a Namer can clean up.
+type resourceKind int
+
+const (
+	resourceCustom resourceKind = iota
+	resourceTopic
+	resourceSubscription
+	resourceBucket
+	resourceDataset
+)
+
+// namedResource is a resource recorded by a Namer.
+type namedResource struct {
+	kind    resourceKind
+	name    string
+	cleanup func(ctx context.Context) error
+}
+
+// Namer produces run-scoped, collision-free resource names and records every
+// name it hands out, so CleanupAll can delete whatever a test leaves behind.
+// Names have the form "<prefix>-<run ID>-<n>", so leftovers of a crashed run
+// can still be traced to it. It is safe for concurrent use, e.g. by parallel
+// subtests sharing one Namer.
+//
+// It works against real Google Cloud projects as well as emulators:
+//
+//	namer := emulators.NewNamer(projectID)
+//	t.Cleanup(func() { require.NoError(t, namer.CleanupAll(context.Background())) })
+//	topicID := namer.Topic("orders")
+type Namer struct {
+	projectID  string
+	clientOpts []option.ClientOption
+	runID      string
+
+	mu        sync.Mutex
+	counter   int
+	resources []namedResource
+}
+
+// NewNamer returns a Namer for projectID with a fresh random run ID. opts are
+// used for the clients CleanupAll creates; pass connInfo.ClientOptions for an
+// emulator, or nothing to use Application Default Credentials.
+func NewNamer(projectID string, opts ...option.ClientOption) *Namer {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return &Namer{projectID: projectID, clientOpts: opts, runID: hex.EncodeToString(b)}
+}
+
+// RunID returns the run ID embedded in every name.
+func (n *Namer) RunID() string {
+	return n.runID
+}
+
+// Name returns a unique "<prefix>-<run ID>-<n>" name without recording it.
+func (n *Namer) Name(prefix string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.nextName(prefix, "-")
+}
+
+// Topic returns a unique Pub/Sub topic ID that CleanupAll deletes.
+func (n *Namer) Topic(prefix string) string {
+	return n.record(resourceTopic, prefix, "-")
+}
+
+// Subscription returns a unique Pub/Sub subscription ID that CleanupAll deletes.
+func (n *Namer) Subscription(prefix string) string {
+	return n.record(resourceSubscription, prefix, "-")
+}
+
+// Bucket returns a unique GCS bucket name that CleanupAll deletes with its
+// objects. Bucket names are global, so the prefix should identify the project.
+func (n *Namer) Bucket(prefix string) string {
+	return n.record(resourceBucket, strings.ToLower(prefix), "-")
+}
+
+// Dataset returns a unique BigQuery dataset ID that CleanupAll deletes with
+// its tables. Dataset IDs cannot contain hyphens, so its parts are joined
+// with underscores.
+func (n *Namer) Dataset(prefix string) string {
+	return n.record(resourceDataset, prefix, "_")
+}
+
+// Register records a cleanup for a resource kind the Namer does not know,
+// to be run by CleanupAll.
+func (n *Namer) Register(cleanup func(ctx context.Context) error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.resources = append(n.resources, namedResource{kind: resourceCustom, cleanup: cleanup})
+}
+
+// CleanupAll deletes every recorded resource, newest first, so subscriptions
+// go before the topics they were created after. Resources that do not exist,
+// because they were never created or already deleted, are skipped. It returns
+// the errors of all failed deletions; failed resources stay recorded so
+// CleanupAll can be retried.
+func (n *Namer) CleanupAll(ctx context.Context) error {
+	n.mu.Lock()
+	resources := n.resources
+	n.resources = nil
+	n.mu.Unlock()
+
+	c := &namerClients{projectID: n.projectID, opts: n.clientOpts}
+	defer c.close()
+
+	var errs []error
+	var failed []namedResource
+	for i := len(resources) - 1; i >= 0; i-- {
+		r := resources[i]
+		if err := c.delete(ctx, r); err != nil && !isNotFound(err) {
+			errs = append(errs, err)
+			failed = append([]namedResource{r}, failed...)
+		}
+	}
+
+	n.mu.Lock()
+	n.resources = append(failed, n.resources...)
+	n.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// record returns a unique name and records it as a resource of kind.
+func (n *Namer) record(kind resourceKind, prefix, sep string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	name := n.nextName(prefix, sep)
+	n.resources = append(n.resources, namedResource{kind: kind, name: name})
+	return name
+}
+
+// nextName returns the next name for prefix. n.mu must be held.
+func (n *Namer) nextName(prefix, sep string) string {
+	n.counter++
+	return strings.Join([]string{prefix, n.runID, fmt.Sprint(n.counter)}, sep)
+}
+
+// namerClients lazily creates the clients CleanupAll needs.
+type namerClients struct {
+	projectID string
+	opts      []option.ClientOption
+	pubsub    *pubsub.Client
+	storage   *storage.Client
+	bigquery  *bigquery.Client
+}
+
+// delete deletes r with the client for its kind.
+func (c *namerClients) delete(ctx context.Context, r namedResource) error {
+	var err error
+	switch r.kind {
+	case resourceCustom:
+		return r.cleanup(ctx)
+	case resourceTopic, resourceSubscription:
+		if c.pubsub == nil {
+			if c.pubsub, err = pubsub.NewClient(ctx, c.projectID, c.opts...); err != nil {
+				return fmt.Errorf("failed to create Pub/Sub client: %w", err)
+			}
+		}
+		if r.kind == resourceSubscription {
+			name := fmt.Sprintf("projects/%s/subscriptions/%s", c.projectID, r.name)
+			err = c.pubsub.SubscriptionAdminClient.DeleteSubscription(ctx, &pubsubpb.DeleteSubscriptionRequest{Subscription: name})
+		} else {
+			name := fmt.Sprintf("projects/%s/topics/%s", c.projectID, r.name)
+			err = c.pubsub.TopicAdminClient.DeleteTopic(ctx, &pubsubpb.DeleteTopicRequest{Topic: name})
+		}
+	case resourceBucket:
+		if c.storage == nil {
+			if c.storage, err = storage.NewClient(ctx, c.opts...); err != nil {
+				return fmt.Errorf("failed to create storage client: %w", err)
+			}
+		}
+		err = deleteBucket(ctx, c.storage, r.name)
+	case resourceDataset:
+		if c.bigquery == nil {
+			if c.bigquery, err = bigquery.NewClient(ctx, c.projectID, c.opts...); err != nil {
+				return fmt.Errorf("failed to create BigQuery client: %w", err)
+			}
+		}
+		err = c.bigquery.Dataset(r.name).DeleteWithContents(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", r.name, err)
+	}
+	return nil
+}
+
+// close closes every client that was created.
+func (c *namerClients) close() {
+	if c.pubsub != nil {
+		_ = c.pubsub.Close()
+	}
+	if c.storage != nil {
+		_ = c.storage.Close()
+	}
+	if c.bigquery != nil {
+		_ = c.bigquery.Close()
+	}
+}
+
+// isNotFound reports whether err means the resource does not exist.
+func isNotFound(err error) bool {
+	if errors.Is(err, storage.ErrBucketNotExist) || status.Code(err) == codes.NotFound {
+		return true
+	}
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,98 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+func TestNamerNames(t *testing.T) {
+	namer := NewNamer("test-project")
+	require.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}$`), namer.RunID())
+	require.NotEqual(t, namer.RunID(), NewNamer("test-project").RunID())
+
+	require.Equal(t, "orders-"+namer.RunID()+"-1", namer.Topic("orders"))
+	require.Equal(t, "orders-sub-"+namer.RunID()+"-2", namer.Subscription("orders-sub"))
+	require.Equal(t, "archive-"+namer.RunID()+"-3", namer.Bucket("Archive"))
+	require.Equal(t, "events_"+namer.RunID()+"_4", namer.Dataset("events"))
+	require.Equal(t, "scratch-"+namer.RunID()+"-5", namer.Name("scratch"))
+
+	// Names stay unique across goroutines.
+	seen := make(map[string]bool)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := namer.Name("topic")
+			mu.Lock()
+			defer mu.Unlock()
+			seen[name] = true
+		}()
+	}
+	wg.Wait()
+	require.Len(t, seen, 50)
+}
+
+func TestNamerCleanupAllCustom(t *testing.T) {
+	namer := NewNamer("test-project")
+	var order []int
+	errBusy := errors.New("busy")
+	fail := true
+	namer.Register(func(context.Context) error { order = append(order, 1); return nil })
+	namer.Register(func(context.Context) error {
+		order = append(order, 2)
+		if fail {
+			return errBusy
+		}
+		return nil
+	})
+	namer.Register(func(context.Context) error { order = append(order, 3); return nil })
+
+	err := namer.CleanupAll(context.Background())
+	require.ErrorIs(t, err, errBusy)
+	require.Equal(t, []int{3, 2, 1}, order, "resources are deleted newest first")
+
+	// Only the failed cleanup is retried.
+	fail = false
+	order = nil
+	require.NoError(t, namer.CleanupAll(context.Background()))
+	require.Equal(t, []int{2}, order)
+	require.NoError(t, namer.CleanupAll(context.Background()))
+}
+
+func TestNamerCleanupAllIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+	connInfo := SetupPubsubEmulator(t, ctx, GetDefaultPubsubConfig("namer-project"))
+	client, err := pubsub.NewClient(ctx, connInfo.ProjectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	namer := NewNamer(connInfo.ProjectID, connInfo.ClientOptions...)
+	topicID := namer.Topic("orders")
+	subID := namer.Subscription("orders-sub")
+	_ = namer.Topic("never-created")
+	_, err = client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/namer-project/topics/" + topicID})
+	require.NoError(t, err)
+	_, err = client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
+		Name:  "projects/namer-project/subscriptions/" + subID,
+		Topic: "projects/namer-project/topics/" + topicID,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, namer.CleanupAll(ctx))
+
+	topics := client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: "projects/namer-project"})
+	_, err = topics.Next()
+	require.ErrorIs(t, err, iterator.Done)
+}
@@ -0,0 +1,26 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/network"
+)
+
+// NewTestNetwork creates a Docker network for the test and removes it via
+// t.Cleanup. Set the returned name as ImageContainer.Network on each emulator,
+// and attach the service container under test to it, so the service can reach
+// the emulators at their EmulatorConnectionInfo.InternalEndpoint.
+func NewTestNetwork(t testing.TB, ctx context.Context) string {
+	t.Helper()
+
+	nw, err := network.New(ctx)
+	require.NoError(t, err, "Failed to create Docker network")
+	t.Cleanup(func() {
+		if err := nw.Remove(context.Background()); err != nil {
+			t.Logf("Failed to remove Docker network %s: %v", nw.Name, err)
+		}
+	})
+	return nw.Name
+}
@@ -0,0 +1,44 @@
+package emulators
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/exec"
+)
+
+func TestSetupRedisContainerOnNetwork(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	cfg := GetDefaultRedisImageContainer()
+	cfg.Network = NewTestNetwork(t, ctx)
+	cfg.NetworkAlias = "cache"
+	connInfo := SetupRedisContainer(t, ctx, cfg)
+	require.Equal(t, "cache:6379", connInfo.InternalEndpoint)
+
+	// A second container on the network stands in for the service under test.
+	client, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:    cloudTestRedisImage,
+			Networks: []string{cfg.Network},
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, client)
+	require.NoError(t, err)
+
+	host, port, err := net.SplitHostPort(connInfo.InternalEndpoint)
+	require.NoError(t, err)
+	code, out, err := client.Exec(ctx, []string{"redis-cli", "-h", host, "-p", port, "ping"}, exec.Multiplexed())
+	require.NoError(t, err)
+	reply, err := io.ReadAll(out)
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	require.Contains(t, string(reply), "PONG")
+}
@@ -0,0 +1,210 @@
+package emulators
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// SetupOption tweaks the container started by a Setup function, on top of
+// its config struct. Options are applied after the setup function has built
+// its container request, so they take precedence over the config.
+type SetupOption func(*setupOptions)
+
+// setupOptions holds the result of applying SetupOptions.
+type setupOptions struct {
+	image          string
+	tag            string
+	env            map[string]string
+	cmdArgs        []string
+	waitStrategy   wait.Strategy
+	startupTimeout time.Duration
+	// retries and retryBackoff are set by WithStartupRetries; retriesSet
+	// tells an explicit zero apart from the package default.
+	retries      int
+	retryBackoff time.Duration
+	retriesSet   bool
+	// logName and logf receive the container logs when StreamLogs or
+	// LogsOnFailure is set; failed reports whether the test has failed.
+	logName string
+	logf    func(format string, args ...any)
+	failed  func() bool
+}
+
+// WithImage replaces the container image, e.g. to use a mirror registry.
+func WithImage(image string) SetupOption {
+	return func(o *setupOptions) {
+		o.image = image
+	}
+}
+
+// WithTag replaces the tag of the container image (from the config or
+// WithImage), e.g. to test against another emulator version.
+func WithTag(tag string) SetupOption {
+	return func(o *setupOptions) {
+		o.tag = tag
+	}
+}
+
+// WithEnv adds environment variables to the container. It can be given more
+// than once; later values win.
+func WithEnv(env map[string]string) SetupOption {
+	return func(o *setupOptions) {
+		if o.env == nil {
+			o.env = make(map[string]string)
+		}
+		for k, v := range env {
+			o.env[k] = v
+		}
+	}
+}
+
+// WithCmdArgs appends arguments to the container command, e.g. extra
+// emulator flags.
+func WithCmdArgs(args ...string) SetupOption {
+	return func(o *setupOptions) {
+		o.cmdArgs = append(o.cmdArgs, args...)
+	}
+}
+
+// WithWaitStrategy replaces the readiness check of the container.
+func WithWaitStrategy(strategy wait.Strategy) SetupOption {
+	return func(o *setupOptions) {
+		o.waitStrategy = strategy
+	}
+}
+
+// WithStartupTimeout sets how long to wait for the container to become
+// ready, e.g. on slow CI machines.
+func WithStartupTimeout(timeout time.Duration) SetupOption {
+	return func(o *setupOptions) {
+		o.startupTimeout = timeout
+	}
+}
+
+// WithStartupRetries retries a failed container start up to retries more
+// times, waiting backoff before the first retry and doubling it after each,
+// to ride out transient Docker daemon or image pull failures. It overrides
+// the default set with SetStartupRetries.
+func WithStartupRetries(retries int, backoff time.Duration) SetupOption {
+	return func(o *setupOptions) {
+		o.retries = retries
+		o.retryBackoff = backoff
+		o.retriesSet = true
+	}
+}
+
+// startupRetries holds the package-level default set by SetStartupRetries.
+var startupRetries struct {
+	sync.RWMutex
+	retries int
+	backoff time.Duration
+}
+
+// SetStartupRetries sets the default number of times a failed container start
+// is retried, for every emulator including those started by
+// SetupEmulatorSuite and RunWithSuite. Call it once, e.g. from TestMain, on CI
+// machines prone to transient Docker failures. The default is no retries.
+func SetStartupRetries(retries int, backoff time.Duration) {
+	startupRetries.Lock()
+	defer startupRetries.Unlock()
+	startupRetries.retries = retries
+	startupRetries.backoff = backoff
+}
+
+// startupRetryPolicy returns the retries and initial backoff for o.
+func (o setupOptions) startupRetryPolicy() (int, time.Duration) {
+	if o.retriesSet {
+		return o.retries, o.retryBackoff
+	}
+	startupRetries.RLock()
+	defer startupRetries.RUnlock()
+	return startupRetries.retries, startupRetries.backoff
+}
+
+// logsTo routes the container logs, when ImageContainer.StreamLogs or
+// LogsOnFailure is set, to logf with name as the line prefix. failed reports
+// whether buffered logs should be written when the container is terminated.
+func logsTo(name string, logf func(format string, args ...any), failed func() bool) SetupOption {
+	return func(o *setupOptions) {
+		o.logName = name
+		o.logf = logf
+		o.failed = failed
+	}
+}
+
+// withTestLogs returns opts preceded by an option that sends the container
+// logs, when enabled, to t.Logf under name.
+func withTestLogs(t testing.TB, name string, opts []SetupOption) []SetupOption {
+	return append([]SetupOption{logsTo(name, t.Logf, t.Failed)}, opts...)
+}
+
+// applySetupOptions applies opts to req and returns the applied options.
+func applySetupOptions(req *testcontainers.ContainerRequest, opts []SetupOption) setupOptions {
+	var o setupOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.image != "" {
+		req.Image = o.image
+	}
+	if o.tag != "" {
+		req.Image = imageWithTag(req.Image, o.tag)
+	}
+	if len(o.env) > 0 {
+		env := make(map[string]string, len(req.Env)+len(o.env))
+		for k, v := range req.Env {
+			env[k] = v
+		}
+		for k, v := range o.env {
+			env[k] = v
+		}
+		req.Env = env
+	}
+	req.Cmd = append(req.Cmd, o.cmdArgs...)
+	if o.waitStrategy != nil {
+		req.WaitingFor = o.waitStrategy
+	}
+	if o.startupTimeout > 0 && req.WaitingFor != nil {
+		req.WaitingFor = withStartupTimeout(req.WaitingFor, o.startupTimeout)
+	}
+	return o
+}
+
+// imageWithTag returns image with its tag (or digest) replaced by tag.
+func imageWithTag(image, tag string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	// A colon before the last slash belongs to a registry port, not a tag.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
+
+// withStartupTimeout sets the startup timeout of strategy and, for ForAll
+// strategies, of every strategy inside it. Strategy types without a timeout
+// are wrapped so the overall wait is still bounded.
+func withStartupTimeout(strategy wait.Strategy, timeout time.Duration) wait.Strategy {
+	switch s := strategy.(type) {
+	case *wait.MultiStrategy:
+		for i, inner := range s.Strategies {
+			s.Strategies[i] = withStartupTimeout(inner, timeout)
+		}
+		return s.WithDeadline(timeout)
+	case *wait.HostPortStrategy:
+		return s.WithStartupTimeout(timeout)
+	case *wait.HTTPStrategy:
+		return s.WithStartupTimeout(timeout)
+	case *wait.LogStrategy:
+		return s.WithStartupTimeout(timeout)
+	default:
+		return wait.ForAll(strategy).WithDeadline(timeout)
+	}
+}
@@ -0,0 +1,67 @@
+package emulators
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestApplySetupOptions(t *testing.T) {
+	req := testcontainers.ContainerRequest{
+		Image:      "redis:8.0.2-alpine",
+		Env:        map[string]string{"KEEP": "1", "OVERRIDE": "old"},
+		Cmd:        []string{"redis-server"},
+		WaitingFor: wait.ForListeningPort("6379/tcp"),
+	}
+	custom := wait.ForLog("Ready to accept connections")
+
+	applySetupOptions(&req, []SetupOption{
+		WithImage("mirror.example:5000/library/redis:7"),
+		WithTag("7.4-alpine"),
+		WithEnv(map[string]string{"OVERRIDE": "new"}),
+		WithEnv(map[string]string{"ADDED": "2"}),
+		WithCmdArgs("--maxmemory", "10mb"),
+		WithWaitStrategy(custom),
+		WithStartupTimeout(3 * time.Minute),
+	})
+
+	require.Equal(t, "mirror.example:5000/library/redis:7.4-alpine", req.Image)
+	require.Equal(t, map[string]string{"KEEP": "1", "OVERRIDE": "new", "ADDED": "2"}, req.Env)
+	require.Equal(t, []string{"redis-server", "--maxmemory", "10mb"}, req.Cmd)
+	require.Same(t, custom, req.WaitingFor)
+	require.Equal(t, 3*time.Minute, *custom.Timeout())
+}
+
+func TestApplySetupOptionsNone(t *testing.T) {
+	req := testcontainers.ContainerRequest{Image: "redis:8.0.2-alpine"}
+	applySetupOptions(&req, nil)
+	require.Equal(t, testcontainers.ContainerRequest{Image: "redis:8.0.2-alpine"}, req)
+}
+
+func TestImageWithTag(t *testing.T) {
+	tests := map[string]string{
+		"redis":                          "redis:7",
+		"redis:8.0.2-alpine":             "redis:7",
+		"localhost:5000/redis":           "localhost:5000/redis:7",
+		"localhost:5000/redis:8":         "localhost:5000/redis:7",
+		"redis@sha256:0123456789abcdef":  "redis:7",
+		"gcr.io/project/emulator:latest": "gcr.io/project/emulator:7",
+	}
+	for image, want := range tests {
+		require.Equal(t, want, imageWithTag(image, "7"), image)
+	}
+}
+
+func TestWithStartupTimeoutAppliesToAllStrategies(t *testing.T) {
+	port := wait.ForListeningPort("8080/tcp").WithStartupTimeout(10 * time.Second)
+	log := wait.ForLog("started")
+	all := wait.ForAll(port, log)
+
+	withStartupTimeout(all, 2*time.Minute)
+
+	require.Equal(t, 2*time.Minute, *port.Timeout())
+	require.Equal(t, 2*time.Minute, *log.Timeout())
+}
@@ -0,0 +1,173 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// otelCollectorImage is the default OpenTelemetry collector image to use.
+	// The contrib distribution includes the Prometheus exporter.
+	otelCollectorImage = "otel/opentelemetry-collector-contrib:0.128.0"
+	// otelCollectorHTTPPort and otelCollectorGRPCPort are the internal OTLP ports.
+	otelCollectorHTTPPort = "4318"
+	otelCollectorGRPCPort = "4317"
+	// otelCollectorMetricsPort is the internal port of the Prometheus exporter
+	// in the default configuration.
+	otelCollectorMetricsPort = "8889"
+	// otelCollectorConfigPath is where the configuration is mounted in the container.
+	otelCollectorConfigPath = "/etc/otelcol/config.yaml"
+)
+
+// defaultOTelCollectorConfig receives OTLP over gRPC and HTTP, exposes metrics
+// in Prometheus format and writes traces and logs to the container log.
+const defaultOTelCollectorConfig = `receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+      http:
+        endpoint: 0.0.0.0:4318
+exporters:
+  prometheus:
+    endpoint: 0.0.0.0:8889
+  debug:
+    verbosity: detailed
+service:
+  pipelines:
+    metrics:
+      receivers: [otlp]
+      exporters: [prometheus]
+    traces:
+      receivers: [otlp]
+      exporters: [debug]
+    logs:
+      receivers: [otlp]
+      exporters: [debug]
+`
+
+// OTelCollectorConfig holds configuration for an OpenTelemetry collector container.
+type OTelCollectorConfig struct {
+	ImageContainer
+	// Config is the collector configuration (YAML). The default receives OTLP
+	// on EmulatorGRPCPort and EmulatorPort, exposes received metrics in
+	// Prometheus format on MetricsPort, and writes traces and logs to the
+	// container log (see ImageContainer.StreamLogs).
+	Config string
+	// MetricsPort is the internal port of a Prometheus exporter in Config,
+	// returned as EmulatorConnectionInfo.MetricsEndpoint. Leave it empty if
+	// Config has no Prometheus exporter.
+	MetricsPort string
+}
+
+// GetDefaultOTelCollectorConfig returns a default configuration for an
+// OpenTelemetry collector container.
+func GetDefaultOTelCollectorConfig() OTelCollectorConfig {
+	return OTelCollectorConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage:    otelCollectorImage,
+			EmulatorPort:     otelCollectorHTTPPort,
+			EmulatorGRPCPort: otelCollectorGRPCPort,
+		},
+		Config:      defaultOTelCollectorConfig,
+		MetricsPort: otelCollectorMetricsPort,
+	}
+}
+
+// SetupOTelCollector starts an OpenTelemetry collector container with
+// cfg.Config. It automatically handles container startup and teardown via
+// t.Cleanup. The returned connection info has the OTLP/HTTP endpoint
+// (e.g., "http://localhost:54321") as HTTPEndpoint, the OTLP/gRPC endpoint
+// (e.g., "localhost:54322") as GRPCEndpoint and, when cfg.MetricsPort is set,
+// the Prometheus exporter as MetricsEndpoint.
+func SetupOTelCollector(t testing.TB, ctx context.Context, cfg OTelCollectorConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
+
+	connInfo, terminate, err := StartOTelCollector(ctx, cfg, withTestLogs(t, "OTel collector", opts)...)
+	require.NoError(t, err, "Failed to start OpenTelemetry collector container")
+
+	t.Cleanup(func() {
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate OpenTelemetry collector container: %v", err)
+		}
+	})
+
+	t.Logf("OpenTelemetry collector started with OTLP/gRPC at %s and OTLP/HTTP at %s", connInfo.GRPCEndpoint.Endpoint, connInfo.HTTPEndpoint.Endpoint)
+	return connInfo
+}
+
+// StartOTelCollector starts an OpenTelemetry collector container and waits
+// for its pipelines to start. Unlike SetupOTelCollector it needs no
+// testing.TB; the caller must call the returned TerminateFunc.
+func StartOTelCollector(ctx context.Context, cfg OTelCollectorConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	dir, err := os.MkdirTemp("", "otelcol-")
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to create collector config directory: %w", err)
+	}
+	config := cfg.Config
+	if config == "" {
+		config = defaultOTelCollectorConfig
+	}
+	file, err := writeContainerFile(dir, "config.yaml", otelCollectorConfigPath, []byte(config))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return EmulatorConnectionInfo{}, nil, err
+	}
+
+	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	grpcPort := fmt.Sprintf("%s/tcp", cfg.EmulatorGRPCPort)
+	ports := []string{httpPort, grpcPort}
+	if cfg.MetricsPort != "" {
+		ports = append(ports, cfg.MetricsPort+"/tcp")
+	}
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: ports,
+		Cmd:          []string{"--config=" + otelCollectorConfigPath},
+		Files:        []testcontainers.ContainerFile{file},
+		// Logged once every configured pipeline is running, whatever the config.
+		WaitingFor: wait.ForLog("Everything is ready").WithStartupTimeout(60 * time.Second),
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, dir, opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	httpAddr, err := mappedAddress(ctx, container, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	grpcAddr, err := mappedAddress(ctx, container, grpcPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	connInfo = EmulatorConnectionInfo{
+		Service:      ServiceOTelCollector,
+		HTTPEndpoint: Endpoint{Port: cfg.EmulatorPort, Endpoint: "http://" + httpAddr},
+		GRPCEndpoint: Endpoint{Port: cfg.EmulatorGRPCPort, Endpoint: grpcAddr},
+	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, httpPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if cfg.MetricsPort != "" {
+		metricsAddr, err := mappedAddress(ctx, container, cfg.MetricsPort+"/tcp")
+		if err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+		connInfo.MetricsEndpoint = "http://" + metricsAddr + "/metrics"
+	}
+	return connInfo, terminate, nil
+}
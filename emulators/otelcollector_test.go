@@ -0,0 +1,43 @@
+package emulators
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// otlpGaugeJSON is an OTLP/JSON export request with a single gauge point.
+const otlpGaugeJSON = `{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"emulators-test"}}]},
+"scopeMetrics":[{"metrics":[{"name":"test_queue_depth","gauge":{"dataPoints":[{"asInt":"7","timeUnixNano":"%d"}]}}]}]}]}`
+
+func TestSetupOTelCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	connInfo := SetupOTelCollector(t, ctx, GetDefaultOTelCollectorConfig())
+	require.NotEmpty(t, connInfo.GRPCEndpoint.Endpoint)
+	require.NotEmpty(t, connInfo.MetricsEndpoint)
+	require.Equal(t, connInfo.HTTPEndpoint.Endpoint, connInfo.EnvVars()["OTEL_EXPORTER_OTLP_ENDPOINT"])
+
+	body := strings.Replace(otlpGaugeJSON, "%d", strconv.FormatInt(time.Now().UnixNano(), 10), 1)
+	resp, err := http.Post(connInfo.HTTPEndpoint.Endpoint+"/v1/metrics", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(connInfo.MetricsEndpoint)
+		if err != nil {
+			return false
+		}
+		defer func() { _ = resp.Body.Close() }()
+		metrics, err := io.ReadAll(resp.Body)
+		return err == nil && strings.Contains(string(metrics), "test_queue_depth")
+	}, 30*time.Second, 500*time.Millisecond, "Collector did not export the received metric")
+}
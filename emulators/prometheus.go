@@ -0,0 +1,226 @@
+package emulators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// prometheusImage is the default Prometheus image to use.
+	prometheusImage = "prom/prometheus:v3.4.1"
+	// prometheusPort is the internal port of the Prometheus HTTP API.
+	prometheusPort = "9090"
+	// prometheusConfigPath is where the configuration is mounted in the container.
+	prometheusConfigPath = "/etc/prometheus/prometheus.yml"
+)
+
+// PrometheusConfig holds configuration for a Prometheus server container.
+type PrometheusConfig struct {
+	ImageContainer
+	// ScrapeHostPorts are ports on the host, i.e. of services under test
+	// running in the test process, that serve /metrics. They are made
+	// reachable from the container and scraped every ScrapeInterval. The
+	// ports must be listening before Prometheus is started.
+	ScrapeHostPorts []int
+	// ScrapeInterval is how often targets are scraped. It defaults to one
+	// second, so tests do not wait long for fresh samples.
+	ScrapeInterval time.Duration
+	// Config replaces the generated prometheus.yml. ScrapeHostPorts are still
+	// reachable from the container, as host.testcontainers.internal:<port>.
+	Config string
+}
+
+// GetDefaultPrometheusConfig returns a default configuration for a Prometheus
+// server scraping the given host ports.
+func GetDefaultPrometheusConfig(scrapeHostPorts ...int) PrometheusConfig {
+	return PrometheusConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: prometheusImage,
+			EmulatorPort:  prometheusPort,
+		},
+		ScrapeHostPorts: scrapeHostPorts,
+		ScrapeInterval:  time.Second,
+	}
+}
+
+// SetupPrometheus starts a Prometheus server container that scrapes
+// cfg.ScrapeHostPorts. It automatically handles container startup and
+// teardown via t.Cleanup. The returned HTTPEndpoint is the base URL of the
+// Prometheus API (e.g., "http://localhost:54321"); see QueryPrometheus.
+func SetupPrometheus(t testing.TB, ctx context.Context, cfg PrometheusConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
+
+	connInfo, terminate, err := StartPrometheus(ctx, cfg, withTestLogs(t, "Prometheus", opts)...)
+	require.NoError(t, err, "Failed to start Prometheus container")
+
+	t.Cleanup(func() {
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate Prometheus container: %v", err)
+		}
+	})
+
+	t.Logf("Prometheus container started at: %s", connInfo.HTTPEndpoint.Endpoint)
+	return connInfo
+}
+
+// StartPrometheus starts a Prometheus server container and waits for it to
+// report ready. Unlike SetupPrometheus it needs no testing.TB; the caller must
+// call the returned TerminateFunc.
+func StartPrometheus(ctx context.Context, cfg PrometheusConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	dir, err := os.MkdirTemp("", "prometheus-")
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to create Prometheus config directory: %w", err)
+	}
+	config := cfg.Config
+	if config == "" {
+		config = prometheusConfigFile(cfg)
+	}
+	file, err := writeContainerFile(dir, "prometheus.yml", prometheusConfigPath, []byte(config))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return EmulatorConnectionInfo{}, nil, err
+	}
+
+	httpPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: []string{httpPort},
+		Cmd: []string{
+			"--config.file=" + prometheusConfigPath,
+			"--storage.tsdb.path=/prometheus",
+			// Lets services under test push samples instead of being scraped.
+			"--web.enable-remote-write-receiver",
+		},
+		Files:           []testcontainers.ContainerFile{file},
+		HostAccessPorts: cfg.ScrapeHostPorts,
+		WaitingFor:      wait.ForHTTP("/-/ready").WithPort(nat.Port(httpPort)).WithStartupTimeout(60 * time.Second),
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, dir, opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	addr, err := mappedAddress(ctx, container, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	internal, err := internalAddress(ctx, container, cfg.ImageContainer, httpPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	return EmulatorConnectionInfo{
+		Service:          ServicePrometheus,
+		HTTPEndpoint:     Endpoint{Port: cfg.EmulatorPort, Endpoint: "http://" + addr},
+		InternalEndpoint: internal,
+	}, terminate, nil
+}
+
+// prometheusConfigFile renders a prometheus.yml that scrapes each of
+// cfg.ScrapeHostPorts as a target of the "host" job.
+func prometheusConfigFile(cfg PrometheusConfig) string {
+	interval := cfg.ScrapeInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "global:\n  scrape_interval: %s\n  evaluation_interval: %s\n", interval, interval)
+	if len(cfg.ScrapeHostPorts) == 0 {
+		return b.String()
+	}
+	b.WriteString("scrape_configs:\n  - job_name: host\n    static_configs:\n      - targets:\n")
+	for _, port := range cfg.ScrapeHostPorts {
+		fmt.Fprintf(&b, "          - %s:%d\n", testcontainers.HostInternal, port)
+	}
+	return b.String()
+}
+
+// PrometheusSample is one series of an instant query result.
+type PrometheusSample struct {
+	// Labels are the series labels, including __name__.
+	Labels map[string]string
+	Value  float64
+}
+
+// QueryPrometheus runs an instant PromQL query against a Prometheus server
+// started by SetupPrometheus and returns the resulting samples. It fails the
+// test unless the query succeeds with a vector result. Scrapes are periodic,
+// so wrap it in require.Eventually when waiting for new samples.
+func QueryPrometheus(t testing.TB, ctx context.Context, connInfo EmulatorConnectionInfo, query string) []PrometheusSample {
+	t.Helper()
+
+	samples, err := queryPrometheus(ctx, connInfo.HTTPEndpoint.Endpoint, query)
+	require.NoError(t, err, "Prometheus query %q failed", query)
+	return samples
+}
+
+// queryPrometheus runs an instant query against the Prometheus API at endpoint.
+func queryPrometheus(ctx context.Context, endpoint, query string) ([]PrometheusSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/api/v1/query?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", body.Error)
+	}
+	if body.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("unsupported result type %q, want vector", body.Data.ResultType)
+	}
+	var result []struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]any            `json:"value"`
+	}
+	if err := json.Unmarshal(body.Data.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode vector: %w", err)
+	}
+
+	samples := make([]PrometheusSample, 0, len(result))
+	for _, r := range result {
+		// Values are [<unix time>, "<value>"].
+		s, ok := r.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected sample value %v", r.Value[1])
+		}
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sample value %q: %w", s, err)
+		}
+		samples = append(samples, PrometheusSample{Labels: r.Metric, Value: value})
+	}
+	return samples, nil
+}
@@ -0,0 +1,80 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusConfigFile(t *testing.T) {
+	cfg := GetDefaultPrometheusConfig(8080, 9100)
+	require.Equal(t, `global:
+  scrape_interval: 1s
+  evaluation_interval: 1s
+scrape_configs:
+  - job_name: host
+    static_configs:
+      - targets:
+          - host.testcontainers.internal:8080
+          - host.testcontainers.internal:9100
+`, prometheusConfigFile(cfg))
+
+	cfg = GetDefaultPrometheusConfig()
+	cfg.ScrapeInterval = 5 * time.Second
+	require.Equal(t, "global:\n  scrape_interval: 5s\n  evaluation_interval: 5s\n", prometheusConfigFile(cfg))
+}
+
+func TestQueryPrometheus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "up":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"host"},"value":[1700000000.1,"1"]}]}}`)
+		case "scalar(1)":
+			_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1700000000.1,"1"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	samples, err := queryPrometheus(ctx, server.URL, "up")
+	require.NoError(t, err)
+	require.Equal(t, []PrometheusSample{{Labels: map[string]string{"__name__": "up", "job": "host"}, Value: 1}}, samples)
+
+	_, err = queryPrometheus(ctx, server.URL, "scalar(1)")
+	require.ErrorContains(t, err, "unsupported result type")
+
+	_, err = queryPrometheus(ctx, server.URL, "up{")
+	require.ErrorContains(t, err, "parse error")
+}
+
+func TestSetupPrometheus(t *testing.T) {
+	// A service under test exposing a counter on the host.
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "# TYPE test_requests_total counter\ntest_requests_total 42\n")
+	}))
+	t.Cleanup(target.Close)
+	_, port, err := net.SplitHostPort(target.Listener.Addr().String())
+	require.NoError(t, err)
+	hostPort, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+	connInfo := SetupPrometheus(t, ctx, GetDefaultPrometheusConfig(hostPort))
+	require.Equal(t, ServicePrometheus, connInfo.Service)
+
+	require.Eventually(t, func() bool {
+		samples := QueryPrometheus(t, ctx, connInfo, "test_requests_total")
+		return len(samples) == 1 && samples[0].Value == 42
+	}, 30*time.Second, time.Second, "Prometheus did not scrape the host target")
+}
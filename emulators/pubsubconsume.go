@@ -0,0 +1,58 @@
+package emulators
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// ConsumeMessages receives n messages from the subscription subID and
+// returns them in the order they arrived, failing the test if fewer arrive
+// within timeout. It does the receive-and-cancel dance tests otherwise get
+// wrong: every message returned is acked, redeliveries of one already
+// received are acked and dropped, and messages beyond the nth are nacked so
+// a later call receives them. The receiver has stopped by the time it
+// returns, so the subscription can be deleted straight away.
+//
+// With n 0 it waits the whole timeout and fails the test if any message
+// arrives, to check that nothing was published.
+func ConsumeMessages(t testing.TB, ctx context.Context, client *pubsub.Client, subID string, n int, timeout time.Duration) []*pubsub.Message {
+	t.Helper()
+
+	receiveCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var messages []*pubsub.Message
+	seen := make(map[string]bool)
+	err := client.Subscriber(subID).Receive(receiveCtx, func(_ context.Context, msg *pubsub.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case seen[msg.ID]:
+			msg.Ack()
+		case len(messages) >= n && n > 0:
+			msg.Nack()
+		default:
+			seen[msg.ID] = true
+			messages = append(messages, msg)
+			msg.Ack()
+			if len(messages) == n {
+				cancel()
+			}
+		}
+	})
+	require.NoError(t, err, "Failed to receive messages from %s", subID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n == 0 {
+		require.Empty(t, messages, "Expected no messages on %s", subID)
+	}
+	require.GreaterOrEqual(t, len(messages), n, "Received %d of %d messages from %s within %s", len(messages), n, subID, timeout)
+	return messages
+}
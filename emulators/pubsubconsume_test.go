@@ -0,0 +1,109 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newFakePubsubClient returns a client of an in-process fake Pub/Sub server
+// with the topic "events" and its subscription "events-sub".
+func newFakePubsubClient(t *testing.T) *pubsub.Client {
+	t.Helper()
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	_, err := srv.GServer.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/test-project/topics/events"})
+	require.NoError(t, err)
+	_, err = srv.GServer.CreateSubscription(ctx, &pubsubpb.Subscription{
+		Name:  "projects/test-project/subscriptions/events-sub",
+		Topic: "projects/test-project/topics/events",
+	})
+	require.NoError(t, err)
+	client, err := pubsub.NewClient(ctx, "test-project",
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// publishEvents publishes n messages to "events" and waits for them.
+func publishEvents(t *testing.T, client *pubsub.Client, n int) {
+	t.Helper()
+	publisher := client.Publisher("events")
+	t.Cleanup(publisher.Stop)
+	msgs := make([]*pubsub.Message, n)
+	for i := range msgs {
+		msgs[i] = &pubsub.Message{Data: []byte(fmt.Sprintf("event-%d", i))}
+	}
+	PublishAndConfirm(t, context.Background(), publisher, msgs...)
+}
+
+func TestConsumeMessages(t *testing.T) {
+	ctx := context.Background()
+	client := newFakePubsubClient(t)
+	publishEvents(t, client, 5)
+
+	messages := ConsumeMessages(t, ctx, client, "events-sub", 3, 5*time.Second)
+	require.Len(t, messages, 3)
+	// The rest were nacked or not yet delivered, so a later call gets them.
+	rest := ConsumeMessages(t, ctx, client, "events-sub", 2, 5*time.Second)
+	var got []string
+	for _, msg := range append(messages, rest...) {
+		got = append(got, string(msg.Data))
+	}
+	require.ElementsMatch(t, []string{"event-0", "event-1", "event-2", "event-3", "event-4"}, got)
+
+	ConsumeMessages(t, ctx, client, "events-sub", 0, 200*time.Millisecond)
+}
+
+func TestConsumeMessages_TimesOut(t *testing.T) {
+	client := newFakePubsubClient(t)
+	publishEvents(t, client, 1)
+
+	recorder := &failRecorder{TB: t}
+	start := time.Now()
+	done := make(chan struct{})
+	// FailNow ends the goroutine, as it would a test.
+	go func() {
+		defer close(done)
+		ConsumeMessages(recorder, context.Background(), client, "events-sub", 2, 300*time.Millisecond)
+	}()
+	<-done
+	require.True(t, recorder.failed, "receiving too few messages fails the test")
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+// failRecorder is a testing.TB that records a failure instead of failing
+// the test.
+type failRecorder struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (r *failRecorder) Helper() {}
+
+func (r *failRecorder) Errorf(string, ...any) { r.failed = true }
+
+func (r *failRecorder) Fatalf(format string, args ...any) {
+	r.message = fmt.Sprintf(format, args...)
+	r.FailNow()
+}
+
+func (r *failRecorder) FailNow() {
+	r.failed = true
+	runtime.Goexit()
+}
@@ -0,0 +1,188 @@
+package emulators
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+)
+
+// PubsubLiteConfig holds configuration for the Pub/Sub Lite shim.
+//
+// There is no official Pub/Sub Lite emulator. Instead, the shim runs the
+// standard Pub/Sub emulator and models each Lite partition as its own
+// topic and subscription pair, so code that relies on Lite's partition
+// semantics (key-based routing, per-partition ordering) can be tested locally.
+type PubsubLiteConfig struct {
+	PubsubConfig
+	// Partitions is the number of partitions each Lite topic is created with.
+	Partitions int
+}
+
+// GetDefaultPubsubLiteConfig provides a default configuration for the Pub/Sub Lite shim.
+func GetDefaultPubsubLiteConfig(projectID string, partitions int) PubsubLiteConfig {
+	return PubsubLiteConfig{
+		PubsubConfig: GetDefaultPubsubConfig(projectID),
+		Partitions:   partitions,
+	}
+}
+
+// SetupPubsubLiteEmulator starts the Pub/Sub emulator that backs the Lite shim.
+// It automatically handles container startup and teardown via t.Cleanup.
+// Use CreateLiteTopic with a client built from the returned connection info
+// to create partitioned topics.
+func SetupPubsubLiteEmulator(t testing.TB, ctx context.Context, cfg PubsubLiteConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
+	require.Greater(t, cfg.Partitions, 0, "Pub/Sub Lite shim requires at least one partition")
+	return SetupPubsubEmulator(t, ctx, cfg.PubsubConfig, opts...)
+}
+
+// LiteTopic emulates a partitioned Pub/Sub Lite topic. Each partition is
+// backed by a standard topic named "<topicID>-partition-<n>".
+type LiteTopic struct {
+	client     *pubsub.Client
+	topicID    string
+	partitions int
+	publishers []*pubsub.Publisher
+	next       atomic.Uint64
+}
+
+// CreateLiteTopic creates one backing topic per partition and registers
+// t.Cleanup hooks to stop the publishers and delete the topics.
+func CreateLiteTopic(t testing.TB, ctx context.Context, client *pubsub.Client, topicID string, partitions int) *LiteTopic {
+	t.Helper()
+	require.Greater(t, partitions, 0, "Lite topic requires at least one partition")
+
+	lt := &LiteTopic{
+		client:     client,
+		topicID:    topicID,
+		partitions: partitions,
+		publishers: make([]*pubsub.Publisher, partitions),
+	}
+	for p := 0; p < partitions; p++ {
+		topicName := fmt.Sprintf("projects/%s/topics/%s", client.Project(), lt.PartitionTopicID(p))
+		_, err := client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: topicName})
+		require.NoError(t, err, "Failed to create topic for partition %d", p)
+		t.Cleanup(func() {
+			_ = client.TopicAdminClient.DeleteTopic(context.Background(), &pubsubpb.DeleteTopicRequest{Topic: topicName})
+		})
+
+		publisher := client.Publisher(topicName)
+		// Lite guarantees ordering within a partition, so keyed messages are
+		// published with ordering enabled.
+		publisher.EnableMessageOrdering = true
+		lt.publishers[p] = publisher
+		t.Cleanup(publisher.Stop)
+	}
+	return lt
+}
+
+// Partitions returns the number of partitions in the topic.
+func (lt *LiteTopic) Partitions() int {
+	return lt.partitions
+}
+
+// PartitionTopicID returns the ID of the standard topic backing partition p.
+func (lt *LiteTopic) PartitionTopicID(p int) string {
+	return fmt.Sprintf("%s-partition-%d", lt.topicID, p)
+}
+
+// PartitionForKey returns the partition a keyed message is routed to.
+// It mirrors Pub/Sub Lite's default routing: the SHA-256 hash of the key,
+// read as a big-endian integer, modulo the number of partitions.
+func (lt *LiteTopic) PartitionForKey(key []byte) int {
+	return partitionForKey(key, lt.partitions)
+}
+
+func partitionForKey(key []byte, partitions int) int {
+	sum := sha256.Sum256(key)
+	n := new(big.Int).SetBytes(sum[:])
+	return int(n.Mod(n, big.NewInt(int64(partitions))).Int64())
+}
+
+// Publish routes a message to a partition and publishes it, returning the
+// partition and the server-assigned message ID. Messages with a key are
+// routed by PartitionForKey; messages without a key are distributed
+// round-robin, as Pub/Sub Lite does.
+func (lt *LiteTopic) Publish(ctx context.Context, key, data []byte, attributes map[string]string) (int, string, error) {
+	var partition int
+	if len(key) > 0 {
+		partition = lt.PartitionForKey(key)
+	} else {
+		partition = int((lt.next.Add(1) - 1) % uint64(lt.partitions))
+	}
+
+	res := lt.publishers[partition].Publish(ctx, &pubsub.Message{
+		Data:        data,
+		Attributes:  attributes,
+		OrderingKey: string(key),
+	})
+	id, err := res.Get(ctx)
+	if err != nil {
+		return partition, "", fmt.Errorf("failed to publish to partition %d of %s: %w", partition, lt.topicID, err)
+	}
+	return partition, id, nil
+}
+
+// LiteSubscription emulates a Pub/Sub Lite subscription, which receives
+// messages from every partition of its topic.
+type LiteSubscription struct {
+	client *pubsub.Client
+	subIDs []string
+}
+
+// CreateLiteSubscription creates one ordered subscription per partition of the
+// topic and registers t.Cleanup hooks to delete them.
+func CreateLiteSubscription(t testing.TB, ctx context.Context, lt *LiteTopic, subID string) *LiteSubscription {
+	t.Helper()
+	ls := &LiteSubscription{
+		client: lt.client,
+		subIDs: make([]string, lt.partitions),
+	}
+	for p := 0; p < lt.partitions; p++ {
+		ls.subIDs[p] = fmt.Sprintf("%s-partition-%d", subID, p)
+		subName := fmt.Sprintf("projects/%s/subscriptions/%s", lt.client.Project(), ls.subIDs[p])
+		_, err := lt.client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
+			Name:                  subName,
+			Topic:                 fmt.Sprintf("projects/%s/topics/%s", lt.client.Project(), lt.PartitionTopicID(p)),
+			EnableMessageOrdering: true,
+		})
+		require.NoError(t, err, "Failed to create subscription for partition %d", p)
+		t.Cleanup(func() {
+			_ = lt.client.SubscriptionAdminClient.DeleteSubscription(context.Background(), &pubsubpb.DeleteSubscriptionRequest{Subscription: subName})
+		})
+	}
+	return ls
+}
+
+// Receive calls f for messages from every partition until ctx is done.
+// Calls for the same partition are made in publish order for keyed messages.
+// It returns the first non-cancellation error reported by any partition.
+func (ls *LiteSubscription) Receive(ctx context.Context, f func(ctx context.Context, partition int, msg *pubsub.Message)) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(ls.subIDs))
+	for p, subID := range ls.subIDs {
+		wg.Add(1)
+		go func(p int, subID string) {
+			defer wg.Done()
+			errs[p] = ls.client.Subscriber(subID).Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+				f(ctx, p, msg)
+			})
+		}(p, subID)
+	}
+	wg.Wait()
+
+	for p, err := range errs {
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("receive on partition %d failed: %w", p, err)
+		}
+	}
+	return nil
+}
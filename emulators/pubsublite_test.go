@@ -0,0 +1,97 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupPubsubLiteEmulator(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-pubsublite"
+	cfg := GetDefaultPubsubLiteConfig(projectID, 3)
+	connInfo := SetupPubsubLiteEmulator(t, context.Background(), cfg)
+
+	client, err := pubsub.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	topic := CreateLiteTopic(t, ctx, client, "lite-topic", cfg.Partitions)
+	sub := CreateLiteSubscription(t, ctx, topic, "lite-sub")
+
+	key := []byte("device-42")
+	wantPartition := topic.PartitionForKey(key)
+
+	var mu sync.Mutex
+	var received []string
+	var partitions []int
+	receiveCtx, cancelReceive := context.WithCancel(ctx)
+	t.Cleanup(cancelReceive)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = sub.Receive(receiveCtx, func(_ context.Context, partition int, msg *pubsub.Message) {
+			msg.Ack()
+			mu.Lock()
+			defer mu.Unlock()
+			partitions = append(partitions, partition)
+			received = append(received, string(msg.Data))
+			if len(received) == 3 {
+				cancelReceive()
+			}
+		})
+	}()
+
+	for i := 0; i < 3; i++ {
+		partition, _, err := topic.Publish(ctx, key, []byte(fmt.Sprintf("msg-%d", i)), nil)
+		require.NoError(t, err)
+		require.Equal(t, wantPartition, partition)
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for Lite messages: %v", ctx.Err())
+	}
+	require.Equal(t, []string{"msg-0", "msg-1", "msg-2"}, received, "messages within a partition should arrive in order")
+	require.Equal(t, []int{wantPartition, wantPartition, wantPartition}, partitions, "keyed messages arrived on the wrong partition")
+}
+
+func TestPartitionForKey(t *testing.T) {
+	// Routing must be deterministic and stay within range.
+	for _, key := range []string{"a", "device-1", "device-2", "a-much-longer-key-value"} {
+		p := partitionForKey([]byte(key), 4)
+		require.GreaterOrEqual(t, p, 0)
+		require.Less(t, p, 4)
+		require.Equal(t, p, partitionForKey([]byte(key), 4), "routing for %q should be stable", key)
+	}
+	require.Equal(t, 0, partitionForKey([]byte("anything"), 1))
+}
+
+func TestGetDefaultPubsubLiteConfig(t *testing.T) {
+	cfg := GetDefaultPubsubLiteConfig("lite-proj", 5)
+
+	if cfg.EmulatorImage != testEmulatorImage {
+		t.Errorf("Expected image %q, got %q", testEmulatorImage, cfg.EmulatorImage)
+	}
+	if cfg.EmulatorPort != testPubsubEmulatorPort {
+		t.Errorf("Expected port %q, got %q", testPubsubEmulatorPort, cfg.EmulatorPort)
+	}
+	if cfg.ProjectID != "lite-proj" {
+		t.Errorf("Expected project ID %q, got %q", "lite-proj", cfg.ProjectID)
+	}
+	if cfg.Partitions != 5 {
+		t.Errorf("Expected 5 partitions, got %d", cfg.Partitions)
+	}
+}
@@ -0,0 +1,91 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// ReceiveOrdered receives messages from subscriber until every message in
+// want (a map of ordering keys to message data in publish order) has
+// arrived, and fails the test unless each key's messages arrived in that
+// order. Every message is acked with Message.AckWithResult and the test fails
+// if an ack is rejected, which catches exactly-once delivery errors.
+// Redeliveries of a message that was already received are ignored, since the
+// emulator may redeliver ordered messages after a slow ack. It returns the
+// data received per key.
+//
+// The subscription must have message ordering enabled, e.g. with
+// ResourceSpec.Ordered. Bound the wait with ctx.
+func ReceiveOrdered(t testing.TB, ctx context.Context, subscriber *pubsub.Subscriber, want map[string][]string) map[string][]string {
+	t.Helper()
+
+	rec := newOrderedRecorder(want)
+	receiveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var ackErrs []error
+	var mu sync.Mutex
+	err := subscriber.Receive(receiveCtx, func(ctx context.Context, msg *pubsub.Message) {
+		if _, err := msg.AckWithResult().Get(ctx); err != nil {
+			mu.Lock()
+			ackErrs = append(ackErrs, fmt.Errorf("ack of message %s failed: %w", msg.ID, err))
+			mu.Unlock()
+			return
+		}
+		if rec.record(msg.OrderingKey, msg.ID, string(msg.Data)) {
+			cancel()
+		}
+	})
+	require.NoError(t, err, "Failed to receive messages")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Empty(t, ackErrs, "Acks were rejected")
+	got := rec.received()
+	require.Equal(t, want, got, "Messages did not arrive in order per ordering key")
+	return got
+}
+
+// orderedRecorder collects the data of received messages per ordering key.
+type orderedRecorder struct {
+	mu    sync.Mutex
+	want  int
+	seen  map[string]bool
+	byKey map[string][]string
+}
+
+func newOrderedRecorder(want map[string][]string) *orderedRecorder {
+	n := 0
+	for _, data := range want {
+		n += len(data)
+	}
+	return &orderedRecorder{want: n, seen: make(map[string]bool), byKey: make(map[string][]string)}
+}
+
+// record records a message unless its ID was seen before, and reports whether
+// all wanted messages have arrived.
+func (r *orderedRecorder) record(key, id, data string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.seen[id] {
+		r.seen[id] = true
+		r.byKey[key] = append(r.byKey[key], data)
+	}
+	return len(r.seen) >= r.want
+}
+
+// received returns a copy of the data received per key.
+func (r *orderedRecorder) received() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	got := make(map[string][]string, len(r.byKey))
+	for key, data := range r.byKey {
+		got[key] = append([]string(nil), data...)
+	}
+	return got
+}
@@ -0,0 +1,58 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedRecorder(t *testing.T) {
+	rec := newOrderedRecorder(map[string][]string{"a": {"1", "2"}, "b": {"1"}})
+	require.False(t, rec.record("a", "m1", "1"))
+	require.False(t, rec.record("b", "m2", "1"))
+	require.False(t, rec.record("a", "m1", "1"), "redeliveries are not counted")
+	require.True(t, rec.record("a", "m3", "2"))
+	require.Equal(t, map[string][]string{"a": {"1", "2"}, "b": {"1"}}, rec.received())
+}
+
+func TestReceiveOrderedIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-ordering"
+	connInfo := SetupPubsubEmulator(t, context.Background(), GetDefaultPubsubConfig(projectID))
+	client, err := pubsub.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	CreatePubsubResources(t, ctx, client, ResourceSpec{
+		Subscriptions: map[string]string{"orders-sub": "orders"},
+		Ordered:       []string{"orders-sub"},
+		ExactlyOnce:   []string{"orders-sub"},
+	})
+	sub, err := client.SubscriptionAdminClient.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{Subscription: fmt.Sprintf("projects/%s/subscriptions/orders-sub", projectID)})
+	require.NoError(t, err)
+	require.True(t, sub.GetEnableMessageOrdering())
+	require.True(t, sub.GetEnableExactlyOnceDelivery())
+
+	publisher := client.Publisher("orders")
+	publisher.EnableMessageOrdering = true
+	t.Cleanup(publisher.Stop)
+	want := map[string][]string{}
+	var msgs []*pubsub.Message
+	for _, key := range []string{"device-1", "device-2"} {
+		for i := range 5 {
+			data := fmt.Sprintf("%s-%d", key, i)
+			msgs = append(msgs, &pubsub.Message{Data: []byte(data), OrderingKey: key})
+			want[key] = append(want[key], data)
+		}
+	}
+	PublishAndConfirm(t, ctx, publisher, msgs...)
+
+	ReceiveOrdered(t, ctx, client.Subscriber("orders-sub"), want)
+}
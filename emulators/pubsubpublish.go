@@ -0,0 +1,39 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// PublishAndConfirm publishes msgs with publisher as one batch, waits until
+// the server has accepted every one, and returns their server IDs in the
+// order of msgs. If any publish fails, it fails the test, listing each
+// failed message with its index, ordering key, size and error. A failed
+// ordered publish pauses its ordering key, so the messages after it with the
+// same key fail too until Publisher.ResumePublish is called.
+func PublishAndConfirm(t testing.TB, ctx context.Context, publisher *pubsub.Publisher, msgs ...*pubsub.Message) []string {
+	t.Helper()
+
+	results := make([]*pubsub.PublishResult, len(msgs))
+	for i, msg := range msgs {
+		results[i] = publisher.Publish(ctx, msg)
+	}
+	ids := make([]string, len(msgs))
+	var failures []string
+	for i, result := range results {
+		id, err := result.Get(ctx)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("message %d (ordering key %q, %d bytes): %v", i, msgs[i].OrderingKey, len(msgs[i].Data), err))
+			continue
+		}
+		ids[i] = id
+	}
+	if len(failures) > 0 {
+		t.Fatalf("Failed to publish %d of %d messages to %s:\n%s", len(failures), len(msgs), publisher.ID(), strings.Join(failures, "\n"))
+	}
+	return ids
+}
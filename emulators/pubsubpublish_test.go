@@ -0,0 +1,35 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishAndConfirm(t *testing.T) {
+	ctx := context.Background()
+	client := newFakePubsubClient(t)
+	publisher := client.Publisher("events")
+	t.Cleanup(publisher.Stop)
+
+	ids := PublishAndConfirm(t, ctx, publisher, &pubsub.Message{Data: []byte("a")}, &pubsub.Message{Data: []byte("b")})
+	require.Len(t, ids, 2)
+	require.NotEmpty(t, ids[0])
+	require.NotEqual(t, ids[0], ids[1])
+
+	// Publishing to a missing topic fails with the detail of each message.
+	missing := client.Publisher("missing")
+	t.Cleanup(missing.Stop)
+	recorder := &failRecorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		PublishAndConfirm(recorder, ctx, missing, &pubsub.Message{Data: []byte("abc")})
+	}()
+	<-done
+	require.True(t, recorder.failed)
+	require.Contains(t, recorder.message, "Failed to publish 1 of 1 messages to missing")
+	require.Contains(t, recorder.message, `message 0 (ordering key "", 3 bytes): rpc error: code = NotFound`)
+}
@@ -0,0 +1,124 @@
+package emulators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// pushEndpointBuffer is the number of push deliveries buffered before the
+// endpoint starts rejecting them (which makes the emulator retry).
+const pushEndpointBuffer = 100
+
+// PushMessage is a single push delivery received from the Pub/Sub emulator.
+type PushMessage struct {
+	// Subscription is the full name of the subscription that pushed the message.
+	Subscription string
+	// MessageID is the server-assigned message ID.
+	MessageID string
+	// Data is the decoded message payload.
+	Data []byte
+	// Attributes are the message attributes.
+	Attributes map[string]string
+	// PublishTime is when the message was published.
+	PublishTime time.Time
+}
+
+// pushEnvelope is the JSON body of a Pub/Sub push request.
+type pushEnvelope struct {
+	Message struct {
+		Data        []byte            `json:"data"`
+		Attributes  map[string]string `json:"attributes"`
+		MessageID   string            `json:"messageId"`
+		PublishTime time.Time         `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// PushEndpoint is a local HTTP endpoint that receives push deliveries from
+// the Pub/Sub emulator container.
+type PushEndpoint struct {
+	// Port is the host port the endpoint listens on. Pass it to
+	// PubsubConfig.HostAccessPorts so the emulator container can reach it.
+	Port int
+	// Messages receives every successfully decoded push delivery.
+	Messages <-chan PushMessage
+}
+
+// StartPushEndpoint starts a local HTTP endpoint for push subscriptions.
+// It must be started before the emulator, and its Port added to
+// PubsubConfig.HostAccessPorts, so the container is given a route back to the host.
+// The server is closed automatically via t.Cleanup.
+func StartPushEndpoint(t testing.TB) *PushEndpoint {
+	t.Helper()
+
+	// The testcontainers host port forwarder reaches the endpoint over
+	// loopback, so it need not be exposed on the host's other interfaces.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	messages := make(chan PushMessage, pushEndpointBuffer)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var envelope pushEnvelope
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg := PushMessage{
+			Subscription: envelope.Subscription,
+			MessageID:    envelope.Message.MessageID,
+			Data:         envelope.Message.Data,
+			Attributes:   envelope.Message.Attributes,
+			PublishTime:  envelope.Message.PublishTime,
+		}
+		select {
+		case messages <- msg:
+			// A 2xx status acknowledges the message.
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			// Any other status is treated as a nack and the emulator will retry.
+			http.Error(w, "push endpoint buffer full", http.StatusServiceUnavailable)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return &PushEndpoint{
+		Port:     listener.Addr().(*net.TCPAddr).Port,
+		Messages: messages,
+	}
+}
+
+// URL returns the push endpoint address as seen from inside the emulator container.
+func (e *PushEndpoint) URL() string {
+	return fmt.Sprintf("http://%s:%d/push", testcontainers.HostInternal, e.Port)
+}
+
+// CreatePushSubscription creates a subscription on topicID that pushes to the
+// given endpoint, and registers a t.Cleanup hook to delete it.
+// The topic must already exist.
+func CreatePushSubscription(t testing.TB, ctx context.Context, client *pubsub.Client, topicID, subID string, endpoint *PushEndpoint) {
+	t.Helper()
+
+	subName := fmt.Sprintf("projects/%s/subscriptions/%s", client.Project(), subID)
+	_, err := client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{
+		Name:       subName,
+		Topic:      fmt.Sprintf("projects/%s/topics/%s", client.Project(), topicID),
+		PushConfig: &pubsubpb.PushConfig{PushEndpoint: endpoint.URL()},
+	})
+	require.NoError(t, err, "Failed to create push subscription %s", subID)
+	t.Cleanup(func() {
+		_ = client.SubscriptionAdminClient.DeleteSubscription(context.Background(), &pubsubpb.DeleteSubscriptionRequest{Subscription: subName})
+	})
+}
@@ -0,0 +1,74 @@
+package emulators
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupPubsubEmulatorWithPushSubscription(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-push"
+	endpoint := StartPushEndpoint(t)
+
+	cfg := GetDefaultPubsubConfig(projectID)
+	cfg.HostAccessPorts = []int{endpoint.Port}
+	connInfo := SetupPubsubEmulator(t, context.Background(), cfg)
+
+	client, err := pubsub.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	topicID := "push-topic"
+	_, err = client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: fmt.Sprintf("projects/%s/topics/%s", projectID, topicID)})
+	require.NoError(t, err)
+	CreatePushSubscription(t, ctx, client, topicID, "push-sub", endpoint)
+
+	publisher := client.Publisher(topicID)
+	t.Cleanup(publisher.Stop)
+	PublishAndConfirm(t, ctx, publisher, &pubsub.Message{Data: []byte("pushed"), Attributes: map[string]string{"k": "v"}})
+
+	select {
+	case msg := <-endpoint.Messages:
+		require.Equal(t, "pushed", string(msg.Data))
+		require.Equal(t, "v", msg.Attributes["k"])
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for push delivery: %v", ctx.Err())
+	}
+}
+
+func TestPushEndpointDecodesEnvelope(t *testing.T) {
+	endpoint := StartPushEndpoint(t)
+
+	body := fmt.Sprintf(`{"message":{"data":%q,"attributes":{"a":"b"},"messageId":"42","publishTime":"2024-01-02T03:04:05Z"},"subscription":"projects/p/subscriptions/s"}`,
+		base64.StdEncoding.EncodeToString([]byte("hello")))
+	resp, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/push", endpoint.Port), "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	msg := <-endpoint.Messages
+	require.Equal(t, "hello", string(msg.Data))
+	require.Equal(t, "42", msg.MessageID)
+	require.Equal(t, "b", msg.Attributes["a"])
+	require.Equal(t, "projects/p/subscriptions/s", msg.Subscription)
+
+	resp, err = http.Post(fmt.Sprintf("http://127.0.0.1:%d/push", endpoint.Port), "application/json", bytes.NewBufferString("not json"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,114 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+)
+
+// DeadLetterSpec configures dead lettering for a subscription.
+type DeadLetterSpec struct {
+	// TopicID is the dead-letter topic. It is created if it is not listed in
+	// ResourceSpec.Topics.
+	TopicID string
+	// MaxDeliveryAttempts is the number of delivery attempts before a message
+	// is forwarded to the dead-letter topic (5-100; 0 uses the server default).
+	MaxDeliveryAttempts int32
+}
+
+// ResourceSpec describes the Pub/Sub topics and subscriptions a test needs.
+type ResourceSpec struct {
+	// Topics holds the IDs of the topics to create.
+	Topics []string
+	// Subscriptions holds a map of subscription IDs to the topic ID they attach to.
+	// Topics referenced here are created if they are not listed in Topics.
+	Subscriptions map[string]string
+	// DeadLetter holds a map of subscription IDs to their dead-letter policy.
+	DeadLetter map[string]DeadLetterSpec
+	// Filters holds a map of subscription IDs to their message filter expression.
+	Filters map[string]string
+	// Ordered holds the IDs of subscriptions with message ordering enabled.
+	// Publishers must also enable ordering (Publisher.EnableMessageOrdering).
+	Ordered []string
+	// ExactlyOnce holds the IDs of subscriptions with exactly-once delivery
+	// enabled. The emulator accepts the setting but does not guarantee
+	// exactly-once semantics, so acks should still be checked with
+	// Message.AckWithResult (as ReceiveOrdered does).
+	ExactlyOnce []string
+}
+
+// CreatePubsubResources creates the topics and subscriptions described by spec
+// and registers t.Cleanup hooks to delete them. Subscriptions are deleted
+// before the topics they are attached to.
+func CreatePubsubResources(t testing.TB, ctx context.Context, client *pubsub.Client, spec ResourceSpec) {
+	t.Helper()
+	projectID := client.Project()
+
+	for subID := range spec.DeadLetter {
+		_, ok := spec.Subscriptions[subID]
+		require.True(t, ok, "Dead-letter policy given for unknown subscription %s", subID)
+	}
+	for subID := range spec.Filters {
+		_, ok := spec.Subscriptions[subID]
+		require.True(t, ok, "Filter given for unknown subscription %s", subID)
+	}
+	for _, subID := range spec.Ordered {
+		_, ok := spec.Subscriptions[subID]
+		require.True(t, ok, "Ordering given for unknown subscription %s", subID)
+	}
+	for _, subID := range spec.ExactlyOnce {
+		_, ok := spec.Subscriptions[subID]
+		require.True(t, ok, "Exactly-once delivery given for unknown subscription %s", subID)
+	}
+
+	created := make(map[string]bool)
+	createTopic := func(topicID string) {
+		if created[topicID] {
+			return
+		}
+		topicName := fmt.Sprintf("projects/%s/topics/%s", projectID, topicID)
+		_, err := client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: topicName})
+		require.NoError(t, err, "Failed to create topic %s", topicID)
+		t.Cleanup(func() {
+			_ = client.TopicAdminClient.DeleteTopic(context.Background(), &pubsubpb.DeleteTopicRequest{Topic: topicName})
+		})
+		created[topicID] = true
+	}
+
+	for _, topicID := range spec.Topics {
+		createTopic(topicID)
+	}
+	for _, topicID := range spec.Subscriptions {
+		createTopic(topicID)
+	}
+	for _, dl := range spec.DeadLetter {
+		createTopic(dl.TopicID)
+	}
+
+	for subID, topicID := range spec.Subscriptions {
+		sub := &pubsubpb.Subscription{
+			Name:   fmt.Sprintf("projects/%s/subscriptions/%s", projectID, subID),
+			Topic:  fmt.Sprintf("projects/%s/topics/%s", projectID, topicID),
+			Filter: spec.Filters[subID],
+
+			EnableMessageOrdering:     slices.Contains(spec.Ordered, subID),
+			EnableExactlyOnceDelivery: slices.Contains(spec.ExactlyOnce, subID),
+		}
+		if dl, ok := spec.DeadLetter[subID]; ok {
+			sub.DeadLetterPolicy = &pubsubpb.DeadLetterPolicy{
+				DeadLetterTopic:     fmt.Sprintf("projects/%s/topics/%s", projectID, dl.TopicID),
+				MaxDeliveryAttempts: dl.MaxDeliveryAttempts,
+			}
+		}
+		_, err := client.SubscriptionAdminClient.CreateSubscription(ctx, sub)
+		require.NoError(t, err, "Failed to create subscription %s", subID)
+		t.Cleanup(func() {
+			_ = client.SubscriptionAdminClient.DeleteSubscription(context.Background(), &pubsubpb.DeleteSubscriptionRequest{Subscription: sub.Name})
+		})
+	}
+}
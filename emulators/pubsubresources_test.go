@@ -0,0 +1,49 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupPubsubEmulatorWithResources(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	projectID := "test-project-resources"
+	connInfo := SetupPubsubEmulator(t, context.Background(), GetDefaultPubsubConfig(projectID))
+
+	client, err := pubsub.NewClient(ctx, projectID, connInfo.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	CreatePubsubResources(t, ctx, client, ResourceSpec{
+		Topics:        []string{"standalone-topic"},
+		Subscriptions: map[string]string{"orders-sub": "orders", "filtered-sub": "orders"},
+		DeadLetter:    map[string]DeadLetterSpec{"orders-sub": {TopicID: "orders-dlq", MaxDeliveryAttempts: 5}},
+		Filters:       map[string]string{"filtered-sub": `attributes.kind = "priority"`},
+	})
+
+	for _, topicID := range []string{"standalone-topic", "orders", "orders-dlq"} {
+		_, err := client.TopicAdminClient.GetTopic(ctx, &pubsubpb.GetTopicRequest{Topic: fmt.Sprintf("projects/%s/topics/%s", projectID, topicID)})
+		require.NoError(t, err, "topic %s should exist", topicID)
+	}
+
+	sub, err := client.SubscriptionAdminClient.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{Subscription: fmt.Sprintf("projects/%s/subscriptions/orders-sub", projectID)})
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("projects/%s/topics/orders-dlq", projectID), sub.GetDeadLetterPolicy().GetDeadLetterTopic())
+	require.EqualValues(t, 5, sub.GetDeadLetterPolicy().GetMaxDeliveryAttempts())
+
+	sub, err = client.SubscriptionAdminClient.GetSubscription(ctx, &pubsubpb.GetSubscriptionRequest{Subscription: fmt.Sprintf("projects/%s/subscriptions/filtered-sub", projectID)})
+	require.NoError(t, err)
+	require.Equal(t, `attributes.kind = "priority"`, sub.GetFilter())
+}
@@ -0,0 +1,85 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// readinessProbeTimeout bounds how long a ReadinessProbe is retried.
+	readinessProbeTimeout = 60 * time.Second
+	// readinessProbeMaxBackoff caps the wait between ReadinessProbe attempts.
+	readinessProbeMaxBackoff = 2 * time.Second
+)
+
+// ReadinessProbe performs a real operation against a started emulator and
+// returns an error until the emulator serves it. See GCImageContainer.ReadinessProbe.
+type ReadinessProbe func(ctx context.Context, connInfo EmulatorConnectionInfo) error
+
+// runReadinessProbe retries probe with exponential backoff until it succeeds,
+// ctx is done or readinessProbeTimeout passes. A nil probe always succeeds.
+func runReadinessProbe(ctx context.Context, probe ReadinessProbe, connInfo EmulatorConnectionInfo) error {
+	if probe == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, readinessProbeTimeout)
+	defer cancel()
+
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := probe(ctx, connInfo)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("readiness probe did not succeed after %d attempts: %w", attempt, errors.Join(ctx.Err(), err))
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, readinessProbeMaxBackoff)
+	}
+}
+
+// PubsubListTopicsProbe returns a ReadinessProbe that lists the topics of
+// projectID on a Pub/Sub emulator.
+func PubsubListTopicsProbe(projectID string) ReadinessProbe {
+	return func(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+		client, err := pubsub.NewClient(ctx, projectID, connInfo.ClientOptions...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = client.Close() }()
+
+		it := client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: "projects/" + projectID})
+		if _, err := it.Next(); err != nil && !errors.Is(err, iterator.Done) {
+			return err
+		}
+		return nil
+	}
+}
+
+// FirestoreWriteProbe returns a ReadinessProbe that writes and deletes a
+// document in projectID on a Firestore emulator.
+func FirestoreWriteProbe(projectID string) ReadinessProbe {
+	return func(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+		client, err := firestore.NewClient(ctx, projectID, connInfo.ClientOptions...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = client.Close() }()
+
+		doc := client.Collection("emulators-readiness").Doc("probe")
+		if _, err := doc.Set(ctx, map[string]any{"ready": true}); err != nil {
+			return err
+		}
+		_, err = doc.Delete(ctx)
+		return err
+	}
+}
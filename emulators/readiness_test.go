@@ -0,0 +1,39 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReadinessProbe(t *testing.T) {
+	ctx := context.Background()
+	connInfo := EmulatorConnectionInfo{Service: ServicePubsub}
+
+	require.NoError(t, runReadinessProbe(ctx, nil, connInfo))
+
+	attempts := 0
+	err := runReadinessProbe(ctx, func(ctx context.Context, got EmulatorConnectionInfo) error {
+		require.Equal(t, connInfo, got)
+		attempts++
+		if attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, connInfo)
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	// The probe gives up when the context ends, reporting the last failure.
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	t.Cleanup(cancel)
+	errNotServing := errors.New("not serving")
+	err = runReadinessProbe(ctx, func(context.Context, EmulatorConnectionInfo) error {
+		return errNotServing
+	}, connInfo)
+	require.ErrorIs(t, err, errNotServing)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -2,7 +2,9 @@ package emulators
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
@@ -17,46 +19,153 @@ const (
 	cloudTestRedisImage = "redis:8.0.2-alpine"
 	// cloudTestRedisPort is the default internal port for Redis.
 	cloudTestRedisPort = "6379/tcp"
+	// cloudTestRedisTLSPort is the internal port of the optional TLS listener.
+	cloudTestRedisTLSPort = "6380/tcp"
+
+	// redisTLSDir is where TLS material is mounted in the container.
+	redisTLSDir = "/tls"
 )
 
+// RedisConfig holds configuration for a Redis container.
+type RedisConfig struct {
+	ImageContainer
+	// RequirePass, when set, enables Redis AUTH with this password. It is
+	// returned as EmulatorConnectionInfo.Password for clients to use.
+	RequirePass string
+	// EnableTLS adds a TLS listener on port 6380 alongside the plain listener.
+	// A throwaway CA and server certificate are generated; the CA is returned
+	// as EmulatorConnectionInfo.CACert and the listener as TLSAddress.
+	EnableTLS bool
+}
+
 // GetDefaultRedisImageContainer returns a default configuration for the Redis container.
-func GetDefaultRedisImageContainer() ImageContainer {
-	return ImageContainer{
-		EmulatorImage: cloudTestRedisImage,
-		EmulatorPort:  cloudTestRedisPort,
+func GetDefaultRedisImageContainer() RedisConfig {
+	return RedisConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: cloudTestRedisImage,
+			EmulatorPort:  cloudTestRedisPort,
+		},
 	}
 }
 
 // SetupRedisContainer starts a Redis container and returns its connection information.
 // It automatically handles container startup and teardown via t.Cleanup.
 // It returns an EmulatorConnectionInfo struct with the EmulatorAddress field populated
-// (e.g., "localhost:54321").
-func SetupRedisContainer(t *testing.T, ctx context.Context, imageContainer ImageContainer) EmulatorConnectionInfo {
+// (e.g., "localhost:54321"), plus Password, TLSAddress and CACert when those options are set.
+func SetupRedisContainer(t testing.TB, ctx context.Context, cfg RedisConfig, opts ...SetupOption) EmulatorConnectionInfo {
 	t.Helper()
-	req := testcontainers.ContainerRequest{
-		Image:        imageContainer.EmulatorImage,
-		ExposedPorts: []string{imageContainer.EmulatorPort},
-		WaitingFor:   wait.ForListeningPort(nat.Port(imageContainer.EmulatorPort)).WithStartupTimeout(60 * time.Second),
-	}
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+
+	connInfo, terminate, err := StartRedisContainer(ctx, cfg, withTestLogs(t, "Redis", opts)...)
 	require.NoError(t, err, "Failed to start Redis container")
 
 	t.Cleanup(func() {
-		if err := container.Terminate(context.Background()); err != nil {
+		if err := terminate(context.Background()); err != nil {
 			t.Logf("Failed to terminate Redis container: %v", err)
 		}
 		t.Log("Redis container terminated.")
 	})
 
-	host, err := container.Host(ctx)
-	require.NoError(t, err)
-	port, err := container.MappedPort(ctx, nat.Port(imageContainer.EmulatorPort))
-	require.NoError(t, err)
+	t.Logf("Redis container started at: %s", connInfo.EmulatorAddress)
+	if connInfo.TLSAddress != "" {
+		t.Logf("Redis TLS listener available at: %s", connInfo.TLSAddress)
+	}
+	return connInfo
+}
 
-	redisAddr := fmt.Sprintf("%s:%s", host, port.Port())
-	t.Logf("Redis container started at: %s", redisAddr)
+// StartRedisContainer starts a Redis container without needing a testing.TB,
+// e.g. for TestMain or a local dev harness. The caller must call the returned
+// TerminateFunc.
+func StartRedisContainer(ctx context.Context, cfg RedisConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	var certs *TLSCertificates
+	if cfg.EnableTLS {
+		// Certificates are generated per start, so a reused container would
+		// present one that clients no longer trust.
+		if cfg.Reuse {
+			return EmulatorConnectionInfo{}, nil, errors.New("Redis TLS listener cannot be used with a reused container")
+		}
+		if certs, err = generateDaemonCertificates(ctx); err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+	}
+	dir, err := os.MkdirTemp("", "redis-")
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, fmt.Errorf("failed to create Redis config directory: %w", err)
+	}
+	req, err := redisContainerRequest(dir, cfg, certs)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return EmulatorConnectionInfo{}, nil, err
+	}
 
-	return EmulatorConnectionInfo{
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, dir, opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	redisAddr, err := mappedAddress(ctx, container, cfg.EmulatorPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	connInfo = EmulatorConnectionInfo{
+		Service:         ServiceRedis,
 		EmulatorAddress: redisAddr,
+		Password:        cfg.RequirePass,
+	}
+	if connInfo.InternalEndpoint, err = internalAddress(ctx, container, cfg.ImageContainer, cfg.EmulatorPort); err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	if certs != nil {
+		if connInfo.TLSAddress, err = mappedAddress(ctx, container, cloudTestRedisTLSPort); err != nil {
+			return EmulatorConnectionInfo{}, nil, err
+		}
+		connInfo.CACert = certs.CACert
+	}
+	return connInfo, terminate, nil
+}
+
+// redisContainerRequest builds the Redis container request, writing any TLS
+// material into dir. certs is nil unless the TLS listener is enabled.
+func redisContainerRequest(dir string, cfg RedisConfig, certs *TLSCertificates) (testcontainers.ContainerRequest, error) {
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: []string{cfg.EmulatorPort},
+		WaitingFor:   wait.ForListeningPort(nat.Port(cfg.EmulatorPort)).WithStartupTimeout(60 * time.Second),
+	}
+	if cfg.RequirePass == "" && certs == nil {
+		return req, nil
+	}
+
+	req.Cmd = []string{"redis-server", "--port", nat.Port(cfg.EmulatorPort).Port()}
+	if cfg.RequirePass != "" {
+		req.Cmd = append(req.Cmd, "--requirepass", cfg.RequirePass)
+	}
+	if certs != nil {
+		for name, content := range map[string][]byte{"ca.crt": certs.CACert, "server.crt": certs.ServerCert, "server.key": certs.ServerKey} {
+			file, err := writeContainerFile(dir, name, redisTLSDir+"/"+name, content)
+			if err != nil {
+				return testcontainers.ContainerRequest{}, err
+			}
+			req.Files = append(req.Files, file)
+		}
+		req.Cmd = append(req.Cmd,
+			"--tls-port", nat.Port(cloudTestRedisTLSPort).Port(),
+			"--tls-cert-file", redisTLSDir+"/server.crt",
+			"--tls-key-file", redisTLSDir+"/server.key",
+			"--tls-ca-cert-file", redisTLSDir+"/ca.crt",
+			// Clients only need to trust the CA, not present certificates.
+			"--tls-auth-clients", "no",
+		)
+		req.ExposedPorts = append(req.ExposedPorts, cloudTestRedisTLSPort)
+		req.WaitingFor = wait.ForAll(
+			wait.ForListeningPort(nat.Port(cfg.EmulatorPort)),
+			wait.ForListeningPort(cloudTestRedisTLSPort),
+		).WithDeadline(60 * time.Second)
 	}
+	return req, nil
 }
@@ -2,6 +2,7 @@ package emulators
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -62,3 +63,80 @@ func TestGetDefaultRedisImageContainer(t *testing.T) {
 		t.Errorf("Expected port %q, got %q", cloudTestRedisPort, cfg.EmulatorPort)
 	}
 }
+
+func TestSetupRedisContainerWithPasswordAndTLS(t *testing.T) {
+	t.Parallel()
+
+	testCtx, testCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(testCancel)
+
+	cfg := GetDefaultRedisImageContainer()
+	cfg.RequirePass = "s3cret"
+	cfg.EnableTLS = true
+	connInfo := SetupRedisContainer(t, context.Background(), cfg)
+	require.Equal(t, "s3cret", connInfo.Password)
+	require.NotEmpty(t, connInfo.TLSAddress, "TLSAddress is empty")
+
+	// Unauthenticated clients are rejected.
+	anonymous := redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress})
+	t.Cleanup(func() { _ = anonymous.Close() })
+	require.Error(t, anonymous.Set(testCtx, "key", "value", 0).Err(), "Unauthenticated command should be refused")
+
+	tlsConfig, err := TLSConfigFromCA(connInfo.CACert)
+	require.NoError(t, err)
+	rdb := redis.NewClient(&redis.Options{
+		Addr:      connInfo.TLSAddress,
+		Password:  connInfo.Password,
+		TLSConfig: tlsConfig,
+	})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	pong, err := rdb.Ping(testCtx).Result()
+	require.NoError(t, err, "Failed to ping Redis over TLS")
+	require.Equal(t, "PONG", pong)
+}
+
+func TestRedisContainerRequest(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		req, err := redisContainerRequest(t.TempDir(), GetDefaultRedisImageContainer(), nil)
+		require.NoError(t, err)
+		require.Empty(t, req.Cmd, "Default container should use the image command")
+		require.Equal(t, []string{cloudTestRedisPort}, req.ExposedPorts)
+	})
+
+	t.Run("password and TLS", func(t *testing.T) {
+		certs, err := generateTestCertificates()
+		require.NoError(t, err)
+		cfg := GetDefaultRedisImageContainer()
+		cfg.RequirePass = "s3cret"
+
+		req, err := redisContainerRequest(t.TempDir(), cfg, &certs)
+		require.NoError(t, err)
+		cmd := strings.Join(req.Cmd, " ")
+		require.Contains(t, cmd, "--requirepass s3cret")
+		require.Contains(t, cmd, "--tls-port 6380")
+		require.Contains(t, cmd, "--tls-cert-file /tls/server.crt")
+		require.Equal(t, []string{cloudTestRedisPort, cloudTestRedisTLSPort}, req.ExposedPorts)
+		require.Len(t, req.Files, 3)
+	})
+}
+
+func TestStartRedisContainer(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	connInfo, terminate, err := StartRedisContainer(ctx, GetDefaultRedisImageContainer())
+	require.NoError(t, err)
+	require.NotEmpty(t, connInfo.EmulatorAddress)
+
+	rdb := redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress})
+	require.NoError(t, rdb.Ping(ctx).Err())
+	require.NoError(t, rdb.Close())
+
+	require.NoError(t, terminate(context.Background()))
+	// The container is gone, so a fresh client can no longer connect.
+	rdb = redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress, MaxRetries: -1})
+	t.Cleanup(func() { _ = rdb.Close() })
+	require.Error(t, rdb.Ping(ctx).Err())
+}
@@ -0,0 +1,118 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// RedisStreamSpec describes the Redis streams and consumer groups a test needs.
+type RedisStreamSpec struct {
+	// Streams holds the keys of the streams to create.
+	Streams []string
+	// Groups holds a map of stream keys to the consumer groups to create on them.
+	// Streams referenced here are created if they are not listed in Streams.
+	Groups map[string][]string
+}
+
+// CreateRedisStreams creates the streams and consumer groups described by spec
+// and registers t.Cleanup hooks that delete the streams (and so their groups).
+// Groups start at the beginning of the stream, so they see every entry.
+func CreateRedisStreams(t testing.TB, ctx context.Context, rdb redis.UniversalClient, spec RedisStreamSpec) {
+	t.Helper()
+
+	keys := append([]string(nil), spec.Streams...)
+	for stream := range spec.Groups {
+		keys = append(keys, stream)
+	}
+	// Create in a stable order so failures are reproducible.
+	sort.Strings(keys)
+
+	created := make(map[string]bool)
+	for _, stream := range keys {
+		if created[stream] {
+			continue
+		}
+		created[stream] = true
+
+		// A stream cannot exist without entries or a group, so a stream with no
+		// groups is created with a placeholder group that is removed again.
+		groups := spec.Groups[stream]
+		if len(groups) == 0 {
+			require.NoError(t, rdb.XGroupCreateMkStream(ctx, stream, "bootstrap", "0").Err(), "Failed to create stream %s", stream)
+			require.NoError(t, rdb.XGroupDestroy(ctx, stream, "bootstrap").Err(), "Failed to create stream %s", stream)
+		}
+		for _, group := range groups {
+			err := rdb.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+			require.NoError(t, err, "Failed to create consumer group %s on stream %s", group, stream)
+		}
+
+		t.Cleanup(func() {
+			_ = rdb.Del(context.Background(), stream).Err()
+		})
+	}
+}
+
+// ConsumeRedisStream reads stream as consumer in group until the test ends,
+// acknowledging each entry and sending it on the returned channel. The group
+// must already exist (see CreateRedisStreams). The reader is stopped via
+// t.Cleanup, after which the channel is closed.
+func ConsumeRedisStream(t testing.TB, ctx context.Context, rdb redis.UniversalClient, stream, group, consumer string) <-chan redis.XMessage {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctx)
+	entries := make(chan redis.XMessage, 100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(entries)
+		for {
+			streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: consumer,
+				Streams:  []string{stream, ">"},
+				Count:    100,
+				Block:    250 * time.Millisecond,
+			}).Result()
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				// Reading a stream deleted by another cleanup hook is expected.
+				if !strings.Contains(err.Error(), "NOGROUP") {
+					t.Logf("Redis stream consumer %s stopped: %v", consumer, err)
+				}
+				return
+			}
+			for _, s := range streams {
+				for _, msg := range s.Messages {
+					if err := rdb.XAck(ctx, stream, group, msg.ID).Err(); err != nil && ctx.Err() == nil {
+						t.Logf("Failed to ack stream entry %s: %v", msg.ID, err)
+					}
+					select {
+					case entries <- msg:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	return entries
+}
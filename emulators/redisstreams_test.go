@@ -0,0 +1,54 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupRedisContainerWithStreams(t *testing.T) {
+	t.Parallel()
+
+	testCtx, testCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(testCancel)
+
+	connInfo := SetupRedisContainer(t, context.Background(), GetDefaultRedisImageContainer())
+	rdb := redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	CreateRedisStreams(t, testCtx, rdb, RedisStreamSpec{
+		Streams: []string{"audit"},
+		Groups:  map[string][]string{"telemetry": {"processor", "archiver"}},
+	})
+
+	// Every stream and group exists before anything is produced.
+	exists, err := rdb.Exists(testCtx, "audit", "telemetry").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), exists)
+	groups, err := rdb.XInfoGroups(testCtx, "telemetry").Result()
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	entries := ConsumeRedisStream(t, testCtx, rdb, "telemetry", "processor", "processor-1")
+	for _, reading := range []string{"21.5", "22.0"} {
+		require.NoError(t, rdb.XAdd(testCtx, &redis.XAddArgs{Stream: "telemetry", Values: map[string]any{"temp": reading}}).Err())
+	}
+
+	for _, want := range []string{"21.5", "22.0"} {
+		select {
+		case msg := <-entries:
+			require.Equal(t, want, msg.Values["temp"])
+		case <-testCtx.Done():
+			t.Fatalf("Timed out waiting for stream entry %s", want)
+		}
+	}
+
+	// Consumed entries are acknowledged.
+	require.Eventually(t, func() bool {
+		pending, err := rdb.XPending(testCtx, "telemetry", "processor").Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,116 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// imageRegistry holds the package-level registry settings.
+var imageRegistry struct {
+	sync.RWMutex
+	prefix string
+	auth   *registry.AuthConfig
+}
+
+// SetImageRegistry makes every emulator pull its image through a private
+// registry or mirror, for CI environments that cannot reach gcr.io, ghcr.io
+// or Docker Hub directly. The registry host of each image is replaced by
+// prefix and the repository path is kept, so with prefix
+// "mirror.example.com/proxy":
+//
+//	redis:8.0.2-alpine                        -> mirror.example.com/proxy/redis:8.0.2-alpine
+//	gcr.io/google.com/cloudsdktool/cloud-sdk  -> mirror.example.com/proxy/google.com/cloudsdktool/cloud-sdk
+//
+// It applies to images set in configs and with WithImage alike. Call it once,
+// e.g. from TestMain; an empty prefix restores the original images.
+func SetImageRegistry(prefix string) {
+	imageRegistry.Lock()
+	defer imageRegistry.Unlock()
+	imageRegistry.prefix = strings.TrimSuffix(prefix, "/")
+}
+
+// SetImageRegistryAuth sets the credentials used to pull images from the
+// registry given to SetImageRegistry. Without it, Docker's usual credential
+// sources (the Docker config file or DOCKER_AUTH_CONFIG) are used.
+func SetImageRegistryAuth(username, password string) {
+	imageRegistry.Lock()
+	defer imageRegistry.Unlock()
+	imageRegistry.auth = &registry.AuthConfig{Username: username, Password: password}
+}
+
+// registryImage returns image rewritten to use the configured registry prefix.
+func registryImage(image string) string {
+	imageRegistry.RLock()
+	prefix := imageRegistry.prefix
+	imageRegistry.RUnlock()
+
+	if prefix == "" || strings.HasPrefix(image, prefix+"/") {
+		return image
+	}
+	// The first path component is a registry host if it looks like one;
+	// otherwise the image is on Docker Hub and has no host to strip.
+	if host, path, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		image = path
+	}
+	return prefix + "/" + image
+}
+
+// pullWithRegistryAuth pulls image with the credentials set by
+// SetImageRegistryAuth, unless none are set or the image is already present.
+// Testcontainers then finds the image locally instead of pulling it itself.
+func pullWithRegistryAuth(ctx context.Context, image string) error {
+	imageRegistry.RLock()
+	auth := imageRegistry.auth
+	imageRegistry.RUnlock()
+	if auth == nil {
+		return nil
+	}
+
+	provider, err := testcontainers.NewDockerProvider()
+	if err != nil {
+		return fmt.Errorf("failed to create docker provider: %w", err)
+	}
+	defer func() { _ = provider.Close() }()
+	return pullImage(ctx, provider, image)
+}
+
+// pullImage pulls image unless it is already present, with the credentials
+// set by SetImageRegistryAuth or else Docker's usual credential sources.
+func pullImage(ctx context.Context, provider *testcontainers.DockerProvider, image string) error {
+	cli := provider.Client()
+	if _, err := cli.ImageInspect(ctx, image); err == nil {
+		return nil
+	}
+
+	imageRegistry.RLock()
+	auth := imageRegistry.auth
+	imageRegistry.RUnlock()
+	if auth == nil {
+		if err := provider.PullImage(ctx, image); err != nil {
+			return fmt.Errorf("failed to pull image %s: %w", image, err)
+		}
+		return nil
+	}
+
+	encoded, err := registry.EncodeAuthConfig(*auth)
+	if err != nil {
+		return fmt.Errorf("failed to encode registry auth: %w", err)
+	}
+	pull, err := cli.ImagePull(ctx, image, dockerimage.PullOptions{RegistryAuth: encoded})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	defer func() { _ = pull.Close() }()
+	// The pull only completes once its progress stream has been read.
+	if _, err := io.Copy(io.Discard, pull); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package emulators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryImage(t *testing.T) {
+	t.Cleanup(func() { SetImageRegistry("") })
+
+	require.Equal(t, cloudTestRedisImage, registryImage(cloudTestRedisImage), "images are unchanged without a registry")
+
+	SetImageRegistry("mirror.example.com/proxy/")
+	tests := map[string]string{
+		cloudTestRedisImage:                "mirror.example.com/proxy/redis:8.0.2-alpine",
+		testGCSImage:                       "mirror.example.com/proxy/fsouza/fake-gcs-server:latest",
+		testEmulatorImage:                  "mirror.example.com/proxy/google.com/cloudsdktool/cloud-sdk:emulators",
+		testBigQueryEmulatorImage:          "mirror.example.com/proxy/goccy/bigquery-emulator:0.6.6",
+		"localhost:5000/redis:7":           "mirror.example.com/proxy/redis:7",
+		"mirror.example.com/proxy/redis:7": "mirror.example.com/proxy/redis:7",
+	}
+	for image, want := range tests {
+		require.Equal(t, want, registryImage(image), image)
+	}
+}
@@ -0,0 +1,185 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/storage"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+// ResetEmulator wipes the state of a running emulator, so tests sharing a
+// long-lived emulator (see RunWithSuite and ImageContainer.Reuse) can start
+// from a clean slate without restarting the container:
+//
+//   - Firestore: every document is deleted through the emulator's reset endpoint.
+//   - Pub/Sub: every subscription and topic of the project is deleted.
+//   - GCS: every object and bucket of the project is deleted.
+//   - BigQuery: every dataset of the project is deleted with its tables.
+//   - Redis: every key is flushed.
+//
+// It fails the test for other services, such as MQTT.
+func ResetEmulator(t testing.TB, ctx context.Context, connInfo EmulatorConnectionInfo) {
+	t.Helper()
+
+	var err error
+	switch connInfo.Service {
+	case ServiceFirestore:
+		err = resetFirestore(ctx, connInfo)
+	case ServicePubsub:
+		err = resetPubsub(ctx, connInfo)
+	case ServiceGCS:
+		err = resetGCS(ctx, connInfo)
+	case ServiceBigQuery:
+		err = resetBigQuery(ctx, connInfo)
+	case ServiceRedis:
+		err = resetRedis(ctx, connInfo)
+	default:
+		err = fmt.Errorf("resetting %q emulators is not supported", connInfo.Service)
+	}
+	require.NoError(t, err, "Failed to reset %s emulator", connInfo.Service)
+}
+
+// resetFirestore deletes all documents of the default database.
+func resetFirestore(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s/databases/(default)/documents", connInfo.HTTPEndpoint.Endpoint, connInfo.ProjectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reset returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// resetPubsub deletes all subscriptions, then all topics, of the project.
+// The emulator has no reset endpoint.
+func resetPubsub(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+	client, err := pubsub.NewClient(ctx, connInfo.ProjectID, connInfo.ClientOptions...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	project := "projects/" + connInfo.ProjectID
+
+	subs := client.SubscriptionAdminClient.ListSubscriptions(ctx, &pubsubpb.ListSubscriptionsRequest{Project: project})
+	for {
+		sub, err := subs.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list subscriptions: %w", err)
+		}
+		if err := client.SubscriptionAdminClient.DeleteSubscription(ctx, &pubsubpb.DeleteSubscriptionRequest{Subscription: sub.Name}); err != nil {
+			return fmt.Errorf("failed to delete subscription %s: %w", sub.Name, err)
+		}
+	}
+
+	topics := client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: project})
+	for {
+		topic, err := topics.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list topics: %w", err)
+		}
+		if err := client.TopicAdminClient.DeleteTopic(ctx, &pubsubpb.DeleteTopicRequest{Topic: topic.Name}); err != nil {
+			return fmt.Errorf("failed to delete topic %s: %w", topic.Name, err)
+		}
+	}
+	return nil
+}
+
+// resetGCS deletes all objects and buckets of the project. fake-gcs-server
+// has no reset endpoint.
+func resetGCS(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+	client, err := newGCSClient(ctx, connInfo)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	buckets := client.Buckets(ctx, connInfo.ProjectID)
+	for {
+		attrs, err := buckets.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list buckets: %w", err)
+		}
+		if err := deleteBucket(ctx, client, attrs.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteBucket deletes every object in a bucket, then the bucket itself.
+func deleteBucket(ctx context.Context, client *storage.Client, name string) error {
+	bucket := client.Bucket(name)
+	objects := bucket.Objects(ctx, nil)
+	for {
+		obj, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list objects in %s: %w", name, err)
+		}
+		if err := bucket.Object(obj.Name).Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete gs://%s/%s: %w", name, obj.Name, err)
+		}
+	}
+	if err := bucket.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete bucket %s: %w", name, err)
+	}
+	return nil
+}
+
+// resetBigQuery deletes all datasets of the project with their tables.
+func resetBigQuery(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+	client, err := bigquery.NewClient(ctx, connInfo.ProjectID, connInfo.ClientOptions...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	datasets := client.Datasets(ctx)
+	for {
+		ds, err := datasets.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list datasets: %w", err)
+		}
+		if err := ds.DeleteWithContents(ctx); err != nil {
+			return fmt.Errorf("failed to delete dataset %s: %w", ds.DatasetID, err)
+		}
+	}
+	return nil
+}
+
+// resetRedis flushes all keys of all databases.
+func resetRedis(ctx context.Context, connInfo EmulatorConnectionInfo) error {
+	rdb := redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress, Password: connInfo.Password})
+	defer func() { _ = rdb.Close() }()
+	return rdb.FlushAll(ctx).Err()
+}
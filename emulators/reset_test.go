@@ -0,0 +1,98 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func TestResetFirestore(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + r.URL.Path
+	}))
+	t.Cleanup(server.Close)
+
+	connInfo := EmulatorConnectionInfo{
+		Service:      ServiceFirestore,
+		ProjectID:    "test-project",
+		HTTPEndpoint: Endpoint{Endpoint: strings.TrimPrefix(server.URL, "http://")},
+	}
+	ResetEmulator(t, context.Background(), connInfo)
+	require.Equal(t, "DELETE /emulator/v1/projects/test-project/databases/(default)/documents", got)
+}
+
+func TestResetGCS(t *testing.T) {
+	// An emulator in http mode: its endpoint has no scheme and no HTTPClient.
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/storage/v1/b":
+			require.Equal(t, "test-project", r.URL.Query().Get("project"))
+			_, _ = w.Write([]byte(`{"kind":"storage#buckets","items":[{"kind":"storage#bucket","name":"uploads"}]}`))
+		case r.URL.Path == "/storage/v1/b/uploads/o":
+			_, _ = w.Write([]byte(`{"kind":"storage#objects","items":[{"kind":"storage#object","bucket":"uploads","name":"a.txt"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	connInfo := EmulatorConnectionInfo{
+		Service:       ServiceGCS,
+		ProjectID:     "test-project",
+		HTTPEndpoint:  Endpoint{Endpoint: strings.TrimPrefix(server.URL, "http://")},
+		ClientOptions: []option.ClientOption{option.WithoutAuthentication()},
+	}
+	ResetEmulator(t, context.Background(), connInfo)
+	require.Equal(t, []string{"/storage/v1/b/uploads/o/a.txt", "/storage/v1/b/uploads"}, deleted)
+}
+
+func TestResetEmulatorIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	t.Cleanup(cancel)
+
+	t.Run("Pub/Sub", func(t *testing.T) {
+		connInfo := SetupPubsubEmulator(t, ctx, GetDefaultPubsubConfig("reset-project"))
+		client, err := pubsub.NewClient(ctx, connInfo.ProjectID, connInfo.ClientOptions...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+		CreatePubsubResources(t, ctx, client, ResourceSpec{Subscriptions: map[string]string{"orders-sub": "orders"}})
+
+		ResetEmulator(t, ctx, connInfo)
+
+		topics := client.TopicAdminClient.ListTopics(ctx, &pubsubpb.ListTopicsRequest{Project: "projects/reset-project"})
+		_, err = topics.Next()
+		require.ErrorIs(t, err, iterator.Done)
+	})
+
+	t.Run("Redis", func(t *testing.T) {
+		connInfo := SetupRedisContainer(t, ctx, GetDefaultRedisImageContainer())
+		rdb := redis.NewClient(&redis.Options{Addr: connInfo.EmulatorAddress})
+		t.Cleanup(func() { _ = rdb.Close() })
+		for i := range 3 {
+			require.NoError(t, rdb.Set(ctx, fmt.Sprintf("key-%d", i), i, 0).Err())
+		}
+
+		ResetEmulator(t, ctx, connInfo)
+
+		size, err := rdb.DBSize(ctx).Result()
+		require.NoError(t, err)
+		require.Zero(t, size)
+	})
+}
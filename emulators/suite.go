@@ -0,0 +1,228 @@
+package emulators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+// SuiteConfig selects the emulators SetupEmulatorSuite starts. A nil field
+// means that emulator is not started.
+type SuiteConfig struct {
+	Pubsub    *PubsubConfig
+	Firestore *FirestoreConfig
+	GCS       *GCSConfig
+	BigQuery  *BigQueryConfig
+	Redis     *RedisConfig
+	MQTT      *MqttConfig
+}
+
+// EmulatorSuite holds the connection info of each emulator started by
+// SetupEmulatorSuite. Fields for emulators that were not requested are zero.
+type EmulatorSuite struct {
+	Pubsub    EmulatorConnectionInfo
+	Firestore EmulatorConnectionInfo
+	GCS       EmulatorConnectionInfo
+	BigQuery  EmulatorConnectionInfo
+	Redis     EmulatorConnectionInfo
+	MQTT      EmulatorConnectionInfo
+}
+
+// SetupEmulatorSuite starts every emulator requested in cfg concurrently and
+// returns their connection infos. Each emulator behaves exactly as if it were
+// started by its own Setup function, including teardown via t.Cleanup.
+//
+// If any emulator fails to start, the test fails after the others have
+// finished starting; all started containers are still terminated.
+func SetupEmulatorSuite(t testing.TB, ctx context.Context, cfg SuiteConfig) EmulatorSuite {
+	t.Helper()
+
+	suite, members, err := startEmulatorSuite(ctx, cfg, t.Logf, t.Failed)
+	for _, member := range members {
+		t.Cleanup(func() {
+			if err := member.terminate(context.Background()); err != nil {
+				t.Logf("Failed to terminate %s emulator container: %v", member.name, err)
+			}
+		})
+	}
+	require.NoError(t, err, "Failed to start emulator suite")
+
+	for _, member := range members {
+		t.Logf("%s emulator started for suite", member.name)
+	}
+	if cfg.GCS != nil {
+		useGCSEmulator(t, ctx, suite.GCS)
+	}
+	if cfg.Pubsub != nil && cfg.Pubsub.SetEnvVariables {
+		suite.Pubsub.SetEnv(t)
+	}
+	if cfg.Firestore != nil && cfg.Firestore.SetEnvVariables {
+		suite.Firestore.SetEnv(t)
+	}
+	if cfg.BigQuery != nil && cfg.BigQuery.SetEnvVariables {
+		suite.BigQuery.SetEnv(t)
+	}
+	return suite
+}
+
+// StartEmulatorSuite starts every emulator requested in cfg concurrently,
+// like SetupEmulatorSuite but without a testing.TB. The returned TerminateFunc
+// terminates all of them. If any emulator fails to start, the ones that did
+// start are terminated before the error is returned.
+func StartEmulatorSuite(ctx context.Context, cfg SuiteConfig) (EmulatorSuite, TerminateFunc, error) {
+	suite, members, err := startEmulatorSuite(ctx, cfg, nil, nil)
+	terminate := func(ctx context.Context) error {
+		var errs []error
+		for _, member := range members {
+			if err := member.terminate(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", member.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	if err != nil {
+		_ = terminate(context.Background())
+		return EmulatorSuite{}, nil, err
+	}
+	return suite, terminate, nil
+}
+
+// suiteMember is an emulator started as part of a suite.
+type suiteMember struct {
+	name      string
+	terminate TerminateFunc
+}
+
+// startEmulatorSuite starts the emulators requested in cfg concurrently.
+// Container logs go to logf, or the package logger when nil; failed reports
+// whether buffered logs should be written on termination. The returned
+// members are every emulator that started, even when err is non-nil, and the
+// caller must terminate them.
+func startEmulatorSuite(ctx context.Context, cfg SuiteConfig, logf func(format string, args ...any), failed func() bool) (EmulatorSuite, []suiteMember, error) {
+	type starter struct {
+		name  string
+		into  *EmulatorConnectionInfo
+		start func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error)
+	}
+	var suite EmulatorSuite
+	var starters []starter
+	if cfg.Pubsub != nil {
+		starters = append(starters, starter{"Pub/Sub", &suite.Pubsub, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartPubsubEmulator(ctx, *cfg.Pubsub, opts...)
+		}})
+	}
+	if cfg.Firestore != nil {
+		starters = append(starters, starter{"Firestore", &suite.Firestore, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartFirestoreEmulator(ctx, *cfg.Firestore, opts...)
+		}})
+	}
+	if cfg.GCS != nil {
+		starters = append(starters, starter{"GCS", &suite.GCS, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartGCSEmulator(ctx, *cfg.GCS, opts...)
+		}})
+	}
+	if cfg.BigQuery != nil {
+		starters = append(starters, starter{"BigQuery", &suite.BigQuery, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartBigQueryEmulator(ctx, *cfg.BigQuery, opts...)
+		}})
+	}
+	if cfg.Redis != nil {
+		starters = append(starters, starter{"Redis", &suite.Redis, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartRedisContainer(ctx, *cfg.Redis, opts...)
+		}})
+	}
+	if cfg.MQTT != nil {
+		starters = append(starters, starter{"MQTT", &suite.MQTT, func(opts ...SetupOption) (EmulatorConnectionInfo, TerminateFunc, error) {
+			return StartMqttBroker(ctx, *cfg.MQTT, opts...)
+		}})
+	}
+
+	// The Start functions do not touch t, so they are safe to run in
+	// goroutines; cleanups and assertions happen back on the caller's goroutine.
+	terminates := make([]TerminateFunc, len(starters))
+	errs := make([]error, len(starters))
+	var wg sync.WaitGroup
+	for i, s := range starters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			*s.into, terminates[i], err = s.start(logsTo(s.name, logf, failed))
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", s.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var members []suiteMember
+	for i, s := range starters {
+		if terminates[i] != nil {
+			members = append(members, suiteMember{name: s.name, terminate: terminates[i]})
+		}
+	}
+	return suite, members, errors.Join(errs...)
+}
+
+// packageSuite holds the suite started by RunWithSuite.
+var packageSuite *EmulatorSuite
+
+// RunWithSuite starts the emulators requested in cfg once for the whole
+// package, runs the tests and terminates the emulators, returning the exit
+// code for os.Exit. Call it from TestMain and read the connection info in
+// tests with PackageSuite:
+//
+//	func TestMain(m *testing.M) {
+//		pubsubCfg := emulators.GetDefaultPubsubConfig("test-project")
+//		os.Exit(emulators.RunWithSuite(m, emulators.SuiteConfig{Pubsub: &pubsubCfg}))
+//	}
+//
+// Tests share the emulators, so they should use unique resource names.
+// STORAGE_EMULATOR_HOST is set for the whole run when GCS is requested.
+func RunWithSuite(m *testing.M, cfg SuiteConfig) int {
+	ctx := context.Background()
+	// Buffered container logs are written at exit if any test failed.
+	code := 0
+	suite, members, err := startEmulatorSuite(ctx, cfg, nil, func() bool { return code != 0 })
+	defer func() {
+		for _, member := range members {
+			termCtx, termCancel := context.WithTimeout(context.Background(), 60*time.Second)
+			if err := member.terminate(termCtx); err != nil {
+				log.Warn().Err(err).Str("emulator", member.name).Msg("Failed to terminate suite emulator container")
+			}
+			termCancel()
+		}
+	}()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start emulator suite")
+		return 1
+	}
+
+	if cfg.GCS != nil {
+		if err := os.Setenv("STORAGE_EMULATOR_HOST", gcsEmulatorHost(suite.GCS)); err != nil {
+			log.Error().Err(err).Msg("Failed to set STORAGE_EMULATOR_HOST")
+			return 1
+		}
+		defer func() { _ = os.Unsetenv("STORAGE_EMULATOR_HOST") }()
+	}
+
+	packageSuite = &suite
+	defer func() { packageSuite = nil }()
+	code = m.Run()
+	return code
+}
+
+// PackageSuite returns the suite started by RunWithSuite. It fails the test
+// if the package's TestMain does not use RunWithSuite.
+func PackageSuite(t testing.TB) EmulatorSuite {
+	t.Helper()
+	require.NotNil(t, packageSuite, "PackageSuite requires TestMain to call RunWithSuite")
+	return *packageSuite
+}
@@ -0,0 +1,56 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupEmulatorSuite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	pubsubCfg := GetDefaultPubsubConfig("suite-project")
+	redisCfg := GetDefaultRedisImageContainer()
+	mqttCfg := GetDefaultMqttImageContainer()
+	suite := SetupEmulatorSuite(t, context.Background(), SuiteConfig{
+		Pubsub: &pubsubCfg,
+		Redis:  &redisCfg,
+		MQTT:   &mqttCfg,
+	})
+
+	require.NotEmpty(t, suite.Pubsub.HTTPEndpoint.Endpoint)
+	require.NotEmpty(t, suite.Redis.EmulatorAddress)
+	require.NotEmpty(t, suite.MQTT.EmulatorAddress)
+	require.Empty(t, suite.GCS.HTTPEndpoint.Endpoint, "GCS was not requested")
+
+	psClient, err := pubsub.NewClient(ctx, "suite-project", suite.Pubsub.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = psClient.Close() })
+
+	rdb := redis.NewClient(&redis.Options{Addr: suite.Redis.EmulatorAddress})
+	t.Cleanup(func() { _ = rdb.Close() })
+	require.NoError(t, rdb.Ping(ctx).Err())
+
+	client, err := CreateTestMqttClient(suite.MQTT, "suite-client")
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(250) })
+	require.True(t, client.IsConnected())
+}
+
+func TestEmulatorSuiteEmptyConfig(t *testing.T) {
+	suite := SetupEmulatorSuite(t, context.Background(), SuiteConfig{})
+	require.Equal(t, EmulatorSuite{}, suite)
+}
+
+func TestPackageSuite(t *testing.T) {
+	want := EmulatorSuite{Redis: EmulatorConnectionInfo{EmulatorAddress: "localhost:6379"}}
+	packageSuite = &want
+	t.Cleanup(func() { packageSuite = nil })
+
+	require.Equal(t, want, PackageSuite(t))
+}
@@ -0,0 +1,128 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// temporalImage is the default Temporal CLI image, which runs the
+	// single-binary dev server.
+	temporalImage = "temporalio/temporal:latest"
+	// temporalFrontendPort is the internal port of the frontend (gRPC) service.
+	temporalFrontendPort = "7233"
+	// temporalUIPort is the internal port of the web UI.
+	temporalUIPort = "8233"
+	// temporalDefaultNamespace always exists on the dev server.
+	temporalDefaultNamespace = "default"
+)
+
+// TemporalConfig holds configuration for a Temporal dev server container.
+type TemporalConfig struct {
+	ImageContainer
+	// Namespace is registered at startup and returned as
+	// EmulatorConnectionInfo.Namespace. It defaults to "default".
+	Namespace string
+	// UIPort is the internal port of the web UI, returned as
+	// EmulatorConnectionInfo.HTTPEndpoint.
+	UIPort string
+}
+
+// GetDefaultTemporalConfig returns a default configuration for a Temporal dev
+// server container with the given namespace.
+func GetDefaultTemporalConfig(namespace string) TemporalConfig {
+	return TemporalConfig{
+		ImageContainer: ImageContainer{
+			EmulatorImage: temporalImage,
+			EmulatorPort:  temporalFrontendPort,
+		},
+		Namespace: namespace,
+		UIPort:    temporalUIPort,
+	}
+}
+
+// SetupTemporalContainer starts a Temporal dev server container. It
+// automatically handles container startup and teardown via t.Cleanup. The
+// returned connection info has the frontend address (e.g., "localhost:54321")
+// as GRPCEndpoint, the namespace as Namespace and the web UI as HTTPEndpoint.
+//
+// The dev server keeps its state in memory and needs no database, unlike the
+// temporalio/auto-setup image, so it starts in seconds.
+func SetupTemporalContainer(t testing.TB, ctx context.Context, cfg TemporalConfig, opts ...SetupOption) EmulatorConnectionInfo {
+	t.Helper()
+
+	connInfo, terminate, err := StartTemporalContainer(ctx, cfg, withTestLogs(t, "Temporal", opts)...)
+	require.NoError(t, err, "Failed to start Temporal container")
+
+	t.Cleanup(func() {
+		if err := terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate Temporal container: %v", err)
+		}
+	})
+
+	t.Logf("Temporal dev server started at %s with namespace %s", connInfo.GRPCEndpoint.Endpoint, connInfo.Namespace)
+	return connInfo
+}
+
+// StartTemporalContainer starts a Temporal dev server container and waits for
+// its frontend and UI to accept connections. Unlike SetupTemporalContainer it
+// needs no testing.TB; the caller must call the returned TerminateFunc.
+func StartTemporalContainer(ctx context.Context, cfg TemporalConfig, opts ...SetupOption) (connInfo EmulatorConnectionInfo, terminate TerminateFunc, err error) {
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = temporalDefaultNamespace
+	}
+	frontendPort := fmt.Sprintf("%s/tcp", cfg.EmulatorPort)
+	uiPort := fmt.Sprintf("%s/tcp", cfg.UIPort)
+	cmd := []string{"server", "start-dev", "--ip", "0.0.0.0",
+		"--port", cfg.EmulatorPort, "--ui-port", cfg.UIPort}
+	if namespace != temporalDefaultNamespace {
+		cmd = append(cmd, "--namespace", namespace)
+	}
+	req := testcontainers.ContainerRequest{
+		Image:        cfg.EmulatorImage,
+		ExposedPorts: []string{frontendPort, uiPort},
+		Cmd:          cmd,
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort(nat.Port(frontendPort)),
+			wait.ForHTTP("/").WithPort(nat.Port(uiPort)),
+		).WithDeadline(90 * time.Second),
+	}
+	container, terminate, err := startContainer(ctx, cfg.ImageContainer, req, "", opts...)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = terminate(context.Background())
+			terminate = nil
+		}
+	}()
+
+	frontendAddr, err := mappedAddress(ctx, container, frontendPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	uiAddr, err := mappedAddress(ctx, container, uiPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	internal, err := internalAddress(ctx, container, cfg.ImageContainer, frontendPort)
+	if err != nil {
+		return EmulatorConnectionInfo{}, nil, err
+	}
+	return EmulatorConnectionInfo{
+		Service:          ServiceTemporal,
+		Namespace:        namespace,
+		GRPCEndpoint:     Endpoint{Port: cfg.EmulatorPort, Endpoint: frontendAddr},
+		HTTPEndpoint:     Endpoint{Port: cfg.UIPort, Endpoint: "http://" + uiAddr},
+		InternalEndpoint: internal,
+	}, terminate, nil
+}
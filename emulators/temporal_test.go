@@ -0,0 +1,38 @@
+package emulators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGetDefaultTemporalConfig(t *testing.T) {
+	cfg := GetDefaultTemporalConfig("orders")
+	require.Equal(t, temporalImage, cfg.EmulatorImage)
+	require.Equal(t, temporalFrontendPort, cfg.EmulatorPort)
+	require.Equal(t, temporalUIPort, cfg.UIPort)
+	require.Equal(t, "orders", cfg.Namespace)
+}
+
+func TestSetupTemporalContainer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	connInfo := SetupTemporalContainer(t, ctx, GetDefaultTemporalConfig("orders"))
+	require.Equal(t, "orders", connInfo.Namespace)
+	require.Equal(t, "orders", connInfo.EnvVars()["TEMPORAL_NAMESPACE"])
+
+	conn, err := grpc.NewClient(connInfo.GRPCEndpoint.Endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{
+		Service: "temporal.api.workflowservice.v1.WorkflowService",
+	})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus())
+}
@@ -1,5 +1,19 @@
 package emulators
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/rs/zerolog/log"
+	"github.com/testcontainers/testcontainers-go"
+)
+
 // ImageContainer holds basic, non-cloud-specific container configuration.
 type ImageContainer struct {
 	// EmulatorImage is the full Docker image name and tag (e.g., "redis:8.0.2-alpine").
@@ -8,6 +22,29 @@ type ImageContainer struct {
 	EmulatorPort string
 	// EmulatorGRPCPort is the secondary *internal* gRPC port, used by services like BigQuery.
 	EmulatorGRPCPort string
+	// ContainerName is an optional fixed name for the container.
+	ContainerName string
+	// Reuse shares the container named ContainerName across tests, packages and
+	// `go test` invocations instead of starting a fresh one. A reused container
+	// is not terminated at the end of the test and keeps the configuration and
+	// state it was created with, so change ContainerName when the config changes.
+	// To outlive the test process, run with TESTCONTAINERS_RYUK_DISABLED=true.
+	Reuse bool
+	// StreamLogs writes the container's output to the test log (t.Log),
+	// prefixed with the emulator name, to debug startup failures and
+	// emulator-side errors.
+	StreamLogs bool
+	// LogsOnFailure buffers the container's output and writes it to the test
+	// log only if the test has failed when the container is terminated, or if
+	// the container fails to start. It has no effect with StreamLogs.
+	LogsOnFailure bool
+	// Network is the name of a Docker network (e.g., from NewTestNetwork) to
+	// attach the container to, so other containers on it can reach the
+	// emulator at EmulatorConnectionInfo.InternalEndpoint.
+	Network string
+	// NetworkAlias is the host name of the container on Network. It defaults
+	// to the container name.
+	NetworkAlias string
 }
 
 // GCImageContainer extends ImageContainer with configuration specific
@@ -16,7 +53,155 @@ type GCImageContainer struct {
 	ImageContainer
 	// ProjectID is the Google Cloud Project ID to configure the emulator with.
 	ProjectID string
-	// SetEnvVariables determines if the setup function should set environment
-	// variables (like STORAGE_EMULATOR_HOST).
+	// SetEnvVariables determines if the setup function should set the
+	// emulator's canonical environment variables (see EmulatorConnectionInfo.SetEnv),
+	// like PUBSUB_EMULATOR_HOST. GCS always sets STORAGE_EMULATOR_HOST.
 	SetEnvVariables bool
+	// ReadinessProbe, when set, is run once the container is up and retried
+	// with backoff until it succeeds, to prove the emulator serves requests
+	// (e.g., PubsubListTopicsProbe or FirestoreWriteProbe). Startup fails if it
+	// does not succeed within a minute.
+	ReadinessProbe ReadinessProbe
+}
+
+// TerminateFunc stops an emulator container and removes any host files that
+// were written for it. It is returned by the Start functions, the
+// error-returning counterparts of the Setup functions for use outside tests
+// (example programs, TestMain, local dev harnesses). Setup functions
+// register it with t.Cleanup.
+type TerminateFunc func(ctx context.Context) error
+
+// startContainer starts req and returns the container with its TerminateFunc,
+// which also removes dir when it is set. The container is named and reused as
+// configured in ic; a reused container is left running by TerminateFunc.
+// opts and the registry set with SetImageRegistry are applied to req before
+// it is started. If start-up fails, any partially started container and dir
+// are removed before the error is returned.
+func startContainer(ctx context.Context, ic ImageContainer, req testcontainers.ContainerRequest, dir string, opts ...SetupOption) (testcontainers.Container, TerminateFunc, error) {
+	var container testcontainers.Container
+	var logs *containerLogConsumer
+	var failed func() bool
+	terminate := func(ctx context.Context) error {
+		if logs != nil {
+			if failed != nil && failed() {
+				logs.flush()
+			}
+			logs.stop()
+		}
+		var err error
+		if !ic.Reuse {
+			err = testcontainers.TerminateContainer(container, testcontainers.StopContext(ctx))
+		}
+		if dir != "" {
+			err = errors.Join(err, os.RemoveAll(dir))
+		}
+		return err
+	}
+	fail := func(err error) (testcontainers.Container, TerminateFunc, error) {
+		// Buffered logs are most useful when the container never became ready.
+		if logs != nil {
+			logs.flush()
+		}
+		_ = terminate(context.Background())
+		return nil, nil, err
+	}
+
+	if ic.Reuse && ic.ContainerName == "" {
+		return fail(errors.New("ContainerName is required to reuse a container"))
+	}
+	req.Name = ic.ContainerName
+	if ic.Network != "" {
+		req.Networks = append(req.Networks, ic.Network)
+		if ic.NetworkAlias != "" {
+			req.NetworkAliases = map[string][]string{ic.Network: {ic.NetworkAlias}}
+		}
+	}
+	o := applySetupOptions(&req, opts)
+	req.Image = registryImage(req.Image)
+	if ic.StreamLogs || ic.LogsOnFailure {
+		name := o.logName
+		if name == "" {
+			name = req.Image
+		}
+		// Streaming already shows everything, so only buffer without it.
+		logs = newContainerLogConsumer(name, o.logf, !ic.StreamLogs)
+		failed = o.failed
+		req.LogConsumerCfg = &testcontainers.LogConsumerConfig{Consumers: []testcontainers.LogConsumer{logs}}
+	}
+	retries, backoff := o.startupRetryPolicy()
+	warnf := o.logf
+	if warnf == nil {
+		warnf = func(format string, args ...any) { log.Warn().Msgf(format, args...) }
+	}
+	var attemptErrs []error
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		err := pullWithRegistryAuth(ctx, req.Image)
+		if err == nil {
+			container, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true, Reuse: ic.Reuse})
+		}
+		if err == nil {
+			return container, terminate, nil
+		}
+		lastErr = err
+		attemptErrs = append(attemptErrs, fmt.Errorf("attempt %d: %w", attempt, err))
+		if attempt > retries || ctx.Err() != nil {
+			break
+		}
+
+		warnf("Container %s failed to start (attempt %d of %d), retrying in %s: %v", req.Image, attempt, retries+1, backoff, err)
+		// Remove a partially started container, which would otherwise hold
+		// the container name; a reused one is simply attached to again.
+		if container != nil && !ic.Reuse {
+			_ = testcontainers.TerminateContainer(container)
+		}
+		container = nil
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if len(attemptErrs) == 1 {
+		return fail(lastErr)
+	}
+	return fail(fmt.Errorf("container failed to start after %d attempts: %w", len(attemptErrs), errors.Join(attemptErrs...)))
+}
+
+// mappedAddress returns the external "host:port" address of an internal
+// container port, such as "8085" or "6379/tcp".
+func mappedAddress(ctx context.Context, container testcontainers.Container, port string) (string, error) {
+	host, err := container.Host(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container host: %w", err)
+	}
+	mapped, err := container.MappedPort(ctx, nat.Port(port))
+	if err != nil {
+		return "", fmt.Errorf("failed to get mapped port for %s: %w", port, err)
+	}
+	return net.JoinHostPort(host, mapped.Port()), nil
+}
+
+// internalAddress returns the "host:port" address of an internal container
+// port as seen from other containers: the network alias or container name on
+// ic.Network, or else the container IP on the default bridge network.
+func internalAddress(ctx context.Context, container testcontainers.Container, ic ImageContainer, port string) (string, error) {
+	port = nat.Port(port).Port()
+	if ic.Network == "" {
+		ip, err := container.ContainerIP(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get container IP: %w", err)
+		}
+		return net.JoinHostPort(ip, port), nil
+	}
+	host := ic.NetworkAlias
+	if host == "" {
+		name, err := container.Name(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get container name: %w", err)
+		}
+		host = strings.TrimPrefix(name, "/")
+	}
+	return net.JoinHostPort(host, port), nil
 }
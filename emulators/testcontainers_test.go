@@ -0,0 +1,69 @@
+package emulators
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestStartContainerReuseRequiresName(t *testing.T) {
+	dir := t.TempDir() + "/config"
+	require.NoError(t, os.Mkdir(dir, 0755))
+
+	ic := GetDefaultRedisImageContainer().ImageContainer
+	ic.Reuse = true
+	_, _, err := startContainer(context.Background(), ic, testcontainers.ContainerRequest{Image: ic.EmulatorImage}, dir)
+	require.ErrorContains(t, err, "ContainerName is required")
+
+	_, statErr := os.Stat(dir)
+	require.True(t, os.IsNotExist(statErr), "config directory should be removed on failure")
+}
+
+func TestStartContainerRetries(t *testing.T) {
+	ic := ImageContainer{EmulatorImage: "invalid image reference"}
+	var warnings []string
+	logf := func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+
+	_, _, err := startContainer(context.Background(), ic, testcontainers.ContainerRequest{Image: ic.EmulatorImage}, "",
+		logsTo("test", logf, nil), WithStartupRetries(2, time.Millisecond))
+	require.ErrorContains(t, err, "failed to start after 3 attempts")
+	require.ErrorContains(t, err, "attempt 1:")
+	require.ErrorContains(t, err, "attempt 3:")
+	require.Len(t, warnings, 2)
+	require.Contains(t, warnings[1], "attempt 2 of 3")
+
+	// Without retries the error is returned as is.
+	_, _, err = startContainer(context.Background(), ic, testcontainers.ContainerRequest{Image: ic.EmulatorImage}, "")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "attempt")
+}
+
+func TestSetupRedisContainerReuse(t *testing.T) {
+	ctx := context.Background()
+	cfg := GetDefaultRedisImageContainer()
+	cfg.ContainerName = "go-test-redis-reuse"
+	cfg.Reuse = true
+
+	first, terminate, err := StartRedisContainer(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// Remove the shared container so repeated test runs start clean.
+		c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{Name: cfg.ContainerName, Image: cfg.EmulatorImage},
+			Reuse:            true,
+		})
+		if err == nil {
+			_ = c.Terminate(ctx)
+		}
+	})
+	require.NoError(t, terminate(ctx), "terminating a reused container should leave it running")
+
+	// The second start finds the running container instead of creating one.
+	second := SetupRedisContainer(t, ctx, cfg)
+	require.Equal(t, first.EmulatorAddress, second.EmulatorAddress)
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package loadgen_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/illmade-knight/go-test/loadgen"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestAMQPClient_PublishWithConfirms(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	// 1. Start a RabbitMQ broker.
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "rabbitmq:4.1-alpine",
+			ExposedPorts: []string{"5672/tcp"},
+			WaitingFor:   wait.ForLog("Server startup complete").WithStartupTimeout(90 * time.Second),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err)
+	port, err := container.MappedPort(ctx, nat.Port("5672/tcp"))
+	require.NoError(t, err)
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	url := fmt.Sprintf("amqp://guest:guest@%s:%s/", host, port.Port())
+
+	// 2. Declare a topic exchange and a queue bound to one device's messages.
+	conn, err := amqp.Dial(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	require.NoError(t, ch.ExchangeDeclare("telemetry", "topic", false, true, false, false, nil))
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, ch.QueueBind(queue.Name, "devices.device-123.data", "telemetry", false, nil))
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	require.NoError(t, err)
+
+	// 3. Create the client under test.
+	publisher := loadgen.NewAMQPClient(loadgen.AMQPClientConfig{
+		URL:        url,
+		Exchange:   "telemetry",
+		RoutingKey: "devices.{deviceID}.data",
+		Confirms:   true,
+	}, zerolog.Nop())
+	require.NoError(t, publisher.Connect())
+	t.Cleanup(publisher.Disconnect)
+
+	mockGenerator := new(MockPayloadGenerator)
+	mockGenerator.On("GeneratePayload").Return([]byte(`{"id":"device-123"}`), nil)
+	device := &loadgen.Device{ID: "device-123", PayloadGenerator: mockGenerator}
+
+	// Act
+	ok, err := publisher.Publish(ctx, device)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, ok, "A confirmed publish should count as published")
+	select {
+	case d := <-deliveries:
+		assert.Equal(t, `{"id":"device-123"}`, string(d.Body))
+		assert.Equal(t, "application/json", d.ContentType)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message to be received")
+	}
+
+	// Publishing to a missing exchange closes the channel, so it cannot be confirmed.
+	missing := loadgen.NewAMQPClient(loadgen.AMQPClientConfig{URL: url, Exchange: "missing", RoutingKey: "x", Confirms: true}, zerolog.Nop())
+	require.NoError(t, missing.Connect())
+	t.Cleanup(missing.Disconnect)
+	ok, err = missing.Publish(ctx, device)
+	assert.False(t, ok)
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package loadgen_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMQPClient_ConnectFails(t *testing.T) {
+	// Grab a free port and close it again, so nothing is listening there.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	client := loadgen.NewAMQPClient(loadgen.AMQPClientConfig{URL: "amqp://guest:guest@" + addr + "/"}, zerolog.Nop())
+	require.Error(t, client.Connect())
+	client.Disconnect() // Safe without a connection.
+}
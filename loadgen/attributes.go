@@ -0,0 +1,39 @@
+// loadgen/attributes.go
+
+package loadgen
+
+import (
+	"fmt"
+	"maps"
+)
+
+// GenerateMessage returns the payload of the next message of device and the
+// attributes to send with it: the device's Headers, overridden by those its
+// PayloadGenerator returns if it is an AttributeGenerator. Clients call it
+// instead of GeneratePayload, and should not modify the attributes.
+func GenerateMessage(device *Device) ([]byte, map[string]string, error) {
+	payload, attributes, err := generateMessage(device.PayloadGenerator, device)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
+	}
+	if len(attributes) == 0 {
+		return payload, device.Headers, nil
+	}
+	if len(device.Headers) > 0 {
+		merged := maps.Clone(device.Headers)
+		maps.Copy(merged, attributes)
+		attributes = merged
+	}
+	return payload, attributes, nil
+}
+
+// generateMessage returns the payload and attributes of the next message of
+// device from generator, which wrappers of a device's PayloadGenerator call
+// to keep its attributes.
+func generateMessage(generator PayloadGenerator, device *Device) ([]byte, map[string]string, error) {
+	if g, ok := generator.(AttributeGenerator); ok {
+		return g.GenerateMessage(device)
+	}
+	payload, err := generator.GeneratePayload(device)
+	return payload, nil, err
+}
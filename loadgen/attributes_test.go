@@ -0,0 +1,127 @@
+package loadgen_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// sequencedGenerator is an AttributeGenerator that numbers the messages it
+// generates in a "seq" attribute.
+type sequencedGenerator struct {
+	mu  sync.Mutex
+	seq int
+}
+
+func (g *sequencedGenerator) GeneratePayload(device *loadgen.Device) ([]byte, error) {
+	payload, _, err := g.GenerateMessage(device)
+	return payload, err
+}
+
+func (g *sequencedGenerator) GenerateMessage(_ *loadgen.Device) ([]byte, map[string]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.seq++
+	return []byte(fmt.Sprintf(`{"seq":%d}`, g.seq)), map[string]string{"seq": fmt.Sprint(g.seq), "event": "reading"}, nil
+}
+
+func TestGenerateMessage(t *testing.T) {
+	headers := map[string]string{"tenant": "acme", "event": "device"}
+
+	payload, attributes, err := loadgen.GenerateMessage(&loadgen.Device{ID: "d1", PayloadGenerator: &sequencedGenerator{}, Headers: headers})
+	require.NoError(t, err)
+	assert.Equal(t, `{"seq":1}`, string(payload))
+	assert.Equal(t, map[string]string{"tenant": "acme", "event": "reading", "seq": "1"}, attributes)
+	assert.Equal(t, map[string]string{"tenant": "acme", "event": "device"}, headers, "the device's Headers must not be modified")
+
+	payload, attributes, err = loadgen.GenerateMessage(&loadgen.Device{ID: "d1", PayloadGenerator: staticPayloadGenerator(`{}`), Headers: headers})
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(payload))
+	assert.Equal(t, headers, attributes)
+}
+
+func TestHTTPClient_Attributes(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	t.Cleanup(server.Close)
+
+	client := loadgen.NewHTTPClient(server.URL, nil, time.Second, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "d1", PayloadGenerator: &sequencedGenerator{}, Headers: map[string]string{"X-Tenant": "acme"}}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	got := <-headers
+	assert.Equal(t, "1", got.Get("Seq"))
+	assert.Equal(t, "reading", got.Get("Event"))
+	assert.Equal(t, "acme", got.Get("X-Tenant"))
+}
+
+func TestPubsubClient_Attributes(t *testing.T) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	_, err := srv.GServer.CreateTopic(context.Background(), &pubsubpb.Topic{Name: "projects/load-project/topics/telemetry"})
+	require.NoError(t, err)
+
+	client := loadgen.NewPubsubClient(loadgen.PubsubClientConfig{
+		ProjectID: "load-project",
+		TopicID:   "telemetry",
+		ClientOptions: []option.ClientOption{
+			option.WithEndpoint(srv.Addr),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		},
+		Attributes: map[string]string{"source": "loadgen"},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "garden-01", PayloadGenerator: &sequencedGenerator{}})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	msgs := srv.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, map[string]string{"source": "loadgen", "seq": "1", "event": "reading"}, msgs[0].Attributes)
+}
+
+func TestLoadGenerator_RetriesKeepAttributes(t *testing.T) {
+	var mu sync.Mutex
+	var seqs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seqs = append(seqs, r.Header.Get("Seq"))
+		if len(seqs) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	devices := []*loadgen.Device{{ID: "d1", MessageRate: 1, PayloadGenerator: &sequencedGenerator{}}}
+	lg := loadgen.NewLoadGenerator(loadgen.NewHTTPClient(server.URL, nil, time.Second, zerolog.Nop()), devices, zerolog.Nop())
+	lg.SetRetryPolicy(loadgen.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	result, err := lg.RunUntil(context.Background(), 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Published)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"1", "1"}, seqs, "a retry resends the same message")
+}
@@ -0,0 +1,158 @@
+// loadgen/backpressure.go
+
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BackpressurePolicy says what a run does with a message due while the
+// client already has the most publishes in flight that SetMaxInFlight
+// allows.
+type BackpressurePolicy string
+
+const (
+	// BackpressureBlock holds the message until a publish in flight
+	// completes, so devices fall behind their schedules.
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureShed drops the message and counts it as shed.
+	BackpressureShed BackpressurePolicy = "shed"
+)
+
+// SetMaxInFlight limits the publishes the client has in flight at once to
+// n, so a broker that acknowledges slower than the fleet sends pushes back
+// on the generator instead of piling up publishes that all time out. A
+// message due at the limit waits for a free slot or is dropped, as policy
+// says; either way fewer messages are published than ExpectedRange allows.
+// Results.Backpressure reports how often the limit was reached. n <= 0
+// removes the limit.
+func (lg *LoadGenerator) SetMaxInFlight(n int, policy BackpressurePolicy) {
+	lg.maxInFlight, lg.backpressurePolicy = n, policy
+}
+
+// Backpressure is how a run fared against the limit set by SetMaxInFlight,
+// after any warm-up.
+type Backpressure struct {
+	MaxInFlight int
+	Policy      BackpressurePolicy
+	// Incidents is the number of times messages found the limit reached. An
+	// incident lasts until a message is next published without waiting.
+	Incidents int
+	// Blocked is the number of messages that waited for a free slot under
+	// BackpressureBlock, and BlockedTime how long they waited in all.
+	Blocked     int
+	BlockedTime time.Duration
+	// Shed is the number of messages dropped under BackpressureShed.
+	Shed int
+}
+
+// inFlightLimiter enforces the limit set by SetMaxInFlight during a run.
+type inFlightLimiter struct {
+	lg    *LoadGenerator
+	slots chan struct{}
+	// saturated is set during an incident.
+	saturated atomic.Bool
+
+	mu    sync.Mutex
+	stats Backpressure
+}
+
+// newInFlightLimiter returns the limiter of a run, or nil if it has no
+// limit.
+func (lg *LoadGenerator) newInFlightLimiter() *inFlightLimiter {
+	if lg.maxInFlight <= 0 {
+		return nil
+	}
+	policy := lg.backpressurePolicy
+	if policy == "" {
+		policy = BackpressureBlock
+	}
+	return &inFlightLimiter{
+		lg:    lg,
+		slots: make(chan struct{}, lg.maxInFlight),
+		stats: Backpressure{MaxInFlight: lg.maxInFlight, Policy: policy},
+	}
+}
+
+// acquire takes a slot for a publish. It returns false if the message is
+// shed, or if the run whose context is ctx ends while it waits. warmup says
+// the run is warming up, which leaves the message out of the stats.
+func (l *inFlightLimiter) acquire(ctx context.Context, warmup bool) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		if l.saturated.Load() {
+			l.saturated.Store(false)
+		}
+		return true
+	default:
+	}
+
+	if l.saturated.CompareAndSwap(false, true) && !warmup {
+		l.mu.Lock()
+		l.stats.Incidents++
+		incidents := l.stats.Incidents
+		l.mu.Unlock()
+		event := l.lg.logger.Debug()
+		if incidents == 1 {
+			event = l.lg.logger.Warn()
+		}
+		event.Int("max_in_flight", l.stats.MaxInFlight).Str("policy", string(l.stats.Policy)).
+			Msg("Publishes in flight reached the limit, the client is acknowledging slower than the send rate.")
+	}
+	if l.stats.Policy == BackpressureShed {
+		if !warmup {
+			l.mu.Lock()
+			l.stats.Shed++
+			l.mu.Unlock()
+		}
+		return false
+	}
+
+	began := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	if !warmup {
+		l.mu.Lock()
+		l.stats.Blocked++
+		l.stats.BlockedTime += time.Since(began)
+		l.mu.Unlock()
+	}
+	return true
+}
+
+// release frees the slot of a publish.
+func (l *inFlightLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// results returns the stats of the run, or nil if it had no limit.
+func (l *inFlightLimiter) results() *Backpressure {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	return &stats
+}
+
+// shed returns the number of messages shed after the warm-up.
+func (l *inFlightLimiter) shed() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats.Shed
+}
@@ -0,0 +1,85 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerator_SetMaxInFlight(t *testing.T) {
+	// 10 devices send 1000 messages a second, but two publishes in flight
+	// that take 20ms each get through 100.
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(100).Build()
+	require.NoError(t, err)
+	run := func(t *testing.T, policy loadgen.BackpressurePolicy) (loadgen.RunResult, loadgen.Results, *slowClient) {
+		client := &slowClient{delay: 20 * time.Millisecond}
+		lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+		lg.SetMaxInFlight(2, policy)
+		result, err := lg.RunUntil(context.Background(), 200*time.Millisecond)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, client.peakInFlight, 2)
+		assert.Zero(t, result.Failed)
+		assert.LessOrEqual(t, result.Published, 24)
+		return result, lg.Results(), client
+	}
+
+	t.Run("block", func(t *testing.T) {
+		result, results, _ := run(t, loadgen.BackpressureBlock)
+		assert.GreaterOrEqual(t, result.Published, 16)
+		bp := results.Backpressure
+		require.NotNil(t, bp)
+		assert.Equal(t, 2, bp.MaxInFlight)
+		assert.Equal(t, loadgen.BackpressureBlock, bp.Policy)
+		assert.GreaterOrEqual(t, bp.Incidents, 1)
+		assert.GreaterOrEqual(t, bp.Blocked, result.Published-2, "all but the first publishes waited")
+		assert.Positive(t, bp.BlockedTime)
+		assert.Zero(t, bp.Shed)
+		assert.Zero(t, result.Shed)
+		assert.Contains(t, results.Summary(), "blocked")
+	})
+
+	t.Run("shed", func(t *testing.T) {
+		// A slot freed between sends stays free until the next one.
+		result, results, _ := run(t, loadgen.BackpressureShed)
+		assert.GreaterOrEqual(t, result.Published, 8)
+		bp := results.Backpressure
+		require.NotNil(t, bp)
+		assert.Equal(t, loadgen.BackpressureShed, bp.Policy)
+		assert.GreaterOrEqual(t, bp.Incidents, 1)
+		assert.Zero(t, bp.Blocked)
+		// 10 devices publish or shed on schedule, 21 times each.
+		assert.Greater(t, bp.Shed, 150)
+		assert.Equal(t, bp.Shed, result.Shed)
+		assert.Regexp(t, `Backpressure:\s+\d+ incidents at 2 in flight; \d+ shed`, results.Summary())
+
+		var buf bytes.Buffer
+		require.NoError(t, results.WriteJSON(&buf))
+		var decoded struct {
+			Backpressure map[string]any `json:"backpressure"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, "shed", decoded.Backpressure["policy"])
+		assert.Equal(t, float64(bp.Shed), decoded.Backpressure["shed"])
+	})
+}
+
+func TestLoadGenerator_NoMaxInFlight(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(100).Build()
+	require.NoError(t, err)
+	client := &slowClient{delay: 20 * time.Millisecond}
+	lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+
+	result, err := lg.RunUntil(context.Background(), 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 10, client.peakInFlight)
+	assert.Zero(t, result.Shed)
+	assert.Nil(t, lg.Results().Backpressure)
+	assert.NotContains(t, lg.Results().Summary(), "Backpressure:")
+}
@@ -0,0 +1,125 @@
+// loadgen/binarygenerator.go
+
+package loadgen
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// SizeDistribution decides the size in bytes of each payload of a
+// BinaryPayloadGenerator.
+type SizeDistribution interface {
+	// Size returns the size of the next payload.
+	Size() int
+}
+
+// randSizes is implemented by the SizeDistributions of this package, which
+// draw from the random source of the device whose payload they size.
+type randSizes interface {
+	sizeRand(r *rand.Rand) int
+}
+
+// FixedSize makes every payload n bytes.
+func FixedSize(n int) SizeDistribution {
+	return fixedSize{n: max(n, 0)}
+}
+
+type fixedSize struct {
+	n int
+}
+
+func (f fixedSize) Size() int {
+	return f.n
+}
+
+// UniformSize makes payloads of a uniformly random size in [min, max].
+func UniformSize(min, max int) SizeDistribution {
+	return uniformSize{min: min, max: max}
+}
+
+type uniformSize struct {
+	min, max int
+}
+
+func (u uniformSize) Size() int {
+	return u.sizeRand(unseeded)
+}
+
+func (u uniformSize) sizeRand(r *rand.Rand) int {
+	if u.max <= u.min {
+		return max(u.min, 0)
+	}
+	return max(u.min+r.IntN(u.max-u.min+1), 0)
+}
+
+// NormalSize makes payloads of a normally distributed size with the given
+// mean and standard deviation. Sizes below zero are clamped to zero.
+func NormalSize(mean, stddev int) SizeDistribution {
+	return normalSize{mean: float64(mean), stddev: float64(stddev)}
+}
+
+type normalSize struct {
+	mean, stddev float64
+}
+
+func (n normalSize) Size() int {
+	return n.sizeRand(unseeded)
+}
+
+func (n normalSize) sizeRand(r *rand.Rand) int {
+	return max(int(math.Round(n.mean+r.NormFloat64()*n.stddev)), 0)
+}
+
+// ParetoSize makes payloads whose sizes follow a Pareto distribution with
+// the given minimum and shape alpha: mostly close to min, with a heavy tail
+// of much larger payloads, as with real telemetry carrying occasional
+// diagnostics dumps. A smaller alpha gives a heavier tail. Sizes are capped
+// at max.
+func ParetoSize(min int, alpha float64, max int) SizeDistribution {
+	return paretoSize{min: float64(min), alpha: alpha, max: max}
+}
+
+type paretoSize struct {
+	min, alpha float64
+	max        int
+}
+
+func (p paretoSize) Size() int {
+	return p.sizeRand(unseeded)
+}
+
+func (p paretoSize) sizeRand(r *rand.Rand) int {
+	// Inverse transform sampling; 1-Float64 is in (0, 1], so never divides by zero.
+	size := p.min / math.Pow(1-r.Float64(), 1/p.alpha)
+	if size >= float64(p.max) {
+		return p.max
+	}
+	return max(int(size), 0)
+}
+
+// BinaryPayloadGenerator generates random, opaque payloads whose sizes follow
+// a SizeDistribution, to test how brokers and pipelines behave under varying
+// message sizes regardless of content.
+type BinaryPayloadGenerator struct {
+	sizes SizeDistribution
+}
+
+// NewBinaryPayloadGenerator creates a generator with payload sizes from sizes.
+func NewBinaryPayloadGenerator(sizes SizeDistribution) *BinaryPayloadGenerator {
+	return &BinaryPayloadGenerator{sizes: sizes}
+}
+
+// GeneratePayload returns random bytes of the next size.
+func (g *BinaryPayloadGenerator) GeneratePayload(device *Device) ([]byte, error) {
+	r := deviceRand(device)
+	var size int
+	if sizes, ok := g.sizes.(randSizes); ok {
+		size = sizes.sizeRand(r)
+	} else {
+		size = g.sizes.Size()
+	}
+	payload := make([]byte, size)
+	_, _ = randReader{r}.Read(payload)
+	return payload, nil
+}
@@ -0,0 +1,60 @@
+package loadgen_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeDistribution_Size(t *testing.T) {
+	const samples = 20000
+
+	testCases := []struct {
+		name     string
+		sizes    loadgen.SizeDistribution
+		min, max int
+		mean     float64
+	}{
+		{"fixed", loadgen.FixedSize(512), 512, 512, 512},
+		{"uniform", loadgen.UniformSize(100, 300), 100, 300, 200},
+		{"normal", loadgen.NormalSize(1000, 100), 0, 2000, 1000},
+		{"normal clamped", loadgen.NormalSize(0, 100), 0, 1000, 40},
+		// The mean of Pareto(min, alpha) is alpha*min/(alpha-1).
+		{"pareto", loadgen.ParetoSize(100, 3, 1<<20), 100, 1 << 20, 150},
+		{"pareto capped", loadgen.ParetoSize(100, 0.5, 1000), 100, 1000, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			total := 0
+			for range samples {
+				size := tc.sizes.Size()
+				require.GreaterOrEqual(t, size, tc.min)
+				require.LessOrEqual(t, size, tc.max)
+				total += size
+			}
+			if tc.mean > 0 {
+				assert.InDelta(t, tc.mean, float64(total)/samples, tc.mean/10)
+			}
+		})
+	}
+}
+
+func TestBinaryPayloadGenerator(t *testing.T) {
+	gen := loadgen.NewBinaryPayloadGenerator(loadgen.UniformSize(0, 37))
+	seen := make(map[int]bool)
+	for range 2000 {
+		payload, err := gen.GeneratePayload(&loadgen.Device{ID: "d1"})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(payload), 37)
+		seen[len(payload)] = true
+	}
+	assert.Len(t, seen, 38, "every size in the range should occur")
+
+	a, err := loadgen.NewBinaryPayloadGenerator(loadgen.FixedSize(64)).GeneratePayload(nil)
+	require.NoError(t, err)
+	b, err := loadgen.NewBinaryPayloadGenerator(loadgen.FixedSize(64)).GeneratePayload(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b, "payloads should be random")
+}
@@ -0,0 +1,170 @@
+// loadgen/chaos.go
+
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChaosConfig sets the failures a ChaosClient injects. The rates are
+// probabilities per publish, from 0 (never) to 1 (always).
+type ChaosConfig struct {
+	// DropRate is the rate of publishes that are lost: the message is not
+	// sent, but the publish reports success, as when a network loses a
+	// message the publisher believes delivered.
+	DropRate float64
+	// Latency delays every publish, by Latency plus a random part of
+	// LatencyJitter.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// DuplicateRate is the rate of messages that are sent twice, with the
+	// same payload.
+	DuplicateRate float64
+	// DisconnectRate is the rate of publishes preceded by a forced
+	// disconnect: the wrapped client is disconnected and, after
+	// ReconnectDelay, connected again. Publishes of other devices wait for
+	// the reconnect.
+	DisconnectRate float64
+	ReconnectDelay time.Duration
+}
+
+// ChaosCounts are the failures a ChaosClient has injected.
+type ChaosCounts struct {
+	Dropped     int
+	Duplicated  int
+	Disconnects int
+}
+
+// ChaosClient wraps a Client and injects failures into its publishes, so the
+// resilience of downstream consumers can be tested without changing the real
+// client. Its random choices follow SetSeed.
+type ChaosClient struct {
+	client Client
+	config ChaosConfig
+
+	// mu is held for writing during a forced disconnect, and for reading by
+	// publishes.
+	mu          sync.RWMutex
+	dropped     atomic.Int64
+	duplicated  atomic.Int64
+	disconnects atomic.Int64
+}
+
+// NewChaosClient returns a ChaosClient that publishes through client.
+func NewChaosClient(client Client, config ChaosConfig) *ChaosClient {
+	return &ChaosClient{client: client, config: config}
+}
+
+// Connect connects the wrapped client.
+func (c *ChaosClient) Connect() error {
+	return c.client.Connect()
+}
+
+// Disconnect disconnects the wrapped client.
+func (c *ChaosClient) Disconnect() {
+	c.client.Disconnect()
+}
+
+// Counts returns the failures injected so far.
+func (c *ChaosClient) Counts() ChaosCounts {
+	return ChaosCounts{
+		Dropped:     int(c.dropped.Load()),
+		Duplicated:  int(c.duplicated.Load()),
+		Disconnects: int(c.disconnects.Load()),
+	}
+}
+
+// Publish publishes a message of device through the wrapped client,
+// injecting the configured failures.
+func (c *ChaosClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	r := deviceRand(device)
+	if r.Float64() < c.config.DisconnectRate {
+		if err := c.forceDisconnect(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	delay := c.config.Latency
+	if c.config.LatencyJitter > 0 {
+		delay += time.Duration(r.Int64N(int64(c.config.LatencyJitter)))
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if r.Float64() < c.config.DropRate {
+		// The payload is still generated, so sequence numbers show the gap.
+		if _, err := device.PayloadGenerator.GeneratePayload(device); err != nil {
+			return false, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
+		}
+		c.dropped.Add(1)
+		return true, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if r.Float64() >= c.config.DuplicateRate {
+		return c.client.Publish(ctx, device)
+	}
+	// Both sends must carry the same payload, so the generator runs once.
+	twice := *device
+	twice.PayloadGenerator = &onePayload{generator: device.PayloadGenerator}
+	ok, err := c.client.Publish(ctx, &twice)
+	if err != nil || !ok {
+		return ok, err
+	}
+	c.duplicated.Add(1)
+	return c.client.Publish(ctx, &twice)
+}
+
+// onePayload generates one message with generator and returns it again on
+// every later call.
+type onePayload struct {
+	generator  PayloadGenerator
+	payload    []byte
+	attributes map[string]string
+	err        error
+	done       bool
+}
+
+func (p *onePayload) GeneratePayload(device *Device) ([]byte, error) {
+	payload, _, err := p.GenerateMessage(device)
+	return payload, err
+}
+
+func (p *onePayload) GenerateMessage(device *Device) ([]byte, map[string]string, error) {
+	if !p.done {
+		p.payload, p.attributes, p.err = generateMessage(p.generator, device)
+		p.done = true
+	}
+	return p.payload, p.attributes, p.err
+}
+
+// forceDisconnect disconnects the wrapped client and connects it again after
+// the ReconnectDelay.
+func (c *ChaosClient) forceDisconnect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnects.Add(1)
+	c.client.Disconnect()
+	timer := time.NewTimer(c.config.ReconnectDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	if err := c.client.Connect(); err != nil {
+		return fmt.Errorf("failed to reconnect after forced disconnect: %w", err)
+	}
+	return nil
+}
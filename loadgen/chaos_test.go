@@ -0,0 +1,103 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectCountingClient is a recordingClient that counts its connections.
+type connectCountingClient struct {
+	recordingClient
+	connects, disconnects int
+}
+
+func (c *connectCountingClient) Connect() error {
+	c.connects++
+	return nil
+}
+
+func (c *connectCountingClient) Disconnect() { c.disconnects++ }
+
+func seqDevice(t *testing.T) *loadgen.Device {
+	t.Helper()
+	gen, err := loadgen.NewTemplatePayloadGenerator("{{.Seq}}")
+	require.NoError(t, err)
+	return &loadgen.Device{ID: "device-1", PayloadGenerator: gen}
+}
+
+func publishN(t *testing.T, client loadgen.Client, device *loadgen.Device, n int) {
+	t.Helper()
+	for range n {
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
+
+func TestChaosClient(t *testing.T) {
+	t.Run("drops", func(t *testing.T) {
+		inner := &recordingClient{}
+		client := loadgen.NewChaosClient(inner, loadgen.ChaosConfig{DropRate: 1})
+		device := seqDevice(t)
+		publishN(t, client, device, 3)
+		assert.Empty(t, inner.payloads)
+		assert.Equal(t, loadgen.ChaosCounts{Dropped: 3}, client.Counts())
+
+		// Dropped messages still use up their sequence numbers.
+		publishN(t, loadgen.NewChaosClient(inner, loadgen.ChaosConfig{}), device, 1)
+		assert.Equal(t, []string{"4"}, inner.payloads)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		inner := &recordingClient{}
+		client := loadgen.NewChaosClient(inner, loadgen.ChaosConfig{DuplicateRate: 1})
+		publishN(t, client, seqDevice(t), 2)
+		assert.Equal(t, []string{"1", "1", "2", "2"}, inner.payloads)
+		assert.Equal(t, loadgen.ChaosCounts{Duplicated: 2}, client.Counts())
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		client := loadgen.NewChaosClient(&recordingClient{}, loadgen.ChaosConfig{Latency: 20 * time.Millisecond, LatencyJitter: 10 * time.Millisecond})
+		start := time.Now()
+		publishN(t, client, seqDevice(t), 2)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ok, err := client.Publish(ctx, seqDevice(t))
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("disconnects", func(t *testing.T) {
+		inner := &connectCountingClient{}
+		client := loadgen.NewChaosClient(inner, loadgen.ChaosConfig{DisconnectRate: 1, ReconnectDelay: time.Millisecond})
+		require.NoError(t, client.Connect())
+		publishN(t, client, seqDevice(t), 2)
+		client.Disconnect()
+
+		assert.Equal(t, 3, inner.connects)
+		assert.Equal(t, 3, inner.disconnects)
+		assert.Len(t, inner.payloads, 2)
+		assert.Equal(t, loadgen.ChaosCounts{Disconnects: 2}, client.Counts())
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		t.Cleanup(loadgen.ClearSeed)
+		run := func() []string {
+			loadgen.SetSeed(3)
+			inner := &recordingClient{}
+			client := loadgen.NewChaosClient(inner, loadgen.ChaosConfig{DropRate: 0.3, DuplicateRate: 0.3})
+			publishN(t, client, seqDevice(t), 20)
+			return inner.payloads
+		}
+		first := run()
+		assert.Equal(t, first, run())
+		assert.NotEqual(t, 20, len(first), "some messages are dropped or duplicated")
+	})
+}
@@ -0,0 +1,352 @@
+// loadgen/coapclient.go
+
+package loadgen
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CoAP message types and codes (RFC 7252).
+const (
+	coapConfirmable     = 0
+	coapNonConfirmable  = 1
+	coapAcknowledgement = 2
+	coapReset           = 3
+
+	coapCodeEmpty = 0x00
+	coapCodePost  = 0x02
+
+	coapOptionUriPath       = 11
+	coapOptionContentFormat = 12
+	coapPayloadMarker       = 0xFF
+
+	// CoAPContentFormatJSON is the Content-Format number of application/json.
+	CoAPContentFormatJSON = 50
+)
+
+// CoAPClientConfig holds configuration for a CoAPClient.
+type CoAPClientConfig struct {
+	// Address is the "host:port" of the CoAP server, usually port 5683.
+	Address string
+	// PathPattern is the resource each message is POSTed to, with
+	// DeviceIDPlaceholder replaced by the device ID
+	// (e.g. "/devices/{deviceID}/telemetry").
+	PathPattern string
+	// Confirmable sends confirmable (CON) requests, which are retransmitted
+	// until the server acknowledges them; Publish then returns true only for
+	// a 2.xx response. Otherwise requests are non-confirmable (NON) and
+	// count as published once sent, as a constrained device would do.
+	Confirmable bool
+	// ContentFormat is the Content-Format option of every request. It
+	// defaults to CoAPContentFormatJSON.
+	ContentFormat uint16
+	// AckTimeout is the initial retransmission timeout of confirmable
+	// requests, doubled on every retransmission. It defaults to 2s.
+	AckTimeout time.Duration
+	// MaxRetransmit is the number of retransmissions before a confirmable
+	// request fails. It defaults to 4.
+	MaxRetransmit int
+}
+
+// coapMessage is a decoded CoAP message.
+type coapMessage struct {
+	typ       uint8
+	code      uint8
+	messageID uint16
+	token     []byte
+}
+
+// coapExchange is a confirmable request waiting for its response.
+type coapExchange struct {
+	acked    chan struct{} // closed on an empty ACK (a separate response follows)
+	response chan coapMessage
+	ackOnce  sync.Once
+}
+
+// CoAPClient implements the Client interface for CoAP over UDP, sharing one
+// socket between all devices.
+type CoAPClient struct {
+	cfg       CoAPClientConfig
+	conn      *net.UDPConn
+	messageID atomic.Uint32
+	token     atomic.Uint64
+	logger    zerolog.Logger
+
+	mu      sync.Mutex
+	byMID   map[uint16]*coapExchange
+	byToken map[string]*coapExchange
+	done    chan struct{}
+}
+
+// NewCoAPClient creates a new CoAP client.
+func NewCoAPClient(cfg CoAPClientConfig, logger zerolog.Logger) Client {
+	if cfg.ContentFormat == 0 {
+		cfg.ContentFormat = CoAPContentFormatJSON
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 2 * time.Second
+	}
+	if cfg.MaxRetransmit <= 0 {
+		cfg.MaxRetransmit = 4
+	}
+	return &CoAPClient{cfg: cfg, logger: logger}
+}
+
+// Connect opens the UDP socket and starts reading responses.
+func (c *CoAPClient) Connect() error {
+	addr, err := net.ResolveUDPAddr("udp", c.cfg.Address)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to resolve CoAP server address")
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to open CoAP socket")
+		return err
+	}
+	c.conn = conn
+	c.byMID = make(map[uint16]*coapExchange)
+	c.byToken = make(map[string]*coapExchange)
+	c.done = make(chan struct{})
+	go c.readLoop(conn, c.done)
+	c.logger.Info().Str("address", c.cfg.Address).Bool("confirmable", c.cfg.Confirmable).Msg("CoAP client ready")
+	return nil
+}
+
+// Disconnect closes the socket.
+func (c *CoAPClient) Disconnect() {
+	if c.conn == nil {
+		return
+	}
+	_ = c.conn.Close()
+	<-c.done
+	c.conn = nil
+	c.logger.Info().Msg("CoAP client disconnected")
+}
+
+// Publish generates a payload and POSTs it to the device's resource.
+func (c *CoAPClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	// CoAP has no headers for the attributes.
+	payloadBytes, _, err := GenerateMessage(device)
+	if err != nil {
+		return false, err
+	}
+
+	typ := uint8(coapNonConfirmable)
+	if c.cfg.Confirmable {
+		typ = coapConfirmable
+	}
+	mid := uint16(c.messageID.Add(1))
+	token := binary.BigEndian.AppendUint64(nil, c.token.Add(1))
+	path := expandDeviceID(c.cfg.PathPattern, device)
+	setSpanDestination(ctx, path)
+	msg := encodeCoAPRequest(typ, mid, token, path, c.cfg.ContentFormat, payloadBytes)
+
+	if !c.cfg.Confirmable {
+		if _, err := c.conn.Write(msg); err != nil {
+			err = fmt.Errorf("coap publish error for device %s: %w", device.ID, err)
+			c.logger.Warn().Err(err).Msg("Publish failed")
+			return false, err
+		}
+		c.logger.Debug().Str("device_id", device.ID).Str("path", path).Msg("Message published")
+		return true, nil
+	}
+
+	resp, err := c.exchange(ctx, mid, token, msg)
+	if err == nil && resp.code>>5 != 2 {
+		err = fmt.Errorf("server answered %d.%02d", resp.code>>5, resp.code&0x1F)
+	}
+	if err != nil {
+		err = fmt.Errorf("coap publish error for device %s: %w", device.ID, err)
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+	c.logger.Debug().Str("device_id", device.ID).Str("path", path).Msg("Message published")
+	return true, nil
+}
+
+// exchange sends a confirmable request, retransmitting it with exponential
+// backoff until it is acknowledged, and waits for its response.
+func (c *CoAPClient) exchange(ctx context.Context, mid uint16, token, msg []byte) (coapMessage, error) {
+	ex := &coapExchange{acked: make(chan struct{}), response: make(chan coapMessage, 1)}
+	c.mu.Lock()
+	c.byMID[mid] = ex
+	c.byToken[string(token)] = ex
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.byMID, mid)
+		delete(c.byToken, string(token))
+		c.mu.Unlock()
+	}()
+
+	timeout := c.cfg.AckTimeout
+	for attempt := 0; ; attempt++ {
+		if _, err := c.conn.Write(msg); err != nil {
+			return coapMessage{}, err
+		}
+		timer := time.NewTimer(timeout)
+		select {
+		case resp := <-ex.response:
+			timer.Stop()
+			if resp.typ == coapReset {
+				return coapMessage{}, errors.New("server reset the request")
+			}
+			return resp, nil
+		case <-ex.acked:
+			// Acknowledged without a response: wait for the separate response.
+			timer.Stop()
+			select {
+			case resp := <-ex.response:
+				return resp, nil
+			case <-ctx.Done():
+				return coapMessage{}, ctx.Err()
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return coapMessage{}, ctx.Err()
+		case <-timer.C:
+			if attempt == c.cfg.MaxRetransmit {
+				return coapMessage{}, fmt.Errorf("no acknowledgement after %d retransmissions", attempt)
+			}
+			timeout *= 2
+		}
+	}
+}
+
+// readLoop dispatches incoming messages to waiting exchanges until the
+// socket is closed.
+func (c *CoAPClient) readLoop(conn *net.UDPConn, done chan struct{}) {
+	defer close(done)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := conn.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			c.logger.Debug().Err(err).Msg("CoAP read failed")
+			continue
+		}
+		msg, err := decodeCoAPMessage(buf[:n])
+		if err != nil {
+			c.logger.Debug().Err(err).Msg("Ignoring malformed CoAP message")
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+// dispatch hands msg to the exchange it belongs to.
+func (c *CoAPClient) dispatch(msg coapMessage) {
+	c.mu.Lock()
+	var ex *coapExchange
+	switch msg.typ {
+	case coapAcknowledgement, coapReset:
+		ex = c.byMID[msg.messageID]
+	default:
+		ex = c.byToken[string(msg.token)]
+	}
+	c.mu.Unlock()
+
+	if msg.typ == coapConfirmable {
+		// A separate response must be acknowledged, even if nobody waits for it.
+		ack := make([]byte, 4)
+		ack[0] = 1<<6 | coapAcknowledgement<<4
+		binary.BigEndian.PutUint16(ack[2:], msg.messageID)
+		_, _ = c.conn.Write(ack)
+	}
+	if ex == nil {
+		return
+	}
+	if msg.typ == coapAcknowledgement && msg.code == coapCodeEmpty {
+		ex.ackOnce.Do(func() { close(ex.acked) })
+		return
+	}
+	select {
+	case ex.response <- msg:
+	default: // A duplicate response.
+	}
+}
+
+// encodeCoAPRequest encodes a request with Uri-Path and Content-Format options.
+func encodeCoAPRequest(typ uint8, mid uint16, token []byte, path string, contentFormat uint16, payload []byte) []byte {
+	msg := []byte{1<<6 | typ<<4 | uint8(len(token)), coapCodePost, 0, 0}
+	binary.BigEndian.PutUint16(msg[2:], mid)
+	msg = append(msg, token...)
+
+	last := 0
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		msg = appendCoAPOption(msg, coapOptionUriPath-last, []byte(segment))
+		last = coapOptionUriPath
+	}
+	format := binary.BigEndian.AppendUint16(nil, contentFormat)
+	if contentFormat < 256 {
+		format = format[1:]
+	}
+	msg = appendCoAPOption(msg, coapOptionContentFormat-last, format)
+
+	if len(payload) > 0 {
+		msg = append(msg, coapPayloadMarker)
+		msg = append(msg, payload...)
+	}
+	return msg
+}
+
+// appendCoAPOption appends an option with the given delta from the previous
+// option number.
+func appendCoAPOption(msg []byte, delta int, value []byte) []byte {
+	deltaNibble, deltaExt := coapOptionNibble(delta)
+	lengthNibble, lengthExt := coapOptionNibble(len(value))
+	msg = append(msg, deltaNibble<<4|lengthNibble)
+	msg = append(msg, deltaExt...)
+	msg = append(msg, lengthExt...)
+	return append(msg, value...)
+}
+
+// coapOptionNibble returns the 4-bit encoding of an option delta or length
+// and its extended bytes.
+func coapOptionNibble(v int) (uint8, []byte) {
+	switch {
+	case v < 13:
+		return uint8(v), nil
+	case v < 269:
+		return 13, []byte{uint8(v - 13)}
+	default:
+		return 14, binary.BigEndian.AppendUint16(nil, uint16(v-269))
+	}
+}
+
+// decodeCoAPMessage decodes the header and token of a message.
+func decodeCoAPMessage(data []byte) (coapMessage, error) {
+	if len(data) < 4 || data[0]>>6 != 1 {
+		return coapMessage{}, errors.New("not a CoAP version 1 message")
+	}
+	tkl := int(data[0] & 0x0F)
+	if tkl > 8 || len(data) < 4+tkl {
+		return coapMessage{}, errors.New("invalid token length")
+	}
+	return coapMessage{
+		typ:       data[0] >> 4 & 0x03,
+		code:      data[1],
+		messageID: binary.BigEndian.Uint16(data[2:4]),
+		token:     append([]byte(nil), data[4:4+tkl]...),
+	}, nil
+}
@@ -0,0 +1,193 @@
+package loadgen_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coapRequest is a request received by the fake CoAP server.
+type coapRequest struct {
+	confirmable   bool
+	path          string
+	contentFormat int
+	payload       string
+}
+
+// startCoAPServer starts a fake CoAP server. Requests for paths starting with
+// "/separate" get an empty ACK followed by a separate response, "/missing"
+// gets 4.04, and the first request for "/lossy" is dropped.
+func startCoAPServer(t *testing.T) (string, func() []coapRequest) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var mu sync.Mutex
+	var requests []coapRequest
+	dropped := false
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			data := buf[:n]
+			typ := data[0] >> 4 & 0x03
+			if typ == 2 { // The client's ACK of a separate response.
+				continue
+			}
+			tkl := int(data[0] & 0x0F)
+			mid := data[2:4]
+			token := append([]byte(nil), data[4:4+tkl]...)
+			req := coapRequest{confirmable: typ == 0}
+
+			// Decode the options; deltas and lengths fit in a nibble here.
+			rest, number := data[4+tkl:], 0
+			for len(rest) > 0 && rest[0] != 0xFF {
+				number += int(rest[0] >> 4)
+				length := int(rest[0] & 0x0F)
+				value := rest[1 : 1+length]
+				switch number {
+				case 11:
+					req.path += "/" + string(value)
+				case 12:
+					for _, b := range value {
+						req.contentFormat = req.contentFormat<<8 | int(b)
+					}
+				}
+				rest = rest[1+length:]
+			}
+			if len(rest) > 0 {
+				req.payload = string(rest[1:])
+			}
+
+			mu.Lock()
+			if req.path == "/lossy" && !dropped {
+				dropped = true
+				mu.Unlock()
+				continue
+			}
+			requests = append(requests, req)
+			mu.Unlock()
+			if !req.confirmable {
+				continue
+			}
+
+			code := byte(0x44) // 2.04 Changed
+			if req.path == "/missing" {
+				code = 0x84 // 4.04 Not Found
+			}
+			if strings.HasPrefix(req.path, "/separate") {
+				_, _ = conn.WriteToUDP([]byte{0x60, 0, mid[0], mid[1]}, addr)
+				resp := []byte{0x40 | byte(tkl), 0x41, 0x12, 0x34} // CON 2.01 Created
+				_, _ = conn.WriteToUDP(append(resp, token...), addr)
+				continue
+			}
+			resp := []byte{0x60 | byte(tkl), code, mid[0], mid[1]}
+			_, _ = conn.WriteToUDP(append(resp, token...), addr)
+		}
+	}()
+	return conn.LocalAddr().String(), func() []coapRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]coapRequest(nil), requests...)
+	}
+}
+
+func TestCoAPClient_Publish(t *testing.T) {
+	addr, received := startCoAPServer(t)
+	device := &loadgen.Device{ID: "sensor-7", PayloadGenerator: staticPayloadGenerator(`{"t":21}`)}
+
+	t.Run("Confirmable", func(t *testing.T) {
+		client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{
+			Address: addr, PathPattern: "/devices/{deviceID}/telemetry", Confirmable: true,
+		}, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		t.Cleanup(client.Disconnect)
+
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		requests := received()
+		require.NotEmpty(t, requests)
+		assert.Equal(t, coapRequest{true, "/devices/sensor-7/telemetry", loadgen.CoAPContentFormatJSON, `{"t":21}`}, requests[len(requests)-1])
+	})
+
+	t.Run("Separate response", func(t *testing.T) {
+		client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{Address: addr, PathPattern: "/separate/{deviceID}", Confirmable: true}, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		t.Cleanup(client.Disconnect)
+
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Retransmits lost requests", func(t *testing.T) {
+		client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{
+			Address: addr, PathPattern: "/lossy", Confirmable: true, AckTimeout: 50 * time.Millisecond,
+		}, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		t.Cleanup(client.Disconnect)
+
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Error response", func(t *testing.T) {
+		client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{Address: addr, PathPattern: "/missing", Confirmable: true}, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		t.Cleanup(client.Disconnect)
+
+		ok, err := client.Publish(context.Background(), device)
+		assert.False(t, ok)
+		assert.ErrorContains(t, err, "4.04")
+	})
+
+	t.Run("Non-confirmable", func(t *testing.T) {
+		client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{Address: addr, PathPattern: "/non/{deviceID}"}, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		t.Cleanup(client.Disconnect)
+
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Eventually(t, func() bool {
+			for _, req := range received() {
+				if req.path == "/non/sensor-7" && !req.confirmable {
+					return true
+				}
+			}
+			return false
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestCoAPClient_NoAcknowledgement(t *testing.T) {
+	// A socket that never answers.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	client := loadgen.NewCoAPClient(loadgen.CoAPClientConfig{
+		Address: conn.LocalAddr().String(), PathPattern: "/x", Confirmable: true,
+		AckTimeout: 10 * time.Millisecond, MaxRetransmit: 2,
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "d", PayloadGenerator: staticPayloadGenerator("x")})
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "no acknowledgement after 2 retransmissions")
+}
@@ -0,0 +1,139 @@
+// loadgen/control.go
+
+package loadgen
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// control holds the changes made by Pause, Resume and SetRate, for the
+// scheduler of the current or next run to pick up.
+type control struct {
+	mu     sync.Mutex
+	paused bool
+	rates  map[string]float64
+	// changed lists the devices whose rate changed since the scheduler last
+	// looked.
+	changed []string
+	// wake tells the scheduler that something changed.
+	wake chan struct{}
+}
+
+// wakeChan returns the channel that signals changes.
+func (c *control) wakeChan() chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.wake == nil {
+		c.wake = make(chan struct{}, 1)
+	}
+	return c.wake
+}
+
+// notify wakes the scheduler without blocking.
+func (c *control) notify() {
+	select {
+	case c.wakeChan() <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops the devices publishing until Resume is called. Publishes in
+// flight finish. The run's clock keeps going, so a paused run still ends when
+// its duration elapses. When resumed, the devices carry on with their
+// schedules shifted by the pause, rather than catching up. A LoadGenerator
+// paused between runs starts its next run paused.
+func (lg *LoadGenerator) Pause() {
+	lg.setPaused(true)
+}
+
+// Resume lets the devices publish again after Pause.
+func (lg *LoadGenerator) Resume() {
+	lg.setPaused(false)
+}
+
+func (lg *LoadGenerator) setPaused(paused bool) {
+	lg.ctl.mu.Lock()
+	lg.ctl.paused = paused
+	lg.ctl.mu.Unlock()
+	lg.ctl.notify()
+}
+
+// SetRate sets the message rate in Hz of the device with deviceID, for the
+// rest of the current run and for later runs, e.g. for a closed-loop test
+// that raises the load until the system under test falls behind. For a
+// device with a Burst, rate is the number of bursts per second. The device's
+// next message moves to one interval at the new rate after its previous one.
+//
+// A rate of 0 stops the device until SetRate gives it a rate again; it keeps
+// the run going rather than counting as finished. A device that starts a run
+// stopped publishes its first message as soon as it is given a rate.
+func (lg *LoadGenerator) SetRate(deviceID string, rate float64) error {
+	if rate < 0 {
+		return fmt.Errorf("device %s cannot have a negative rate", deviceID)
+	}
+	if !slices.ContainsFunc(lg.devices, func(d *Device) bool { return d.ID == deviceID }) {
+		return fmt.Errorf("no device %s", deviceID)
+	}
+	lg.setRates(map[string]float64{deviceID: rate})
+	return nil
+}
+
+// setRates sets the rates of devices by ID, as SetRate does, without
+// checking them.
+func (lg *LoadGenerator) setRates(rates map[string]float64) {
+	lg.ctl.mu.Lock()
+	if lg.ctl.rates == nil {
+		lg.ctl.rates = make(map[string]float64, len(rates))
+	}
+	for id, rate := range rates {
+		lg.ctl.rates[id] = rate
+		lg.ctl.changed = append(lg.ctl.changed, id)
+	}
+	lg.ctl.mu.Unlock()
+	lg.ctl.notify()
+}
+
+// rateOverrides returns a copy of the rates set by SetRate.
+func (lg *LoadGenerator) rateOverrides() map[string]float64 {
+	lg.ctl.mu.Lock()
+	defer lg.ctl.mu.Unlock()
+	return maps.Clone(lg.ctl.rates)
+}
+
+// restoreRates replaces the rates set by SetRate with rates, between runs.
+func (lg *LoadGenerator) restoreRates(rates map[string]float64) {
+	lg.ctl.mu.Lock()
+	defer lg.ctl.mu.Unlock()
+	lg.ctl.rates, lg.ctl.changed = rates, nil
+}
+
+// interval returns the time between the messages, or bursts, of device. It
+// is zero if the device has no rate.
+func (lg *LoadGenerator) interval(device *Device) time.Duration {
+	lg.ctl.mu.Lock()
+	rate, ok := lg.ctl.rates[device.ID]
+	lg.ctl.mu.Unlock()
+	switch {
+	case !ok && device.Burst != nil:
+		return max(device.Burst.Interval, 0)
+	case !ok:
+		rate = device.MessageRate
+	}
+	if rate <= 0 {
+		return 0
+	}
+	return max(time.Duration(float64(time.Second)/rate), 1)
+}
+
+// pending returns whether the LoadGenerator is paused and takes the devices
+// whose rate has changed.
+func (lg *LoadGenerator) pending() (paused bool, changed []string) {
+	lg.ctl.mu.Lock()
+	defer lg.ctl.mu.Unlock()
+	changed, lg.ctl.changed = lg.ctl.changed, nil
+	return lg.ctl.paused, changed
+}
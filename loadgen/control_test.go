@@ -0,0 +1,127 @@
+package loadgen_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// count returns the number of payloads recorded so far.
+func (c *recordingClient) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.payloads)
+}
+
+// countingGenerator counts the calls of the PayloadGenerator it wraps.
+type countingGenerator struct {
+	loadgen.PayloadGenerator
+	calls atomic.Int64
+}
+
+func (g *countingGenerator) GeneratePayload(device *loadgen.Device) ([]byte, error) {
+	g.calls.Add(1)
+	return g.PayloadGenerator.GeneratePayload(device)
+}
+
+// runInBackground starts lg.RunUntil and returns a channel of its result.
+func runInBackground(lg *loadgen.LoadGenerator, duration time.Duration) <-chan loadgen.RunResult {
+	done := make(chan loadgen.RunResult, 1)
+	go func() {
+		result, _ := lg.RunUntil(context.Background(), duration)
+		done <- result
+	}()
+	return done
+}
+
+func TestLoadGenerator_PauseResume(t *testing.T) {
+	client := &recordingClient{}
+	device := &loadgen.Device{ID: "device-1", MessageRate: 100, PayloadGenerator: staticPayloadGenerator(`{}`)}
+	lg := loadgen.NewLoadGenerator(client, []*loadgen.Device{device}, zerolog.Nop())
+
+	done := runInBackground(lg, 500*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	lg.Pause()
+	time.Sleep(20 * time.Millisecond)
+	paused := client.count()
+	assert.Positive(t, paused)
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, paused, client.count(), "no messages while paused")
+
+	lg.Resume()
+	result := <-done
+	assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+	assert.Greater(t, result.Published, paused+10)
+	assert.Less(t, result.Published, lg.ExpectedMessagesForDuration(500*time.Millisecond)-10, "resuming does not catch up")
+}
+
+func TestLoadGenerator_SetRate(t *testing.T) {
+	newDevice := func() *loadgen.Device {
+		return &loadgen.Device{ID: "device-1", MessageRate: 10, PayloadGenerator: staticPayloadGenerator(`{}`)}
+	}
+
+	t.Run("faster", func(t *testing.T) {
+		client := &recordingClient{}
+		lg := loadgen.NewLoadGenerator(client, []*loadgen.Device{newDevice()}, zerolog.Nop())
+		done := runInBackground(lg, 400*time.Millisecond)
+		time.Sleep(150 * time.Millisecond)
+		before := client.count()
+		require.NoError(t, lg.SetRate("device-1", 200))
+
+		result := <-done
+		assert.LessOrEqual(t, before, 2)
+		assert.Greater(t, result.Published, 30, "at 10Hz the run would publish 4 messages")
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		client := &recordingClient{}
+		device := newDevice()
+		device.MessageRate = 100
+		lg := loadgen.NewLoadGenerator(client, []*loadgen.Device{device}, zerolog.Nop())
+		done := runInBackground(lg, 300*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, lg.SetRate("device-1", 0))
+		time.Sleep(20 * time.Millisecond)
+		stopped := client.count()
+
+		result := <-done
+		assert.Equal(t, loadgen.StopDuration, result.StoppedBy, "a stopped device keeps the run going")
+		assert.Equal(t, stopped, result.Published)
+
+		// The rate carries over to the next run, whose device starts stopped.
+		result, err := lg.RunUntil(context.Background(), time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopDevicesDone, result.StoppedBy)
+		assert.Zero(t, result.Published)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		client := &recordingClient{}
+		replay := &countingGenerator{PayloadGenerator: loadgen.NewReplayPayloadGenerator([][]byte{[]byte("a")})}
+		devices := []*loadgen.Device{
+			{ID: "replay-1", MessageRate: 100, PayloadGenerator: replay},
+			newDevice(),
+		}
+		lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+		done := runInBackground(lg, 300*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, int64(2), replay.calls.Load(), "the device sends once and then meets EOF")
+		require.NoError(t, lg.SetRate("replay-1", 200))
+
+		result := <-done
+		assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+		assert.Equal(t, int64(2), replay.calls.Load(), "SetRate does not start an exhausted device again")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		lg := loadgen.NewLoadGenerator(&recordingClient{}, []*loadgen.Device{newDevice()}, zerolog.Nop())
+		assert.ErrorContains(t, lg.SetRate("device-2", 1), "no device device-2")
+		assert.Error(t, lg.SetRate("device-1", -1))
+	})
+}
@@ -0,0 +1,12 @@
+// loadgen/cputime_other.go
+
+//go:build !unix
+
+package loadgen
+
+import "time"
+
+// processCPUTime reports that the platform's process CPU time is unknown.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,171 @@
+// loadgen/datasetgenerator.go
+
+package loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Dataset is a table of rows, e.g. sensor readings captured offline, for a
+// DatasetPayloadGenerator.
+type Dataset struct {
+	rows []map[string]any
+}
+
+// Len returns the number of rows.
+func (d *Dataset) Len() int {
+	return len(d.rows)
+}
+
+// ReadCSVDataset reads a CSV dataset whose first record names the columns.
+// The values are strings.
+func ReadCSVDataset(r io.Reader) (*Dataset, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV dataset: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV dataset has no header")
+	}
+	header, records := records[0], records[1:]
+	d := &Dataset{rows: make([]map[string]any, 0, len(records))}
+	for _, record := range records {
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		d.rows = append(d.rows, row)
+	}
+	return d, nil
+}
+
+// ReadJSONLDataset reads a dataset with a JSON object per line. Numbers keep
+// the form they are written in.
+func ReadJSONLDataset(r io.Reader) (*Dataset, error) {
+	d := &Dataset{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxReplayLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		var row map[string]any
+		if err := decoder.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to read JSONL dataset: line %d: %w", line, err)
+		}
+		d.rows = append(d.rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSONL dataset: %w", err)
+	}
+	return d, nil
+}
+
+// LoadDataset reads a ".csv", ".jsonl" or ".ndjson" dataset file.
+func LoadDataset(file string) (*Dataset, error) {
+	var read func(io.Reader) (*Dataset, error)
+	switch filepath.Ext(file) {
+	case ".csv":
+		read = ReadCSVDataset
+	case ".jsonl", ".ndjson":
+		read = ReadJSONLDataset
+	default:
+		return nil, fmt.Errorf("%s is not a .csv, .jsonl or .ndjson file", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	d, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return d, nil
+}
+
+// DatasetOption configures a DatasetPayloadGenerator.
+type DatasetOption func(*DatasetPayloadGenerator)
+
+// WithDatasetDeviceField partitions the rows per device: each device cycles
+// only through the rows whose field equals its ID.
+func WithDatasetDeviceField(field string) DatasetOption {
+	return func(g *DatasetPayloadGenerator) { g.deviceField = field }
+}
+
+// DatasetPayloadGenerator cycles through the rows of a Dataset, rendering each
+// into a payload schema. The schema is a payload template, as for
+// TemplatePayloadGenerator, with the row's values in .Row, e.g.
+//
+//	{"id":{{json .DeviceID}},"ts":"{{rfc3339 .Now}}","temp":{{.Row.temperature}}}
+//
+// An empty schema publishes each row as a JSON object. Every device starts at
+// the first of its rows and wraps around after the last.
+type DatasetPayloadGenerator struct {
+	dataset     *Dataset
+	tmpl        *payloadTemplate
+	deviceField string
+
+	mu      sync.Mutex
+	seq     map[string]uint64
+	byField map[string][]map[string]any
+}
+
+// NewDatasetPayloadGenerator creates a generator for the rows of dataset.
+func NewDatasetPayloadGenerator(dataset *Dataset, schema string, opts ...DatasetOption) (*DatasetPayloadGenerator, error) {
+	if dataset.Len() == 0 {
+		return nil, errors.New("dataset has no rows")
+	}
+	g := &DatasetPayloadGenerator{dataset: dataset, seq: make(map[string]uint64)}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if schema != "" {
+		tmpl, err := parsePayloadTemplate(schema)
+		if err != nil {
+			return nil, err
+		}
+		g.tmpl = tmpl
+	}
+	if g.deviceField != "" {
+		g.byField = make(map[string][]map[string]any)
+		for _, row := range dataset.rows {
+			id := fmt.Sprint(row[g.deviceField])
+			g.byField[id] = append(g.byField[id], row)
+		}
+	}
+	return g, nil
+}
+
+// GeneratePayload renders the next row of device.
+func (g *DatasetPayloadGenerator) GeneratePayload(device *Device) ([]byte, error) {
+	rows := g.dataset.rows
+	if g.byField != nil {
+		rows = g.byField[device.ID]
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("dataset has no rows with %s %q", g.deviceField, device.ID)
+		}
+	}
+	g.mu.Lock()
+	g.seq[device.ID]++
+	seq := g.seq[device.ID]
+	g.mu.Unlock()
+
+	row := rows[(seq-1)%uint64(len(rows))]
+	if g.tmpl == nil {
+		return json.Marshal(row)
+	}
+	return g.tmpl.render(TemplateData{Device: device, DeviceID: device.ID, Seq: seq, Now: time.Now(), Row: row})
+}
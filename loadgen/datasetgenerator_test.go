@@ -0,0 +1,84 @@
+package loadgen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sensorCSV = `device,temperature,humidity
+sensor-a,21.5,40
+sensor-b,18.0,55
+sensor-a,22.1,41
+`
+
+func TestDatasetPayloadGenerator_CSV(t *testing.T) {
+	dataset, err := loadgen.ReadCSVDataset(strings.NewReader(sensorCSV))
+	require.NoError(t, err)
+	require.Equal(t, 3, dataset.Len())
+
+	gen, err := loadgen.NewDatasetPayloadGenerator(dataset, `{"id":{{json .DeviceID}},"seq":{{.Seq}},"temp":{{.Row.temperature}}}`)
+	require.NoError(t, err)
+	device := &loadgen.Device{ID: "replayer"}
+	var got []string
+	for range 4 {
+		payload, err := gen.GeneratePayload(device)
+		require.NoError(t, err)
+		got = append(got, string(payload))
+	}
+	assert.Equal(t, []string{
+		`{"id":"replayer","seq":1,"temp":21.5}`,
+		`{"id":"replayer","seq":2,"temp":18.0}`,
+		`{"id":"replayer","seq":3,"temp":22.1}`,
+		`{"id":"replayer","seq":4,"temp":21.5}`,
+	}, got)
+}
+
+func TestDatasetPayloadGenerator_PartitionedJSONL(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "readings.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte(`{"device":"sensor-a","temperature":21.50}
+{"device":"sensor-b","temperature":18}
+
+{"device":"sensor-a","temperature":22.1}
+`), 0o644))
+	dataset, err := loadgen.LoadDataset(file)
+	require.NoError(t, err)
+
+	gen, err := loadgen.NewDatasetPayloadGenerator(dataset, "", loadgen.WithDatasetDeviceField("device"))
+	require.NoError(t, err)
+	a, b := &loadgen.Device{ID: "sensor-a"}, &loadgen.Device{ID: "sensor-b"}
+	next := func(device *loadgen.Device) string {
+		payload, err := gen.GeneratePayload(device)
+		require.NoError(t, err)
+		return string(payload)
+	}
+	assert.Equal(t, `{"device":"sensor-a","temperature":21.50}`, next(a))
+	assert.Equal(t, `{"device":"sensor-b","temperature":18}`, next(b))
+	assert.Equal(t, `{"device":"sensor-a","temperature":22.1}`, next(a))
+	assert.Equal(t, `{"device":"sensor-b","temperature":18}`, next(b))
+	assert.Equal(t, `{"device":"sensor-a","temperature":21.50}`, next(a))
+
+	_, err = gen.GeneratePayload(&loadgen.Device{ID: "sensor-c"})
+	assert.ErrorContains(t, err, `dataset has no rows with device "sensor-c"`)
+}
+
+func TestDataset_Errors(t *testing.T) {
+	_, err := loadgen.ReadCSVDataset(strings.NewReader(""))
+	assert.ErrorContains(t, err, "CSV dataset has no header")
+	_, err = loadgen.ReadCSVDataset(strings.NewReader("a,b\n1\n"))
+	assert.ErrorContains(t, err, "failed to read CSV dataset")
+	_, err = loadgen.ReadJSONLDataset(strings.NewReader("{\"a\":1}\n[1]\n"))
+	assert.ErrorContains(t, err, "line 2")
+	_, err = loadgen.LoadDataset("readings.txt")
+	assert.ErrorContains(t, err, "is not a .csv, .jsonl or .ndjson file")
+
+	empty, err := loadgen.ReadCSVDataset(strings.NewReader("a,b\n"))
+	require.NoError(t, err)
+	_, err = loadgen.NewDatasetPayloadGenerator(empty, "")
+	assert.ErrorContains(t, err, "dataset has no rows")
+}
@@ -0,0 +1,134 @@
+// loadgen/deviceerrors.go
+
+package loadgen
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// errNotAcknowledged is the error recorded for a publish that failed
+// without one, e.g. one the broker did not acknowledge.
+var errNotAcknowledged = errors.New("publish not acknowledged")
+
+// DeviceErrors are the failed publishes of a device in a run, so failures
+// stay attributable in a large fleet.
+type DeviceErrors struct {
+	DeviceID string
+	// Failures is the number of the device's publishes that failed.
+	Failures int
+	// ConsecutiveFailures is the number of failures the device ended the
+	// run on, 0 if its last publish succeeded, and MaxConsecutiveFailures
+	// its longest run of them.
+	ConsecutiveFailures    int
+	MaxConsecutiveFailures int
+	// FirstError and LastError are the errors of its first and last
+	// failures, and FirstErrorAt and LastErrorAt when they happened.
+	FirstError   error
+	FirstErrorAt time.Time
+	LastError    error
+	LastErrorAt  time.Time
+}
+
+// SetFailureLogThreshold makes a run log a device's failures at WARN only
+// once it has failed n times in a row, and at INFO when it recovers, rather
+// than logging every failure at ERROR, so one flaky device out of 10k does
+// not flood the log. Each failure is still logged at DEBUG. Results reports
+// the failures of every device either way. n <= 0 restores logging every
+// failure.
+func (lg *LoadGenerator) SetFailureLogThreshold(n int) {
+	lg.failureLogThreshold = n
+}
+
+// deviceErrorStats gathers the failures of a device during a run.
+type deviceErrorStats struct {
+	mu     sync.Mutex
+	errors DeviceErrors
+	// warned is set once the current run of failures has been logged.
+	warned bool
+}
+
+// newDeviceErrorStats returns empty stats for each device, by ID.
+func newDeviceErrorStats(devices []*Device) map[string]*deviceErrorStats {
+	stats := make(map[string]*deviceErrorStats, len(devices))
+	for _, device := range devices {
+		stats[device.ID] = &deviceErrorStats{errors: DeviceErrors{DeviceID: device.ID}}
+	}
+	return stats
+}
+
+// recordFailures records the outcome of a measured publish of device and
+// logs it.
+func (lg *LoadGenerator) recordFailures(device *Device, success bool, err error) {
+	stats := lg.deviceErrors[device.ID]
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	e := &stats.errors
+	if success {
+		if stats.warned {
+			lg.logger.Info().Str("device_id", device.ID).Int("consecutive_failures", e.ConsecutiveFailures).Msg("Device recovered.")
+		}
+		e.ConsecutiveFailures, stats.warned = 0, false
+		return
+	}
+
+	cause := err
+	if cause == nil {
+		cause = errNotAcknowledged
+	}
+	now := time.Now()
+	if e.Failures == 0 {
+		e.FirstError, e.FirstErrorAt = cause, now
+	}
+	e.LastError, e.LastErrorAt = cause, now
+	e.Failures++
+	e.ConsecutiveFailures++
+	e.MaxConsecutiveFailures = max(e.MaxConsecutiveFailures, e.ConsecutiveFailures)
+
+	threshold := lg.failureLogThreshold
+	switch {
+	case threshold <= 0 && err != nil:
+		lg.logger.Error().Err(err).Str("device_id", device.ID).Msg("Failed to publish message.")
+	case threshold <= 0:
+		// Unacknowledged publishes carry no error to log.
+	case e.ConsecutiveFailures >= threshold && !stats.warned:
+		stats.warned = true
+		lg.logger.Warn().Err(cause).Str("device_id", device.ID).Int("consecutive_failures", e.ConsecutiveFailures).
+			AnErr("first_error", e.FirstError).Int("failures", e.Failures).Msg("Device keeps failing to publish.")
+	default:
+		lg.logger.Debug().Err(cause).Str("device_id", device.ID).Int("consecutive_failures", e.ConsecutiveFailures).Msg("Failed to publish message.")
+	}
+}
+
+// logUncounted logs a failed publish of device left out of the run's
+// counts: one made during the warm-up or cut short by the end of the run.
+func (lg *LoadGenerator) logUncounted(device *Device, err error) {
+	if err == nil {
+		return
+	}
+	if lg.failureLogThreshold <= 0 {
+		lg.logger.Error().Err(err).Str("device_id", device.ID).Msg("Failed to publish message.")
+		return
+	}
+	lg.logger.Debug().Err(err).Str("device_id", device.ID).Msg("Failed to publish message.")
+}
+
+// failingDevices returns the errors of the devices with failures, most
+// failures first.
+func failingDevices(stats map[string]*deviceErrorStats) []DeviceErrors {
+	var failing []DeviceErrors
+	for _, s := range stats {
+		s.mu.Lock()
+		if s.errors.Failures > 0 {
+			failing = append(failing, s.errors)
+		}
+		s.mu.Unlock()
+	}
+	slices.SortFunc(failing, func(a, b DeviceErrors) int {
+		return cmp.Or(cmp.Compare(b.Failures, a.Failures), cmp.Compare(a.DeviceID, b.DeviceID))
+	})
+	return failing
+}
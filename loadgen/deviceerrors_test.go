@@ -0,0 +1,126 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingDeviceClient is a Client whose publishes of device-1 always fail,
+// and of device-2 fail three times before they succeed.
+type failingDeviceClient struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (c *failingDeviceClient) Connect() error { return nil }
+
+func (c *failingDeviceClient) Disconnect() {}
+
+func (c *failingDeviceClient) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts[device.ID]++
+	switch {
+	case device.ID == "device-1":
+		return false, errors.New("connection refused")
+	case device.ID == "device-2" && c.attempts[device.ID] <= 3:
+		return false, errors.New("timeout")
+	}
+	return true, nil
+}
+
+// logLines returns the JSON log lines written to buf at level.
+func logLines(t *testing.T, buf *bytes.Buffer, level string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["level"] == level {
+			lines = append(lines, entry)
+		}
+	}
+	return lines
+}
+
+func TestLoadGenerator_DeviceErrors(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(3).WithRate(100).Build()
+	require.NoError(t, err)
+	// The publish workers log concurrently; the buffer is read once the run
+	// has returned.
+	var logs bytes.Buffer
+	lg := loadgen.NewLoadGenerator(&failingDeviceClient{attempts: make(map[string]int)}, devices, zerolog.New(zerolog.SyncWriter(&logs)).Level(zerolog.InfoLevel))
+	lg.SetFailureLogThreshold(3)
+
+	result, err := lg.RunUntil(context.Background(), 100*time.Millisecond)
+	require.NoError(t, err)
+
+	results := lg.Results()
+	require.Len(t, results.DeviceErrors, 2)
+	broken, flaky := results.DeviceErrors[0], results.DeviceErrors[1]
+	assert.Equal(t, "device-1", broken.DeviceID)
+	assert.Equal(t, result.Failed-3, broken.Failures)
+	assert.Equal(t, broken.Failures, broken.ConsecutiveFailures)
+	assert.Equal(t, broken.Failures, broken.MaxConsecutiveFailures)
+	assert.EqualError(t, broken.FirstError, "connection refused")
+	assert.False(t, broken.LastErrorAt.Before(broken.FirstErrorAt))
+
+	assert.Equal(t, "device-2", flaky.DeviceID)
+	assert.Equal(t, 3, flaky.Failures)
+	assert.Zero(t, flaky.ConsecutiveFailures)
+	assert.Equal(t, 3, flaky.MaxConsecutiveFailures)
+	assert.EqualError(t, flaky.LastError, "timeout")
+
+	// Each device's run of failures is logged once, and device-2's recovery.
+	assert.Empty(t, logLines(t, &logs, "error"))
+	warnings := logLines(t, &logs, "warn")
+	require.Len(t, warnings, 2)
+	assert.ElementsMatch(t, []any{"device-1", "device-2"}, []any{warnings[0]["device_id"], warnings[1]["device_id"]})
+	assert.Equal(t, float64(3), warnings[0]["consecutive_failures"])
+	var recovered []any
+	for _, line := range logLines(t, &logs, "info") {
+		if line["message"] == "Device recovered." {
+			recovered = append(recovered, line["device_id"])
+		}
+	}
+	assert.Equal(t, []any{"device-2"}, recovered)
+
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteJSON(&buf))
+	var decoded struct {
+		DeviceErrors []map[string]any `json:"device_errors"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded.DeviceErrors, 2)
+	assert.Equal(t, "device-1", decoded.DeviceErrors[0]["device_id"])
+	assert.Equal(t, "connection refused", decoded.DeviceErrors[0]["last_error"])
+
+	summary := results.Summary()
+	assert.Contains(t, summary, "Failing devices: 2")
+	assert.Regexp(t, `device-2\s+3\s+0\s+timeout`, summary)
+}
+
+func TestLoadGenerator_DeviceErrorsLogEveryFailureByDefault(t *testing.T) {
+	devices := []*loadgen.Device{{ID: "device-1", MessageRate: 100}}
+	var logs bytes.Buffer
+	lg := loadgen.NewLoadGenerator(&failingDeviceClient{attempts: make(map[string]int)}, devices, zerolog.New(zerolog.SyncWriter(&logs)))
+
+	result, err := lg.RunUntil(context.Background(), 50*time.Millisecond)
+	require.NoError(t, err)
+	// A publish cut short by the end of the run is logged but not counted.
+	errorLines := len(logLines(t, &logs, "error"))
+	assert.GreaterOrEqual(t, errorLines, result.Failed)
+	assert.LessOrEqual(t, errorLines, result.Failed+1)
+	assert.Empty(t, logLines(t, &logs, "warn"))
+}
@@ -0,0 +1,113 @@
+// loadgen/downlink.go
+
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// CommandHandler handles a command sent to device on topic. The reply it
+// returns, if not nil, is published to the device's reply topic.
+type CommandHandler func(device *Device, topic string, payload []byte) (reply []byte, err error)
+
+// AckCommands returns a CommandHandler that replies to every command with
+// ack.
+func AckCommands(ack []byte) CommandHandler {
+	return func(*Device, string, []byte) ([]byte, error) {
+		return ack, nil
+	}
+}
+
+// DownlinkConfig makes the devices of an MqttDeviceClient subscribe to their
+// command topics, so the downlink side of bidirectional device protocols is
+// load tested too.
+type DownlinkConfig struct {
+	// CommandTopic is the TopicTemplate pattern of each device's command
+	// topic, e.g. "devices/{deviceID}/commands". It may hold MQTT wildcards.
+	CommandTopic string
+	// ReplyTopic is the TopicTemplate pattern that replies are published to,
+	// e.g. "devices/{deviceID}/acks". Without it, replies are not sent.
+	ReplyTopic string
+	// QoS is the QoS of the command subscriptions and of the replies.
+	QoS byte
+	// Handler is called for every command a device receives. It is called
+	// concurrently for different devices.
+	Handler CommandHandler
+}
+
+// downlink subscribes the devices of an MqttDeviceClient to their commands.
+type downlink struct {
+	cfg      DownlinkConfig
+	commands *TopicTemplate
+	replies  *TopicTemplate
+	client   *MqttDeviceClient
+}
+
+// newDownlink parses the topics of cfg.
+func newDownlink(cfg DownlinkConfig, client *MqttDeviceClient) (*downlink, error) {
+	if cfg.CommandTopic == "" || cfg.Handler == nil {
+		return nil, errors.New("downlink needs a command topic and a handler")
+	}
+	d := &downlink{cfg: cfg, client: client}
+	var err error
+	if d.commands, err = ParseTopicTemplate(cfg.CommandTopic); err != nil {
+		return nil, fmt.Errorf("invalid command topic: %w", err)
+	}
+	if cfg.ReplyTopic != "" {
+		if d.replies, err = ParseTopicTemplate(cfg.ReplyTopic); err != nil {
+			return nil, fmt.Errorf("invalid reply topic: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// subscribe subscribes conn, the connection of device, to its commands.
+func (d *downlink) subscribe(conn mqtt.Client, device *Device) error {
+	topic, err := d.commands.Expand(device)
+	if err != nil {
+		return fmt.Errorf("failed to build command topic for device %s: %w", device.ID, err)
+	}
+	token := conn.Subscribe(topic, d.cfg.QoS, func(conn mqtt.Client, msg mqtt.Message) {
+		d.handle(conn, device, msg)
+	})
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out subscribing device %s to %s", device.ID, topic)
+	}
+	if token.Error() != nil {
+		return fmt.Errorf("failed to subscribe device %s to %s: %w", device.ID, topic, token.Error())
+	}
+	return nil
+}
+
+// handle passes a command to the handler and publishes its reply.
+func (d *downlink) handle(conn mqtt.Client, device *Device, msg mqtt.Message) {
+	logger := d.client.logger
+	reply, err := d.cfg.Handler(device, msg.Topic(), msg.Payload())
+	if err != nil {
+		logger.Warn().Err(err).Str("device_id", device.ID).Str("topic", msg.Topic()).Msg("Command handler failed")
+		return
+	}
+	logger.Debug().Str("device_id", device.ID).Str("topic", msg.Topic()).Msg("Command handled")
+	if reply == nil || d.replies == nil {
+		return
+	}
+	topic, err := d.replies.Expand(device)
+	if err != nil {
+		logger.Warn().Err(err).Str("device_id", device.ID).Msg("Failed to build reply topic")
+		return
+	}
+	// Waiting here would block the connection's delivery of messages, so the
+	// acknowledgement is awaited apart.
+	token := conn.Publish(topic, d.cfg.QoS, false, reply)
+	go func() {
+		if !token.WaitTimeout(d.client.settings.ackTimeout(d.cfg.QoS)) {
+			logger.Warn().Str("device_id", device.ID).Str("topic", topic).Msg("Timed out sending command reply")
+		} else if token.Error() != nil {
+			logger.Warn().Err(token.Error()).Str("device_id", device.ID).Str("topic", topic).Msg("Failed to send command reply")
+		}
+	}()
+}
@@ -0,0 +1,59 @@
+// loadgen/drain.go
+
+package loadgen
+
+import (
+	"context"
+	"time"
+)
+
+// SetDrainTimeout gives the publishes in flight when a run ends up to d to
+// complete before the client disconnects, so the end of a run does not cut
+// off messages awaiting their acknowledgement. Publishes that complete
+// during the drain are counted as usual, and those still in flight when it
+// times out are cancelled and left out of the counts. No new messages are
+// sent once the run has ended. d <= 0, the default, cancels the publishes in
+// flight as soon as the run ends.
+func (lg *LoadGenerator) SetDrainTimeout(d time.Duration) {
+	lg.drainTimeout = d
+}
+
+// RunUntilCancelled runs the load test until ctx is cancelled or one of
+// conditions is met, with no time limit, e.g. for a soak test stopped by an
+// operator or a signal handler. The run's publishes in flight when it ends
+// are given the drain timeout set by SetDrainTimeout to complete.
+func (lg *LoadGenerator) RunUntilCancelled(ctx context.Context, conditions ...StopCondition) (RunResult, error) {
+	return lg.run(ctx, 0, nil, conditions)
+}
+
+// drainContext returns the context for the publishes of the run whose
+// context is runCtx, and a function to call once they have all returned.
+// Without a drain timeout it is runCtx. Otherwise it keeps runCtx's values
+// but outlives it by up to the timeout.
+func (lg *LoadGenerator) drainContext(runCtx context.Context) (context.Context, func()) {
+	if lg.drainTimeout <= 0 {
+		return runCtx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(runCtx))
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-runCtx.Done():
+		case <-done:
+			return
+		}
+		lg.logger.Debug().Dur("drain_timeout", lg.drainTimeout).Msg("Draining publishes in flight.")
+		timer := time.NewTimer(lg.drainTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			lg.logger.Warn().Dur("drain_timeout", lg.drainTimeout).Msg("Drain timed out, cancelling publishes in flight.")
+			cancel(context.Cause(runCtx))
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
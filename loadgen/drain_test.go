@@ -0,0 +1,118 @@
+package loadgen_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowClient is a Client whose publishes take delay to be acknowledged,
+// unless their context is done first. It records publishes acknowledged
+// after it disconnected, and the most it had in flight at once.
+type slowClient struct {
+	delay time.Duration
+
+	mu           sync.Mutex
+	disconnected bool
+	acked        int
+	ackedLate    int
+	inFlight     int
+	peakInFlight int
+}
+
+func (c *slowClient) Connect() error { return nil }
+
+func (c *slowClient) Disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = true
+}
+
+func (c *slowClient) Publish(ctx context.Context, _ *loadgen.Device) (bool, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.peakInFlight = max(c.peakInFlight, c.inFlight)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked++
+	if c.disconnected {
+		c.ackedLate++
+	}
+	return true, nil
+}
+
+func TestLoadGenerator_RunUntilCancelled(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(5).WithRate(100).Build()
+	require.NoError(t, err)
+	// Each device's first publish is still in flight when the run is
+	// cancelled.
+	run := func(t *testing.T, delay, drain time.Duration) (loadgen.RunResult, *slowClient, time.Duration) {
+		client := &slowClient{delay: delay}
+		lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+		lg.SetDrainTimeout(drain)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		began := time.Now()
+		result, err := lg.RunUntilCancelled(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopContext, result.StoppedBy)
+		return result, client, time.Since(began)
+	}
+
+	t.Run("without drain", func(t *testing.T) {
+		result, client, took := run(t, 200*time.Millisecond, 0)
+		assert.Zero(t, result.Published)
+		assert.Zero(t, result.Failed, "publishes cut short are not failures")
+		assert.Zero(t, client.acked)
+		assert.Less(t, took, 150*time.Millisecond)
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		result, client, took := run(t, 200*time.Millisecond, time.Second)
+		assert.Equal(t, 5, result.Published)
+		assert.Zero(t, result.Failed)
+		assert.Equal(t, 5, client.acked)
+		assert.Zero(t, client.ackedLate, "publishes drain before the client disconnects")
+		assert.GreaterOrEqual(t, took, 200*time.Millisecond)
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		result, client, took := run(t, time.Second, 50*time.Millisecond)
+		assert.Zero(t, result.Published)
+		assert.Zero(t, result.Failed)
+		assert.Zero(t, client.acked)
+		assert.GreaterOrEqual(t, took, 100*time.Millisecond)
+		assert.Less(t, took, 500*time.Millisecond)
+	})
+}
+
+func TestLoadGenerator_DrainSendsNoNewMessages(t *testing.T) {
+	// Bursts of 3 that take 30ms a message: the run ends during the first.
+	devices := []*loadgen.Device{{ID: "device-1", Burst: &loadgen.Burst{Size: 3, Interval: time.Second}}}
+	client := &slowClient{delay: 30 * time.Millisecond}
+	lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+	lg.SetDrainTimeout(time.Second)
+
+	result, err := lg.RunUntil(context.Background(), 45*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+	assert.Equal(t, 2, result.Published, "the publish in flight completes, the third is not sent")
+}
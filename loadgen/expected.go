@@ -0,0 +1,185 @@
+// loadgen/expected.go
+
+package loadgen
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"time"
+)
+
+// poissonBound is the number of standard deviations either side of their
+// mean that an expected range allows counts under PoissonTiming. A run falls
+// outside about once in 16,000.
+const poissonBound = 4
+
+// MessageRange is a range of message counts, Min to Max inclusive. A Max of
+// math.MaxInt means there is no upper bound.
+type MessageRange struct {
+	Min, Max int
+}
+
+// Contains reports whether n is within the range.
+func (r MessageRange) Contains(n int) bool {
+	return n >= r.Min && n <= r.Max
+}
+
+func (r MessageRange) String() string {
+	switch r.Max {
+	case math.MaxInt:
+		return fmt.Sprintf("%d+", r.Min)
+	case r.Min:
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// ExpectedRange returns the range of message counts a run of duration
+// publishes, after any warm-up, for verification code to assert against
+// when the devices' schedules are not deterministic. It covers every device
+// profile:
+//
+//   - FixedTiming devices publish exactly ExpectedMessagesForDuration, or one
+//     message more or less each after a warm-up, whose end can fall anywhere
+//     in their schedules.
+//   - JitteredTiming devices publish between the counts of their longest and
+//     shortest waits. A jitter of 1 allows waits of zero, so no upper bound.
+//   - PoissonTiming devices publish within four standard deviations of their
+//     mean count.
+//   - Timings of your own have no upper bound.
+//   - Devices with a Burst count Burst.Size messages per send.
+//
+// Rates set with SetRate are taken into account, and SetRateLimit caps the
+// upper bound. The range assumes the client keeps up, so slow publishes and
+// failures can leave a run below it.
+func (lg *LoadGenerator) ExpectedRange(duration time.Duration) MessageRange {
+	var sum rangeSum
+	for _, device := range lg.devices {
+		interval := lg.interval(device)
+		if interval <= 0 || device.Burst != nil && device.Burst.Size <= 0 {
+			continue
+		}
+		sum.add(device, float64(duration)/float64(interval), 0, lg.warmup == 0)
+	}
+	return lg.capRange(sum.result(), duration)
+}
+
+// ExpectedScenarioRange returns the ranges of message counts RunScenario
+// publishes for scenario, in all and in each phase, as ExpectedRange does
+// for a run. A phase's range allows one send per device more or less at its
+// boundaries, where it races the phase change, and a ramp's allows for it
+// changing the rate in steps.
+func (lg *LoadGenerator) ExpectedScenarioRange(scenario Scenario) (MessageRange, []MessageRange) {
+	sr := lg.newScenarioRun(scenario)
+	rates := make(map[string]float64, len(sr.running))
+	for _, device := range sr.running {
+		rates[device.ID] = sr.weights[device.ID] / float64(burstSize(device))
+	}
+
+	var total rangeSum
+	phases := make([]MessageRange, len(scenario.Phases))
+	fromSend := lg.warmup == 0
+	for i, p := range scenario.Phases {
+		before := maps.Clone(rates)
+		switch p.Kind {
+		case PhaseRamp:
+			maps.Copy(rates, sr.share(p.Rate))
+		case PhaseKill:
+			maps.Copy(rates, sr.stop(p.Fraction))
+		}
+		var phase rangeSum
+		for _, device := range lg.devices {
+			from, to := before[device.ID], rates[device.ID]
+			if from == 0 && to == 0 || device.Burst != nil && device.Burst.Size <= 0 {
+				continue
+			}
+			if p.Kind != PhaseRamp || p.Duration == 0 {
+				from = to
+			}
+			sends := (from + to) / 2 * p.Duration.Seconds()
+			slack := 1 + math.Abs(to-from)*rampStep(p.Duration).Seconds()
+			phase.add(device, sends, slack, fromSend)
+		}
+		phases[i] = lg.capRange(phase.result(), p.Duration)
+		total.merge(phase)
+		fromSend = false
+	}
+	return lg.capRange(total.result(), scenario.Duration()), phases
+}
+
+// capRange caps r at what the fleet's rate limit lets through in duration.
+func (lg *LoadGenerator) capRange(r MessageRange, duration time.Duration) MessageRange {
+	if lg.rateLimit <= 0 {
+		return r
+	}
+	r.Max = min(r.Max, lg.rateBurst+int(math.Ceil(lg.rateLimit*duration.Seconds())))
+	r.Min = min(r.Min, r.Max)
+	return r
+}
+
+// rangeSum adds up the bounds of devices' message counts. Counts under
+// PoissonTiming are bounded together, by their mean and variance.
+type rangeSum struct {
+	min, max        float64
+	unbounded       bool
+	poissonMean     float64
+	poissonVariance float64
+}
+
+// add adds the count of device over a window in which it is expected to make
+// sends sends, give or take slack. fromSend says the window starts with one
+// of its sends, as a run does; otherwise it may start anywhere in its
+// schedule.
+func (s *rangeSum) add(device *Device, sends, slack float64, fromSend bool) {
+	size := float64(burstSize(device))
+	first, extra := 0.0, 1.0
+	if fromSend {
+		first, extra = 1, 0
+	}
+	var low, high float64
+	switch t := device.Timing.(type) {
+	case nil, fixedTiming:
+		low, high = first+math.Floor(sends), first+math.Floor(sends)+extra
+	case jitteredTiming:
+		low = first + math.Floor(sends/(1+t.jitter))
+		if t.jitter < 1 {
+			high = first + math.Floor(sends/(1-t.jitter)) + extra
+		} else {
+			s.unbounded = true
+		}
+	case poissonTiming:
+		// Poisson arrivals have no schedule for a window to start within.
+		low, high = first, first
+		s.poissonMean += sends * size
+		s.poissonVariance += sends * size * size
+	default:
+		low = first
+		s.unbounded = true
+	}
+	s.min += max(low-slack, 0) * size
+	s.max += (high + slack) * size
+}
+
+// merge adds the bounds of o.
+func (s *rangeSum) merge(o rangeSum) {
+	s.min += o.min
+	s.max += o.max
+	s.unbounded = s.unbounded || o.unbounded
+	s.poissonMean += o.poissonMean
+	s.poissonVariance += o.poissonVariance
+}
+
+// result returns the range of the sum.
+func (s rangeSum) result() MessageRange {
+	deviation := poissonBound * math.Sqrt(s.poissonVariance)
+	r := MessageRange{
+		Min: int(max(math.Floor(s.min+s.poissonMean-deviation), s.min)),
+		Max: math.MaxInt,
+	}
+	if !s.unbounded {
+		r.Max = int(math.Ceil(s.max + s.poissonMean + deviation))
+	}
+	return r
+}
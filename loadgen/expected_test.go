@@ -0,0 +1,122 @@
+package loadgen_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRange(t *testing.T) {
+	r := loadgen.MessageRange{Min: 7, Max: 21}
+	assert.True(t, r.Contains(7))
+	assert.True(t, r.Contains(21))
+	assert.False(t, r.Contains(6))
+	assert.False(t, r.Contains(22))
+	assert.Equal(t, "7-21", r.String())
+	assert.Equal(t, "7", loadgen.MessageRange{Min: 7, Max: 7}.String())
+	assert.Equal(t, "7+", loadgen.MessageRange{Min: 7, Max: math.MaxInt}.String())
+}
+
+func TestLoadGenerator_ExpectedRange(t *testing.T) {
+	newLG := func(devices ...*loadgen.Device) *loadgen.LoadGenerator {
+		return loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+	}
+
+	t.Run("fixed", func(t *testing.T) {
+		lg := newLG(&loadgen.Device{ID: "d1", MessageRate: 10}, &loadgen.Device{ID: "d2", MessageRate: 3})
+		want := lg.ExpectedMessagesForDuration(time.Second)
+		assert.Equal(t, loadgen.MessageRange{Min: want, Max: want}, lg.ExpectedRange(time.Second))
+
+		lg.SetWarmupDuration(time.Second)
+		assert.Equal(t, loadgen.MessageRange{Min: want - 2, Max: want}, lg.ExpectedRange(time.Second))
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		// Waits of 50ms to 150ms over 1s: 1+6 to 1+20 messages.
+		lg := newLG(&loadgen.Device{ID: "d1", MessageRate: 10, Timing: loadgen.JitteredTiming(0.5)})
+		assert.Equal(t, loadgen.MessageRange{Min: 7, Max: 21}, lg.ExpectedRange(time.Second))
+
+		lg = newLG(&loadgen.Device{ID: "d1", MessageRate: 10, Timing: loadgen.JitteredTiming(1)})
+		assert.Equal(t, loadgen.MessageRange{Min: 6, Max: math.MaxInt}, lg.ExpectedRange(time.Second))
+	})
+
+	t.Run("poisson", func(t *testing.T) {
+		// 100 devices expect 1 + 10 messages each, with a variance of 1000
+		// in all.
+		devices, err := loadgen.NewFleet().WithCount(100).WithRate(10).WithTiming(loadgen.PoissonTiming()).Build()
+		require.NoError(t, err)
+		r := newLG(devices...).ExpectedRange(time.Second)
+		deviation := 4 * math.Sqrt(1000)
+		assert.Equal(t, int(math.Floor(1100-deviation)), r.Min)
+		assert.Equal(t, int(math.Ceil(1100+deviation)), r.Max)
+	})
+
+	t.Run("burst", func(t *testing.T) {
+		lg := newLG(&loadgen.Device{ID: "d1", Burst: &loadgen.Burst{Size: 5, Interval: 100 * time.Millisecond}, Timing: loadgen.JitteredTiming(0.5)})
+		assert.Equal(t, loadgen.MessageRange{Min: 35, Max: 105}, lg.ExpectedRange(time.Second))
+	})
+
+	t.Run("SetRate and rate limit", func(t *testing.T) {
+		lg := newLG(&loadgen.Device{ID: "d1", MessageRate: 10}, &loadgen.Device{ID: "d2", MessageRate: 10})
+		require.NoError(t, lg.SetRate("d2", 0))
+		assert.Equal(t, loadgen.MessageRange{Min: 11, Max: 11}, lg.ExpectedRange(time.Second))
+
+		lg.SetRateLimit(5, 2)
+		assert.Equal(t, loadgen.MessageRange{Min: 7, Max: 7}, lg.ExpectedRange(time.Second))
+	})
+}
+
+func TestLoadGenerator_ExpectedRangeHolds(t *testing.T) {
+	loadgen.SetSeed(42)
+	defer loadgen.ClearSeed()
+	devices, err := loadgen.NewFleet().
+		WithCount(5).WithRate(20).WithTiming(loadgen.JitteredTiming(0.3)).
+		AddGroup().WithCount(5).WithTiming(loadgen.PoissonTiming()).
+		AddGroup().WithCount(2).WithBurst(loadgen.Burst{Size: 3, Interval: 50 * time.Millisecond}).WithTiming(nil).
+		Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	want := lg.ExpectedRange(500 * time.Millisecond)
+	result, err := lg.RunUntil(context.Background(), 500*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, want.Contains(result.Published), "published %d, expected %s", result.Published, want)
+}
+
+func TestLoadGenerator_ExpectedScenarioRange(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(10).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	scenario := loadgen.Scenario{Phases: []loadgen.Phase{
+		loadgen.Hold(300 * time.Millisecond),
+		loadgen.RampTo(300, 300*time.Millisecond),
+		loadgen.KillDevices(0.5),
+		loadgen.Hold(300 * time.Millisecond),
+	}}
+	total, phases := lg.ExpectedScenarioRange(scenario)
+	require.Len(t, phases, 4)
+	// The hold starts with a send from each device: 1 + 3 each, give or
+	// take one at the phase's end.
+	assert.Equal(t, loadgen.MessageRange{Min: 30, Max: 50}, phases[0])
+	// Ramping from 10 to 30 messages per second each publishes about 6.
+	assert.True(t, phases[1].Contains(60), "ramp expected %s", phases[1])
+	// Five devices at 30 Hz for 300ms: 9 each, less one or more two as the
+	// window may start anywhere in their schedules.
+	assert.Equal(t, loadgen.MessageRange{Min: 40, Max: 55}, phases[3])
+	assert.InDelta(t, phases[0].Min+phases[1].Min+phases[2].Min+phases[3].Min, total.Min, 1)
+	assert.InDelta(t, phases[0].Max+phases[1].Max+phases[2].Max+phases[3].Max, total.Max, 1)
+
+	result, err := lg.RunScenario(context.Background(), scenario)
+	require.NoError(t, err)
+	assert.True(t, total.Contains(result.Published), "published %d, expected %s", result.Published, total)
+	for i, p := range result.Phases {
+		assert.True(t, phases[i].Contains(p.Published), "phase %d published %d, expected %s", i, p.Published, phases[i])
+	}
+}
@@ -0,0 +1,189 @@
+// loadgen/fleet.go
+
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// GeneratorFactory returns the PayloadGenerator of the device with id. It
+// may return the same generator for every device.
+type GeneratorFactory func(id string) PayloadGenerator
+
+// FleetBuilder builds the devices of a load test in groups that share their
+// settings, instead of a hand-written loop per test:
+//
+//	devices, err := loadgen.NewFleet().
+//		WithCount(9000).WithIDPattern("sensor-%05d").WithRate(0.1).WithGenerator(newSensor).
+//		AddGroup().WithCount(1000).WithIDPattern("gateway-%04d").WithRate(1).
+//		Build()
+//
+// A group starts with the settings of the group before it, so it only needs
+// to override what differs. Devices are numbered across the whole fleet,
+// from 0, and their IDs are formatted from the number with the ID pattern.
+type FleetBuilder struct {
+	groups []*fleetGroup
+}
+
+// fleetGroup is a group of devices of a fleet.
+type fleetGroup struct {
+	count     int
+	idPattern string
+	rate      float64
+	generator GeneratorFactory
+	timing    Timing
+	burst     *Burst
+	metadata  map[string]string
+	headers   map[string]string
+	topic     string
+	qos       *byte
+	customize []func(index int, device *Device)
+}
+
+// NewFleet starts a fleet with one group of devices named "device-<n>".
+func NewFleet() *FleetBuilder {
+	return &FleetBuilder{groups: []*fleetGroup{{idPattern: "device-%d"}}}
+}
+
+// group returns the group being configured.
+func (f *FleetBuilder) group() *fleetGroup {
+	return f.groups[len(f.groups)-1]
+}
+
+// AddGroup starts a new group with the settings of the current one.
+func (f *FleetBuilder) AddGroup() *FleetBuilder {
+	g := *f.group()
+	g.customize = append([]func(int, *Device){}, g.customize...)
+	f.groups = append(f.groups, &g)
+	return f
+}
+
+// WithCount sets the number of devices of the group.
+func (f *FleetBuilder) WithCount(n int) *FleetBuilder {
+	f.group().count = n
+	return f
+}
+
+// WithIDPattern sets the fmt pattern that formats a device's number into
+// its ID, e.g. "dev-%05d".
+func (f *FleetBuilder) WithIDPattern(pattern string) *FleetBuilder {
+	f.group().idPattern = pattern
+	return f
+}
+
+// WithRate sets the message rate in Hz of each device of the group.
+func (f *FleetBuilder) WithRate(rate float64) *FleetBuilder {
+	f.group().rate = rate
+	return f
+}
+
+// WithGenerator sets the factory of the devices' payload generators.
+func (f *FleetBuilder) WithGenerator(factory GeneratorFactory) *FleetBuilder {
+	f.group().generator = factory
+	return f
+}
+
+// WithTiming sets the Timing of the devices.
+func (f *FleetBuilder) WithTiming(timing Timing) *FleetBuilder {
+	f.group().timing = timing
+	return f
+}
+
+// WithBurst makes the devices send bursts instead of single messages.
+func (f *FleetBuilder) WithBurst(burst Burst) *FleetBuilder {
+	f.group().burst = &burst
+	return f
+}
+
+// WithMetadata sets the devices' Metadata. Each device gets its own copy.
+func (f *FleetBuilder) WithMetadata(metadata map[string]string) *FleetBuilder {
+	f.group().metadata = metadata
+	return f
+}
+
+// WithHeaders sets the devices' Headers. Each device gets its own copy.
+func (f *FleetBuilder) WithHeaders(headers map[string]string) *FleetBuilder {
+	f.group().headers = headers
+	return f
+}
+
+// WithTopic sets the devices' MQTT topic override.
+func (f *FleetBuilder) WithTopic(topic string) *FleetBuilder {
+	f.group().topic = topic
+	return f
+}
+
+// WithQoS sets the devices' MQTT QoS override.
+func (f *FleetBuilder) WithQoS(qos byte) *FleetBuilder {
+	f.group().qos = &qos
+	return f
+}
+
+// WithDevice adds a function that customises each device of the group after
+// the other settings are applied. index is the device's fleet-wide number.
+func (f *FleetBuilder) WithDevice(customize func(index int, device *Device)) *FleetBuilder {
+	f.group().customize = append(f.group().customize, customize)
+	return f
+}
+
+// Build creates the devices of the fleet, group by group. It fails if a
+// group has a negative count or two devices would have the same ID.
+func (f *FleetBuilder) Build() ([]*Device, error) {
+	var devices []*Device
+	ids := make(map[string]bool)
+	index := 0
+	for n, g := range f.groups {
+		if g.count < 0 {
+			return nil, fmt.Errorf("fleet group %d has a negative count", n)
+		}
+		for range g.count {
+			device := g.device(index)
+			if strings.Contains(device.ID, "%!") {
+				return nil, fmt.Errorf("fleet group %d has an invalid ID pattern %q", n, g.idPattern)
+			}
+			if device.ID == "" {
+				return nil, fmt.Errorf("fleet group %d gives device %d an empty ID", n, index)
+			}
+			if ids[device.ID] {
+				return nil, fmt.Errorf("fleet group %d repeats device ID %s", n, device.ID)
+			}
+			ids[device.ID] = true
+			devices = append(devices, device)
+			index++
+		}
+	}
+	if len(devices) == 0 {
+		return nil, errors.New("fleet has no devices")
+	}
+	return devices, nil
+}
+
+// device creates the device numbered index.
+func (g *fleetGroup) device(index int) *Device {
+	device := &Device{
+		ID:          fmt.Sprintf(g.idPattern, index),
+		MessageRate: g.rate,
+		Timing:      g.timing,
+		Topic:       g.topic,
+		Metadata:    maps.Clone(g.metadata),
+		Headers:     maps.Clone(g.headers),
+	}
+	if g.generator != nil {
+		device.PayloadGenerator = g.generator(device.ID)
+	}
+	if g.burst != nil {
+		burst := *g.burst
+		device.Burst = &burst
+	}
+	if g.qos != nil {
+		qos := *g.qos
+		device.QoS = &qos
+	}
+	for _, customize := range g.customize {
+		customize(index, device)
+	}
+	return device
+}
@@ -0,0 +1,72 @@
+package loadgen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleetBuilder(t *testing.T) {
+	shared := staticPayloadGenerator("{}")
+	var factoryIDs []string
+	devices, err := loadgen.NewFleet().
+		WithCount(3).WithIDPattern("sensor-%03d").WithRate(0.1).
+		WithGenerator(func(id string) loadgen.PayloadGenerator {
+			factoryIDs = append(factoryIDs, id)
+			return shared
+		}).
+		WithMetadata(map[string]string{"type": "sensor"}).
+		WithTiming(loadgen.PoissonTiming()).
+		AddGroup().WithCount(2).WithIDPattern("gateway-%d").WithRate(1).WithQoS(2).
+		WithMetadata(map[string]string{"type": "gateway"}).
+		WithDevice(func(index int, device *loadgen.Device) {
+			device.Metadata["site"] = []string{"north", "south"}[index%2]
+		}).
+		AddGroup().WithCount(1).WithIDPattern("batcher-%d").WithBurst(loadgen.Burst{Size: 10, Interval: time.Minute}).
+		Build()
+	require.NoError(t, err)
+
+	var ids []string
+	for _, d := range devices {
+		ids = append(ids, d.ID)
+	}
+	assert.Equal(t, []string{"sensor-000", "sensor-001", "sensor-002", "gateway-3", "gateway-4", "batcher-5"}, ids)
+	assert.Equal(t, ids, factoryIDs, "every group inherits the generator factory")
+
+	sensor, gateway, batcher := devices[0], devices[3], devices[5]
+	assert.Equal(t, 0.1, sensor.MessageRate)
+	assert.Equal(t, map[string]string{"type": "sensor"}, sensor.Metadata)
+	assert.Equal(t, loadgen.PoissonTiming(), sensor.Timing)
+	assert.Nil(t, sensor.QoS)
+
+	assert.Equal(t, 1.0, gateway.MessageRate)
+	require.NotNil(t, gateway.QoS)
+	assert.Equal(t, byte(2), *gateway.QoS)
+	assert.Equal(t, map[string]string{"type": "gateway", "site": "south"}, gateway.Metadata)
+	assert.Equal(t, "north", devices[4].Metadata["site"], "devices get their own Metadata")
+
+	assert.Equal(t, &loadgen.Burst{Size: 10, Interval: time.Minute}, batcher.Burst)
+	assert.Equal(t, "south", batcher.Metadata["site"], "the customisation is inherited too")
+}
+
+func TestFleetBuilder_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fleet   *loadgen.FleetBuilder
+		wantErr string
+	}{
+		{"empty", loadgen.NewFleet(), "fleet has no devices"},
+		{"negative count", loadgen.NewFleet().WithCount(-1), "fleet group 0 has a negative count"},
+		{"no verb", loadgen.NewFleet().WithCount(2).WithIDPattern("sensor"), `fleet group 0 has an invalid ID pattern "sensor"`},
+		{"duplicate", loadgen.NewFleet().WithCount(2).WithDevice(func(_ int, d *loadgen.Device) { d.ID = "same" }), "fleet group 0 repeats device ID same"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.fleet.Build()
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
@@ -4,12 +4,14 @@ package loadgen
 
 import (
 	"context"
+	"errors"
+	"io"
 	"math"
-	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
 )
 
 // Device represents a single simulated device in the load test.
@@ -17,6 +19,25 @@ type Device struct {
 	ID               string
 	MessageRate      float64
 	PayloadGenerator PayloadGenerator
+	// Timing decides the wait between messages, with a mean of 1/MessageRate,
+	// or between bursts. Nil means FixedTiming.
+	Timing Timing
+	// Burst, when set, replaces MessageRate: the device sends bursts of
+	// messages instead of one message at a time.
+	Burst *Burst
+	// Metadata describes the device, e.g. its tenant or type. Topic templates
+	// refer to it by key, and PayloadGenerators can use it too.
+	Metadata map[string]string
+	// QoS, when set, overrides the client's MQTT QoS for this device.
+	QoS *byte
+	// Topic, when set, overrides the MQTT client's topic pattern for this
+	// device. It is a TopicTemplate pattern.
+	Topic string
+	// Headers are added to each message of the device, overriding the
+	// client's own: as HTTP headers, gRPC metadata, AMQP headers, Pub/Sub
+	// attributes or MQTT v5 user properties. The attributes of an
+	// AttributeGenerator override them in turn.
+	Headers map[string]string
 }
 
 // LoadGenerator orchestrates the load test.
@@ -25,6 +46,71 @@ type LoadGenerator struct {
 	devices        []*Device
 	logger         zerolog.Logger
 	publishedCount int64
+	failedCount    int64
+	// retriedCount is the number of publishes of the current run retried
+	// under the retry policy.
+	retriedCount int64
+	// exhaustedCount is the number of devices of the current run whose
+	// PayloadGenerator ran out of payloads.
+	exhaustedCount int64
+
+	// stop and cancelRun are the stop conditions and cancel function of the
+	// current run.
+	stop      stopConditions
+	cancelRun context.CancelCauseFunc
+
+	// progress is called every progressInterval during a run, if set.
+	progress         ProgressFunc
+	progressInterval time.Duration
+
+	// workers is the number of publishing goroutines set by SetWorkers.
+	workers int
+
+	// rateLimit and rateBurst are the fleet-wide cap set by SetRateLimit,
+	// and limiter enforces it during a run.
+	rateLimit float64
+	rateBurst int
+	limiter   *rate.Limiter
+
+	// retry is the policy set by SetRetryPolicy.
+	retry RetryPolicy
+
+	// warmup is the duration set by SetWarmupDuration, and measureFrom the
+	// end of the current run's warm-up.
+	warmup          time.Duration
+	measureFrom     time.Time
+	warmupPublished int64
+
+	// ctl holds the changes made by Pause, Resume and SetRate.
+	ctl control
+
+	// snapshots are the progress snapshots of the current run, and results
+	// the metrics of the last one.
+	snapshots []Snapshot
+	results   Results
+
+	// groups are the stats of the current run's device groups, also by
+	// device ID, and latency that of all its publishes.
+	groups         []*groupStats
+	groupsByDevice map[string]*groupStats
+	latency        *latencyHistogram
+
+	// failureLogThreshold is set by SetFailureLogThreshold, and
+	// deviceErrors are the failures of the current run's devices by ID.
+	failureLogThreshold int
+	deviceErrors        map[string]*deviceErrorStats
+
+	// profiling is set by SetProfiling.
+	profiling ProfilingConfig
+
+	// drainTimeout is set by SetDrainTimeout.
+	drainTimeout time.Duration
+
+	// maxInFlight and backpressurePolicy are set by SetMaxInFlight, and
+	// inFlight enforces them during a run.
+	maxInFlight        int
+	backpressurePolicy BackpressurePolicy
+	inFlight           *inFlightLimiter
 }
 
 // NewLoadGenerator creates a new LoadGenerator.
@@ -38,9 +124,17 @@ func NewLoadGenerator(client Client, devices []*Device, logger zerolog.Logger) *
 
 // ExpectedMessagesForDuration calculates the exact number of messages that will be sent
 // by all devices for a given duration, based on the "publish-then-tick" logic.
+// For devices with a randomised Timing it is only the expected number;
+// ExpectedRange bounds it.
 func (lg *LoadGenerator) ExpectedMessagesForDuration(duration time.Duration) int {
 	totalExpected := 0
 	for _, device := range lg.devices {
+		if b := device.Burst; b != nil {
+			if b.Size > 0 && b.Interval > 0 {
+				totalExpected += b.Size * (1 + int(duration/b.Interval))
+			}
+			continue
+		}
 		if device.MessageRate > 0 {
 			// The number of ticks is the floor of the duration divided by the interval.
 			// Total messages = 1 (for T=0) + number of subsequent ticks.
@@ -59,80 +153,158 @@ func (lg *LoadGenerator) ExpectedMessagesForDuration(duration time.Duration) int
 
 // Run now returns the total number of successfully published messages.
 func (lg *LoadGenerator) Run(ctx context.Context, duration time.Duration) (int, error) {
+	result, err := lg.RunUntil(ctx, duration)
+	return result.Published, err
+}
+
+// RunUntil runs the load test until duration elapses, ctx is cancelled or
+// one of conditions is met, and reports which happened. A duration of zero
+// or less sets no time limit. The duration starts after any warm-up.
+func (lg *LoadGenerator) RunUntil(ctx context.Context, duration time.Duration, conditions ...StopCondition) (RunResult, error) {
+	return lg.run(ctx, duration, nil, conditions)
+}
+
+// run runs the load test as RunUntil does. If during is set, it runs
+// alongside the devices, from the start of the run until runCtx is done or
+// devicesDone is closed.
+func (lg *LoadGenerator) run(ctx context.Context, duration time.Duration, during func(runCtx context.Context, start time.Time, devicesDone <-chan struct{}), conditions []StopCondition) (RunResult, error) {
 	atomic.StoreInt64(&lg.publishedCount, 0)
-	lg.logger.Info().Int("num_devices", len(lg.devices)).Dur("duration", duration).Msg("Starting...")
+	atomic.StoreInt64(&lg.failedCount, 0)
+	atomic.StoreInt64(&lg.retriedCount, 0)
+	atomic.StoreInt64(&lg.exhaustedCount, 0)
+	atomic.StoreInt64(&lg.warmupPublished, 0)
+	lg.logger.Info().Int("num_devices", len(lg.devices)).Dur("duration", duration).Dur("warmup", lg.warmup).Msg("Starting...")
 
 	if err := lg.client.Connect(); err != nil {
 		lg.logger.Error().Err(err).Msg("Failed to connect client")
-		return 0, err
+		return RunResult{}, err
 	}
 	defer lg.client.Disconnect()
 
-	runCtx, cancel := context.WithTimeout(ctx, duration)
-	defer cancel()
+	start := time.Now()
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	lg.measureFrom = time.Time{}
+	if lg.warmup > 0 {
+		lg.measureFrom = start.Add(lg.warmup)
+	}
+	if duration > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeoutCause(runCtx, lg.warmup+duration, stopError{StopDuration})
+		defer cancelTimeout()
+	}
+	lg.stop, lg.cancelRun = newStopConditions(conditions), cancel
+	lg.limiter = lg.newRateLimiter()
+	lg.inFlight = lg.newInFlightLimiter()
+	if signal := lg.stop.signal; signal != nil {
+		go func() {
+			select {
+			case <-signal:
+				cancel(stopError{StopSignal})
+			case <-runCtx.Done():
+			}
+		}()
+	}
 
-	var wg sync.WaitGroup
-	for _, device := range lg.devices {
-		wg.Add(1)
-		go func(d *Device) {
-			defer wg.Done()
-			lg.runDevice(runCtx, d)
-		}(device)
+	lg.snapshots = nil
+	lg.groupsByDevice, lg.groups = newGroupStats(lg.devices)
+	lg.latency = &latencyHistogram{}
+	lg.deviceErrors = newDeviceErrorStats(lg.devices)
+	devicesDone := make(chan struct{})
+	progressDone := make(chan struct{})
+	if lg.progress != nil && lg.progressInterval > 0 {
+		go func() {
+			defer close(progressDone)
+			lg.reportProgress(start, devicesDone)
+		}()
+	} else {
+		close(progressDone)
 	}
 
-	wg.Wait()
-	finalCount := int(atomic.LoadInt64(&lg.publishedCount))
-	lg.logger.Info().Int("successful_publishes", finalCount).Msg("Finished")
-	return finalCount, nil
-}
+	duringDone := make(chan struct{})
+	if during != nil {
+		go func() {
+			defer close(duringDone)
+			during(runCtx, start, devicesDone)
+		}()
+	} else {
+		close(duringDone)
+	}
 
-// runDevice runs the message publishing loop for a single device.
-// It is deterministic: it publishes one message immediately at T=0, and then enters
-// a "wait-then-publish" loop for subsequent messages. This ensures that for a given
-// rate R and duration D, the number of messages is exactly ceil(R*D).
-// For example, a rate of 1Hz for 2 seconds sends messages at T=0s and T=1s
-// for a total of 2 messages. A rate of 1Hz for 2.1 seconds sends messages
-// at T=0s, T=1s, and T=2s for a total of 3 messages.
-func (lg *LoadGenerator) runDevice(ctx context.Context, device *Device) {
-	if device.MessageRate <= 0 {
-		lg.logger.Warn().Str("device_id", device.ID).Msg("Device has a message rate of 0, no messages will be sent.")
-		return
-	}
-
-	interval := time.Duration(float64(time.Second) / device.MessageRate)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	lg.logger.Info().Str("device_id", device.ID).Float64("rate_hz", device.MessageRate).Dur("interval", interval).Msg("Device starting loop.")
-
-	// 1. Publish the first message immediately for T=0, but only if the context isn't already done.
-	select {
-	case <-ctx.Done():
-		lg.logger.Info().Str("device_id", device.ID).Msg("Context already done before first message.")
-		return
-	default:
-		// Context is not done, so proceed with the first publish.
-		if success, err := lg.client.Publish(ctx, device); err != nil {
-			lg.logger.Error().Err(err).Str("device_id", device.ID).Msg("Failed to publish message.")
-		} else if success {
-			atomic.AddInt64(&lg.publishedCount, 1)
-		}
+	prof := lg.startProfiling(start)
+	publishCtx, drained := lg.drainContext(runCtx)
+	lg.runDevices(runCtx, publishCtx, start)
+	drained()
+	close(devicesDone)
+	<-progressDone
+	<-duringDone
+	result := RunResult{
+		Published: int(atomic.LoadInt64(&lg.publishedCount)),
+		Failed:    int(atomic.LoadInt64(&lg.failedCount)),
+		Retries:   int(atomic.LoadInt64(&lg.retriedCount)),
+		Shed:      lg.inFlight.shed(),
+		StoppedBy: stopReason(runCtx),
+
+		WarmupPublished: int(atomic.LoadInt64(&lg.warmupPublished)),
 	}
+	if result.StoppedBy == StopDevicesDone && atomic.LoadInt64(&lg.exhaustedCount) > 0 {
+		result.StoppedBy = StopPayloadsExhausted
+	}
+	lg.results = lg.newResults(start, result, lg.snapshots)
+	lg.results.Profile = prof.stop()
+	lg.logger.Info().Int("successful_publishes", result.Published).Int("failed_publishes", result.Failed).Int("retries", result.Retries).Str("stopped_by", string(result.StoppedBy)).Msg("Finished")
+	return result, nil
+}
 
-	// 2. Loop for all subsequent messages, using a "wait-then-publish" pattern.
-	for {
-		select {
-		case <-ctx.Done():
-			// The duration is up, stop waiting for more ticks.
-			lg.logger.Info().Str("device_id", device.ID).Msg("Device stopping.")
-			return
-		case <-ticker.C:
-			// A tick occurred. We are now allowed to publish another message.
-			if success, err := lg.client.Publish(ctx, device); err != nil {
-				lg.logger.Error().Err(err).Str("device_id", device.ID).Msg("Failed to publish message.")
-			} else if success {
-				atomic.AddInt64(&lg.publishedCount, 1)
-			}
+// publishOne waits for the fleet's rate limit, publishes one message of
+// device under the retry policy, counts it, and stops the run if that meets
+// a stop condition. ctx is the run's context and publishCtx that of the
+// publish, which outlives it by the drain timeout. Messages the rate limit
+// or the in-flight limit hold back past the end of the run are not sent,
+// publishes cut short by the end of the run or its drain are not counted as
+// failed and warm-up publishes are counted apart. It returns false once the
+// device's PayloadGenerator is exhausted, i.e. returns io.EOF.
+func (lg *LoadGenerator) publishOne(ctx, publishCtx context.Context, device *Device) bool {
+	if !lg.waitRateLimit(ctx) || !lg.inFlight.acquire(ctx, IsWarmup(lg.warmupContext(ctx))) {
+		return true
+	}
+	defer lg.inFlight.release()
+	ctx = lg.warmupContext(publishCtx)
+	began := time.Now()
+	success, err := lg.publish(ctx, device)
+	took := time.Since(began)
+	if errors.Is(err, io.EOF) {
+		atomic.AddInt64(&lg.exhaustedCount, 1)
+		lg.logger.Info().Str("device_id", device.ID).Msg("Device payloads exhausted, stopping.")
+		return false
+	}
+	if IsWarmup(ctx) {
+		if success {
+			atomic.AddInt64(&lg.warmupPublished, 1)
+		} else {
+			lg.logUncounted(device, err)
 		}
+		return true
+	}
+	var published, failed int64
+	switch {
+	case success:
+		published = atomic.AddInt64(&lg.publishedCount, 1)
+		failed = atomic.LoadInt64(&lg.failedCount)
+	case ctx.Err() == nil:
+		failed = atomic.AddInt64(&lg.failedCount, 1)
+		published = atomic.LoadInt64(&lg.publishedCount)
+	default:
+		lg.logUncounted(device, err)
+		return true
+	}
+	lg.groupsByDevice[device.ID].record(success, took)
+	lg.recordFailures(device, success, err)
+	if success {
+		lg.latency.record(took)
+	}
+	if reason := lg.stop.check(published, failed); reason != "" {
+		lg.cancelRun(stopError{reason})
 	}
+	return true
 }
@@ -0,0 +1,80 @@
+// loadgen/generators/gardenmonitor.go
+
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/illmade-knight/go-test/loadgen"
+)
+
+// GardenMonitorPayload is the payload of a garden monitor device.
+type GardenMonitorPayload struct {
+	DE           string `json:"de"`
+	SIM          string `json:"sim"`
+	RSSI         string `json:"rssi"`
+	Version      string `json:"version"`
+	Sequence     int    `json:"sequence"`
+	Battery      int    `json:"battery"`
+	Temperature  int    `json:"temperature"`
+	Humidity     int    `json:"humidity"`
+	SoilMoisture int    `json:"soil_moisture"`
+}
+
+// GardenMonitor generates the payloads of garden monitors: soil moisture,
+// temperature and humidity sensors on a slowly draining battery. The device
+// ID is used as the monitor's EUI.
+type GardenMonitor struct {
+	states *deviceStates[gardenMonitorState]
+}
+
+// gardenMonitorState holds the dynamic state of a single garden monitor.
+type gardenMonitorState struct {
+	Sequence     int
+	Battery      int
+	Temperature  int
+	Humidity     int
+	SoilMoisture int
+	RSSI         int
+}
+
+// NewGardenMonitor creates a new generator for garden monitor payloads.
+func NewGardenMonitor(opts ...Option) *GardenMonitor {
+	return &GardenMonitor{states: newDeviceStates(opts, func(r *rand.Rand) *gardenMonitorState {
+		return &gardenMonitorState{
+			Battery:      intIn(r, 80, 100),  // %
+			Temperature:  intIn(r, 10, 24),   // °C
+			Humidity:     intIn(r, 40, 69),   // %
+			SoilMoisture: intIn(r, 300, 699), // raw sensor reading
+			RSSI:         -intIn(r, 50, 89),  // dBm
+		}
+	})}
+}
+
+// GeneratePayload updates the state of device and returns its next payload.
+func (g *GardenMonitor) GeneratePayload(device *loadgen.Device) ([]byte, error) {
+	return g.states.update(device.ID, func(s *gardenMonitorState, r *rand.Rand) ([]byte, error) {
+		s.Sequence++
+		if s.Battery > 10 {
+			s.Battery -= r.IntN(2)
+		}
+		s.Temperature += intIn(r, -1, 1)
+		s.Humidity += intIn(r, -2, 2)
+		s.SoilMoisture = clamp(s.SoilMoisture+intIn(r, -20, 20), 100, 900)
+
+		eui := device.ID
+		return json.Marshal(GardenMonitorPayload{
+			DE:           eui,
+			SIM:          fmt.Sprintf("SIM_LOAD_%s", eui[max(len(eui)-4, 0):]),
+			RSSI:         fmt.Sprintf("%ddBm", s.RSSI),
+			Version:      "1.3.0-loadtest",
+			Sequence:     s.Sequence,
+			Battery:      s.Battery,
+			Temperature:  s.Temperature,
+			Humidity:     s.Humidity,
+			SoilMoisture: s.SoilMoisture,
+		})
+	})
+}
@@ -0,0 +1,43 @@
+package generators_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/illmade-knight/go-test/loadgen/generators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGardenMonitor(t *testing.T) {
+	gen := generators.NewGardenMonitor()
+	device := &loadgen.Device{ID: "test-eui-01"}
+
+	t.Run("GeneratePayload returns valid JSON", func(t *testing.T) {
+		payloadBytes, err := gen.GeneratePayload(device)
+		require.NoError(t, err)
+		assert.True(t, json.Valid(payloadBytes), "Payload should be valid JSON")
+
+		var payload generators.GardenMonitorPayload
+		require.NoError(t, json.Unmarshal(payloadBytes, &payload))
+		assert.Equal(t, "test-eui-01", payload.DE)
+		assert.Equal(t, "SIM_LOAD_i-01", payload.SIM)
+		assert.Equal(t, 1, payload.Sequence)
+		assert.GreaterOrEqual(t, payload.Battery, 79)
+		assert.LessOrEqual(t, payload.Battery, 100)
+	})
+
+	t.Run("State is kept per device", func(t *testing.T) {
+		sequence := func(device *loadgen.Device) int {
+			payloadBytes, err := gen.GeneratePayload(device)
+			require.NoError(t, err)
+			var payload generators.GardenMonitorPayload
+			require.NoError(t, json.Unmarshal(payloadBytes, &payload))
+			return payload.Sequence
+		}
+		assert.Equal(t, 2, sequence(device), "Sequence number should be incremented")
+		assert.Equal(t, 1, sequence(&loadgen.Device{ID: "eu"}), "Another device has its own sequence")
+		assert.Equal(t, 3, sequence(device), "Sequence number should be incremented again")
+	})
+}
@@ -0,0 +1,125 @@
+package generators_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/illmade-knight/go-test/loadgen/generators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payloads returns n payloads of each device, generating them round-robin
+// in the given device order.
+func payloads(t *testing.T, gen loadgen.PayloadGenerator, n int, ids ...string) map[string][]string {
+	t.Helper()
+	out := make(map[string][]string)
+	for range n {
+		for _, id := range ids {
+			payload, err := gen.GeneratePayload(&loadgen.Device{ID: id})
+			require.NoError(t, err)
+			out[id] = append(out[id], string(payload))
+		}
+	}
+	return out
+}
+
+func TestWithSeed(t *testing.T) {
+	// The garden monitor's payloads have no timestamps, so they can be
+	// compared whole.
+	a := payloads(t, generators.NewGardenMonitor(generators.WithSeed(42)), 5, "eui-0001", "eui-0002")
+	b := payloads(t, generators.NewGardenMonitor(generators.WithSeed(42)), 5, "eui-0002", "eui-0001")
+	assert.Equal(t, a, b, "a seed gives each device the same values, whatever the order")
+
+	c := payloads(t, generators.NewGardenMonitor(generators.WithSeed(43)), 5, "eui-0001", "eui-0002")
+	assert.NotEqual(t, a, c)
+	assert.NotEqual(t, a["eui-0001"], a["eui-0002"], "devices get different values")
+}
+
+func TestSetSeed(t *testing.T) {
+	t.Cleanup(loadgen.ClearSeed)
+
+	loadgen.SetSeed(42)
+	a := payloads(t, generators.NewGardenMonitor(), 5, "eui-0001", "eui-0002")
+	loadgen.SetSeed(42)
+	b := payloads(t, generators.NewGardenMonitor(), 5, "eui-0002", "eui-0001")
+	assert.Equal(t, a, b, "without WithSeed a generator follows loadgen.SetSeed")
+
+	c := payloads(t, generators.NewGardenMonitor(generators.WithSeed(42)), 5, "eui-0001", "eui-0002")
+	assert.Equal(t, a, c, "SetSeed and WithSeed derive the same source from a seed")
+
+	loadgen.SetSeed(43)
+	d := payloads(t, generators.NewGardenMonitor(), 5, "eui-0001", "eui-0002")
+	assert.NotEqual(t, a, d)
+}
+
+func TestGPSTracker(t *testing.T) {
+	gen := generators.NewGPSTracker(51.5074, -0.1278, generators.WithSeed(1))
+	var fixes []generators.GPSTrackerPayload
+	for range 5 {
+		raw, err := gen.GeneratePayload(&loadgen.Device{ID: "van-7"})
+		require.NoError(t, err)
+		var fix generators.GPSTrackerPayload
+		require.NoError(t, json.Unmarshal(raw, &fix))
+		fixes = append(fixes, fix)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i, fix := range fixes {
+		assert.Equal(t, "van-7", fix.DeviceID)
+		assert.Equal(t, i+1, fix.Sequence)
+		assert.InDelta(t, 51.5074, fix.Latitude, 0.2)
+		assert.InDelta(t, -0.1278, fix.Longitude, 0.2)
+		assert.GreaterOrEqual(t, fix.SpeedKmh, 0.0)
+		assert.LessOrEqual(t, fix.SpeedKmh, 130.0)
+		assert.GreaterOrEqual(t, fix.Heading, 0.0)
+		assert.LessOrEqual(t, fix.Heading, 360.0)
+		assert.WithinDuration(t, time.Now(), fix.Timestamp, time.Minute)
+	}
+}
+
+func TestGPSTracker_SeedReproducesPositions(t *testing.T) {
+	fixes := func() [][4]float64 {
+		gen := generators.NewGPSTracker(51.5074, -0.1278, generators.WithSeed(7))
+		var out [][4]float64
+		for range 5 {
+			raw, err := gen.GeneratePayload(&loadgen.Device{ID: "van-7"})
+			require.NoError(t, err)
+			var fix generators.GPSTrackerPayload
+			require.NoError(t, json.Unmarshal(raw, &fix))
+			out = append(out, [4]float64{fix.Latitude, fix.Longitude, fix.SpeedKmh, fix.Heading})
+			time.Sleep(5 * time.Millisecond)
+		}
+		return out
+	}
+
+	a := fixes()
+	b := fixes()
+	assert.Equal(t, a, b, "the positions do not depend on the time between fixes")
+	assert.NotEqual(t, a[0][:2], a[4][:2], "the tracker moves")
+}
+
+func TestPowerMeter(t *testing.T) {
+	gen := generators.NewPowerMeter(generators.WithSeed(1))
+	var readings []generators.PowerMeterPayload
+	for range 5 {
+		raw, err := gen.GeneratePayload(&loadgen.Device{ID: "meter-1"})
+		require.NoError(t, err)
+		var reading generators.PowerMeterPayload
+		require.NoError(t, json.Unmarshal(raw, &reading))
+		readings = append(readings, reading)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i, reading := range readings {
+		assert.Equal(t, i+1, reading.Sequence)
+		assert.InDelta(t, 230, reading.Voltage, 15)
+		assert.Positive(t, reading.Current)
+		assert.InDelta(t, reading.Voltage*reading.Current*reading.PowerFactor, reading.PowerW, 10)
+		if i > 0 {
+			assert.GreaterOrEqual(t, reading.EnergyKWh, readings[i-1].EnergyKWh, "the energy register never decreases")
+		}
+	}
+}
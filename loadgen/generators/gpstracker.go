@@ -0,0 +1,91 @@
+// loadgen/generators/gpstracker.go
+
+package generators
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+)
+
+// fixInterval is the time a tracker travels between fixes. It is fixed,
+// rather than the time between calls, so that a seed reproduces the
+// positions as well as the other values.
+const fixInterval = 10 * time.Second
+
+// GPSTrackerPayload is the payload of a GPS tracker.
+type GPSTrackerPayload struct {
+	DeviceID  string    `json:"device_id"`
+	Sequence  int       `json:"sequence"`
+	Timestamp time.Time `json:"timestamp"`
+	Latitude  float64   `json:"lat"`
+	Longitude float64   `json:"lon"`
+	SpeedKmh  float64   `json:"speed_kmh"`
+	Heading   float64   `json:"heading"`
+	Battery   int       `json:"battery"`
+}
+
+// GPSTracker generates the payloads of vehicle trackers that wander around
+// an origin, changing speed and heading gradually between fixes.
+type GPSTracker struct {
+	states *deviceStates[gpsTrackerState]
+}
+
+// gpsTrackerState holds the dynamic state of a single tracker.
+type gpsTrackerState struct {
+	Sequence  int
+	Latitude  float64
+	Longitude float64
+	SpeedKmh  float64
+	Heading   float64
+	Battery   int
+}
+
+// NewGPSTracker creates a new generator for GPS tracker payloads. Each
+// tracker starts within about 10km of the origin (in degrees).
+func NewGPSTracker(originLat, originLon float64, opts ...Option) *GPSTracker {
+	return &GPSTracker{states: newDeviceStates(opts, func(r *rand.Rand) *gpsTrackerState {
+		return &gpsTrackerState{
+			Latitude:  originLat + (r.Float64()*2-1)*0.09,
+			Longitude: originLon + (r.Float64()*2-1)*0.09/math.Max(math.Cos(originLat*math.Pi/180), 0.01),
+			SpeedKmh:  r.Float64() * 60,
+			Heading:   r.Float64() * 360,
+			Battery:   intIn(r, 60, 100),
+		}
+	})}
+}
+
+// GeneratePayload moves the tracker of device and returns its next fix. It
+// travels at its current speed for fixInterval since its last fix, however
+// long ago that was.
+func (g *GPSTracker) GeneratePayload(device *loadgen.Device) ([]byte, error) {
+	return g.states.update(device.ID, func(s *gpsTrackerState, r *rand.Rand) ([]byte, error) {
+		if s.Sequence > 0 {
+			km := s.SpeedKmh * fixInterval.Hours()
+			rad := s.Heading * math.Pi / 180
+			s.Latitude = clamp(s.Latitude+km/111.32*math.Cos(rad), -90, 90)
+			s.Longitude += km / (111.32 * math.Max(math.Cos(s.Latitude*math.Pi/180), 0.01)) * math.Sin(rad)
+			s.Longitude = math.Mod(s.Longitude+540, 360) - 180
+		}
+		s.Sequence++
+		s.SpeedKmh = clamp(s.SpeedKmh+r.NormFloat64()*5, 0, 130)
+		s.Heading = math.Mod(s.Heading+r.NormFloat64()*15+360, 360)
+		if s.Sequence%20 == 0 && s.Battery > 5 {
+			s.Battery--
+		}
+
+		return json.Marshal(GPSTrackerPayload{
+			DeviceID:  device.ID,
+			Sequence:  s.Sequence,
+			Timestamp: time.Now().UTC(),
+			Latitude:  math.Round(s.Latitude*1e6) / 1e6,
+			Longitude: math.Round(s.Longitude*1e6) / 1e6,
+			SpeedKmh:  math.Round(s.SpeedKmh*10) / 10,
+			Heading:   math.Round(s.Heading),
+			Battery:   s.Battery,
+		})
+	})
+}
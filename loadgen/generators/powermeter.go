@@ -0,0 +1,78 @@
+// loadgen/generators/powermeter.go
+
+package generators
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+)
+
+// PowerMeterPayload is the payload of a smart power meter.
+type PowerMeterPayload struct {
+	DeviceID    string    `json:"device_id"`
+	Sequence    int       `json:"sequence"`
+	Timestamp   time.Time `json:"timestamp"`
+	Voltage     float64   `json:"voltage"`
+	Current     float64   `json:"current"`
+	PowerW      float64   `json:"power_w"`
+	PowerFactor float64   `json:"power_factor"`
+	EnergyKWh   float64   `json:"energy_kwh"`
+}
+
+// PowerMeter generates the payloads of single-phase smart meters on a 230V
+// supply: a fluctuating load and a cumulative energy register.
+type PowerMeter struct {
+	states *deviceStates[powerMeterState]
+}
+
+// powerMeterState holds the dynamic state of a single meter.
+type powerMeterState struct {
+	Sequence    int
+	Current     float64
+	PowerFactor float64
+	EnergyKWh   float64
+	LastReading time.Time
+}
+
+// NewPowerMeter creates a new generator for power meter payloads.
+func NewPowerMeter(opts ...Option) *PowerMeter {
+	return &PowerMeter{states: newDeviceStates(opts, func(r *rand.Rand) *powerMeterState {
+		return &powerMeterState{
+			Current:     0.5 + r.Float64()*10,
+			PowerFactor: 0.85 + r.Float64()*0.14,
+			EnergyKWh:   float64(intIn(r, 1000, 50000)),
+		}
+	})}
+}
+
+// GeneratePayload updates the meter of device and returns its next reading.
+// The energy register advances by the power drawn since the last reading.
+func (g *PowerMeter) GeneratePayload(device *loadgen.Device) ([]byte, error) {
+	return g.states.update(device.ID, func(s *powerMeterState, r *rand.Rand) ([]byte, error) {
+		now := time.Now()
+		voltage := 230 + r.NormFloat64()*2
+		s.Current = clamp(s.Current+r.NormFloat64()*0.5, 0.1, 40)
+		s.PowerFactor = clamp(s.PowerFactor+r.NormFloat64()*0.01, 0.7, 1)
+		power := voltage * s.Current * s.PowerFactor
+		if !s.LastReading.IsZero() {
+			s.EnergyKWh += power / 1000 * now.Sub(s.LastReading).Hours()
+		}
+		s.LastReading = now
+		s.Sequence++
+
+		return json.Marshal(PowerMeterPayload{
+			DeviceID:    device.ID,
+			Sequence:    s.Sequence,
+			Timestamp:   now.UTC(),
+			Voltage:     math.Round(voltage*10) / 10,
+			Current:     math.Round(s.Current*100) / 100,
+			PowerW:      math.Round(power),
+			PowerFactor: math.Round(s.PowerFactor*100) / 100,
+			EnergyKWh:   math.Round(s.EnergyKWh*1000) / 1000,
+		})
+	})
+}
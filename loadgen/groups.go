@@ -0,0 +1,167 @@
+// loadgen/groups.go
+
+package loadgen
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GroupMetadataKey is the Metadata key that puts devices into groups, whose
+// metrics Results reports apart, e.g. "sensor" and "gateway" devices.
+// Devices without it form one unnamed group.
+const GroupMetadataKey = "group"
+
+// GroupResults are the metrics of a group of devices in a run.
+type GroupResults struct {
+	// Name is the devices' Metadata[GroupMetadataKey].
+	Name      string
+	Devices   int
+	Published int
+	Failed    int
+	// Rate is the number of messages published per second after the
+	// warm-up.
+	Rate float64
+	// Latency is the time successful publishes took, retries included,
+	// accurate to within 2%.
+	Latency LatencyStats
+}
+
+// groupStats gathers the metrics of a group of devices during a run.
+type groupStats struct {
+	name      string
+	devices   int
+	published atomic.Int64
+	failed    atomic.Int64
+	latency   latencyHistogram
+}
+
+// newGroupStats returns the stats of each group of devices, by device ID,
+// and the groups in the order their first devices appear.
+func newGroupStats(devices []*Device) (map[string]*groupStats, []*groupStats) {
+	byDevice := make(map[string]*groupStats, len(devices))
+	byName := make(map[string]*groupStats)
+	var groups []*groupStats
+	for _, device := range devices {
+		name := device.Metadata[GroupMetadataKey]
+		g, ok := byName[name]
+		if !ok {
+			g = &groupStats{name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.devices++
+		byDevice[device.ID] = g
+	}
+	return byDevice, groups
+}
+
+// record counts a measured publish of the group.
+func (g *groupStats) record(success bool, took time.Duration) {
+	if success {
+		g.published.Add(1)
+		g.latency.record(took)
+	} else {
+		g.failed.Add(1)
+	}
+}
+
+// results returns the metrics of the group, over measured.
+func (g *groupStats) results(measured time.Duration) GroupResults {
+	r := GroupResults{
+		Name:      g.name,
+		Devices:   g.devices,
+		Published: int(g.published.Load()),
+		Failed:    int(g.failed.Load()),
+		Latency:   g.latency.stats(),
+	}
+	if measured > 0 {
+		r.Rate = float64(r.Published) / measured.Seconds()
+	}
+	return r
+}
+
+// latencyBits is the number of leading bits a latencyHistogram keeps of
+// each latency in nanoseconds, so its buckets are at most 1/64 wide
+// relative to their values.
+const latencyBits = 7
+
+// latencyHistogram summarises latencies in log-linear buckets, in constant
+// memory however many it records: values below 2^latencyBits ns have a
+// bucket each, and every power of two above that is split into
+// 2^(latencyBits-1) buckets.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts [(64-latencyBits+1)<<(latencyBits-1) + 1<<(latencyBits-1)]uint64
+	n      uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// latencyBucket returns the bucket of v.
+func latencyBucket(v uint64) int {
+	if v < 1<<latencyBits {
+		return int(v)
+	}
+	shift := bits.Len64(v) - latencyBits
+	half := uint64(1) << (latencyBits - 1)
+	return int(1<<latencyBits + uint64(shift-1)*half + v>>shift - half)
+}
+
+// latencyBucketRange returns the lowest and highest values of a bucket.
+func latencyBucketRange(index int) (low, high uint64) {
+	if index < 1<<latencyBits {
+		return uint64(index), uint64(index)
+	}
+	half := 1 << (latencyBits - 1)
+	shift := (index-1<<latencyBits)/half + 1
+	mantissa := uint64(index-1<<latencyBits)%uint64(half) + uint64(half)
+	return mantissa << shift, (mantissa+1)<<shift - 1
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	d = max(d, 0)
+	index := latencyBucket(uint64(d))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.counts[index]++
+	h.n++
+	h.sum += d
+}
+
+// stats summarises the recorded latencies. Percentiles are the midpoints
+// of their buckets.
+func (h *latencyHistogram) stats() LatencyStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n == 0 {
+		return LatencyStats{}
+	}
+	percentile := func(p float64) time.Duration {
+		rank := max(uint64(p*float64(h.n)+0.5), 1)
+		var seen uint64
+		for i, count := range h.counts {
+			if seen += count; seen >= rank {
+				low, high := latencyBucketRange(i)
+				mid := time.Duration(low + (high-low)/2)
+				return min(max(mid, h.min), h.max)
+			}
+		}
+		return h.max
+	}
+	return LatencyStats{
+		Min:  h.min,
+		Mean: h.sum / time.Duration(h.n),
+		P50:  percentile(0.50),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  h.max,
+	}
+}
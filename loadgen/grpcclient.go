@@ -0,0 +1,141 @@
+// loadgen/grpcclient.go
+
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Sender sends one generated payload to a gRPC ingestion endpoint, e.g. by
+// wrapping it in the request message of a unary call or by calling Send on a
+// client stream. It is called concurrently by all devices sharing a
+// connection, so a streaming Sender must serialize its Send calls.
+type Sender func(ctx context.Context, payload []byte) error
+
+// SenderFactory creates the Sender for one pooled connection, typically by
+// creating a generated client stub (and opening a stream) on conn.
+type SenderFactory func(ctx context.Context, conn *grpc.ClientConn) (Sender, error)
+
+// GRPCClientConfig holds configuration for a GRPCClient.
+type GRPCClientConfig struct {
+	// Target is the server address, e.g. "localhost:50051".
+	Target string
+	// NewSender creates the Sender for each pooled connection.
+	NewSender SenderFactory
+	// PoolSize is the number of connections to open. Each device always uses
+	// the same connection, so streams see a device's messages in order.
+	// It defaults to 1.
+	PoolSize int
+	// Metadata, when set, returns the outgoing metadata (e.g. a device token)
+	// to attach to each message of a device.
+	Metadata func(device *Device) metadata.MD
+	// DialOptions are used for every connection. Without any, connections
+	// use insecure (plain-text) credentials.
+	DialOptions []grpc.DialOption
+}
+
+// GRPCClient implements the Client interface for gRPC ingestion endpoints.
+type GRPCClient struct {
+	cfg     GRPCClientConfig
+	conns   []*grpc.ClientConn
+	senders []Sender
+	logger  zerolog.Logger
+}
+
+// NewGRPCClient creates a new gRPC client.
+func NewGRPCClient(cfg GRPCClientConfig, logger zerolog.Logger) Client {
+	if cfg.PoolSize < 1 {
+		cfg.PoolSize = 1
+	}
+	return &GRPCClient{cfg: cfg, logger: logger}
+}
+
+// Connect opens the connection pool and creates a Sender for each connection.
+func (c *GRPCClient) Connect() error {
+	if c.cfg.NewSender == nil {
+		return errors.New("gRPC client needs a SenderFactory")
+	}
+	opts := c.cfg.DialOptions
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	for i := range c.cfg.PoolSize {
+		conn, err := grpc.NewClient(c.cfg.Target, opts...)
+		if err != nil {
+			c.Disconnect()
+			return fmt.Errorf("failed to create gRPC connection %d to %s: %w", i, c.cfg.Target, err)
+		}
+		c.conns = append(c.conns, conn)
+		sender, err := c.cfg.NewSender(context.Background(), conn)
+		if err != nil {
+			c.Disconnect()
+			return fmt.Errorf("failed to create sender for gRPC connection %d: %w", i, err)
+		}
+		c.senders = append(c.senders, sender)
+	}
+	c.logger.Info().Str("target", c.cfg.Target).Int("pool_size", c.cfg.PoolSize).Msg("Successfully connected to gRPC server")
+	return nil
+}
+
+// Disconnect closes every pooled connection.
+func (c *GRPCClient) Disconnect() {
+	for _, conn := range c.conns {
+		_ = conn.Close()
+	}
+	if len(c.conns) > 0 {
+		c.logger.Info().Msg("gRPC client disconnected")
+	}
+	c.conns, c.senders = nil, nil
+}
+
+// Publish generates a payload and sends it on the device's connection.
+// It returns true only when the Sender succeeds.
+func (c *GRPCClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	payloadBytes, attributes, err := GenerateMessage(device)
+	if err != nil {
+		return false, err
+	}
+
+	var md metadata.MD
+	if c.cfg.Metadata != nil {
+		md = c.cfg.Metadata(device).Copy()
+	}
+	if len(attributes) > 0 {
+		if md == nil {
+			md = metadata.MD{}
+		}
+		for k, v := range attributes {
+			md.Set(k, v)
+		}
+	}
+	if md != nil {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	if err := c.senders[c.connIndex(device)](ctx, payloadBytes); err != nil {
+		err = fmt.Errorf("grpc publish error for device %s: %w", device.ID, err)
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+	c.logger.Debug().Str("device_id", device.ID).Msg("Message published")
+	return true, nil
+}
+
+// connIndex returns the index of the pooled connection a device uses.
+func (c *GRPCClient) connIndex(device *Device) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(device.ID))
+	return int(h.Sum32() % uint32(len(c.senders)))
+}
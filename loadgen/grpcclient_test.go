@@ -0,0 +1,118 @@
+package loadgen_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ingestMethod is the unary method served by startIngestServer.
+const ingestMethod = "/test.Ingest/Send"
+
+// startIngestServer starts an in-memory gRPC server that records the payload
+// and "device-id" metadata of every call to ingestMethod.
+func startIngestServer(t *testing.T) (*bufconn.Listener, func() map[string][]string) {
+	t.Helper()
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != ingestMethod {
+			return status.Error(codes.Unimplemented, method)
+		}
+		var req wrapperspb.BytesValue
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		if string(req.GetValue()) == "reject" {
+			return status.Error(codes.InvalidArgument, "rejected")
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		deviceID := ""
+		if ids := md.Get("device-id"); len(ids) > 0 {
+			deviceID = ids[0]
+		}
+		mu.Lock()
+		received[deviceID] = append(received[deviceID], string(req.GetValue()))
+		mu.Unlock()
+		return stream.SendMsg(&emptypb.Empty{})
+	}))
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	return lis, func() map[string][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+func TestGRPCClient_Publish(t *testing.T) {
+	lis, received := startIngestServer(t)
+	var factoryCalls int
+	client := loadgen.NewGRPCClient(loadgen.GRPCClientConfig{
+		Target: "passthrough:///bufnet",
+		NewSender: func(_ context.Context, conn *grpc.ClientConn) (loadgen.Sender, error) {
+			factoryCalls++
+			return func(ctx context.Context, payload []byte) error {
+				return conn.Invoke(ctx, ingestMethod, wrapperspb.Bytes(payload), &emptypb.Empty{})
+			}, nil
+		},
+		PoolSize: 3,
+		Metadata: func(device *loadgen.Device) metadata.MD {
+			return metadata.Pairs("device-id", device.ID)
+		},
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+	assert.Equal(t, 3, factoryCalls, "one sender per pooled connection")
+
+	for _, id := range []string{"device-1", "device-2"} {
+		device := &loadgen.Device{ID: id, PayloadGenerator: staticPayloadGenerator(`{"id":"` + id + `"}`)}
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, map[string][]string{
+		"device-1": {`{"id":"device-1"}`},
+		"device-2": {`{"id":"device-2"}`},
+	}, received())
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "device-3", PayloadGenerator: staticPayloadGenerator("reject")})
+	assert.False(t, ok)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCClient_ConnectErrors(t *testing.T) {
+	client := loadgen.NewGRPCClient(loadgen.GRPCClientConfig{Target: "localhost:1"}, zerolog.Nop())
+	require.Error(t, client.Connect(), "a SenderFactory is required")
+
+	factoryErr := errors.New("stream refused")
+	client = loadgen.NewGRPCClient(loadgen.GRPCClientConfig{
+		Target: "localhost:1",
+		NewSender: func(context.Context, *grpc.ClientConn) (loadgen.Sender, error) {
+			return nil, factoryErr
+		},
+	}, zerolog.Nop())
+	require.ErrorIs(t, client.Connect(), factoryErr)
+}
@@ -0,0 +1,123 @@
+// loadgen/httpclient.go
+
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DeviceIDPlaceholder is replaced by the (path-escaped) device ID in the URL
+// pattern of an HTTPClient.
+const DeviceIDPlaceholder = "{deviceID}"
+
+// HTTPClient implements the Client interface for HTTP ingestion endpoints.
+// Each message is POSTed as the request body to the URL pattern with the
+// device ID substituted.
+type HTTPClient struct {
+	client     *http.Client
+	urlPattern string
+	headers    map[string]string
+	timeout    time.Duration
+	logger     zerolog.Logger
+}
+
+// NewHTTPClient creates a new HTTP client. urlPattern may contain
+// DeviceIDPlaceholder, e.g. "http://localhost:8080/devices/{deviceID}/telemetry".
+// headers are added to every request; Content-Type defaults to
+// "application/json". timeout bounds each request, including reading the
+// response; zero means no timeout beyond the run's context.
+func NewHTTPClient(urlPattern string, headers map[string]string, timeout time.Duration, logger zerolog.Logger) Client {
+	return &HTTPClient{
+		urlPattern: urlPattern,
+		headers:    headers,
+		timeout:    timeout,
+		logger:     logger,
+	}
+}
+
+// Connect prepares the underlying HTTP client. Connections are opened lazily
+// and reused across requests, so many devices share a small pool.
+func (c *HTTPClient) Connect() error {
+	if _, err := url.Parse(strings.ReplaceAll(c.urlPattern, DeviceIDPlaceholder, "device")); err != nil {
+		return fmt.Errorf("invalid URL pattern %q: %w", c.urlPattern, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The default of 2 idle connections per host would make concurrent
+	// devices open and close connections constantly.
+	transport.MaxIdleConns = 1000
+	transport.MaxIdleConnsPerHost = 1000
+	c.client = &http.Client{Transport: transport}
+	c.logger.Info().Str("url_pattern", c.urlPattern).Msg("HTTP client ready")
+	return nil
+}
+
+// Disconnect closes any idle connections.
+func (c *HTTPClient) Disconnect() {
+	if c.client != nil {
+		c.client.CloseIdleConnections()
+		c.logger.Info().Msg("HTTP client disconnected")
+	}
+}
+
+// Publish generates a payload and POSTs it for the device.
+// It returns true only when the server answers with a 2xx status.
+func (c *HTTPClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	payloadBytes, attributes, err := GenerateMessage(device)
+	if err != nil {
+		return false, err
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	target := strings.ReplaceAll(c.urlPattern, DeviceIDPlaceholder, url.PathEscape(device.ID))
+	setSpanDestination(ctx, target)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request for device %s: %w", device.ID, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range attributes {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("http publish error for device %s: %w", device.ID, err)
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("http publish for device %s returned status %d", device.ID, resp.StatusCode)
+		c.logger.Warn().Err(err).Msg("Publish rejected")
+		// Client errors other than a timeout or throttling would fail again.
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			err = Permanent(err)
+		}
+		return false, err
+	}
+	c.logger.Debug().Str("device_id", device.ID).Str("url", target).Msg("Message published")
+	return true, nil
+}
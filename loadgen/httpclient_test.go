@@ -0,0 +1,136 @@
+package loadgen_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticPayloadGenerator returns the same payload for every message.
+type staticPayloadGenerator []byte
+
+func (g staticPayloadGenerator) GeneratePayload(_ *loadgen.Device) ([]byte, error) {
+	return g, nil
+}
+
+func TestHTTPClient_Publish(t *testing.T) {
+	type request struct {
+		path, contentType, apiKey, body string
+	}
+	var mu sync.Mutex
+	var got []request
+	var newConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, request{r.URL.EscapedPath(), r.Header.Get("Content-Type"), r.Header.Get("X-Api-Key"), string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := loadgen.NewHTTPClient(server.URL+"/devices/{deviceID}/telemetry", map[string]string{"X-Api-Key": "secret"}, time.Second, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "garden/01", PayloadGenerator: staticPayloadGenerator(`{"t":21}`)}
+	for range 3 {
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, got, 3)
+	assert.Equal(t, request{"/devices/garden%2F01/telemetry", "application/json", "secret", `{"t":21}`}, got[0])
+	assert.EqualValues(t, 1, newConns.Load(), "Connections should be reused")
+}
+
+func TestHTTPClient_DeviceHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	t.Cleanup(server.Close)
+
+	client := loadgen.NewHTTPClient(server.URL, map[string]string{"X-Api-Key": "secret", "X-Source": "loadgen"}, time.Second, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "d1", PayloadGenerator: staticPayloadGenerator("{}"), Headers: map[string]string{"X-Api-Key": "device-secret"}}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	got := <-headers
+	assert.Equal(t, "device-secret", got.Get("X-Api-Key"))
+	assert.Equal(t, "loadgen", got.Get("X-Source"))
+}
+
+func TestHTTPClient_PublishErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	device := &loadgen.Device{ID: "device-1", PayloadGenerator: staticPayloadGenerator(`{}`)}
+
+	t.Run("Non-2xx status", func(t *testing.T) {
+		client := loadgen.NewHTTPClient(server.URL+"/ingest", nil, time.Second, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		ok, err := client.Publish(context.Background(), device)
+		assert.False(t, ok)
+		assert.ErrorContains(t, err, "status 503")
+		assert.True(t, loadgen.IsRetryable(err))
+	})
+
+	t.Run("Rejected request", func(t *testing.T) {
+		client := loadgen.NewHTTPClient(server.URL+"/bad", nil, time.Second, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		ok, err := client.Publish(context.Background(), device)
+		assert.False(t, ok)
+		assert.ErrorContains(t, err, "status 400")
+		assert.False(t, loadgen.IsRetryable(err), "a 4xx status is permanent")
+	})
+
+	t.Run("Per-request timeout", func(t *testing.T) {
+		client := loadgen.NewHTTPClient(server.URL+"/slow", nil, 50*time.Millisecond, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		ok, err := client.Publish(context.Background(), device)
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		client := loadgen.NewHTTPClient(server.URL+"/ingest", nil, time.Second, zerolog.Nop())
+		require.NoError(t, client.Connect())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ok, err := client.Publish(ctx, device)
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -11,6 +11,19 @@ type PayloadGenerator interface {
 	GeneratePayload(device *Device) ([]byte, error)
 }
 
+// AttributeGenerator is a PayloadGenerator whose messages carry key/value
+// attributes alongside their payloads, e.g. an event type or schema
+// version. Clients send them as Pub/Sub attributes, MQTT v5 user
+// properties, HTTP headers, gRPC metadata or AMQP headers, over the
+// device's Headers. The MQTT v3.1.1 and CoAP clients have nowhere to put
+// them and send the payload alone.
+type AttributeGenerator interface {
+	PayloadGenerator
+	// GenerateMessage returns the payload and attributes of the next message
+	// of device. Clients call it instead of GeneratePayload.
+	GenerateMessage(device *Device) ([]byte, map[string]string, error)
+}
+
 // Client defines the interface for a client that can publish messages.
 type Client interface {
 	Connect() error
@@ -8,7 +8,6 @@ import (
 	"testing"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/illmade-knight/go-test/emulators"
 	"github.com/illmade-knight/go-test/loadgen"
 	"github.com/rs/zerolog"
@@ -35,24 +34,8 @@ func TestMqttClient_Publish_PayloadFormat(t *testing.T) {
 		publisher.Disconnect()
 	})
 
-	// 3. Create a standard Paho client to subscribe and receive the message.
-	messageCh := make(chan []byte, 1)
-	opts := mqtt.NewClientOptions().AddBroker(mqttConnInfo.EmulatorAddress).SetClientID("test-subscriber")
-	subscriber := mqtt.NewClient(opts)
-	token := subscriber.Connect()
-	require.True(t, token.WaitTimeout(5*time.Second), "subscriber failed to connect")
-	require.NoError(t, token.Error())
-	t.Cleanup(func() {
-		subscriber.Disconnect(250)
-	})
-
-	// Subscribe to the specific topic we expect the publisher to use.
-	topicToReceive := "test/device-123/data"
-	token = subscriber.Subscribe(topicToReceive, 1, func(client mqtt.Client, msg mqtt.Message) {
-		messageCh <- msg.Payload()
-	})
-	require.True(t, token.WaitTimeout(5*time.Second), "subscriber failed to subscribe")
-	require.NoError(t, token.Error())
+	// 3. Capture what a subscriber to the expected topic receives.
+	capture := emulators.CaptureMQTTMessages(t, mqttConnInfo.EmulatorAddress, "test/device-123/data")
 
 	// 4. Setup mock payload generator to return a known payload.
 	mockGenerator := new(MockPayloadGenerator)
@@ -69,12 +52,94 @@ func TestMqttClient_Publish_PayloadFormat(t *testing.T) {
 	require.NoError(t, err)
 
 	// Assert
-	// Wait for the message to be received by the subscriber, with a timeout.
-	select {
-	case receivedPayload := <-messageCh:
-		// The core of the test: assert that the received payload is exactly what the generator produced.
-		assert.Equal(t, string(expectedPayload), string(receivedPayload), "The published payload should not be wrapped")
-	case <-time.After(5 * time.Second):
-		t.Fatal("timed out waiting for message to be received")
+	messages := capture.WaitForCount(1, 5*time.Second)
+	// The core of the test: assert that the received payload is exactly what the generator produced.
+	assert.Equal(t, string(expectedPayload), string(messages[0].Payload), "The published payload should not be wrapped")
+}
+
+func TestMqttClient_TLSWithCredentials(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	cfg := emulators.GetDefaultMqttImageContainer()
+	cfg.EnableTLS = true
+	cfg.Username = "loadgen"
+	cfg.Password = "s3cret"
+	mqttConnInfo := emulators.SetupMosquittoContainer(t, ctx, cfg)
+
+	mockGenerator := new(MockPayloadGenerator)
+	mockGenerator.On("GeneratePayload").Return([]byte(`{}`), nil)
+	device := &loadgen.Device{ID: "device-123", PayloadGenerator: mockGenerator}
+
+	// Act
+	publisher := loadgen.NewMqttClient(mqttConnInfo.TLSAddress, "test/+/data", 1, zerolog.Nop(),
+		loadgen.WithMqttCACert(mqttConnInfo.CACert),
+		loadgen.WithMqttCredentials(mqttConnInfo.Username, mqttConnInfo.Password),
+		loadgen.WithMqttKeepAlive(10*time.Second),
+	)
+	require.NoError(t, publisher.Connect())
+	t.Cleanup(publisher.Disconnect)
+	ok, err := publisher.Publish(ctx, device)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Without credentials the broker refuses the connection.
+	anonymous := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{
+		BrokerURL:    mqttConnInfo.TLSAddress,
+		TopicPattern: "test/+/data",
+		Options:      []loadgen.MqttOption{loadgen.WithMqttCACert(mqttConnInfo.CACert)},
+	}, []*loadgen.Device{device}, zerolog.Nop())
+	assert.Error(t, anonymous.Connect())
+}
+
+func TestMqttClient_RetainedQoS2(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	mqttConnInfo := emulators.SetupMosquittoContainer(t, ctx, emulators.GetDefaultMqttImageContainer())
+
+	publisher := loadgen.NewMqttClient(mqttConnInfo.EmulatorAddress, "test/+/state", 2, zerolog.Nop(),
+		loadgen.WithMqttRetained(true),
+	)
+	require.NoError(t, publisher.Connect())
+	t.Cleanup(publisher.Disconnect)
+
+	mockGenerator := new(MockPayloadGenerator)
+	mockGenerator.On("GeneratePayload").Return([]byte("on"), nil)
+	ok, err := publisher.Publish(ctx, &loadgen.Device{ID: "device-123", PayloadGenerator: mockGenerator})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A subscriber that connects after the publish still gets the retained message.
+	capture := emulators.CaptureMQTTMessages(t, mqttConnInfo.EmulatorAddress, "test/device-123/state")
+	msg := capture.WaitForCount(1, 5*time.Second)[0]
+	assert.True(t, msg.Retained)
+	assert.Equal(t, byte(2), msg.QoS)
+	assert.Equal(t, "on", string(msg.Payload))
+}
+
+func TestVerifier_MqttSource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	mqttConnInfo := emulators.SetupMosquittoContainer(t, ctx, emulators.GetDefaultMqttImageContainer())
+
+	source := loadgen.NewMqttSource(mqttConnInfo.EmulatorAddress, "verify/+/data", 1, zerolog.Nop())
+	verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: source}, zerolog.Nop())
+	require.NoError(t, verifier.Start(ctx))
+
+	client := loadgen.NewMqttClient(mqttConnInfo.EmulatorAddress, "verify/{deviceID}/data", 1, zerolog.Nop())
+	mockGenerator := new(MockPayloadGenerator)
+	mockGenerator.On("GeneratePayload").Return([]byte(`{"temp":21}`), nil)
+	devices := []*loadgen.Device{
+		{ID: "device-1", MessageRate: 10, PayloadGenerator: mockGenerator},
+		{ID: "device-2", MessageRate: 10, PayloadGenerator: mockGenerator},
 	}
+	count, err := loadgen.NewLoadGenerator(verifier.WrapClient(client), devices, zerolog.Nop()).Run(ctx, time.Second)
+	require.NoError(t, err)
+
+	report := verifier.Finish(5 * time.Second)
+	assert.Equal(t, count, report.Delivered)
+	assert.Zero(t, report.Missing)
+	assert.Zero(t, report.Unexpected)
 }
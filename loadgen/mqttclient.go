@@ -4,8 +4,8 @@ package loadgen
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/eclipse/paho.mqtt.golang"
@@ -13,27 +13,44 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// ErrPublishUnconfirmed is wrapped by the error of an MQTT publish whose
+// acknowledgement timed out. The message is not counted as published, but
+// the broker may still have received it; for QoS 2 it may even be delivered
+// once the handshake completes.
+var ErrPublishUnconfirmed = errors.New("publish unconfirmed")
+
 // MqttClient implements the Client interface for MQTT.
 type MqttClient struct {
 	client       mqtt.Client
 	brokerURL    string
 	topicPattern string
+	topics       *deviceTopics
 	qos          byte
+	settings     mqttSettings
 	logger       zerolog.Logger
 }
 
-// NewMqttClient creates a new MQTT client.
-func NewMqttClient(brokerURL, topicPattern string, qos byte, logger zerolog.Logger) Client {
+// NewMqttClient creates a new MQTT client. topicPattern is a TopicTemplate
+// pattern, e.g. "devices/{deviceID}/data". opts configure TLS, credentials,
+// clean-session and keepalive; without any it connects anonymously in plain text.
+func NewMqttClient(brokerURL, topicPattern string, qos byte, logger zerolog.Logger, opts ...MqttOption) Client {
 	return &MqttClient{
 		brokerURL:    brokerURL,
 		topicPattern: topicPattern,
 		qos:          qos,
+		settings:     newMqttSettings(opts),
 		logger:       logger,
 	}
 }
 
 // Connect establishes a connection to the MQTT broker.
 func (c *MqttClient) Connect() error {
+	topics, err := newDeviceTopics(c.topicPattern)
+	if err != nil {
+		return err
+	}
+	c.topics = topics
+
 	opts := mqtt.NewClientOptions().
 		AddBroker(c.brokerURL).
 		SetClientID(fmt.Sprintf("loadgen-client-%s", uuid.New().String())).
@@ -47,6 +64,9 @@ func (c *MqttClient) Connect() error {
 		SetOnConnectHandler(func(client mqtt.Client) {
 			c.logger.Info().Str("broker", c.brokerURL).Msg("Successfully connected to MQTT broker")
 		})
+	if err := c.settings.apply(opts); err != nil {
+		return err
+	}
 
 	c.client = mqtt.NewClient(opts)
 	if token := c.client.Connect(); token.WaitTimeout(10*time.Second) && token.Error() != nil {
@@ -84,12 +104,17 @@ func (c *MqttClient) Publish(ctx context.Context, device *Device) (bool, error)
 		return false, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
 	}
 
-	topic := strings.Replace(c.topicPattern, "+", device.ID, 1)
-	token := c.client.Publish(topic, c.qos, false, payloadBytes)
+	topic, err := c.topics.expand(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to build topic for device %s: %w", device.ID, err)
+	}
+	setSpanDestination(ctx, topic)
+	pubOpts := c.settings.publishOptions(device, c.qos)
+	token := c.client.Publish(topic, pubOpts.QoS, pubOpts.Retained, payloadBytes)
 
 	// CORRECTED: This no longer uses the main context, which was causing the race condition.
 	// It now waits for a fixed, reasonable duration for the broker to acknowledge the publish.
-	if token.WaitTimeout(2 * time.Second) {
+	if token.WaitTimeout(c.settings.ackTimeout(pubOpts.QoS)) {
 		if token.Error() != nil {
 			err := fmt.Errorf("mqtt publish error for device %s: %w", device.ID, token.Error())
 			c.logger.Warn().Err(err).Msg("Publish failed")
@@ -101,7 +126,7 @@ func (c *MqttClient) Publish(ctx context.Context, device *Device) (bool, error)
 
 	// This now correctly indicates a genuine timeout waiting for the broker's ACK,
 	// not a premature context cancellation.
-	err = fmt.Errorf("timed out waiting for publish confirmation for device %s", device.ID)
-	c.logger.Error().Err(err).Str("device_id", device.ID).Msg("Publish timeout")
+	err = fmt.Errorf("timed out waiting for publish confirmation for device %s: %w", device.ID, ErrPublishUnconfirmed)
+	c.logger.Error().Err(err).Str("device_id", device.ID).Uint8("qos", pubOpts.QoS).Msg("Publish timeout")
 	return false, err
 }
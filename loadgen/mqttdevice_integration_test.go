@@ -0,0 +1,126 @@
+//go:build integration
+
+package loadgen_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/illmade-knight/go-test/emulators"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMqttDeviceClient_OneConnectionPerDevice(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	mqttConnInfo := emulators.SetupMosquittoContainer(t, ctx, emulators.GetDefaultMqttImageContainer())
+
+	// Subscribe to all device topics.
+	var mu sync.Mutex
+	received := make(map[string]int)
+	subscriber := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(mqttConnInfo.EmulatorAddress).SetClientID("test-subscriber"))
+	token := subscriber.Connect()
+	require.True(t, token.WaitTimeout(5*time.Second), "subscriber failed to connect")
+	require.NoError(t, token.Error())
+	t.Cleanup(func() { subscriber.Disconnect(250) })
+	token = subscriber.Subscribe("devices/+/data", 1, func(_ mqtt.Client, msg mqtt.Message) {
+		mu.Lock()
+		received[msg.Topic()]++
+		mu.Unlock()
+	})
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	mockGenerator := new(MockPayloadGenerator)
+	mockGenerator.On("GeneratePayload").Return([]byte(`{}`), nil)
+	devices := []*loadgen.Device{
+		{ID: "device-1", MessageRate: 10, PayloadGenerator: mockGenerator},
+		{ID: "device-2", MessageRate: 10, PayloadGenerator: mockGenerator},
+		{ID: "device-3", MessageRate: 10, PayloadGenerator: mockGenerator},
+	}
+	client := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{
+		BrokerURL:      mqttConnInfo.EmulatorAddress,
+		TopicPattern:   "devices/+/data",
+		QoS:            1,
+		ConnectStagger: 50 * time.Millisecond,
+		KeepAlive:      10 * time.Second,
+	}, devices, zerolog.Nop())
+
+	// Act
+	start := time.Now()
+	count, err := loadgen.NewLoadGenerator(client, devices, zerolog.Nop()).Run(ctx, 250*time.Millisecond)
+
+	// Assert
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond, "connections should be staggered")
+	assert.Equal(t, 9, count)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received["devices/device-1/data"] == 3 && received["devices/device-2/data"] == 3 && received["devices/device-3/data"] == 3
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestMqttDeviceClient_Downlink(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	mqttConnInfo := emulators.SetupMosquittoContainer(t, ctx, emulators.GetDefaultMqttImageContainer())
+
+	var mu sync.Mutex
+	commands := make(map[string]string)
+	acks := make(map[string]string)
+	devices := []*loadgen.Device{{ID: "device-1"}, {ID: "device-2"}}
+	client := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{
+		BrokerURL:    mqttConnInfo.EmulatorAddress,
+		TopicPattern: "devices/+/data",
+		Downlink: &loadgen.DownlinkConfig{
+			CommandTopic: "devices/{deviceID}/commands",
+			ReplyTopic:   "devices/{deviceID}/acks",
+			QoS:          1,
+			Handler: func(device *loadgen.Device, topic string, payload []byte) ([]byte, error) {
+				mu.Lock()
+				commands[device.ID] = string(payload)
+				mu.Unlock()
+				return loadgen.AckCommands([]byte("ok"))(device, topic, payload)
+			},
+		},
+	}, devices, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	backend := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(mqttConnInfo.EmulatorAddress).SetClientID("test-backend"))
+	token := backend.Connect()
+	require.True(t, token.WaitTimeout(5*time.Second), "backend failed to connect")
+	require.NoError(t, token.Error())
+	t.Cleanup(func() { backend.Disconnect(250) })
+	token = backend.Subscribe("devices/+/acks", 1, func(_ mqtt.Client, msg mqtt.Message) {
+		mu.Lock()
+		acks[msg.Topic()] = string(msg.Payload())
+		mu.Unlock()
+	})
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	// Act
+	token = backend.Publish("devices/device-2/commands", 1, false, "reboot")
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return acks["devices/device-2/acks"] == "ok"
+	}, 5*time.Second, 50*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]string{"device-2": "reboot"}, commands)
+}
@@ -0,0 +1,187 @@
+// loadgen/mqttdeviceclient.go
+
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog"
+)
+
+// MqttDeviceClientConfig holds configuration for an MqttDeviceClient.
+type MqttDeviceClientConfig struct {
+	BrokerURL string
+	// TopicPattern is the TopicTemplate pattern of each message's topic, as
+	// for MqttClient.
+	TopicPattern string
+	QoS          byte
+	// ClientIDPattern is the MQTT client ID of each device's connection, with
+	// DeviceIDPlaceholder replaced by the device ID. It defaults to
+	// "loadgen-{deviceID}".
+	ClientIDPattern string
+	// ConnectStagger is the pause between opening two device connections, so
+	// a large fleet does not hit the broker with a connection storm.
+	ConnectStagger time.Duration
+	// KeepAlive is the keepalive interval of each connection. It defaults to
+	// the paho default of 30s.
+	KeepAlive time.Duration
+	// PersistentSession asks the broker to keep each device's session state
+	// (subscriptions and queued messages) across reconnects.
+	PersistentSession bool
+	// Options configure TLS, credentials and publishing, as for MqttClient.
+	// They are shared by all connections and override the fields above.
+	Options []MqttOption
+	// Downlink, when set, subscribes each device's connection to its
+	// command topic, again after every reconnect.
+	Downlink *DownlinkConfig
+}
+
+// MqttDeviceClient implements the Client interface for MQTT with one broker
+// connection and client ID per device, so broker-side per-client behaviour
+// (keepalive, session state, connection limits) is load tested too.
+type MqttDeviceClient struct {
+	cfg      MqttDeviceClientConfig
+	settings mqttSettings
+	topics   *deviceTopics
+	downlink *downlink
+	devices  []*Device
+	clients  map[string]mqtt.Client
+	logger   zerolog.Logger
+}
+
+// NewMqttDeviceClient creates a new per-device MQTT client for devices.
+// Publish only accepts these devices.
+func NewMqttDeviceClient(cfg MqttDeviceClientConfig, devices []*Device, logger zerolog.Logger) Client {
+	if cfg.ClientIDPattern == "" {
+		cfg.ClientIDPattern = "loadgen-" + DeviceIDPlaceholder
+	}
+	return &MqttDeviceClient{cfg: cfg, settings: newMqttSettings(cfg.Options), devices: devices, logger: logger}
+}
+
+// Connect opens one connection per device, pausing ConnectStagger between
+// them, and subscribes each to its commands if Downlink is set. If any
+// connection or subscription fails, all are closed and the error is
+// returned.
+func (c *MqttDeviceClient) Connect() error {
+	topics, err := newDeviceTopics(c.cfg.TopicPattern)
+	if err != nil {
+		return err
+	}
+	c.topics = topics
+	if c.cfg.Downlink != nil {
+		if c.downlink, err = newDownlink(*c.cfg.Downlink, c); err != nil {
+			return err
+		}
+	}
+	c.clients = make(map[string]mqtt.Client, len(c.devices))
+	for i, device := range c.devices {
+		if i > 0 && c.cfg.ConnectStagger > 0 {
+			time.Sleep(c.cfg.ConnectStagger)
+		}
+		clientID := expandDeviceID(c.cfg.ClientIDPattern, device)
+		opts := mqtt.NewClientOptions().
+			AddBroker(c.cfg.BrokerURL).
+			SetClientID(clientID).
+			SetConnectTimeout(10 * time.Second).
+			SetCleanSession(!c.cfg.PersistentSession).
+			SetAutoReconnect(true).
+			SetConnectionLostHandler(func(client mqtt.Client, err error) {
+				c.logger.Warn().Err(err).Str("client_id", clientID).Msg("MQTT Connection lost")
+			})
+		if c.cfg.KeepAlive > 0 {
+			opts.SetKeepAlive(c.cfg.KeepAlive)
+		}
+		var subscribed chan error
+		if c.downlink != nil {
+			subscribed = make(chan error, 1)
+			opts.SetOnConnectHandler(func(client mqtt.Client) {
+				err := c.downlink.subscribe(client, device)
+				if err != nil {
+					c.logger.Warn().Err(err).Str("client_id", clientID).Msg("Failed to subscribe to commands")
+				}
+				select {
+				case subscribed <- err:
+				default:
+				}
+			})
+		}
+		if err := c.settings.apply(opts); err != nil {
+			c.Disconnect()
+			return err
+		}
+
+		client := mqtt.NewClient(opts)
+		token := client.Connect()
+		if !token.WaitTimeout(10 * time.Second) {
+			c.Disconnect()
+			return fmt.Errorf("timed out connecting device %s to %s", device.ID, c.cfg.BrokerURL)
+		}
+		if token.Error() != nil {
+			c.Disconnect()
+			return fmt.Errorf("failed to connect device %s to %s: %w", device.ID, c.cfg.BrokerURL, token.Error())
+		}
+		if subscribed != nil {
+			if err := <-subscribed; err != nil {
+				client.Disconnect(250)
+				c.Disconnect()
+				return err
+			}
+		}
+		c.clients[device.ID] = client
+	}
+	c.logger.Info().Str("broker", c.cfg.BrokerURL).Int("connections", len(c.clients)).Msg("Successfully connected devices to MQTT broker")
+	return nil
+}
+
+// Disconnect closes every device connection.
+func (c *MqttDeviceClient) Disconnect() {
+	for _, client := range c.clients {
+		if client.IsConnected() {
+			client.Disconnect(250)
+		}
+	}
+	if len(c.clients) > 0 {
+		c.logger.Info().Int("connections", len(c.clients)).Msg("MQTT device clients disconnected")
+	}
+	c.clients = nil
+}
+
+// Publish generates a payload and sends it on the device's own connection.
+// It returns true only on a successful publish acknowledgement.
+func (c *MqttDeviceClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	client, ok := c.clients[device.ID]
+	if !ok {
+		return false, fmt.Errorf("device %s has no MQTT connection", device.ID)
+	}
+
+	payloadBytes, err := device.PayloadGenerator.GeneratePayload(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
+	}
+
+	topic, err := c.topics.expand(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to build topic for device %s: %w", device.ID, err)
+	}
+	setSpanDestination(ctx, topic)
+	pubOpts := c.settings.publishOptions(device, c.cfg.QoS)
+	token := client.Publish(topic, pubOpts.QoS, pubOpts.Retained, payloadBytes)
+	if !token.WaitTimeout(c.settings.ackTimeout(pubOpts.QoS)) {
+		err = fmt.Errorf("timed out waiting for publish confirmation for device %s: %w", device.ID, ErrPublishUnconfirmed)
+		c.logger.Error().Err(err).Str("device_id", device.ID).Uint8("qos", pubOpts.QoS).Msg("Publish timeout")
+		return false, err
+	}
+	if token.Error() != nil {
+		err := fmt.Errorf("mqtt publish error for device %s: %w", device.ID, token.Error())
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+	c.logger.Debug().Str("device_id", device.ID).Str("topic", topic).Msg("Message published")
+	return true, nil
+}
@@ -0,0 +1,45 @@
+package loadgen_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMqttDeviceClient_ConnectFails(t *testing.T) {
+	// Grab a free port and close it again, so nothing is listening there.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	devices := []*loadgen.Device{{ID: "device-1"}, {ID: "device-2"}}
+	client := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{BrokerURL: "tcp://" + addr, TopicPattern: "devices/+/data"}, devices, zerolog.Nop())
+	err = client.Connect()
+	assert.ErrorContains(t, err, "device-1")
+
+	ok, err := client.Publish(context.Background(), devices[0])
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "no MQTT connection")
+}
+
+func TestMqttDeviceClient_InvalidDownlink(t *testing.T) {
+	devices := []*loadgen.Device{{ID: "device-1"}}
+	client := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{
+		BrokerURL:    "tcp://127.0.0.1:1",
+		TopicPattern: "devices/+/data",
+		Downlink:     &loadgen.DownlinkConfig{CommandTopic: "devices/{deviceID}/commands"},
+	}, devices, zerolog.Nop())
+	assert.ErrorContains(t, client.Connect(), "handler")
+}
+
+func TestAckCommands(t *testing.T) {
+	reply, err := loadgen.AckCommands([]byte(`{"ack":true}`))(&loadgen.Device{ID: "device-1"}, "devices/device-1/commands", []byte(`{"cmd":"reboot"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"ack":true}`, string(reply))
+}
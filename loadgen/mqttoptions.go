@@ -0,0 +1,180 @@
+// loadgen/mqttoptions.go
+
+package loadgen
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// MqttOption configures the broker connections and publishing of an MqttClient,
+// MqttDeviceClient or MqttV5Client, e.g. to target an authenticated production-like broker.
+type MqttOption func(*mqttSettings)
+
+// mqttSettings holds the settings collected from MqttOptions.
+type mqttSettings struct {
+	tlsConfig    *tls.Config
+	caCerts      [][]byte
+	clientCerts  []tls.Certificate
+	username     string
+	password     string
+	cleanSession *bool
+	keepAlive    time.Duration
+
+	retained       bool
+	publishTimeout time.Duration
+	devicePublish  func(device *Device, opts MqttPublishOptions) MqttPublishOptions
+}
+
+// MqttPublishOptions are the QoS and retained flag of one message.
+type MqttPublishOptions struct {
+	QoS      byte
+	Retained bool
+}
+
+// defaultMqttPublishTimeout is how long a QoS 0 or 1 publish waits for its
+// acknowledgement. QoS 2 waits twice as long, for its two round trips.
+const defaultMqttPublishTimeout = 2 * time.Second
+
+// WithMqttTLSConfig connects with TLS using config. Use an "ssl://" or
+// "tls://" broker URL.
+func WithMqttTLSConfig(config *tls.Config) MqttOption {
+	return func(s *mqttSettings) { s.tlsConfig = config }
+}
+
+// WithMqttCACert trusts the PEM-encoded CA certificate when verifying the
+// broker, in addition to any roots of WithMqttTLSConfig. It enables TLS.
+func WithMqttCACert(pem []byte) MqttOption {
+	return func(s *mqttSettings) { s.caCerts = append(s.caCerts, pem) }
+}
+
+// WithMqttClientCertificate presents cert to brokers that require mutual
+// TLS. It enables TLS.
+func WithMqttClientCertificate(cert tls.Certificate) MqttOption {
+	return func(s *mqttSettings) { s.clientCerts = append(s.clientCerts, cert) }
+}
+
+// WithMqttCredentials authenticates with username and password.
+func WithMqttCredentials(username, password string) MqttOption {
+	return func(s *mqttSettings) { s.username, s.password = username, password }
+}
+
+// WithMqttCleanSession sets the clean-session flag. The default is true;
+// false asks the broker to keep session state across reconnects.
+func WithMqttCleanSession(clean bool) MqttOption {
+	return func(s *mqttSettings) { s.cleanSession = &clean }
+}
+
+// WithMqttKeepAlive sets the keepalive interval. The default is 30s.
+func WithMqttKeepAlive(keepAlive time.Duration) MqttOption {
+	return func(s *mqttSettings) { s.keepAlive = keepAlive }
+}
+
+// WithMqttRetained sets the retained flag on every message, so the broker
+// keeps the last message of each topic for new subscribers.
+func WithMqttRetained(retained bool) MqttOption {
+	return func(s *mqttSettings) { s.retained = retained }
+}
+
+// WithMqttDevicePublishOptions sets the QoS and retained flag per device.
+// fn receives the run's options, from the client's or Device.QoS and
+// WithMqttRetained, and returns the options of the device's next message.
+func WithMqttDevicePublishOptions(fn func(device *Device, opts MqttPublishOptions) MqttPublishOptions) MqttOption {
+	return func(s *mqttSettings) { s.devicePublish = fn }
+}
+
+// WithMqttPublishTimeout sets how long a publish waits for its
+// acknowledgement, or for QoS 2 its PUBCOMP, before it is counted as failed
+// with ErrPublishUnconfirmed. The default is 2s, doubled for QoS 2.
+func WithMqttPublishTimeout(timeout time.Duration) MqttOption {
+	return func(s *mqttSettings) { s.publishTimeout = timeout }
+}
+
+// newMqttSettings collects opts.
+func newMqttSettings(opts []MqttOption) mqttSettings {
+	var s mqttSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// apply sets the collected settings on opts.
+func (s mqttSettings) apply(opts *mqtt.ClientOptions) error {
+	config, err := s.tls()
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		opts.SetTLSConfig(config)
+	}
+	if s.username != "" {
+		opts.SetUsername(s.username)
+		opts.SetPassword(s.password)
+	}
+	if s.cleanSession != nil {
+		opts.SetCleanSession(*s.cleanSession)
+	}
+	if s.keepAlive > 0 {
+		opts.SetKeepAlive(s.keepAlive)
+	}
+	return nil
+}
+
+// publishOptions returns the options of device's next message, for a run
+// publishing at qos.
+func (s mqttSettings) publishOptions(device *Device, qos byte) MqttPublishOptions {
+	opts := MqttPublishOptions{QoS: qos, Retained: s.retained}
+	if device.QoS != nil {
+		opts.QoS = *device.QoS
+	}
+	if s.devicePublish != nil {
+		opts = s.devicePublish(device, opts)
+	}
+	return opts
+}
+
+// ackTimeout returns how long a publish at qos waits for its acknowledgement.
+func (s mqttSettings) ackTimeout(qos byte) time.Duration {
+	if s.publishTimeout > 0 {
+		return s.publishTimeout
+	}
+	if qos == 2 {
+		return 2 * defaultMqttPublishTimeout
+	}
+	return defaultMqttPublishTimeout
+}
+
+// tls returns the TLS configuration of the collected settings, or nil if
+// none of them enables TLS.
+func (s mqttSettings) tls() (*tls.Config, error) {
+	if s.tlsConfig == nil && len(s.caCerts) == 0 && len(s.clientCerts) == 0 {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.tlsConfig != nil {
+		// Clone shares the pool and the certificates with the caller's
+		// config, which other clients may use too, so neither is added to.
+		config = s.tlsConfig.Clone()
+		config.Certificates = slices.Clip(config.Certificates)
+	}
+	if len(s.caCerts) > 0 {
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		} else {
+			config.RootCAs = config.RootCAs.Clone()
+		}
+		for _, pem := range s.caCerts {
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, errors.New("invalid MQTT CA certificate")
+			}
+		}
+	}
+	config.Certificates = append(config.Certificates, s.clientCerts...)
+	return config, nil
+}
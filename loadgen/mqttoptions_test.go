@@ -0,0 +1,54 @@
+package loadgen_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/illmade-knight/go-test/auth"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMqttClient_InvalidCACert(t *testing.T) {
+	client := loadgen.NewMqttClient("tls://localhost:8883", "devices/+/data", 1, zerolog.Nop(),
+		loadgen.WithMqttCACert([]byte("not a certificate")),
+		loadgen.WithMqttCredentials("device", "secret"),
+	)
+	assert.ErrorContains(t, client.Connect(), "invalid MQTT CA certificate")
+
+	deviceClient := loadgen.NewMqttDeviceClient(loadgen.MqttDeviceClientConfig{
+		BrokerURL:    "tls://localhost:8883",
+		TopicPattern: "devices/+/data",
+		Options:      []loadgen.MqttOption{loadgen.WithMqttCACert([]byte("not a certificate"))},
+	}, []*loadgen.Device{{ID: "device-1"}}, zerolog.Nop())
+	assert.ErrorContains(t, deviceClient.Connect(), "invalid MQTT CA certificate")
+}
+
+func TestMqttOptions_TLSConfigNotModified(t *testing.T) {
+	ca := auth.NewTestCA(t)
+	cert, err := ca.IssueClient("device-1")
+	require.NoError(t, err)
+	// A shared config, with room in its certificates for an append to
+	// write into.
+	pool := x509.NewCertPool()
+	certs := make([]tls.Certificate, 0, 2)
+	shared := &tls.Config{RootCAs: pool, Certificates: certs}
+
+	// The TLS config is built before the unsupported scheme is refused.
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    "ws://localhost:8080",
+		TopicPattern: "devices/+/data",
+		Options: []loadgen.MqttOption{
+			loadgen.WithMqttTLSConfig(shared),
+			loadgen.WithMqttCACert(ca.CertPEM),
+			loadgen.WithMqttClientCertificate(cert.TLS),
+		},
+	}, zerolog.Nop())
+	assert.ErrorContains(t, client.Connect(), "unsupported broker URL scheme")
+
+	assert.True(t, pool.Equal(x509.NewCertPool()), "the shared pool gained the CA")
+	assert.Empty(t, certs[:1][0].Certificate, "the shared certificates were written to")
+}
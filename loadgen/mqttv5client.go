@@ -0,0 +1,234 @@
+// loadgen/mqttv5client.go
+
+package loadgen
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// MqttV5Properties are the MQTT v5 properties attached to one message.
+type MqttV5Properties struct {
+	// User are the user properties, sent in the order given.
+	User []paho.UserProperty
+	// MessageExpiry is how long the broker keeps the message for subscribers
+	// that have not received it yet. Zero means the message does not expire.
+	MessageExpiry time.Duration
+	// TopicAlias replaces the topic with a number after the first message,
+	// to save bandwidth. An alias must always be used with the same topic, and
+	// must not exceed the broker's topic alias maximum. Zero means no alias.
+	TopicAlias uint16
+}
+
+// MqttV5ClientConfig holds configuration for an MqttV5Client.
+type MqttV5ClientConfig struct {
+	// BrokerURL is the broker address, e.g. "tcp://localhost:1883". An
+	// "ssl://", "tls://" or "mqtts://" URL, or a TLS MqttOption, connects with TLS.
+	BrokerURL string
+	// TopicPattern is the TopicTemplate pattern of each message's topic, as
+	// for MqttClient.
+	TopicPattern string
+	QoS          byte
+	// Properties returns the v5 properties of a device's next message. If
+	// nil, messages have no properties. Device.Headers and the attributes of
+	// an AttributeGenerator are added as user properties.
+	Properties func(device *Device) MqttV5Properties
+	// Options configure TLS, credentials, clean start, keepalive and
+	// publishing, as for MqttClient.
+	Options []MqttOption
+}
+
+// MqttV5Client implements the Client interface for MQTT v5, for brokers and
+// downstream routing that depend on v5 message properties. It uses a single
+// connection for all devices and, unlike MqttClient, does not reconnect.
+type MqttV5Client struct {
+	cfg      MqttV5ClientConfig
+	settings mqttSettings
+	topics   *deviceTopics
+	client   *paho.Client
+	logger   zerolog.Logger
+
+	// aliasMax is the broker's topic alias maximum.
+	aliasMax uint16
+	// aliases maps each topic alias the broker knows to its topic.
+	aliasMu sync.Mutex
+	aliases map[uint16]string
+}
+
+// NewMqttV5Client creates a new MQTT v5 client.
+func NewMqttV5Client(cfg MqttV5ClientConfig, logger zerolog.Logger) Client {
+	return &MqttV5Client{cfg: cfg, settings: newMqttSettings(cfg.Options), logger: logger}
+}
+
+// Connect establishes a connection to the MQTT broker.
+func (c *MqttV5Client) Connect() error {
+	u, err := url.Parse(c.cfg.BrokerURL)
+	if err != nil {
+		return fmt.Errorf("invalid broker URL %s: %w", c.cfg.BrokerURL, err)
+	}
+	if c.topics, err = newDeviceTopics(c.cfg.TopicPattern); err != nil {
+		return err
+	}
+	tlsConfig, err := c.settings.tls()
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "ssl", "tls", "mqtts":
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	case "tcp", "mqtt":
+	default:
+		return fmt.Errorf("unsupported broker URL scheme %q", u.Scheme)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", u.Host)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", c.cfg.BrokerURL, err)
+	}
+
+	clientID := fmt.Sprintf("loadgen-client-%s", uuid.New().String())
+	c.client = paho.NewClient(paho.ClientConfig{
+		ClientID: clientID,
+		// tls.Conn is not safe for concurrent writes, and devices publish concurrently.
+		Conn: packets.NewThreadSafeConn(conn),
+		OnClientError: func(err error) {
+			c.logger.Error().Err(err).Msg("MQTT v5 connection error")
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			c.logger.Error().Uint8("reason_code", d.ReasonCode).Msg("MQTT v5 broker disconnected the client")
+		},
+	})
+
+	cp := &paho.Connect{ClientID: clientID, KeepAlive: 30, CleanStart: true}
+	if c.settings.keepAlive > 0 {
+		cp.KeepAlive = uint16(c.settings.keepAlive / time.Second)
+	}
+	if c.settings.cleanSession != nil {
+		cp.CleanStart = *c.settings.cleanSession
+	}
+	if c.settings.username != "" {
+		cp.Username, cp.UsernameFlag = c.settings.username, true
+		cp.Password, cp.PasswordFlag = []byte(c.settings.password), true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	connack, err := c.client.Connect(ctx, cp)
+	if err != nil {
+		_ = conn.Close()
+		c.logger.Error().Err(err).Msg("Failed to connect to MQTT broker")
+		return fmt.Errorf("failed to connect to %s: %w", c.cfg.BrokerURL, err)
+	}
+
+	c.aliasMax = 0
+	if connack.Properties != nil && connack.Properties.TopicAliasMaximum != nil {
+		c.aliasMax = *connack.Properties.TopicAliasMaximum
+	}
+	c.aliases = make(map[uint16]string)
+	c.logger.Info().Str("broker", c.cfg.BrokerURL).Uint16("topic_alias_max", c.aliasMax).Msg("Successfully connected to MQTT v5 broker")
+	return nil
+}
+
+// Disconnect closes the connection to the MQTT broker.
+func (c *MqttV5Client) Disconnect() {
+	if c.client == nil {
+		return
+	}
+	_ = c.client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+	c.client = nil
+	c.logger.Info().Msg("MQTT v5 client disconnected")
+}
+
+// Publish generates a payload and sends it with the device's v5 properties.
+// It returns true only on a successful publish acknowledgement.
+func (c *MqttV5Client) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	payloadBytes, attributes, err := GenerateMessage(device)
+	if err != nil {
+		return false, err
+	}
+
+	topic, err := c.topics.expand(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to build topic for device %s: %w", device.ID, err)
+	}
+	setSpanDestination(ctx, topic)
+	pubOpts := c.settings.publishOptions(device, c.cfg.QoS)
+	msg := &paho.Publish{QoS: pubOpts.QoS, Retain: pubOpts.Retained, Topic: topic, Payload: payloadBytes}
+	var alias uint16
+	if c.cfg.Properties != nil || len(attributes) > 0 {
+		var props MqttV5Properties
+		if c.cfg.Properties != nil {
+			props = c.cfg.Properties(device)
+		}
+		// Clip so adding the headers cannot write into a slice shared between messages.
+		msg.Properties = &paho.PublishProperties{User: slices.Clip(props.User)}
+		for _, k := range slices.Sorted(maps.Keys(attributes)) {
+			msg.Properties.User.Add(k, attributes[k])
+		}
+		if props.MessageExpiry > 0 {
+			expiry := uint32(props.MessageExpiry / time.Second)
+			msg.Properties.MessageExpiry = &expiry
+		}
+		if alias = props.TopicAlias; alias > 0 {
+			if alias > c.aliasMax {
+				return false, fmt.Errorf("topic alias %d for device %s exceeds the broker maximum of %d", alias, device.ID, c.aliasMax)
+			}
+			msg.Properties.TopicAlias = &alias
+			// Once the broker knows the alias, the topic can be left out.
+			c.aliasMu.Lock()
+			if c.aliases[alias] == topic {
+				msg.Topic = ""
+			}
+			c.aliasMu.Unlock()
+		}
+	}
+
+	// As for MqttClient, the acknowledgement wait is independent of ctx.
+	pubCtx, cancel := context.WithTimeout(context.Background(), c.settings.ackTimeout(pubOpts.QoS))
+	defer cancel()
+	resp, err := c.client.Publish(pubCtx, msg)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out waiting for publish confirmation: %w", ErrPublishUnconfirmed)
+	}
+	if err == nil && resp != nil && resp.ReasonCode >= 0x80 {
+		err = fmt.Errorf("broker rejected the message with reason code %#x", resp.ReasonCode)
+	}
+	if err != nil {
+		err = fmt.Errorf("mqtt publish error for device %s: %w", device.ID, err)
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+
+	if alias > 0 {
+		c.aliasMu.Lock()
+		c.aliases[alias] = topic
+		c.aliasMu.Unlock()
+	}
+	c.logger.Debug().Str("device_id", device.ID).Str("topic", topic).Msg("Message published")
+	return true, nil
+}
@@ -0,0 +1,247 @@
+package loadgen_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startFakeMqttV5Broker accepts one MQTT v5 connection, acknowledges it with
+// a topic alias maximum of aliasMax, and forwards every PUBLISH it receives,
+// acknowledging QoS 1 messages. QoS 2 messages are never acknowledged.
+func startFakeMqttV5Broker(t *testing.T, aliasMax uint16) (string, <-chan *packets.Publish) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	published := make(chan *packets.Publish, 10)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			cp, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p := cp.Content.(type) {
+			case *packets.Connect:
+				ack := packets.NewControlPacket(packets.CONNACK)
+				ack.Content.(*packets.Connack).Properties = &packets.Properties{TopicAliasMaximum: &aliasMax}
+				_, _ = ack.WriteTo(conn)
+			case *packets.Publish:
+				published <- p
+				if p.QoS == 1 {
+					ack := packets.NewControlPacket(packets.PUBACK)
+					ack.Content.(*packets.Puback).PacketID = p.PacketID
+					_, _ = ack.WriteTo(conn)
+				}
+			case *packets.Disconnect:
+				return
+			}
+		}
+	}()
+	return "tcp://" + lis.Addr().String(), published
+}
+
+func TestMqttV5Client_Publish(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 10)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/+/data",
+		QoS:          1,
+		Properties: func(device *loadgen.Device) loadgen.MqttV5Properties {
+			return loadgen.MqttV5Properties{
+				User:          []paho.UserProperty{{Key: "device", Value: device.ID}},
+				MessageExpiry: time.Minute,
+				TopicAlias:    3,
+			}
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "device-1", PayloadGenerator: staticPayloadGenerator("hello")}
+	for range 2 {
+		ok, err := client.Publish(context.Background(), device)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	var got []*packets.Publish
+	for range 2 {
+		select {
+		case p := <-published:
+			got = append(got, p)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+	// The first message establishes the alias; the second uses it alone.
+	assert.Equal(t, "devices/device-1/data", got[0].Topic)
+	assert.Empty(t, got[1].Topic)
+	for _, p := range got {
+		assert.Equal(t, "hello", string(p.Payload))
+		require.NotNil(t, p.Properties.TopicAlias)
+		assert.Equal(t, uint16(3), *p.Properties.TopicAlias)
+		require.NotNil(t, p.Properties.MessageExpiry)
+		assert.Equal(t, uint32(60), *p.Properties.MessageExpiry)
+		assert.Equal(t, []packets.User{{Key: "device", Value: "device-1"}}, p.Properties.User)
+	}
+}
+
+func TestMqttV5Client_TopicAliasAboveBrokerMaximum(t *testing.T) {
+	brokerURL, _ := startFakeMqttV5Broker(t, 0)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/+/data",
+		Properties: func(*loadgen.Device) loadgen.MqttV5Properties {
+			return loadgen.MqttV5Properties{TopicAlias: 1}
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "device-1", PayloadGenerator: staticPayloadGenerator("hello")})
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "exceeds the broker maximum of 0")
+}
+
+func TestMqttV5Client_UnsupportedScheme(t *testing.T) {
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{BrokerURL: "ws://localhost:8080"}, zerolog.Nop())
+	assert.ErrorContains(t, client.Connect(), "unsupported broker URL scheme")
+}
+
+func TestMqttV5Client_RetainedAndDeviceQoS(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 0)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/+/state",
+		QoS:          1,
+		Options: []loadgen.MqttOption{
+			loadgen.WithMqttRetained(true),
+			loadgen.WithMqttDevicePublishOptions(func(device *loadgen.Device, opts loadgen.MqttPublishOptions) loadgen.MqttPublishOptions {
+				if device.ID == "sensor" {
+					opts.QoS, opts.Retained = 0, false
+				}
+				return opts
+			}),
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	for _, id := range []string{"gateway", "sensor"} {
+		ok, err := client.Publish(context.Background(), &loadgen.Device{ID: id, PayloadGenerator: staticPayloadGenerator("on")})
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		select {
+		case p := <-published:
+			if id == "gateway" {
+				assert.Equal(t, byte(1), p.QoS)
+				assert.True(t, p.Retain)
+			} else {
+				assert.Equal(t, byte(0), p.QoS)
+				assert.False(t, p.Retain)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
+func TestMqttV5Client_QoS2HandshakeTimeout(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 0)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/+/data",
+		QoS:          2,
+		Options:      []loadgen.MqttOption{loadgen.WithMqttPublishTimeout(100 * time.Millisecond)},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "device-1", PayloadGenerator: staticPayloadGenerator("hello")})
+	assert.False(t, ok, "an unfinished QoS 2 handshake must not count as published")
+	assert.ErrorIs(t, err, loadgen.ErrPublishUnconfirmed)
+	// The broker did receive the message.
+	select {
+	case p := <-published:
+		assert.Equal(t, byte(2), p.QoS)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestMqttV5Client_TopicTemplate(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 0)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "tenants/{tenant}/{type}/{deviceID}",
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "device-1", PayloadGenerator: staticPayloadGenerator("hello"), Metadata: map[string]string{"tenant": "acme", "type": "meter"}}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	select {
+	case p := <-published:
+		assert.Equal(t, "tenants/acme/meter/device-1", p.Topic)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+
+	ok, err = client.Publish(context.Background(), &loadgen.Device{ID: "device-2", PayloadGenerator: staticPayloadGenerator("hello")})
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "failed to build topic for device device-2")
+}
+
+func TestMqttV5Client_DeviceOverrides(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 0)
+	client := loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/{deviceID}/data",
+		QoS:          1,
+		Properties: func(*loadgen.Device) loadgen.MqttV5Properties {
+			return loadgen.MqttV5Properties{User: []paho.UserProperty{{Key: "source", Value: "loadgen"}}}
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	qos := byte(0)
+	device := &loadgen.Device{
+		ID:               "gw-1",
+		PayloadGenerator: staticPayloadGenerator("hello"),
+		Metadata:         map[string]string{"site": "north"},
+		QoS:              &qos,
+		Topic:            "sites/{site}/gateways/{deviceID}",
+		Headers:          map[string]string{"tenant": "acme", "firmware": "1.2"},
+	}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	select {
+	case p := <-published:
+		assert.Equal(t, "sites/north/gateways/gw-1", p.Topic)
+		assert.Equal(t, byte(0), p.QoS)
+		assert.Equal(t, []packets.User{{Key: "source", Value: "loadgen"}, {Key: "firmware", Value: "1.2"}, {Key: "tenant", Value: "acme"}}, p.Properties.User)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
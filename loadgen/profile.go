@@ -0,0 +1,225 @@
+// loadgen/profile.go
+
+package loadgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// ProfilingConfig selects what SetProfiling captures of the generator's own
+// process during a run. The zero value captures nothing.
+type ProfilingConfig struct {
+	// CPUProfile captures a CPU profile of the whole run. Only one CPU
+	// profile can run in a process at a time, so none is captured under
+	// go test -cpuprofile.
+	CPUProfile bool
+	// HeapProfile captures a heap profile as the run ends.
+	HeapProfile bool
+	// RuntimeInterval samples the process's goroutines, heap, garbage
+	// collection and CPU use every interval. Zero samples only at the start
+	// and end of the run.
+	RuntimeInterval time.Duration
+}
+
+// SetProfiling makes each run profile the generator process as cfg
+// selects and attach the profiles to its Results, to tell a saturated
+// generator from a saturated system under test: if the generator's CPU use
+// nears 100% or its GC pauses grow while the publish rate falls short, the
+// bottleneck is the generator. Runtime metrics are sampled whenever cfg is
+// not the zero value.
+func (lg *LoadGenerator) SetProfiling(cfg ProfilingConfig) {
+	lg.profiling = cfg
+}
+
+// RuntimeSample is the state of the generator process during a run.
+type RuntimeSample struct {
+	// Elapsed is the time since the run started.
+	Elapsed    time.Duration
+	Goroutines int
+	// HeapAlloc is the size of the live and not yet collected heap objects.
+	HeapAlloc uint64
+	// GCCycles and GCPause are the garbage collections since the run
+	// started and how long they stopped the world in all.
+	GCCycles uint32
+	GCPause  time.Duration
+	// CPU is the share of GOMAXPROCS the process used since the previous
+	// sample, 0 to 1, or -1 on platforms that cannot tell.
+	CPU float64
+}
+
+// RunProfile is what a run captured of the generator process under
+// SetProfiling.
+type RunProfile struct {
+	// CPUProfile and HeapProfile are in pprof format, for go tool pprof, or
+	// nil if not captured.
+	CPUProfile  []byte
+	HeapProfile []byte
+	// Samples are the runtime samples, the first at the start of the run
+	// and the last at its end.
+	Samples []RuntimeSample
+	// PeakGoroutines and PeakHeapAlloc are the highest of the samples, and
+	// GCCycles and GCPause those of the whole run.
+	PeakGoroutines int
+	PeakHeapAlloc  uint64
+	GCCycles       uint32
+	GCPause        time.Duration
+	// MeanCPU is the share of GOMAXPROCS the process used over the run, and
+	// PeakCPU the highest of the samples. Both are -1 on platforms that
+	// cannot tell.
+	MeanCPU float64
+	PeakCPU float64
+}
+
+// WriteFiles writes the profiles captured to dir, as cpu.pprof and
+// heap.pprof.
+func (p *RunProfile) WriteFiles(dir string) error {
+	for name, profile := range map[string][]byte{"cpu.pprof": p.CPUProfile, "heap.pprof": p.HeapProfile} {
+		if profile == nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), profile, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// profiler captures a RunProfile during a run.
+type profiler struct {
+	lg    *LoadGenerator
+	start time.Time
+	cpu   bytes.Buffer
+	// cpuOn is set while the CPU profile runs.
+	cpuOn bool
+	// gcBase is the state of the garbage collector when profiling started.
+	gcBase runtime.MemStats
+	// cpuOK says whether the platform reports the process's CPU time,
+	// which was cpuStart when profiling started at cpuStartAt and cpuTime
+	// at the latest sample, taken at cpuAt.
+	cpuOK      bool
+	cpuStart   time.Duration
+	cpuStartAt time.Time
+	cpuTime    time.Duration
+	cpuAt      time.Time
+	samples    []RuntimeSample
+	done       chan struct{}
+	stopped    chan struct{}
+}
+
+// startProfiling starts profiling a run that started at start, or returns
+// nil if SetProfiling selects nothing.
+func (lg *LoadGenerator) startProfiling(start time.Time) *profiler {
+	if lg.profiling == (ProfilingConfig{}) {
+		return nil
+	}
+	p := &profiler{lg: lg, start: start, done: make(chan struct{}), stopped: make(chan struct{})}
+	runtime.ReadMemStats(&p.gcBase)
+	p.cpuStart, p.cpuOK = processCPUTime()
+	p.cpuStartAt = time.Now()
+	p.cpuTime, p.cpuAt = p.cpuStart, p.cpuStartAt
+	p.sample()
+	if lg.profiling.CPUProfile {
+		if err := pprof.StartCPUProfile(&p.cpu); err != nil {
+			lg.logger.Warn().Err(err).Msg("CPU profile not captured.")
+		} else {
+			p.cpuOn = true
+		}
+	}
+	go func() {
+		defer close(p.stopped)
+		if lg.profiling.RuntimeInterval <= 0 {
+			<-p.done
+			return
+		}
+		ticker := time.NewTicker(lg.profiling.RuntimeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.sample()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// sample records the state of the process.
+func (p *profiler) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	now := time.Now()
+	s := RuntimeSample{
+		Elapsed:    now.Sub(p.start),
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  m.HeapAlloc,
+		GCCycles:   m.NumGC - p.gcBase.NumGC,
+		GCPause:    time.Duration(m.PauseTotalNs - p.gcBase.PauseTotalNs),
+		CPU:        -1,
+	}
+	if p.cpuOK {
+		// The first sample has no previous one to measure from.
+		cpuTime, _ := processCPUTime()
+		s.CPU = 0
+		if len(p.samples) > 0 {
+			s.CPU = cpuShare(cpuTime-p.cpuTime, now.Sub(p.cpuAt))
+		}
+		p.cpuTime, p.cpuAt = cpuTime, now
+	}
+	p.samples = append(p.samples, s)
+}
+
+// cpuShare returns the share of GOMAXPROCS that cpu time used over wall
+// time, 0 over no time.
+func cpuShare(cpu, wall time.Duration) float64 {
+	if wall <= 0 {
+		return 0
+	}
+	return float64(cpu) / float64(wall) / float64(runtime.GOMAXPROCS(0))
+}
+
+// stop ends profiling and returns the profile. It is nil if p is.
+func (p *profiler) stop() *RunProfile {
+	if p == nil {
+		return nil
+	}
+	close(p.done)
+	<-p.stopped
+	if p.cpuOn {
+		pprof.StopCPUProfile()
+	}
+	p.sample()
+
+	profile := &RunProfile{Samples: p.samples, MeanCPU: -1, PeakCPU: -1}
+	if p.cpuOn {
+		profile.CPUProfile = p.cpu.Bytes()
+	}
+	if p.lg.profiling.HeapProfile {
+		// The heap profile is as of the latest garbage collection.
+		runtime.GC()
+		var heap bytes.Buffer
+		if err := pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+			p.lg.logger.Warn().Err(err).Msg("Heap profile not captured.")
+		} else {
+			profile.HeapProfile = heap.Bytes()
+		}
+	}
+	for _, s := range p.samples {
+		profile.PeakGoroutines = max(profile.PeakGoroutines, s.Goroutines)
+		profile.PeakHeapAlloc = max(profile.PeakHeapAlloc, s.HeapAlloc)
+		profile.PeakCPU = max(profile.PeakCPU, s.CPU)
+	}
+	last := p.samples[len(p.samples)-1]
+	profile.GCCycles, profile.GCPause = last.GCCycles, last.GCPause
+	if p.cpuOK {
+		profile.MeanCPU = cpuShare(p.cpuTime-p.cpuStart, p.cpuAt.Sub(p.cpuStartAt))
+	}
+	return profile
+}
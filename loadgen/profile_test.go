@@ -0,0 +1,75 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerator_SetProfiling(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(100).WithGenerator(func(string) loadgen.PayloadGenerator {
+		return staticPayloadGenerator(`{"reading": 1}`)
+	}).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(&recordingClient{}, devices, zerolog.Nop())
+	lg.SetProfiling(loadgen.ProfilingConfig{CPUProfile: true, HeapProfile: true, RuntimeInterval: 20 * time.Millisecond})
+
+	_, err = lg.RunUntil(context.Background(), 200*time.Millisecond)
+	require.NoError(t, err)
+
+	results := lg.Results()
+	profile := results.Profile
+	require.NotNil(t, profile)
+	require.GreaterOrEqual(t, len(profile.Samples), 5)
+	assert.Less(t, profile.Samples[0].Elapsed, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, profile.Samples[len(profile.Samples)-1].Elapsed, 200*time.Millisecond)
+	assert.GreaterOrEqual(t, profile.PeakGoroutines, 10, "a worker per device")
+	assert.Positive(t, profile.PeakHeapAlloc)
+	if runtime.GOOS != "windows" {
+		assert.GreaterOrEqual(t, profile.MeanCPU, 0.0)
+		assert.GreaterOrEqual(t, profile.PeakCPU, profile.MeanCPU/2)
+	}
+
+	// pprof profiles are gzipped protocol buffers.
+	gzipMagic := []byte{0x1f, 0x8b}
+	assert.True(t, bytes.HasPrefix(profile.CPUProfile, gzipMagic))
+	assert.True(t, bytes.HasPrefix(profile.HeapProfile, gzipMagic))
+	dir := t.TempDir()
+	require.NoError(t, profile.WriteFiles(dir))
+	for _, name := range []string{"cpu.pprof", "heap.pprof"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.NotEmpty(t, data)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteJSON(&buf))
+	var decoded struct {
+		Profile struct {
+			PeakGoroutines int              `json:"peak_goroutines"`
+			Samples        []map[string]any `json:"samples"`
+		} `json:"profile"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, profile.PeakGoroutines, decoded.Profile.PeakGoroutines)
+	assert.Len(t, decoded.Profile.Samples, len(profile.Samples))
+	assert.Contains(t, results.Summary(), "Generator:")
+}
+
+func TestLoadGenerator_NoProfiling(t *testing.T) {
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), []*loadgen.Device{{ID: "device-1", MessageRate: 10}}, zerolog.Nop())
+	_, err := lg.RunUntil(context.Background(), 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Nil(t, lg.Results().Profile)
+	assert.NotContains(t, lg.Results().Summary(), "Generator:")
+}
@@ -0,0 +1,69 @@
+// loadgen/progress.go
+
+package loadgen
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is the progress of a run at a point in time.
+type Snapshot struct {
+	// Elapsed is the time since the run started.
+	Elapsed time.Duration
+	// Published and Failed are the publishes so far, counted as in RunResult.
+	Published int
+	Failed    int
+	// Retries is the number of retries so far.
+	Retries int
+	// Rate is the number of messages published per second since the
+	// previous snapshot.
+	Rate float64
+	// Final is set on the snapshot taken after every device has stopped.
+	Final bool
+}
+
+// ProgressFunc receives the snapshots of a run. It is never called
+// concurrently, and a slow ProgressFunc delays the next snapshot, not the
+// publishes.
+type ProgressFunc func(Snapshot)
+
+// OnProgress calls fn with a Snapshot every interval while Run or RunUntil
+// runs, and once more with the final counts when the run ends, e.g. to log
+// throughput during a long load test.
+func (lg *LoadGenerator) OnProgress(interval time.Duration, fn ProgressFunc) {
+	lg.progressInterval, lg.progress = interval, fn
+}
+
+// reportProgress calls the ProgressFunc every progressInterval until done
+// is closed, then once more with the final counts.
+func (lg *LoadGenerator) reportProgress(start time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(lg.progressInterval)
+	defer ticker.Stop()
+	last, lastAt := 0, start
+	snapshot := func(final bool) {
+		now := time.Now()
+		s := Snapshot{
+			Elapsed:   now.Sub(start),
+			Published: int(atomic.LoadInt64(&lg.publishedCount)),
+			Failed:    int(atomic.LoadInt64(&lg.failedCount)),
+			Retries:   int(atomic.LoadInt64(&lg.retriedCount)),
+			Final:     final,
+		}
+		if since := now.Sub(lastAt); since > 0 {
+			s.Rate = float64(s.Published-last) / since.Seconds()
+		}
+		last, lastAt = s.Published, now
+		lg.snapshots = append(lg.snapshots, s)
+		lg.progress(s)
+	}
+	for {
+		select {
+		case <-ticker.C:
+			snapshot(false)
+		case <-done:
+			snapshot(true)
+			return
+		}
+	}
+}
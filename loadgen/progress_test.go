@@ -0,0 +1,54 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerator_OnProgress(t *testing.T) {
+	devices := []*loadgen.Device{{ID: "device-1", MessageRate: 100}, {ID: "device-2", MessageRate: 100}}
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	// The ProgressFunc is never called concurrently, so no lock is needed.
+	var snapshots []loadgen.Snapshot
+	lg.OnProgress(50*time.Millisecond, func(s loadgen.Snapshot) {
+		snapshots = append(snapshots, s)
+	})
+	result, err := lg.RunUntil(context.Background(), 275*time.Millisecond)
+	require.NoError(t, err)
+
+	require.GreaterOrEqual(t, len(snapshots), 4)
+	final := snapshots[len(snapshots)-1]
+	assert.True(t, final.Final)
+	assert.Equal(t, result.Published, final.Published)
+	assert.Equal(t, result.Failed, final.Failed)
+	for i, s := range snapshots[:len(snapshots)-1] {
+		assert.False(t, s.Final)
+		assert.Positive(t, s.Rate)
+		if i > 0 {
+			assert.GreaterOrEqual(t, s.Published, snapshots[i-1].Published)
+			assert.Greater(t, s.Elapsed, snapshots[i-1].Elapsed)
+		}
+	}
+}
+
+func TestLoadGenerator_OnProgressFinalOnly(t *testing.T) {
+	// A run shorter than the interval still reports its final counts.
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), []*loadgen.Device{{ID: "device-1", MessageRate: 10}}, zerolog.Nop())
+	var snapshots []loadgen.Snapshot
+	lg.OnProgress(time.Hour, func(s loadgen.Snapshot) {
+		snapshots = append(snapshots, s)
+	})
+	count, err := lg.Run(context.Background(), 50*time.Millisecond)
+	require.NoError(t, err)
+
+	require.Len(t, snapshots, 1)
+	assert.True(t, snapshots[0].Final)
+	assert.Equal(t, count, snapshots[0].Published)
+}
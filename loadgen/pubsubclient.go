@@ -0,0 +1,112 @@
+// loadgen/pubsubclient.go
+
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/rs/zerolog"
+	"google.golang.org/api/option"
+)
+
+// PubsubClientConfig holds configuration for a PubsubClient.
+type PubsubClientConfig struct {
+	// ProjectID and TopicID identify the topic to publish to. The topic must exist.
+	ProjectID string
+	TopicID   string
+	// ClientOptions are passed to the Pub/Sub client, e.g. the ClientOptions of
+	// an emulator's connection info. Leave empty for a real project.
+	ClientOptions []option.ClientOption
+	// OrderingKey, when set, enables message ordering and is used as each
+	// message's ordering key, with DeviceIDPlaceholder replaced by the device
+	// ID (e.g. "{deviceID}" orders per device).
+	OrderingKey string
+	// Attributes are added to every message, with DeviceIDPlaceholder in the
+	// values replaced by the device ID.
+	Attributes map[string]string
+}
+
+// PubsubClient implements the Client interface for Google Cloud Pub/Sub.
+type PubsubClient struct {
+	cfg       PubsubClientConfig
+	client    *pubsub.Client
+	publisher *pubsub.Publisher
+	logger    zerolog.Logger
+}
+
+// NewPubsubClient creates a new Pub/Sub client.
+func NewPubsubClient(cfg PubsubClientConfig, logger zerolog.Logger) Client {
+	return &PubsubClient{cfg: cfg, logger: logger}
+}
+
+// Connect creates the Pub/Sub client and the publisher for the topic.
+func (c *PubsubClient) Connect() error {
+	client, err := pubsub.NewClient(context.Background(), c.cfg.ProjectID, c.cfg.ClientOptions...)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to create Pub/Sub client")
+		return err
+	}
+	c.client = client
+	c.publisher = client.Publisher(c.cfg.TopicID)
+	c.publisher.EnableMessageOrdering = c.cfg.OrderingKey != ""
+	c.logger.Info().Str("project_id", c.cfg.ProjectID).Str("topic_id", c.cfg.TopicID).Msg("Pub/Sub client ready")
+	return nil
+}
+
+// Disconnect flushes pending messages and closes the client.
+func (c *PubsubClient) Disconnect() {
+	if c.client == nil {
+		return
+	}
+	c.publisher.Stop()
+	_ = c.client.Close()
+	c.client, c.publisher = nil, nil
+	c.logger.Info().Msg("Pub/Sub client disconnected")
+}
+
+// Publish generates a payload and publishes it to the topic.
+// It returns true only once the server has acknowledged the message.
+func (c *PubsubClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	payloadBytes, attributes, err := GenerateMessage(device)
+	if err != nil {
+		return false, err
+	}
+
+	setSpanDestination(ctx, c.cfg.TopicID)
+	msg := &pubsub.Message{
+		Data:        payloadBytes,
+		OrderingKey: expandDeviceID(c.cfg.OrderingKey, device),
+	}
+	if len(c.cfg.Attributes)+len(attributes) > 0 {
+		msg.Attributes = make(map[string]string, len(c.cfg.Attributes)+len(attributes))
+		for k, v := range c.cfg.Attributes {
+			msg.Attributes[k] = expandDeviceID(v, device)
+		}
+		maps.Copy(msg.Attributes, attributes)
+	}
+
+	if _, err := c.publisher.Publish(ctx, msg).Get(ctx); err != nil {
+		if msg.OrderingKey != "" {
+			// A failed publish pauses its ordering key until resumed.
+			c.publisher.ResumePublish(msg.OrderingKey)
+		}
+		err = fmt.Errorf("pubsub publish error for device %s: %w", device.ID, err)
+		c.logger.Warn().Err(err).Msg("Publish failed")
+		return false, err
+	}
+	c.logger.Debug().Str("device_id", device.ID).Str("topic_id", c.cfg.TopicID).Msg("Message published")
+	return true, nil
+}
+
+// expandDeviceID replaces DeviceIDPlaceholder in pattern with the device ID.
+func expandDeviceID(pattern string, device *Device) string {
+	return strings.ReplaceAll(pattern, DeviceIDPlaceholder, device.ID)
+}
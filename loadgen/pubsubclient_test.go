@@ -0,0 +1,73 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestPubsubClient_Publish(t *testing.T) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	_, err := srv.GServer.CreateTopic(context.Background(), &pubsubpb.Topic{Name: "projects/load-project/topics/telemetry"})
+	require.NoError(t, err)
+
+	client := loadgen.NewPubsubClient(loadgen.PubsubClientConfig{
+		ProjectID: "load-project",
+		TopicID:   "telemetry",
+		ClientOptions: []option.ClientOption{
+			option.WithEndpoint(srv.Addr),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		},
+		OrderingKey: "{deviceID}",
+		Attributes:  map[string]string{"source": "loadgen", "device": "dev-{deviceID}"},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{
+		ID:               "garden-01",
+		PayloadGenerator: staticPayloadGenerator(`{"t":21}`),
+		Headers:          map[string]string{"tenant": "acme", "source": "device"},
+	}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	msgs := srv.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"t":21}`, string(msgs[0].Data))
+	assert.Equal(t, "garden-01", msgs[0].OrderingKey)
+	assert.Equal(t, map[string]string{"source": "device", "device": "dev-garden-01", "tenant": "acme"}, msgs[0].Attributes)
+}
+
+func TestPubsubClient_PublishToMissingTopic(t *testing.T) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client := loadgen.NewPubsubClient(loadgen.PubsubClientConfig{
+		ProjectID: "load-project",
+		TopicID:   "missing",
+		ClientOptions: []option.ClientOption{
+			option.WithEndpoint(srv.Addr),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		},
+	}, zerolog.Nop())
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "garden-01", PayloadGenerator: staticPayloadGenerator(`{}`)})
+	assert.False(t, ok)
+	assert.Error(t, err)
+}
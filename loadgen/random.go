@@ -0,0 +1,110 @@
+// loadgen/random.go
+
+package loadgen
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+)
+
+// seeding holds the seed set by SetSeed and the random source of each
+// device derived from it.
+var seeding struct {
+	mu      sync.Mutex
+	seeded  bool
+	seed    uint64
+	devices map[string]*rand.Rand
+}
+
+// unseeded draws from the runtime's random source, as the top-level
+// functions of math/rand/v2 do.
+var unseeded = rand.New(runtimeSource{})
+
+type runtimeSource struct{}
+
+func (runtimeSource) Uint64() uint64 { return rand.Uint64() }
+
+// lockedSource makes a rand.Source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+// SetSeed makes the randomness of loadgen reproducible: the jitter of
+// JitteredTiming and PoissonTiming, the sizes and content of
+// BinaryPayloadGenerator payloads and the random functions of payload
+// templates. Each device draws from its own source, derived from seed and
+// its ID, so the values a device sees do not depend on how the devices'
+// goroutines interleave. Call SetSeed again before each run to replay it,
+// and log the seed so a failing run can be replayed.
+func SetSeed(seed uint64) {
+	seeding.mu.Lock()
+	defer seeding.mu.Unlock()
+	seeding.seeded, seeding.seed = true, seed
+	seeding.devices = make(map[string]*rand.Rand)
+}
+
+// ClearSeed makes loadgen's randomness unpredictable again, as it is by
+// default.
+func ClearSeed() {
+	seeding.mu.Lock()
+	defer seeding.mu.Unlock()
+	seeding.seeded, seeding.devices = false, nil
+}
+
+// DeviceRand returns the random source of the device with id, for payload
+// generators and Timings of your own that should follow SetSeed. Without a
+// seed it is unpredictable. It is safe for concurrent use.
+func DeviceRand(id string) *rand.Rand {
+	seeding.mu.Lock()
+	defer seeding.mu.Unlock()
+	if !seeding.seeded {
+		return unseeded
+	}
+	r, ok := seeding.devices[id]
+	if !ok {
+		r = rand.New(&lockedSource{src: DeviceSource(seeding.seed, id)})
+		seeding.devices[id] = r
+	}
+	return r
+}
+
+// DeviceSource returns the random source SetSeed(seed) gives the device with
+// id, for generators that take a seed of their own. It is not safe for
+// concurrent use.
+func DeviceSource(seed uint64, id string) rand.Source {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	return rand.NewPCG(seed, h.Sum64())
+}
+
+// deviceRand returns the random source of device, which may be nil.
+func deviceRand(device *Device) *rand.Rand {
+	if device == nil {
+		return DeviceRand("")
+	}
+	return DeviceRand(device.ID)
+}
+
+// randReader reads random bytes from a rand.Rand.
+type randReader struct {
+	r *rand.Rand
+}
+
+func (rr randReader) Read(p []byte) (int, error) {
+	for i := 0; i < len(p); i += 8 {
+		v := rr.r.Uint64()
+		for j := i; j < min(i+8, len(p)); j++ {
+			p[j] = byte(v)
+			v >>= 8
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,65 @@
+package loadgen_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seededPayloads sets seed and returns n payloads of each device of gen,
+// generated round-robin in the given order.
+func seededPayloads(t *testing.T, seed uint64, newGen func() loadgen.PayloadGenerator, n int, ids ...string) map[string][]string {
+	t.Helper()
+	loadgen.SetSeed(seed)
+	gen := newGen()
+	out := make(map[string][]string)
+	for range n {
+		for _, id := range ids {
+			payload, err := gen.GeneratePayload(&loadgen.Device{ID: id})
+			require.NoError(t, err)
+			out[id] = append(out[id], string(payload))
+		}
+	}
+	return out
+}
+
+func TestSetSeed(t *testing.T) {
+	t.Cleanup(loadgen.ClearSeed)
+
+	generators := map[string]func() loadgen.PayloadGenerator{
+		"binary": func() loadgen.PayloadGenerator {
+			return loadgen.NewBinaryPayloadGenerator(loadgen.ParetoSize(4, 1.5, 64))
+		},
+		"template": func() loadgen.PayloadGenerator {
+			gen, err := loadgen.NewTemplatePayloadGenerator(`{{randInt 0 1000}} {{randFloat 0 1}} {{randBool}} {{randChoice "a" "b" "c"}} {{uuid}}`)
+			require.NoError(t, err)
+			return gen
+		},
+	}
+	for name, newGen := range generators {
+		t.Run(name, func(t *testing.T) {
+			a := seededPayloads(t, 7, newGen, 5, "device-1", "device-2")
+			b := seededPayloads(t, 7, newGen, 5, "device-2", "device-1")
+			assert.Equal(t, a, b, "a seed gives each device the same values, whatever the order")
+			assert.NotEqual(t, a["device-1"], a["device-2"], "devices get different values")
+
+			c := seededPayloads(t, 8, newGen, 5, "device-1", "device-2")
+			assert.NotEqual(t, a, c)
+		})
+	}
+}
+
+func TestDeviceRand(t *testing.T) {
+	t.Cleanup(loadgen.ClearSeed)
+
+	loadgen.SetSeed(1)
+	first := []uint64{loadgen.DeviceRand("d1").Uint64(), loadgen.DeviceRand("d1").Uint64()}
+	loadgen.SetSeed(1)
+	assert.Equal(t, first, []uint64{loadgen.DeviceRand("d1").Uint64(), loadgen.DeviceRand("d1").Uint64()}, "SetSeed restarts the sequences")
+	assert.NotEqual(t, first[0], first[1])
+
+	loadgen.ClearSeed()
+	assert.NotEqual(t, loadgen.DeviceRand("d1").Uint64(), loadgen.DeviceRand("d1").Uint64())
+}
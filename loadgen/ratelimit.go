@@ -0,0 +1,39 @@
+// loadgen/ratelimit.go
+
+package loadgen
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// SetRateLimit caps the publish rate of the whole fleet at limit messages per
+// second, whatever the devices' own rates, e.g. to protect shared staging
+// infrastructure during a large run. It is a token bucket: up to burst
+// messages may go out at once after a lull. Messages over the cap are
+// delayed, not dropped, so devices fall behind their schedules and
+// ExpectedMessagesForDuration overstates what a capped run publishes;
+// ExpectedRange caps its bounds at the limit.
+// limit <= 0 removes the cap.
+func (lg *LoadGenerator) SetRateLimit(limit float64, burst int) {
+	lg.rateLimit, lg.rateBurst = limit, max(burst, 1)
+}
+
+// newRateLimiter returns the limiter of a run, or nil if it is not capped.
+// Each run starts with a full bucket.
+func (lg *LoadGenerator) newRateLimiter() *rate.Limiter {
+	if lg.rateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(lg.rateLimit), lg.rateBurst)
+}
+
+// waitRateLimit waits until the fleet may publish another message. It
+// returns false if the run ends first.
+func (lg *LoadGenerator) waitRateLimit(ctx context.Context) bool {
+	if lg.limiter == nil {
+		return true
+	}
+	return lg.limiter.Wait(ctx) == nil
+}
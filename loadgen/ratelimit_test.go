@@ -0,0 +1,33 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerator_SetRateLimit(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(100).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+	lg.SetRateLimit(100, 5)
+
+	// Uncapped, the fleet would publish 10 * (1 + 50) messages.
+	result, err := lg.RunUntil(context.Background(), 500*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+	assert.Zero(t, result.Failed)
+	assert.GreaterOrEqual(t, result.Published, 40)
+	assert.LessOrEqual(t, result.Published, 5+50)
+
+	// Removing the cap restores the devices' own rates.
+	lg.SetRateLimit(0, 0)
+	count, err := lg.Run(context.Background(), 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Greater(t, count, 5+10)
+}
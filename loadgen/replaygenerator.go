@@ -3,15 +3,31 @@
 package loadgen
 
 import (
+	"bytes"
+	"fmt"
 	"io" // Required for io.EOF
 	"sync"
 )
 
+// ReplayMutator changes a replayed payload before it is published, e.g. to
+// bump a sequence number or refresh a timestamp so repeated messages stay
+// distinct. pass counts the wrap-arounds of a looping replay, starting at 0,
+// and index is the payload's position in the capture. payload is a copy the
+// mutator may modify.
+type ReplayMutator func(payload []byte, pass, index int) ([]byte, error)
+
 // ReplayPayloadGenerator implements loadgen.PayloadGenerator for replaying pre-loaded messages.
 // It allows the load generator to "publish" messages that have already been read from GCS.
 type ReplayPayloadGenerator struct {
+	// Loop wraps around to the first message instead of returning io.EOF,
+	// so a small capture can drive a test of any length.
+	Loop bool
+	// Mutate, if set, is applied to every payload.
+	Mutate ReplayMutator
+
 	messages [][]byte   // Raw JSON payloads to be replayed
 	index    int        // Current index in the messages slice
+	pass     int        // Number of times a looping replay has wrapped around
 	mu       sync.Mutex // Mutex to protect access to index in concurrent scenarios
 }
 
@@ -25,16 +41,29 @@ func NewReplayPayloadGenerator(messages [][]byte) *ReplayPayloadGenerator {
 
 // GeneratePayload returns the next pre-loaded payload.
 // It's called by the load generator to get the message to publish.
-// It returns io.EOF when no more messages are available.
+// It returns io.EOF when no more messages are available, which stops the
+// device's publishing loop, unless the generator loops.
 func (r *ReplayPayloadGenerator) GeneratePayload(device *Device) ([]byte, error) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
+	if r.Loop && r.index >= len(r.messages) && len(r.messages) > 0 {
+		r.index = 0
+		r.pass++
+	}
 	if r.index >= len(r.messages) {
+		r.mu.Unlock()
 		// Signal that there are no more messages to replay for this generator.
 		return nil, io.EOF
 	}
-	payload := r.messages[r.index]
+	payload, pass, index := r.messages[r.index], r.pass, r.index
 	r.index++
+	r.mu.Unlock()
+
+	if r.Mutate == nil {
+		return payload, nil
+	}
+	payload, err := r.Mutate(bytes.Clone(payload), pass, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mutate replayed message %d: %w", index, err)
+	}
 	return payload, nil
 }
@@ -0,0 +1,150 @@
+package loadgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingClient is a Client that generates every payload and records it.
+type recordingClient struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (c *recordingClient) Connect() error { return nil }
+
+func (c *recordingClient) Disconnect() {}
+
+func (c *recordingClient) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	payload, err := device.PayloadGenerator.GeneratePayload(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payloads = append(c.payloads, string(payload))
+	return true, nil
+}
+
+func TestLoadGenerator_ReplayStopsAtEOF(t *testing.T) {
+	replay := func(messages ...string) loadgen.PayloadGenerator {
+		var payloads [][]byte
+		for _, m := range messages {
+			payloads = append(payloads, []byte(m))
+		}
+		return loadgen.NewReplayPayloadGenerator(payloads)
+	}
+
+	t.Run("single messages", func(t *testing.T) {
+		client := &recordingClient{}
+		devices := []*loadgen.Device{
+			{ID: "replay-1", MessageRate: 100, PayloadGenerator: replay("a", "b", "c")},
+			{ID: "replay-2", MessageRate: 100, PayloadGenerator: replay("d"), Timing: loadgen.JitteredTiming(0.1)},
+		}
+		lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+
+		start := time.Now()
+		result, err := lg.RunUntil(context.Background(), time.Minute)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second, "the run should end when the replays are exhausted")
+		assert.Equal(t, loadgen.RunResult{Published: 4, StoppedBy: loadgen.StopPayloadsExhausted}, result)
+		assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, client.payloads)
+	})
+
+	t.Run("bursts", func(t *testing.T) {
+		client := &recordingClient{}
+		devices := []*loadgen.Device{
+			{ID: "replay-1", Burst: &loadgen.Burst{Size: 2, Interval: 10 * time.Millisecond}, PayloadGenerator: replay("a", "b", "c")},
+		}
+		lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+
+		result, err := lg.RunUntil(context.Background(), time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.RunResult{Published: 3, StoppedBy: loadgen.StopPayloadsExhausted}, result)
+		assert.Equal(t, []string{"a", "b", "c"}, client.payloads)
+	})
+
+	t.Run("duration ends first", func(t *testing.T) {
+		devices := []*loadgen.Device{
+			{ID: "replay-1", MessageRate: 10, PayloadGenerator: replay("a", "b", "c", "d", "e", "f", "g", "h", "i", "j")},
+		}
+		lg := loadgen.NewLoadGenerator(&recordingClient{}, devices, zerolog.Nop())
+
+		result, err := lg.RunUntil(context.Background(), 150*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+		assert.Equal(t, 2, result.Published)
+	})
+}
+
+func TestReplayPayloadGenerator_Loop(t *testing.T) {
+	gen := loadgen.NewReplayPayloadGenerator([][]byte{[]byte(`{"seq":1}`), []byte(`{"seq":2}`)})
+	gen.Loop = true
+	gen.Mutate = func(payload []byte, pass, index int) ([]byte, error) {
+		var msg map[string]int
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return nil, err
+		}
+		msg["seq"] += pass * 2
+		msg["index"] = index
+		return json.Marshal(msg)
+	}
+
+	assert.Equal(t, []string{
+		`{"index":0,"seq":1}`, `{"index":1,"seq":2}`,
+		`{"index":0,"seq":3}`, `{"index":1,"seq":4}`,
+		`{"index":0,"seq":5}`,
+	}, replayN(t, gen, 5))
+
+	// The capture itself is not modified.
+	gen.Mutate = nil
+	assert.Equal(t, []string{`{"seq":2}`, `{"seq":1}`}, replayN(t, gen, 2))
+}
+
+func TestReplayPayloadGenerator_LoopEmptyAndMutateError(t *testing.T) {
+	empty := loadgen.NewReplayPayloadGenerator(nil)
+	empty.Loop = true
+	_, err := empty.GeneratePayload(nil)
+	assert.ErrorIs(t, err, io.EOF)
+
+	gen := loadgen.NewReplayPayloadGenerator([][]byte{[]byte("a")})
+	gen.Mutate = func([]byte, int, int) ([]byte, error) { return nil, errors.New("bad payload") }
+	_, err = gen.GeneratePayload(nil)
+	assert.ErrorContains(t, err, "failed to mutate replayed message 0: bad payload")
+}
+
+func TestLoadGenerator_LoopingReplayRunsForDuration(t *testing.T) {
+	gen := loadgen.NewReplayPayloadGenerator([][]byte{[]byte("a"), []byte("b")})
+	gen.Loop = true
+	client := &recordingClient{}
+	lg := loadgen.NewLoadGenerator(client, []*loadgen.Device{{ID: "soak", MessageRate: 50, PayloadGenerator: gen}}, zerolog.Nop())
+
+	result, err := lg.RunUntil(context.Background(), 150*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+	assert.Greater(t, result.Published, 2)
+	assert.Equal(t, []string{"a", "b", "a"}, client.payloads[:3])
+}
+
+// replayN returns the next n payloads of generator.
+func replayN(t *testing.T, generator loadgen.PayloadGenerator, n int) []string {
+	t.Helper()
+	payloads := make([]string, 0, n)
+	for range n {
+		payload, err := generator.GeneratePayload(nil)
+		require.NoError(t, err)
+		payloads = append(payloads, string(payload))
+	}
+	return payloads
+}
@@ -0,0 +1,260 @@
+// loadgen/replayloader.go
+
+package loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// maxReplayLine is the longest JSONL line a replay loader accepts.
+const maxReplayLine = 16 << 20
+
+// ReplaySet holds the replay generators of a capture, keyed by device ID.
+type ReplaySet map[string]*ReplayPayloadGenerator
+
+// Devices returns a device per replay generator, in device ID order, that
+// each publish at messageRate until their messages run out.
+func (s ReplaySet) Devices(messageRate float64) []*Device {
+	devices := make([]*Device, 0, len(s))
+	for _, id := range slices.Sorted(maps.Keys(s)) {
+		devices = append(devices, &Device{ID: id, MessageRate: messageRate, PayloadGenerator: s[id]})
+	}
+	return devices
+}
+
+// ReplayOption configures a replay loader.
+type ReplayOption func(*replayLoader)
+
+// WithReplayDeviceField partitions the messages per device by the value of a
+// top-level field of each JSON message, e.g. "device_id", instead of by
+// path. Every message must be a JSON object with the field.
+func WithReplayDeviceField(field string) ReplayOption {
+	return func(l *replayLoader) { l.deviceField = field }
+}
+
+// replayLoader collects the messages of a capture per device before they
+// become a ReplaySet.
+type replayLoader struct {
+	deviceField string
+	messages    map[string][][]byte
+}
+
+// newReplayLoader creates a replayLoader configured by opts.
+func newReplayLoader(opts []ReplayOption) *replayLoader {
+	l := &replayLoader{messages: make(map[string][][]byte)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// add reads the messages of a captured object: one per non-empty line of a
+// ".jsonl" or ".ndjson" object, or the whole content of any other. They
+// belong to deviceID unless the loader partitions by field.
+func (l *replayLoader) add(deviceID, name string, r io.Reader) error {
+	switch path.Ext(name) {
+	case ".jsonl", ".ndjson":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxReplayLine)
+		for line := 1; scanner.Scan(); line++ {
+			message := bytes.TrimSpace(scanner.Bytes())
+			if len(message) == 0 {
+				continue
+			}
+			if err := l.append(deviceID, bytes.Clone(message)); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		return scanner.Err()
+	default:
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return l.append(deviceID, content)
+	}
+}
+
+// append adds a message to its device.
+func (l *replayLoader) append(deviceID string, message []byte) error {
+	if l.deviceField != "" {
+		var err error
+		if deviceID, err = jsonDeviceID(message, l.deviceField); err != nil {
+			return err
+		}
+	}
+	l.messages[deviceID] = append(l.messages[deviceID], message)
+	return nil
+}
+
+// jsonDeviceID returns the value of field in a JSON object message. A
+// number is used as written.
+func jsonDeviceID(message []byte, field string) (string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(message, &object); err != nil {
+		return "", fmt.Errorf("message is not a JSON object: %w", err)
+	}
+	raw, ok := object[field]
+	if !ok {
+		return "", fmt.Errorf("message has no field %q", field)
+	}
+	var id string
+	if json.Unmarshal(raw, &id) == nil {
+		return id, nil
+	}
+	var number json.Number
+	if json.Unmarshal(raw, &number) == nil {
+		return number.String(), nil
+	}
+	return "", fmt.Errorf("field %q is not a string or number", field)
+}
+
+// replaySet returns a replay generator per device.
+func (l *replayLoader) replaySet() ReplaySet {
+	set := make(ReplaySet, len(l.messages))
+	for id, messages := range l.messages {
+		set[id] = NewReplayPayloadGenerator(messages)
+	}
+	return set
+}
+
+// deviceFromPath returns the device of a captured object from its path
+// relative to the capture's root: the first element of rel, without its
+// extension if it is the object itself, e.g. "device-1" for both
+// "device-1/0001.json" and "device-1.jsonl".
+func deviceFromPath(rel string) string {
+	first, _, nested := strings.Cut(rel, "/")
+	if nested {
+		return first
+	}
+	return strings.TrimSuffix(first, path.Ext(first))
+}
+
+// LoadReplayFromGCS builds a ReplaySet from the objects under prefix in
+// bucket, partitioned per device by the first path element after prefix:
+// "captures/device-1/0001.json" and "captures/device-1.jsonl" both belong
+// to device-1 under the prefix "captures/". A ".jsonl" or ".ndjson" object
+// holds one message per line; any other object is one message. The messages
+// of a device are replayed in object name order. WithReplayDeviceField
+// partitions them by a JSON field instead.
+func LoadReplayFromGCS(ctx context.Context, client *storage.Client, bucket, prefix string, opts ...ReplayOption) (ReplaySet, error) {
+	loader := newReplayLoader(opts)
+	it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in gs://%s/%s: %w", bucket, prefix, err)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(attrs.Name, prefix), "/")
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			// A directory placeholder.
+			continue
+		}
+		if err := loadGCSObject(ctx, client, loader, deviceFromPath(rel), attrs); err != nil {
+			return nil, err
+		}
+	}
+	return loader.replaySet(), nil
+}
+
+// loadGCSObject streams the messages of one object into loader.
+func loadGCSObject(ctx context.Context, client *storage.Client, loader *replayLoader, deviceID string, attrs *storage.ObjectAttrs) error {
+	r, err := client.Bucket(attrs.Bucket).Object(attrs.Name).NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read gs://%s/%s: %w", attrs.Bucket, attrs.Name, err)
+	}
+	defer func() { _ = r.Close() }()
+	if err := loader.add(deviceID, attrs.Name, r); err != nil {
+		return fmt.Errorf("failed to read gs://%s/%s: %w", attrs.Bucket, attrs.Name, err)
+	}
+	return nil
+}
+
+// LoadReplayFromFS builds a ReplaySet from the files under root in fsys,
+// e.g. an embed.FS of captured samples, partitioned and read as
+// LoadReplayFromGCS does with the objects under a prefix. Hidden files are
+// skipped.
+func LoadReplayFromFS(fsys fs.FS, root string, opts ...ReplayOption) (ReplaySet, error) {
+	loader := newReplayLoader(opts)
+	// WalkDir visits the files in lexical order.
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel := name
+		switch {
+		case name == root:
+			// root is itself a file.
+			rel = path.Base(name)
+		case root != ".":
+			rel = strings.TrimPrefix(name, root+"/")
+		}
+		return loadFile(fsys, loader, deviceFromPath(rel), name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replay from %s: %w", root, err)
+	}
+	return loader.replaySet(), nil
+}
+
+// LoadReplayFromDir builds a ReplaySet from the files under dir, as
+// LoadReplayFromFS does.
+func LoadReplayFromDir(dir string, opts ...ReplayOption) (ReplaySet, error) {
+	return LoadReplayFromFS(os.DirFS(dir), ".", opts...)
+}
+
+// LoadReplayFromJSONL builds a ReplaySet from a JSONL file with one message
+// per line. The messages belong to a device named after the file, e.g.
+// "device-1" for "device-1.jsonl", unless WithReplayDeviceField partitions
+// them.
+func LoadReplayFromJSONL(file string, opts ...ReplayOption) (ReplaySet, error) {
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	if path.Ext(name) != ".jsonl" && path.Ext(name) != ".ndjson" {
+		return nil, fmt.Errorf("%s is not a .jsonl or .ndjson file", file)
+	}
+	return LoadReplayFromFS(os.DirFS(dir), name, opts...)
+}
+
+// loadFile reads the messages of the file name in fsys into loader.
+func loadFile(fsys fs.FS, loader *replayLoader, deviceID, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	if err := loader.add(deviceID, name, f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
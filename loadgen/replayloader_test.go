@@ -0,0 +1,169 @@
+package loadgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/storage"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+// newFakeGCS serves objects (name to content) of bucket over the subset of
+// the GCS JSON and XML APIs that listing and reading objects use.
+func newFakeGCS(t *testing.T, bucket string, objects map[string]string) *storage.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/v1/b/"+bucket+"/o" {
+			type item struct {
+				Bucket string `json:"bucket"`
+				Name   string `json:"name"`
+			}
+			var items []item
+			for _, name := range slices.Sorted(maps.Keys(objects)) {
+				if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+					items = append(items, item{Bucket: bucket, Name: name})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"kind": "storage#objects", "items": items})
+			return
+		}
+		name, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"+bucket+"/"))
+		content, ok := objects[name]
+		if err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := storage.NewClient(context.Background(),
+		option.WithEndpoint(server.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// replayAll returns every payload of generator.
+func replayAll(t *testing.T, generator loadgen.PayloadGenerator) []string {
+	t.Helper()
+	var payloads []string
+	for {
+		payload, err := generator.GeneratePayload(nil)
+		if err != nil {
+			return payloads
+		}
+		payloads = append(payloads, string(payload))
+	}
+}
+
+func TestLoadReplayFromGCS(t *testing.T) {
+	client := newFakeGCS(t, "captures", map[string]string{
+		"2024-06-01/":                 "",
+		"2024-06-01/device-1/02.json": `{"seq":2}`,
+		"2024-06-01/device-1/01.json": `{"seq":1}`,
+		"2024-06-01/device-2.jsonl":   "{\"seq\":1}\n\n{\"seq\":2}\n{\"seq\":3}\n",
+		"2024-06-02/device-3.jsonl":   `{"seq":1}`,
+	})
+
+	set, err := loadgen.LoadReplayFromGCS(context.Background(), client, "captures", "2024-06-01/")
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+	assert.Equal(t, []string{`{"seq":1}`, `{"seq":2}`}, replayAll(t, set["device-1"]))
+	assert.Equal(t, []string{`{"seq":1}`, `{"seq":2}`, `{"seq":3}`}, replayAll(t, set["device-2"]))
+
+	devices := set.Devices(5)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "device-1", devices[0].ID)
+	assert.Equal(t, "device-2", devices[1].ID)
+	assert.Equal(t, 5.0, devices[0].MessageRate)
+	assert.Same(t, set["device-1"], devices[0].PayloadGenerator)
+}
+
+func TestLoadReplayFromGCS_Errors(t *testing.T) {
+	client := newFakeGCS(t, "captures", nil)
+	_, err := loadgen.LoadReplayFromGCS(context.Background(), client, "missing", "")
+	assert.ErrorContains(t, err, "failed to list objects in gs://missing/")
+}
+
+func TestLoadReplayFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"captures/device-1/02.json":  {Data: []byte(`{"seq":2}`)},
+		"captures/device-1/01.json":  {Data: []byte(`{"seq":1}`)},
+		"captures/device-2.ndjson":   {Data: []byte("{\"seq\":1}\n{\"seq\":2}\n")},
+		"captures/.DS_Store":         {Data: []byte("junk")},
+		"captures/.cache/device.bin": {Data: []byte("junk")},
+		"other/device-3.jsonl":       {Data: []byte(`{"seq":1}`)},
+	}
+
+	set, err := loadgen.LoadReplayFromFS(fsys, "captures")
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+	assert.Equal(t, []string{`{"seq":1}`, `{"seq":2}`}, replayAll(t, set["device-1"]))
+	assert.Equal(t, []string{`{"seq":1}`, `{"seq":2}`}, replayAll(t, set["device-2"]))
+
+	_, err = loadgen.LoadReplayFromFS(fsys, "missing")
+	assert.ErrorContains(t, err, "failed to load replay from missing")
+}
+
+func TestLoadReplay_DeviceField(t *testing.T) {
+	capture := strings.Join([]string{
+		`{"device_id":"sensor-a","seq":1}`,
+		`{"device_id":42,"seq":1}`,
+		`{"device_id":"sensor-a","seq":2}`,
+	}, "\n")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "capture.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte(capture), 0o644))
+
+	set, err := loadgen.LoadReplayFromJSONL(file, loadgen.WithReplayDeviceField("device_id"))
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+	assert.Equal(t, []string{`{"device_id":"sensor-a","seq":1}`, `{"device_id":"sensor-a","seq":2}`}, replayAll(t, set["sensor-a"]))
+	assert.Equal(t, []string{`{"device_id":42,"seq":1}`}, replayAll(t, set["42"]))
+
+	// Without the field, the file names the device.
+	set, err = loadgen.LoadReplayFromJSONL(file)
+	require.NoError(t, err)
+	assert.Len(t, replayAll(t, set["capture"]), 3)
+
+	// The same partitioning applies to a directory.
+	set, err = loadgen.LoadReplayFromDir(dir, loadgen.WithReplayDeviceField("device_id"))
+	require.NoError(t, err)
+	assert.Len(t, set, 2)
+}
+
+func TestLoadReplay_DeviceFieldErrors(t *testing.T) {
+	tests := []struct {
+		name, capture, wantErr string
+	}{
+		{"missing field", `{"seq":1}`, `line 1: message has no field "device_id"`},
+		{"not an object", "{\"device_id\":\"a\"}\nnot json", "line 2: message is not a JSON object"},
+		{"not a string", `{"device_id":{"nested":true}}`, `field "device_id" is not a string or number`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"capture.jsonl": {Data: []byte(tc.capture)}}
+			_, err := loadgen.LoadReplayFromFS(fsys, ".", loadgen.WithReplayDeviceField("device_id"))
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+
+	_, err := loadgen.LoadReplayFromJSONL(filepath.Join(t.TempDir(), "capture.json"))
+	assert.ErrorContains(t, err, "is not a .jsonl or .ndjson file")
+}
@@ -0,0 +1,402 @@
+// loadgen/results.go
+
+package loadgen
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Results are the metrics of a run, for long-running load tests to persist
+// and trend, e.g. nightly.
+type Results struct {
+	// Start is when the run started, Elapsed how long it took in all and
+	// Warmup how much of that was warm-up.
+	Start   time.Time
+	Elapsed time.Duration
+	Warmup  time.Duration
+	// Devices is the number of devices of the run.
+	Devices int
+	// Published, Failed, Retries, WarmupPublished and StoppedBy are as in
+	// RunResult.
+	Published       int
+	Failed          int
+	Retries         int
+	WarmupPublished int
+	StoppedBy       StopReason
+	// Rate is the number of messages published per second after the
+	// warm-up.
+	Rate float64
+	// Labels describe the run, e.g. the commit or environment under test.
+	// Set them before writing the results.
+	Labels map[string]string
+	// Latency is the time successful publishes took, retries included,
+	// accurate to within 2%.
+	Latency LatencyStats
+	// Groups are the metrics of each group of devices, as grouped by
+	// GroupMetadataKey, in the order their first devices appear.
+	Groups []GroupResults
+	// Backpressure is how the run fared against the limit set by
+	// SetMaxInFlight, or nil if it had none.
+	Backpressure *Backpressure
+	// Profile is what SetProfiling captured of the generator process, or
+	// nil.
+	Profile *RunProfile
+	// DeviceErrors are the failures of each device that had any, most
+	// failures first.
+	DeviceErrors []DeviceErrors
+	// Snapshots are the progress snapshots of the run, if OnProgress was
+	// set. Only WriteJSON writes them, the groups, the device errors and the
+	// runtime samples of the profile.
+	Snapshots []Snapshot
+}
+
+// Results returns the metrics of the last run.
+func (lg *LoadGenerator) Results() Results {
+	return lg.results
+}
+
+// newResults returns the Results of a run that started at start and ended
+// with result.
+func (lg *LoadGenerator) newResults(start time.Time, result RunResult, snapshots []Snapshot) Results {
+	elapsed := time.Since(start)
+	r := Results{
+		Start:           start,
+		Elapsed:         elapsed,
+		Warmup:          min(lg.warmup, elapsed),
+		Devices:         len(lg.devices),
+		Published:       result.Published,
+		Failed:          result.Failed,
+		Retries:         result.Retries,
+		WarmupPublished: result.WarmupPublished,
+		StoppedBy:       result.StoppedBy,
+		Snapshots:       snapshots,
+	}
+	measured := r.Elapsed - r.Warmup
+	if measured > 0 {
+		r.Rate = float64(r.Published) / measured.Seconds()
+	}
+	r.Latency = lg.latency.stats()
+	for _, g := range lg.groups {
+		r.Groups = append(r.Groups, g.results(measured))
+	}
+	r.DeviceErrors = failingDevices(lg.deviceErrors)
+	r.Backpressure = lg.inFlight.results()
+	return r
+}
+
+// resultsColumn is a column of the summary row of Results.
+type resultsColumn struct {
+	name      string
+	fieldType bigquery.FieldType
+	value     any
+}
+
+// columns returns the summary row of r, as written by WriteCSV and
+// WriteBigQuery. The labels are a JSON object.
+func (r Results) columns() []resultsColumn {
+	labels, _ := json.Marshal(r.labels())
+	return []resultsColumn{
+		{"start", bigquery.TimestampFieldType, r.Start},
+		{"elapsed_seconds", bigquery.FloatFieldType, r.Elapsed.Seconds()},
+		{"warmup_seconds", bigquery.FloatFieldType, r.Warmup.Seconds()},
+		{"devices", bigquery.IntegerFieldType, r.Devices},
+		{"published", bigquery.IntegerFieldType, r.Published},
+		{"failed", bigquery.IntegerFieldType, r.Failed},
+		{"retries", bigquery.IntegerFieldType, r.Retries},
+		{"warmup_published", bigquery.IntegerFieldType, r.WarmupPublished},
+		{"stopped_by", bigquery.StringFieldType, string(r.StoppedBy)},
+		{"rate", bigquery.FloatFieldType, r.Rate},
+		{"labels", bigquery.StringFieldType, string(labels)},
+	}
+}
+
+// labels returns the labels of r, never nil.
+func (r Results) labels() map[string]string {
+	if r.Labels == nil {
+		return map[string]string{}
+	}
+	return r.Labels
+}
+
+// resultsJSON is the JSON form of Results.
+type resultsJSON struct {
+	Start           time.Time         `json:"start"`
+	ElapsedSeconds  float64           `json:"elapsed_seconds"`
+	WarmupSeconds   float64           `json:"warmup_seconds"`
+	Devices         int               `json:"devices"`
+	Published       int               `json:"published"`
+	Failed          int               `json:"failed"`
+	Retries         int               `json:"retries"`
+	WarmupPublished int               `json:"warmup_published"`
+	StoppedBy       StopReason        `json:"stopped_by"`
+	Rate            float64           `json:"rate"`
+	Labels          map[string]string `json:"labels"`
+	Latency         latencyJSON       `json:"latency"`
+	Groups          []groupJSON       `json:"groups,omitempty"`
+	DeviceErrors    []deviceErrorJSON `json:"device_errors,omitempty"`
+	Backpressure    *backpressureJSON `json:"backpressure,omitempty"`
+	Profile         *profileJSON      `json:"profile,omitempty"`
+	Snapshots       []snapshotJSON    `json:"snapshots,omitempty"`
+}
+
+// latencyJSON is the JSON form of LatencyStats, in milliseconds.
+type latencyJSON struct {
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+func newLatencyJSON(l LatencyStats) latencyJSON {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return latencyJSON{MinMs: ms(l.Min), MeanMs: ms(l.Mean), P50Ms: ms(l.P50), P95Ms: ms(l.P95), P99Ms: ms(l.P99), MaxMs: ms(l.Max)}
+}
+
+// groupJSON is the JSON form of GroupResults.
+type groupJSON struct {
+	Name      string      `json:"name"`
+	Devices   int         `json:"devices"`
+	Published int         `json:"published"`
+	Failed    int         `json:"failed"`
+	Rate      float64     `json:"rate"`
+	Latency   latencyJSON `json:"latency"`
+}
+
+// deviceErrorJSON is the JSON form of DeviceErrors, with the errors as
+// their messages.
+type deviceErrorJSON struct {
+	DeviceID               string    `json:"device_id"`
+	Failures               int       `json:"failures"`
+	ConsecutiveFailures    int       `json:"consecutive_failures"`
+	MaxConsecutiveFailures int       `json:"max_consecutive_failures"`
+	FirstError             string    `json:"first_error"`
+	FirstErrorAt           time.Time `json:"first_error_at"`
+	LastError              string    `json:"last_error"`
+	LastErrorAt            time.Time `json:"last_error_at"`
+}
+
+// backpressureJSON is the JSON form of Backpressure.
+type backpressureJSON struct {
+	MaxInFlight        int                `json:"max_in_flight"`
+	Policy             BackpressurePolicy `json:"policy"`
+	Incidents          int                `json:"incidents"`
+	Blocked            int                `json:"blocked"`
+	BlockedTimeSeconds float64            `json:"blocked_time_seconds"`
+	Shed               int                `json:"shed"`
+}
+
+func newBackpressureJSON(b *Backpressure) *backpressureJSON {
+	if b == nil {
+		return nil
+	}
+	return &backpressureJSON{
+		MaxInFlight:        b.MaxInFlight,
+		Policy:             b.Policy,
+		Incidents:          b.Incidents,
+		Blocked:            b.Blocked,
+		BlockedTimeSeconds: b.BlockedTime.Seconds(),
+		Shed:               b.Shed,
+	}
+}
+
+// profileJSON is the JSON form of a RunProfile, without the pprof profiles.
+type profileJSON struct {
+	PeakGoroutines     int                 `json:"peak_goroutines"`
+	PeakHeapAllocBytes uint64              `json:"peak_heap_alloc_bytes"`
+	GCCycles           uint32              `json:"gc_cycles"`
+	GCPauseSeconds     float64             `json:"gc_pause_seconds"`
+	MeanCPU            float64             `json:"mean_cpu"`
+	PeakCPU            float64             `json:"peak_cpu"`
+	Samples            []runtimeSampleJSON `json:"samples"`
+}
+
+// runtimeSampleJSON is the JSON form of a RuntimeSample.
+type runtimeSampleJSON struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Goroutines     int     `json:"goroutines"`
+	HeapAllocBytes uint64  `json:"heap_alloc_bytes"`
+	GCCycles       uint32  `json:"gc_cycles"`
+	GCPauseSeconds float64 `json:"gc_pause_seconds"`
+	CPU            float64 `json:"cpu"`
+}
+
+func newProfileJSON(p *RunProfile) *profileJSON {
+	if p == nil {
+		return nil
+	}
+	out := &profileJSON{
+		PeakGoroutines:     p.PeakGoroutines,
+		PeakHeapAllocBytes: p.PeakHeapAlloc,
+		GCCycles:           p.GCCycles,
+		GCPauseSeconds:     p.GCPause.Seconds(),
+		MeanCPU:            p.MeanCPU,
+		PeakCPU:            p.PeakCPU,
+	}
+	for _, s := range p.Samples {
+		out.Samples = append(out.Samples, runtimeSampleJSON{
+			ElapsedSeconds: s.Elapsed.Seconds(),
+			Goroutines:     s.Goroutines,
+			HeapAllocBytes: s.HeapAlloc,
+			GCCycles:       s.GCCycles,
+			GCPauseSeconds: s.GCPause.Seconds(),
+			CPU:            s.CPU,
+		})
+	}
+	return out
+}
+
+// snapshotJSON is the JSON form of a Snapshot.
+type snapshotJSON struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Published      int     `json:"published"`
+	Failed         int     `json:"failed"`
+	Retries        int     `json:"retries"`
+	Rate           float64 `json:"rate"`
+	Final          bool    `json:"final"`
+}
+
+// WriteJSON writes r as an indented JSON object, with durations in seconds,
+// latencies in milliseconds, and its groups, device errors, runtime
+// samples and progress snapshots.
+func (r Results) WriteJSON(w io.Writer) error {
+	out := resultsJSON{
+		Start:           r.Start,
+		ElapsedSeconds:  r.Elapsed.Seconds(),
+		WarmupSeconds:   r.Warmup.Seconds(),
+		Devices:         r.Devices,
+		Published:       r.Published,
+		Failed:          r.Failed,
+		Retries:         r.Retries,
+		WarmupPublished: r.WarmupPublished,
+		StoppedBy:       r.StoppedBy,
+		Rate:            r.Rate,
+		Labels:          r.labels(),
+		Latency:         newLatencyJSON(r.Latency),
+		Backpressure:    newBackpressureJSON(r.Backpressure),
+		Profile:         newProfileJSON(r.Profile),
+	}
+	for _, g := range r.Groups {
+		out.Groups = append(out.Groups, groupJSON{
+			Name:      g.Name,
+			Devices:   g.Devices,
+			Published: g.Published,
+			Failed:    g.Failed,
+			Rate:      g.Rate,
+			Latency:   newLatencyJSON(g.Latency),
+		})
+	}
+	for _, e := range r.DeviceErrors {
+		out.DeviceErrors = append(out.DeviceErrors, deviceErrorJSON{
+			DeviceID:               e.DeviceID,
+			Failures:               e.Failures,
+			ConsecutiveFailures:    e.ConsecutiveFailures,
+			MaxConsecutiveFailures: e.MaxConsecutiveFailures,
+			FirstError:             errorMessage(e.FirstError),
+			FirstErrorAt:           e.FirstErrorAt,
+			LastError:              errorMessage(e.LastError),
+			LastErrorAt:            e.LastErrorAt,
+		})
+	}
+	for _, s := range r.Snapshots {
+		out.Snapshots = append(out.Snapshots, snapshotJSON{
+			ElapsedSeconds: s.Elapsed.Seconds(),
+			Published:      s.Published,
+			Failed:         s.Failed,
+			Retries:        s.Retries,
+			Rate:           s.Rate,
+			Final:          s.Final,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
+
+// errorMessage returns the message of err, or "" if it is nil.
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// WriteCSV writes r as a CSV header and one summary row. Use
+// WriteCSVRow to append the rows of later runs to the same file.
+func (r Results) WriteCSV(w io.Writer) error {
+	columns := r.columns()
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	return r.writeCSV(w, header)
+}
+
+// WriteCSVRow writes r as a CSV summary row without a header.
+func (r Results) WriteCSVRow(w io.Writer) error {
+	return r.writeCSV(w, nil)
+}
+
+func (r Results) writeCSV(w io.Writer, header []string) error {
+	cw := csv.NewWriter(w)
+	if header != nil {
+		_ = cw.Write(header)
+	}
+	var row []string
+	for _, c := range r.columns() {
+		switch v := c.value.(type) {
+		case time.Time:
+			row = append(row, v.UTC().Format(time.RFC3339Nano))
+		case float64:
+			row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			row = append(row, fmt.Sprint(v))
+		}
+	}
+	_ = cw.Write(row)
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
+
+// ResultsSchema returns the BigQuery schema of the rows WriteBigQuery
+// inserts, to create the results table with.
+func ResultsSchema() bigquery.Schema {
+	var schema bigquery.Schema
+	for _, c := range (Results{}).columns() {
+		schema = append(schema, &bigquery.FieldSchema{Name: c.name, Type: c.fieldType})
+	}
+	return schema
+}
+
+// Save implements bigquery.ValueSaver, so Results can be inserted as a row.
+func (r Results) Save() (map[string]bigquery.Value, string, error) {
+	row := make(map[string]bigquery.Value)
+	for _, c := range r.columns() {
+		row[c.name] = c.value
+	}
+	return row, "", nil
+}
+
+// WriteBigQuery inserts r as a row of table, whose schema is ResultsSchema.
+// It works against the BigQuery emulator too, given a client for it.
+func (r Results) WriteBigQuery(ctx context.Context, table *bigquery.Table) error {
+	if err := table.Inserter().Put(ctx, r); err != nil {
+		return fmt.Errorf("failed to insert results into %s: %w", table.FullyQualifiedName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func runForResults(t *testing.T) loadgen.Results {
+	t.Helper()
+	devices := []*loadgen.Device{{ID: "device-1", MessageRate: 100}, {ID: "device-2", MessageRate: 100}}
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+	lg.OnProgress(40*time.Millisecond, func(loadgen.Snapshot) {})
+	result, err := lg.RunUntil(context.Background(), 100*time.Millisecond)
+	require.NoError(t, err)
+
+	results := lg.Results()
+	assert.Equal(t, result.Published, results.Published)
+	assert.Equal(t, loadgen.StopDuration, results.StoppedBy)
+	assert.Equal(t, 2, results.Devices)
+	assert.GreaterOrEqual(t, results.Elapsed, 100*time.Millisecond)
+	assert.InDelta(t, 200, results.Rate, 60)
+	assert.NotEmpty(t, results.Snapshots)
+	results.Labels = map[string]string{"commit": "abc123"}
+	return results
+}
+
+func TestResults_WriteJSON(t *testing.T) {
+	results := runForResults(t)
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteJSON(&buf))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, float64(results.Published), decoded["published"])
+	assert.Equal(t, "duration", decoded["stopped_by"])
+	assert.Equal(t, map[string]any{"commit": "abc123"}, decoded["labels"])
+	assert.InDelta(t, results.Elapsed.Seconds(), decoded["elapsed_seconds"], 1e-9)
+	snapshots := decoded["snapshots"].([]any)
+	assert.Len(t, snapshots, len(results.Snapshots))
+	assert.Equal(t, true, snapshots[len(snapshots)-1].(map[string]any)["final"])
+}
+
+func TestResults_WriteCSV(t *testing.T) {
+	results := runForResults(t)
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteCSV(&buf))
+	require.NoError(t, results.WriteCSVRow(&buf))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"start", "elapsed_seconds", "warmup_seconds", "devices", "published", "failed", "retries", "warmup_published", "stopped_by", "rate", "labels"}, records[0])
+	assert.Equal(t, records[1], records[2])
+	assert.Equal(t, "2", records[1][3])
+	assert.Equal(t, "duration", records[1][8])
+	assert.Equal(t, `{"commit":"abc123"}`, records[1][10])
+}
+
+func TestResults_WriteBigQuery(t *testing.T) {
+	// A fake of the BigQuery insertAll API, which records the inserted rows.
+	var rows []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/projects/test-project/datasets/loadtests/tables/results/insertAll") {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Rows []struct {
+				JSON map[string]any `json:"json"`
+			} `json:"rows"`
+		}
+		_ = json.Unmarshal(body, &req)
+		for _, row := range req.Rows {
+			rows = append(rows, row.JSON)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"bigquery#tableDataInsertAllResponse"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "test-project", option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	results := runForResults(t)
+	require.NoError(t, results.WriteBigQuery(ctx, client.Dataset("loadtests").Table("results")))
+	require.Len(t, rows, 1)
+	assert.Equal(t, "duration", rows[0]["stopped_by"])
+	assert.Equal(t, `{"commit":"abc123"}`, rows[0]["labels"])
+
+	var names []string
+	for _, field := range loadgen.ResultsSchema() {
+		names = append(names, field.Name)
+		assert.Contains(t, rows[0], field.Name)
+	}
+	assert.Len(t, names, len(rows[0]))
+}
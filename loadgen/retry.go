@@ -0,0 +1,123 @@
+// loadgen/retry.go
+
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy retries the publishes of a LoadGenerator that fail with a
+// retryable error or are not acknowledged, with exponential backoff. A retry
+// resends the same payload.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per message, including the
+	// first. 1 or less means no retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. Each later wait is
+	// Multiplier times the one before, up to MaxBackoff if it is set.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier defaults to 2.
+	Multiplier float64
+	// Retryable decides which errors are retried. Nil means IsRetryable.
+	Retryable func(error) bool
+}
+
+// SetRetryPolicy makes the LoadGenerator retry failed publishes under
+// policy, so a transient broker hiccup does not silently lower the published
+// count. A message counts as published or failed once, after its last
+// attempt, and RunResult.Retries counts the retries. A device's worker is
+// busy during its backoff, so the device does not publish in the meantime.
+func (lg *LoadGenerator) SetRetryPolicy(policy RetryPolicy) {
+	lg.retry = policy
+}
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as fatal, so IsRetryable reports false for it and
+// errors wrapping it. Clients use it for rejections such as an HTTP 400.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsRetryable is the default classification of a RetryPolicy. A nil error,
+// i.e. an unacknowledged publish, is retryable. Errors marked Permanent,
+// io.EOF from an exhausted PayloadGenerator and gRPC statuses that report a
+// bad request or missing permissions are not. Every other error is.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return true
+	}
+	var permanent permanentError
+	if errors.As(err, &permanent) || errors.Is(err, io.EOF) {
+		return false
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+			codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented:
+			return false
+		}
+	}
+	return true
+}
+
+// publish publishes a message of device, retrying it under the retry
+// policy until it succeeds, fails for good or ctx is done.
+func (lg *LoadGenerator) publish(ctx context.Context, device *Device) (bool, error) {
+	policy := lg.retry
+	if policy.MaxAttempts <= 1 {
+		return lg.client.Publish(ctx, device)
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	// Every attempt must carry the same payload, so the generator runs once.
+	once := *device
+	once.PayloadGenerator = &onePayload{generator: device.PayloadGenerator}
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		ok, err := lg.client.Publish(ctx, &once)
+		if ok || attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return ok, err
+		}
+		if !IsWarmup(ctx) {
+			atomic.AddInt64(&lg.retriedCount, 1)
+		}
+		lg.logger.Debug().Err(err).Str("device_id", device.ID).Int("attempt", attempt).Dur("backoff", backoff).Msg("Retrying publish.")
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ok, err
+		case <-timer.C:
+		}
+		backoff = time.Duration(float64(backoff) * multiplier)
+		if policy.MaxBackoff > 0 {
+			backoff = min(backoff, policy.MaxBackoff)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package loadgen_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyClient fails the first attempts of every message with err, and
+// records the payload of each attempt.
+type flakyClient struct {
+	failures int
+	err      error
+
+	mu       sync.Mutex
+	attempts []string
+	failed   int
+}
+
+func (c *flakyClient) Connect() error { return nil }
+
+func (c *flakyClient) Disconnect() {}
+
+func (c *flakyClient) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	payload, err := device.PayloadGenerator.GeneratePayload(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate payload for device %s: %w", device.ID, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts = append(c.attempts, string(payload))
+	if c.failed < c.failures {
+		c.failed++
+		return false, c.err
+	}
+	c.failed = 0
+	return true, nil
+}
+
+func TestLoadGenerator_SetRetryPolicy(t *testing.T) {
+	newRun := func(client loadgen.Client, policy loadgen.RetryPolicy) (loadgen.RunResult, error) {
+		gen, err := loadgen.NewTemplatePayloadGenerator("{{.Seq}}")
+		require.NoError(t, err)
+		device := &loadgen.Device{ID: "device-1", MessageRate: 100, PayloadGenerator: gen}
+		lg := loadgen.NewLoadGenerator(client, []*loadgen.Device{device}, zerolog.Nop())
+		lg.SetRetryPolicy(policy)
+		return lg.RunUntil(context.Background(), time.Minute, loadgen.StopAfterMessages(2))
+	}
+
+	t.Run("transient failures", func(t *testing.T) {
+		client := &flakyClient{failures: 2, err: errors.New("broker busy")}
+		result, err := newRun(client, loadgen.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Published)
+		assert.Zero(t, result.Failed)
+		assert.Equal(t, 4, result.Retries)
+		assert.Equal(t, []string{"1", "1", "1", "2", "2", "2"}, client.attempts, "retries resend the same payload")
+	})
+
+	t.Run("attempts run out", func(t *testing.T) {
+		client := &flakyClient{failures: 5, err: errors.New("broker busy")}
+		result, err := newRun(client, loadgen.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+		require.NoError(t, err)
+		assert.Positive(t, result.Failed)
+		assert.Equal(t, []string{"1", "1"}, client.attempts[:2])
+	})
+
+	t.Run("permanent errors", func(t *testing.T) {
+		client := &flakyClient{failures: 1, err: loadgen.Permanent(errors.New("rejected"))}
+		result, err := newRun(client, loadgen.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Published)
+		assert.Equal(t, 2, result.Failed, "the first attempt of each message fails for good")
+		assert.Zero(t, result.Retries)
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, loadgen.IsRetryable(nil), "an unacknowledged publish")
+	assert.True(t, loadgen.IsRetryable(errors.New("connection reset")))
+	assert.True(t, loadgen.IsRetryable(status.Error(codes.Unavailable, "down")))
+	assert.False(t, loadgen.IsRetryable(fmt.Errorf("wrapped: %w", loadgen.Permanent(errors.New("bad")))))
+	assert.False(t, loadgen.IsRetryable(fmt.Errorf("failed to generate payload: %w", io.EOF)))
+	assert.False(t, loadgen.IsRetryable(fmt.Errorf("grpc publish error: %w", status.Error(codes.InvalidArgument, "bad"))))
+	assert.Nil(t, loadgen.Permanent(nil))
+}
@@ -0,0 +1,443 @@
+// loadgen/scenario.go
+
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// PhaseKind is what a Phase of a Scenario does.
+type PhaseKind string
+
+const (
+	// PhaseRamp moves the fleet's message rate linearly to Rate over
+	// Duration, or at once if Duration is zero.
+	PhaseRamp PhaseKind = "ramp"
+	// PhaseHold keeps the fleet's message rate for Duration.
+	PhaseHold PhaseKind = "hold"
+	// PhaseKill stops Fraction of the running devices, then holds for
+	// Duration.
+	PhaseKill PhaseKind = "kill"
+)
+
+// Phase is a step of a Scenario.
+type Phase struct {
+	// Name labels the phase in its results. It defaults to a description
+	// of the phase.
+	Name     string
+	Kind     PhaseKind
+	Duration time.Duration
+	// Rate is the fleet-wide message rate, in messages per second, a ramp
+	// moves to.
+	Rate float64
+	// Fraction is the share, 0 to 1, of the running devices a kill stops.
+	Fraction float64
+}
+
+// RampTo returns a phase that moves the fleet's message rate linearly to
+// rate messages per second over duration.
+func RampTo(rate float64, duration time.Duration) Phase {
+	return Phase{Kind: PhaseRamp, Rate: rate, Duration: duration}
+}
+
+// Hold returns a phase that keeps the fleet's message rate for duration.
+func Hold(duration time.Duration) Phase {
+	return Phase{Kind: PhaseHold, Duration: duration}
+}
+
+// KillDevices returns a phase that stops fraction of the running devices,
+// e.g. 0.2 for a fifth of them.
+func KillDevices(fraction float64) Phase {
+	return Phase{Kind: PhaseKill, Fraction: fraction}
+}
+
+// String describes the phase.
+func (p Phase) String() string {
+	switch p.Kind {
+	case PhaseRamp:
+		return fmt.Sprintf("ramp to %s msg/s over %s", strconv.FormatFloat(p.Rate, 'f', -1, 64), p.Duration)
+	case PhaseHold:
+		return fmt.Sprintf("hold %s", p.Duration)
+	case PhaseKill:
+		return fmt.Sprintf("kill %s%% of devices", strconv.FormatFloat(p.Fraction*100, 'f', -1, 64))
+	}
+	return string(p.Kind)
+}
+
+// name returns the name of the phase in its results.
+func (p Phase) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.String()
+}
+
+// phaseJSON is the JSON form of a Phase, with the duration as a Go duration
+// string, e.g. "2m".
+type phaseJSON struct {
+	Name     string    `json:"name,omitempty"`
+	Kind     PhaseKind `json:"kind"`
+	Duration string    `json:"duration,omitempty"`
+	Rate     float64   `json:"rate,omitempty"`
+	Fraction float64   `json:"fraction,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Phase) MarshalJSON() ([]byte, error) {
+	j := phaseJSON{Name: p.Name, Kind: p.Kind, Rate: p.Rate, Fraction: p.Fraction}
+	if p.Duration != 0 {
+		j.Duration = p.Duration.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Phase) UnmarshalJSON(data []byte) error {
+	var j phaseJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*p = Phase{Name: j.Name, Kind: j.Kind, Rate: j.Rate, Fraction: j.Fraction}
+	if j.Duration != "" {
+		d, err := time.ParseDuration(j.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid phase duration: %w", err)
+		}
+		p.Duration = d
+	}
+	return nil
+}
+
+// Scenario is a load test plan of phases run one after another by
+// RunScenario, so complex plans can live in config:
+//
+//	{"name": "soak", "phases": [
+//	  {"kind": "ramp", "rate": 1000, "duration": "2m"},
+//	  {"kind": "hold", "duration": "10m"},
+//	  {"kind": "kill", "fraction": 0.2, "duration": "1m"},
+//	  {"kind": "ramp", "rate": 0, "duration": "1m"}
+//	]}
+type Scenario struct {
+	Name   string  `json:"name,omitempty"`
+	Phases []Phase `json:"phases"`
+}
+
+// ParseScenario parses and validates a JSON Scenario.
+func ParseScenario(data []byte) (Scenario, error) {
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Scenario{}, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return Scenario{}, err
+	}
+	return s, nil
+}
+
+// LoadScenario reads and validates a JSON Scenario from file.
+func LoadScenario(file string) (Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return ParseScenario(data)
+}
+
+// Duration returns the total duration of the phases.
+func (s Scenario) Duration() time.Duration {
+	var total time.Duration
+	for _, p := range s.Phases {
+		total += p.Duration
+	}
+	return total
+}
+
+// Validate reports the first problem with the scenario's phases.
+func (s Scenario) Validate() error {
+	if len(s.Phases) == 0 {
+		return errors.New("scenario has no phases")
+	}
+	for i, p := range s.Phases {
+		switch {
+		case p.Kind != PhaseRamp && p.Kind != PhaseHold && p.Kind != PhaseKill:
+			return fmt.Errorf("phase %d has an unknown kind %q", i, p.Kind)
+		case p.Duration < 0:
+			return fmt.Errorf("phase %d (%s) has a negative duration", i, p.name())
+		case p.Kind == PhaseRamp && p.Rate < 0:
+			return fmt.Errorf("phase %d (%s) has a negative rate", i, p.name())
+		case p.Kind == PhaseKill && (p.Fraction < 0 || p.Fraction > 1):
+			return fmt.Errorf("phase %d (%s) has a fraction outside 0 to 1", i, p.name())
+		}
+	}
+	if s.Duration() <= 0 {
+		return errors.New("scenario has no duration")
+	}
+	return nil
+}
+
+// PhaseResult is the outcome of a phase of a Scenario.
+type PhaseResult struct {
+	Name string
+	Kind PhaseKind
+	// Start is when the phase began, from the end of the warm-up, and
+	// Elapsed how long it ran.
+	Start   time.Duration
+	Elapsed time.Duration
+	// Published, Failed and Retries count the publishes of the phase.
+	Published int
+	Failed    int
+	Retries   int
+	// Rate is the number of messages published per second in the phase.
+	Rate float64
+	// TargetRate is the fleet-wide message rate the phase ended at, and
+	// Devices the number of devices still running.
+	TargetRate float64
+	Devices    int
+}
+
+// ScenarioResult is the outcome of RunScenario: that of the whole run, and
+// of each phase it reached.
+type ScenarioResult struct {
+	RunResult
+	Phases []PhaseResult
+}
+
+// RunScenario runs the load test through the phases of scenario, for their
+// total duration or until ctx is cancelled or one of conditions is met.
+//
+// The fleet-wide rate a ramp sets is shared among the running devices in
+// proportion to their own rates (MessageRate, or Burst.Size per
+// Burst.Interval), or equally if none has one, and the first ramp starts
+// from the sum of their rates. A kill stops devices spread evenly through
+// the running ones, in fleet order; the rest keep their rates, so the
+// fleet's rate falls by their share. Any warm-up runs at the devices' own
+// rates before the first phase.
+//
+// The phases change the devices' rates as SetRate does. The rates set before
+// RunScenario are restored when it returns.
+func (lg *LoadGenerator) RunScenario(ctx context.Context, scenario Scenario, conditions ...StopCondition) (ScenarioResult, error) {
+	if err := scenario.Validate(); err != nil {
+		return ScenarioResult{}, err
+	}
+	defer lg.restoreRates(lg.rateOverrides())
+
+	sr := lg.newScenarioRun(scenario)
+	lg.logger.Info().Str("scenario", scenario.Name).Int("phases", len(scenario.Phases)).Msg("Running scenario.")
+	result, err := lg.run(ctx, scenario.Duration(), sr.direct, conditions)
+	if err != nil {
+		return ScenarioResult{}, err
+	}
+	if len(sr.results) > 0 {
+		sr.finishPhase(time.Now())
+	}
+	return ScenarioResult{RunResult: result, Phases: sr.results}, nil
+}
+
+// scenarioRun carries out a Scenario during a run.
+type scenarioRun struct {
+	lg     *LoadGenerator
+	phases []Phase
+	// running are the devices not yet killed, weights their share of the
+	// fleet's rate in messages per second, and rate the fleet's rate.
+	running []*Device
+	weights map[string]float64
+	rate    float64
+
+	// results are the results of the phases reached, the last in progress
+	// since startTime, when the run's counts were startCounts.
+	results     []PhaseResult
+	startCounts [3]int64
+	startTime   time.Time
+	measureFrom time.Time
+}
+
+func (lg *LoadGenerator) newScenarioRun(scenario Scenario) *scenarioRun {
+	sr := &scenarioRun{lg: lg, phases: scenario.Phases, weights: make(map[string]float64, len(lg.devices))}
+	for _, device := range lg.devices {
+		sr.running = append(sr.running, device)
+		if interval := lg.interval(device); interval > 0 {
+			sr.weights[device.ID] = float64(burstSize(device)) * float64(time.Second) / float64(interval)
+			sr.rate += sr.weights[device.ID]
+		}
+	}
+	return sr
+}
+
+// burstSize returns the number of messages device sends at a time.
+func burstSize(device *Device) int {
+	if device.Burst != nil && device.Burst.Size > 0 {
+		return device.Burst.Size
+	}
+	return 1
+}
+
+// direct runs the phases, from the end of the warm-up, until runCtx is done
+// or devicesDone is closed.
+func (sr *scenarioRun) direct(runCtx context.Context, start time.Time, devicesDone <-chan struct{}) {
+	sr.measureFrom = start.Add(sr.lg.warmup)
+	timer := time.NewTimer(time.Until(sr.measureFrom))
+	defer timer.Stop()
+	wait := func(until time.Time) bool {
+		timer.Reset(time.Until(until))
+		select {
+		case <-runCtx.Done():
+			return false
+		case <-devicesDone:
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+	if !wait(sr.measureFrom) {
+		return
+	}
+
+	phaseStart := sr.measureFrom
+	for _, p := range sr.phases {
+		sr.startPhase(p, phaseStart)
+		end := phaseStart.Add(p.Duration)
+		switch p.Kind {
+		case PhaseRamp:
+			from, step := sr.rate, rampStep(p.Duration)
+			for now := time.Now(); now.Before(end); now = time.Now() {
+				progress := float64(now.Sub(phaseStart)) / float64(p.Duration)
+				sr.setRate(from + (p.Rate-from)*progress)
+				next := now.Add(step)
+				if end.Before(next) {
+					next = end
+				}
+				if !wait(next) {
+					return
+				}
+			}
+			sr.setRate(p.Rate)
+		case PhaseKill:
+			sr.kill(p.Fraction)
+		}
+		if !wait(end) {
+			return
+		}
+		phaseStart = end
+	}
+}
+
+// rampStep returns how often a ramp over duration updates the rate: about
+// a hundred times, but no more often than every 10ms and at least every
+// second.
+func rampStep(duration time.Duration) time.Duration {
+	return min(max(duration/100, 10*time.Millisecond), time.Second)
+}
+
+// setRate shares the fleet-wide rate among the running devices.
+func (sr *scenarioRun) setRate(rate float64) {
+	sr.lg.setRates(sr.share(rate))
+}
+
+// share makes rate the fleet-wide rate and returns the rate of each running
+// device under it, in sends per second.
+func (sr *scenarioRun) share(rate float64) map[string]float64 {
+	sr.rate = rate
+	var total float64
+	for _, device := range sr.running {
+		total += sr.weights[device.ID]
+	}
+	rates := make(map[string]float64, len(sr.running))
+	for _, device := range sr.running {
+		share := 1 / float64(len(sr.running))
+		if total > 0 {
+			share = sr.weights[device.ID] / total
+		}
+		rates[device.ID] = rate * share / float64(burstSize(device))
+	}
+	return rates
+}
+
+// kill stops fraction of the running devices.
+func (sr *scenarioRun) kill(fraction float64) {
+	rates := sr.stop(fraction)
+	if len(rates) == 0 {
+		return
+	}
+	sr.lg.setRates(rates)
+	sr.lg.logger.Info().Int("killed", len(rates)).Int("running", len(sr.running)).Msg("Scenario killed devices.")
+}
+
+// stop takes fraction of the running devices out of the fleet, spread
+// evenly through it, and returns their rates of 0.
+func (sr *scenarioRun) stop(fraction float64) map[string]float64 {
+	n := len(sr.running)
+	count := int(math.Round(fraction * float64(n)))
+	if count == 0 {
+		return nil
+	}
+	var total, killedWeight float64
+	rates := make(map[string]float64, count)
+	killed := make(map[int]bool, count)
+	for i := range count {
+		killed[int((float64(i)+0.5)*float64(n)/float64(count))] = true
+	}
+	var survivors []*Device
+	for i, device := range sr.running {
+		total += sr.weights[device.ID]
+		if killed[i] {
+			rates[device.ID] = 0
+			killedWeight += sr.weights[device.ID]
+			continue
+		}
+		survivors = append(survivors, device)
+	}
+	switch {
+	case total > 0:
+		sr.rate -= sr.rate * killedWeight / total
+	default:
+		sr.rate -= sr.rate * float64(count) / float64(n)
+	}
+	sr.running = survivors
+	return rates
+}
+
+// counts returns the run's published, failed and retried counts.
+func (sr *scenarioRun) counts() [3]int64 {
+	return [3]int64{
+		atomic.LoadInt64(&sr.lg.publishedCount),
+		atomic.LoadInt64(&sr.lg.failedCount),
+		atomic.LoadInt64(&sr.lg.retriedCount),
+	}
+}
+
+// startPhase finishes the phase in progress, if any, and starts p at start.
+func (sr *scenarioRun) startPhase(p Phase, start time.Time) {
+	// The run counts nothing before the first phase, which starts as the
+	// warm-up ends.
+	var counts [3]int64
+	if len(sr.results) > 0 {
+		counts = sr.finishPhase(start)
+	}
+	sr.lg.logger.Info().Str("phase", p.name()).Msg("Scenario phase started.")
+	sr.results = append(sr.results, PhaseResult{Name: p.name(), Kind: p.Kind, Start: start.Sub(sr.measureFrom)})
+	sr.startCounts, sr.startTime = counts, start
+}
+
+// finishPhase completes the result of the phase in progress at end, and
+// returns the run's counts then.
+func (sr *scenarioRun) finishPhase(end time.Time) [3]int64 {
+	counts := sr.counts()
+	r := &sr.results[len(sr.results)-1]
+	r.Elapsed = end.Sub(sr.startTime)
+	r.Published = int(counts[0] - sr.startCounts[0])
+	r.Failed = int(counts[1] - sr.startCounts[1])
+	r.Retries = int(counts[2] - sr.startCounts[2])
+	if r.Elapsed > 0 {
+		r.Rate = float64(r.Published) / r.Elapsed.Seconds()
+	}
+	r.TargetRate, r.Devices = sr.rate, len(sr.running)
+	return counts
+}
@@ -0,0 +1,147 @@
+package loadgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenario(t *testing.T) {
+	data := `{"name": "soak", "phases": [
+		{"kind": "ramp", "rate": 1000, "duration": "2m"},
+		{"name": "steady", "kind": "hold", "duration": "10m"},
+		{"kind": "kill", "fraction": 0.2},
+		{"kind": "ramp", "rate": 0, "duration": "1m30s"}
+	]}`
+	scenario, err := loadgen.ParseScenario([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, "soak", scenario.Name)
+	assert.Equal(t, []loadgen.Phase{
+		loadgen.RampTo(1000, 2*time.Minute),
+		{Name: "steady", Kind: loadgen.PhaseHold, Duration: 10 * time.Minute},
+		loadgen.KillDevices(0.2),
+		loadgen.RampTo(0, 90*time.Second),
+	}, scenario.Phases)
+	assert.Equal(t, 13*time.Minute+30*time.Second, scenario.Duration())
+	assert.Equal(t, "ramp to 1000 msg/s over 2m0s", scenario.Phases[0].String())
+	assert.Equal(t, "kill 20% of devices", scenario.Phases[2].String())
+
+	encoded, err := json.Marshal(scenario)
+	require.NoError(t, err)
+	decoded, err := loadgen.ParseScenario(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, scenario, decoded)
+
+	file := filepath.Join(t.TempDir(), "scenario.json")
+	require.NoError(t, os.WriteFile(file, []byte(data), 0600))
+	loaded, err := loadgen.LoadScenario(file)
+	require.NoError(t, err)
+	assert.Equal(t, scenario, loaded)
+}
+
+func TestScenario_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"no phases", `{"phases": []}`, "scenario has no phases"},
+		{"unknown kind", `{"phases": [{"kind": "explode", "duration": "1s"}]}`, `phase 0 has an unknown kind "explode"`},
+		{"negative duration", `{"phases": [{"kind": "hold", "duration": "-1s"}]}`, "phase 0 (hold -1s) has a negative duration"},
+		{"negative rate", `{"phases": [{"kind": "ramp", "rate": -1, "duration": "1s"}]}`, "phase 0 (ramp to -1 msg/s over 1s) has a negative rate"},
+		{"bad fraction", `{"phases": [{"kind": "kill", "fraction": 1.5, "duration": "1s"}]}`, "phase 0 (kill 150% of devices) has a fraction outside 0 to 1"},
+		{"no duration", `{"phases": [{"kind": "kill", "fraction": 0.5}]}`, "scenario has no duration"},
+		{"bad duration", `{"phases": [{"kind": "hold", "duration": "soon"}]}`, "invalid phase duration"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadgen.ParseScenario([]byte(tc.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestLoadGenerator_RunScenario(t *testing.T) {
+	// Ten devices of 10 Hz: a fleet rate of 100 msg/s.
+	devices, err := loadgen.NewFleet().WithCount(10).WithRate(10).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	scenario := loadgen.Scenario{Name: "test", Phases: []loadgen.Phase{
+		{Name: "baseline", Kind: loadgen.PhaseHold, Duration: 300 * time.Millisecond},
+		loadgen.RampTo(400, 0),
+		loadgen.Hold(300 * time.Millisecond),
+		{Kind: loadgen.PhaseKill, Fraction: 0.5, Duration: 300 * time.Millisecond},
+		loadgen.RampTo(0, 0),
+		loadgen.Hold(200 * time.Millisecond),
+	}}
+	result, err := lg.RunScenario(context.Background(), scenario)
+	require.NoError(t, err)
+	assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+	require.Len(t, result.Phases, 6)
+
+	baseline, hold, kill, stopped := result.Phases[0], result.Phases[2], result.Phases[3], result.Phases[5]
+	assert.Equal(t, "baseline", baseline.Name)
+	assert.InDelta(t, 100, baseline.TargetRate, 1e-9)
+	assert.InDelta(t, 100, baseline.Rate, 40)
+	assert.Equal(t, "hold 300ms", hold.Name)
+	assert.InDelta(t, 400, hold.TargetRate, 1e-9)
+	assert.InDelta(t, 400, hold.Rate, 100)
+	assert.Equal(t, loadgen.PhaseKill, kill.Kind)
+	assert.Equal(t, 5, kill.Devices)
+	assert.InDelta(t, 200, kill.TargetRate, 1e-9)
+	assert.InDelta(t, 200, kill.Rate, 70)
+	assert.LessOrEqual(t, stopped.Published, 5, "at most the sends due as the rate fell to 0")
+	assert.InDelta(t, 900*time.Millisecond, stopped.Start, float64(50*time.Millisecond))
+
+	var total int
+	for _, p := range result.Phases {
+		total += p.Published
+	}
+	assert.Equal(t, result.Published, total)
+
+	t.Run("rates are restored", func(t *testing.T) {
+		result, err := lg.RunUntil(context.Background(), 250*time.Millisecond)
+		require.NoError(t, err)
+		assert.InDelta(t, lg.ExpectedMessagesForDuration(250*time.Millisecond), result.Published, 2)
+	})
+}
+
+func TestLoadGenerator_RunScenarioRamp(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(4).WithRate(25).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	// Ramping from 0 to 400 msg/s over 500ms publishes about 100 messages.
+	scenario := loadgen.Scenario{Phases: []loadgen.Phase{loadgen.RampTo(0, 0), loadgen.RampTo(400, 500*time.Millisecond)}}
+	result, err := lg.RunScenario(context.Background(), scenario)
+	require.NoError(t, err)
+	require.Len(t, result.Phases, 2)
+	ramp := result.Phases[1]
+	assert.InDelta(t, 400, ramp.TargetRate, 1e-9)
+	assert.InDelta(t, 100, ramp.Published, 35)
+	assert.Equal(t, 4, ramp.Devices)
+}
+
+func TestLoadGenerator_RunScenarioStopsEarly(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(2).WithRate(100).Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices, zerolog.Nop())
+
+	scenario := loadgen.Scenario{Phases: []loadgen.Phase{loadgen.Hold(100 * time.Millisecond), loadgen.Hold(10 * time.Second)}}
+	result, err := lg.RunScenario(context.Background(), scenario, loadgen.StopAfterMessages(50))
+	require.NoError(t, err)
+	assert.Equal(t, loadgen.StopMessageCount, result.StoppedBy)
+	require.Len(t, result.Phases, 2, "the second phase was reached but not finished")
+	assert.Less(t, result.Phases[1].Elapsed, time.Second)
+	assert.Equal(t, result.Published, result.Phases[0].Published+result.Phases[1].Published)
+}
@@ -0,0 +1,329 @@
+// loadgen/scheduler.go
+
+package loadgen
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultWorkers caps the number of publishing goroutines of a run unless
+// SetWorkers sets it.
+const defaultWorkers = 1024
+
+// SetWorkers sets the number of goroutines that publish the devices'
+// messages. The devices wait on one shared schedule rather than a goroutine
+// and ticker each, so a fleet of tens of thousands of low-rate devices only
+// needs as many workers as it has publishes in flight at once. When every
+// worker is busy, due messages wait for the next free one. A device never
+// publishes concurrently with itself. n <= 0 restores the default of one
+// worker per device, up to 1024.
+func (lg *LoadGenerator) SetWorkers(n int) {
+	lg.workers = n
+}
+
+// scheduledDevice is a device on the schedule of a run. Only the
+// dispatcher changes it.
+type scheduledDevice struct {
+	device *Device
+	// timing decides the waits between sends. Nil means the device sends
+	// every interval and, like a ticker, skips the sends a slow publish
+	// misses.
+	timing   Timing
+	interval time.Duration
+	// size is the number of messages per send: the burst size, or 1.
+	size int
+	// last and next are the device's previous and next scheduled sends.
+	last, next time.Time
+	// index is the device's position on the queue, or -1 when it is off the
+	// queue: with a worker, or stopped for want of a rate.
+	index    int
+	inFlight bool
+	// held is set on a device stopped by SetRate, which keeps the run going.
+	held bool
+	// done is set on a device that has nothing more to send in the run, so
+	// SetRate does not start it again.
+	done bool
+}
+
+// schedule is a min-heap of devices by their next send.
+type schedule []*scheduledDevice
+
+func (s schedule) Len() int           { return len(s) }
+func (s schedule) Less(i, j int) bool { return s[i].next.Before(s[j].next) }
+func (s schedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index, s[j].index = i, j
+}
+
+func (s *schedule) Push(x any) {
+	d := x.(*scheduledDevice)
+	d.index = len(*s)
+	*s = append(*s, d)
+}
+
+func (s *schedule) Pop() any {
+	old := *s
+	d := old[len(old)-1]
+	old[len(old)-1] = nil
+	d.index = -1
+	*s = old[:len(old)-1]
+	return d
+}
+
+// scheduleDevice returns device scheduled to send at start, or nil if it
+// can never send. A device without a rate is returned off the queue.
+func (lg *LoadGenerator) scheduleDevice(device *Device, start time.Time) *scheduledDevice {
+	d := &scheduledDevice{device: device, timing: device.Timing, interval: lg.interval(device), size: 1, next: start, index: -1}
+	if b := device.Burst; b != nil {
+		if b.Size <= 0 {
+			lg.logger.Warn().Str("device_id", device.ID).Msg("Device has an empty burst, no messages will be sent.")
+			return nil
+		}
+		if d.timing == nil {
+			d.timing = FixedTiming()
+		}
+		d.size = b.Size
+	}
+	if d.interval <= 0 {
+		lg.logger.Warn().Str("device_id", device.ID).Msg("Device has a message rate of 0, no messages will be sent.")
+		return d
+	}
+	lg.logger.Debug().Str("device_id", device.ID).Int("size", d.size).Dur("interval", d.interval).Msg("Device scheduled.")
+	return d
+}
+
+// advance moves the device's next send on from the one it has just made.
+// The waits are measured from the previous scheduled send, not from the end
+// of the previous publish, so slow publishes do not lower the mean rate.
+func (d *scheduledDevice) advance(now time.Time) {
+	d.last = d.next
+	if d.timing != nil {
+		d.next = d.next.Add(nextWait(d.timing, d.device, d.interval))
+		return
+	}
+	d.next = d.next.Add(d.interval)
+	if missed := now.Sub(d.next); missed > 0 {
+		d.next = d.next.Add((missed + d.interval - 1) / d.interval * d.interval)
+	}
+}
+
+// dispatcher hands the devices of a run to the workers as their sends fall
+// due, and applies Pause, Resume and SetRate.
+type dispatcher struct {
+	lg       *LoadGenerator
+	queue    schedule
+	devices  map[string]*scheduledDevice
+	inFlight int
+	held     int
+	paused   bool
+	pausedAt time.Time
+}
+
+// runDevices publishes the messages of every device, from start, until ctx
+// is done or no device has any left to send, in publishCtx, and returns once
+// the publishes in flight have returned. Sends due at or before ctx's
+// deadline are made even if the deadline passes before they are dispatched.
+// It is deterministic: each device publishes immediately at T=0, and then
+// once per interval. This ensures that for a given rate R and duration D,
+// the number of messages is exactly ceil(R*D).
+// For example, a rate of 1Hz for 2 seconds sends messages at T=0s and T=1s
+// for a total of 2 messages. A rate of 1Hz for 2.1 seconds sends messages
+// at T=0s, T=1s, and T=2s for a total of 3 messages.
+// A device with a Timing waits the intervals it returns instead, and a
+// device with a Burst sends Burst.Size messages at each. A device stops
+// early once its PayloadGenerator returns io.EOF.
+func (lg *LoadGenerator) runDevices(ctx, publishCtx context.Context, start time.Time) {
+	paused, _ := lg.pending()
+	dp := &dispatcher{lg: lg, devices: make(map[string]*scheduledDevice), paused: paused, pausedAt: start}
+	for _, device := range lg.devices {
+		d := lg.scheduleDevice(device, start)
+		if d == nil {
+			continue
+		}
+		dp.devices[device.ID] = d
+		if d.interval > 0 {
+			dp.queue.Push(d)
+		}
+	}
+	if len(dp.queue) == 0 {
+		return
+	}
+	heap.Init(&dp.queue)
+
+	workers := lg.workers
+	if workers <= 0 {
+		workers = min(len(dp.devices), defaultWorkers)
+	}
+	lg.logger.Info().Int("scheduled_devices", len(dp.devices)).Int("workers", workers).Msg("Publishing.")
+
+	// A device is either on the queue, stopped or with a worker, so sent
+	// never holds more than one entry per device and workers never block on
+	// it.
+	jobs := make(chan *scheduledDevice)
+	sent := make(chan sendResult, len(dp.devices))
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				sent <- sendResult{device: d, more: lg.send(ctx, publishCtx, d)}
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	wake := lg.ctl.wakeChan()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for dp.queue.Len() > 0 || dp.inFlight > 0 || dp.held > 0 {
+		// Nil channels disable their cases: dispatch only a due device,
+		// and wait on the timer only for one that is not yet due.
+		var dispatch chan<- *scheduledDevice
+		var due *scheduledDevice
+		var alarm <-chan time.Time
+		if dp.queue.Len() > 0 && !dp.paused {
+			due = dp.queue[0]
+			if wait := time.Until(due.next); wait > 0 {
+				timer.Reset(wait)
+				alarm = timer.C
+			} else {
+				dispatch = jobs
+			}
+		}
+		select {
+		case <-ctx.Done():
+			dp.dispatchDue(ctx, jobs, sent)
+			return
+		case dispatch <- due:
+			heap.Pop(&dp.queue)
+			due.inFlight = true
+			dp.inFlight++
+		case r := <-sent:
+			dp.inFlight--
+			r.device.inFlight = false
+			if r.more {
+				dp.reschedule(r.device, time.Now())
+			} else {
+				r.device.done = true
+			}
+		case <-wake:
+			dp.applyControl(time.Now())
+		case <-alarm:
+		}
+	}
+}
+
+// dispatchDue hands the workers the sends due at or before the deadline of
+// ctx once it has passed, which the timer and the deadline race for. A run
+// cut short some other way sends nothing more.
+func (dp *dispatcher) dispatchDue(ctx context.Context, jobs chan<- *scheduledDevice, sent <-chan sendResult) {
+	for dp.queue.Len() > 0 && !dp.paused && dueBy(ctx, dp.queue[0].next) {
+		select {
+		case jobs <- dp.queue[0]:
+			d := heap.Pop(&dp.queue).(*scheduledDevice)
+			d.inFlight = true
+			dp.inFlight++
+		case <-sent:
+			// The run is over, so the device is not rescheduled.
+			dp.inFlight--
+		}
+	}
+}
+
+// dueBy reports whether a send due at next belongs to the run of ctx: ctx
+// is not done, or its deadline passed no earlier than next.
+func dueBy(ctx context.Context, next time.Time) bool {
+	if ctx.Err() == nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && errors.Is(ctx.Err(), context.DeadlineExceeded) && !next.After(deadline)
+}
+
+// sendResult is a send made by a worker, and whether its device has more
+// to send.
+type sendResult struct {
+	device *scheduledDevice
+	more   bool
+}
+
+// reschedule puts d back on the queue after a send, at its current rate.
+func (dp *dispatcher) reschedule(d *scheduledDevice, now time.Time) {
+	d.interval = dp.lg.interval(d.device)
+	if d.interval <= 0 {
+		d.held = true
+		dp.held++
+		return
+	}
+	d.advance(now)
+	heap.Push(&dp.queue, d)
+}
+
+// applyControl applies the changes made by Pause, Resume and SetRate since
+// it was last called.
+func (dp *dispatcher) applyControl(now time.Time) {
+	paused, changed := dp.lg.pending()
+	switch {
+	case paused && !dp.paused:
+		dp.pausedAt = now
+	case !paused && dp.paused:
+		// Shifting every device alike keeps the queue in order.
+		shift := now.Sub(dp.pausedAt)
+		for _, d := range dp.queue {
+			d.last, d.next = d.last.Add(shift), d.next.Add(shift)
+		}
+	}
+	dp.paused = paused
+
+	for _, id := range changed {
+		d, ok := dp.devices[id]
+		if !ok || d.inFlight || d.done {
+			// reschedule picks up the rate when the send finishes, and a
+			// device that is done stays done.
+			continue
+		}
+		d.interval = dp.lg.interval(d.device)
+		switch {
+		case d.interval <= 0 && d.index >= 0:
+			heap.Remove(&dp.queue, d.index)
+			d.held = true
+			dp.held++
+		case d.interval <= 0:
+		case d.index < 0:
+			// A stopped device starts again straight away.
+			if d.held {
+				d.held = false
+				dp.held--
+			}
+			d.next = now
+			heap.Push(&dp.queue, d)
+		case !d.last.IsZero():
+			d.next = d.last.Add(d.interval)
+			if d.next.Before(now) {
+				d.next = now
+			}
+			heap.Fix(&dp.queue, d.index)
+		}
+	}
+}
+
+// send publishes the messages of one scheduled send of d in publishCtx. It
+// returns false once ctx is done or d's PayloadGenerator is exhausted. A
+// send that fell due by the deadline of ctx still starts, but a burst the
+// end of the run cuts short is not finished.
+func (lg *LoadGenerator) send(ctx, publishCtx context.Context, d *scheduledDevice) bool {
+	for i := range d.size {
+		if ctx.Err() != nil && (i > 0 || !dueBy(ctx, d.next)) || !lg.publishOne(ctx, publishCtx, d.device) {
+			return false
+		}
+	}
+	return true
+}
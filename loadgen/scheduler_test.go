@@ -0,0 +1,90 @@
+package loadgen_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyClient records how many publishes run at once, overall and per
+// device, and how many goroutines exist once publishing has started.
+type concurrencyClient struct {
+	delay time.Duration
+
+	mu         sync.Mutex
+	inFlight   int
+	maxFlight  int
+	devices    map[string]bool
+	overlapped bool
+
+	goroutines atomic.Int64
+}
+
+func (c *concurrencyClient) Connect() error { return nil }
+
+func (c *concurrencyClient) Disconnect() {}
+
+func (c *concurrencyClient) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	c.goroutines.CompareAndSwap(0, int64(runtime.NumGoroutine()))
+	c.mu.Lock()
+	c.inFlight++
+	c.maxFlight = max(c.maxFlight, c.inFlight)
+	if c.devices[device.ID] {
+		c.overlapped = true
+	}
+	c.devices[device.ID] = true
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.inFlight--
+	delete(c.devices, device.ID)
+	c.mu.Unlock()
+	return true, nil
+}
+
+func TestLoadGenerator_SetWorkers(t *testing.T) {
+	devices, err := loadgen.NewFleet().WithCount(5000).WithRate(4).Build()
+	require.NoError(t, err)
+	client := &concurrencyClient{devices: make(map[string]bool)}
+	lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+	lg.SetWorkers(4)
+
+	// Sends at T=0 and T=250ms, well clear of the end of the run.
+	count, err := lg.Run(context.Background(), 400*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Equal(t, lg.ExpectedMessagesForDuration(400*time.Millisecond), count)
+	assert.LessOrEqual(t, client.maxFlight, 4)
+	assert.False(t, client.overlapped, "a device never publishes concurrently with itself")
+	assert.Less(t, client.goroutines.Load(), int64(100), "devices do not get a goroutine each")
+}
+
+func TestLoadGenerator_SetWorkersBusy(t *testing.T) {
+	// With a single worker, slow publishes delay the other devices' sends
+	// rather than running them concurrently.
+	var devices []*loadgen.Device
+	for i := range 3 {
+		devices = append(devices, &loadgen.Device{ID: fmt.Sprintf("device-%d", i), MessageRate: 10})
+	}
+	client := &concurrencyClient{delay: 20 * time.Millisecond, devices: make(map[string]bool)}
+	lg := loadgen.NewLoadGenerator(client, devices, zerolog.Nop())
+	lg.SetWorkers(1)
+
+	count, err := lg.Run(context.Background(), 250*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, client.maxFlight)
+	assert.Positive(t, count)
+	assert.LessOrEqual(t, count, lg.ExpectedMessagesForDuration(250*time.Millisecond))
+}
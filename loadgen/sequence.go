@@ -0,0 +1,196 @@
+// loadgen/sequence.go
+
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// SequenceExtractor returns the device ID and sequence number carried by a
+// received payload, or false if it has none.
+type SequenceExtractor func(payload []byte) (deviceID string, seq uint64, ok bool)
+
+// JSONSequenceExtractor reads the device ID and sequence number from
+// top-level fields of JSON object payloads, e.g. those rendered from
+// {"device":{{json .DeviceID}},"seq":{{.Seq}}} by a TemplatePayloadGenerator.
+// The sequence number may be a JSON number or a numeric string, and the
+// device ID a string or a number.
+func JSONSequenceExtractor(deviceField, seqField string) SequenceExtractor {
+	return func(payload []byte) (string, uint64, bool) {
+		id, err := jsonDeviceID(payload, deviceField)
+		if err != nil {
+			return "", 0, false
+		}
+		var object map[string]json.RawMessage
+		_ = json.Unmarshal(payload, &object)
+		var number json.Number
+		if json.Unmarshal(object[seqField], &number) != nil {
+			return "", 0, false
+		}
+		seq, err := strconv.ParseUint(number.String(), 10, 64)
+		if err != nil {
+			return "", 0, false
+		}
+		return id, seq, true
+	}
+}
+
+// SequenceRange is a run of sequence numbers, From to To inclusive.
+type SequenceRange struct {
+	From, To uint64
+}
+
+func (r SequenceRange) String() string {
+	if r.From == r.To {
+		return strconv.FormatUint(r.From, 10)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// DeviceSequenceReport is the sequence check of one device's messages.
+type DeviceSequenceReport struct {
+	// Received is the number of messages received, duplicates included.
+	Received int
+	// First and Last are the lowest and highest sequence numbers received.
+	First, Last uint64
+	// Missing are the sequence numbers between First and Last never
+	// received.
+	Missing []SequenceRange
+	// Duplicates are the sequence numbers received more than once, with one
+	// entry per extra copy.
+	Duplicates []uint64
+	// OutOfOrder is the number of messages received after one with a higher
+	// sequence number.
+	OutOfOrder int
+}
+
+// MissingCount returns the number of sequence numbers missing.
+func (r DeviceSequenceReport) MissingCount() int {
+	n := 0
+	for _, gap := range r.Missing {
+		n += int(gap.To - gap.From + 1)
+	}
+	return n
+}
+
+// SequenceReport is the outcome of a SequenceChecker.
+type SequenceReport struct {
+	// Devices holds the report of each device, by ID.
+	Devices map[string]DeviceSequenceReport
+	// Received, Missing, Duplicates and OutOfOrder sum those of the devices.
+	Received   int
+	Missing    int
+	Duplicates int
+	OutOfOrder int
+	// Unrecognised is the number of payloads without a device ID and
+	// sequence number.
+	Unrecognised int
+}
+
+// OK reports whether every device's messages arrived once each, in order
+// and without gaps.
+func (r SequenceReport) OK() bool {
+	return r.Missing == 0 && r.Duplicates == 0 && r.OutOfOrder == 0 && r.Unrecognised == 0
+}
+
+// SequenceChecker checks the stream of messages a consumer receives for
+// per-device gaps, duplicates and reordering, from the sequence numbers
+// the messages carry. Its Receive method is a MessageSource handler:
+//
+//	checker := loadgen.NewSequenceChecker(loadgen.JSONSequenceExtractor("device", "seq"))
+//	require.NoError(t, source.Start(ctx, checker.Receive))
+//	// ... run the load test, then wait for the stream to drain ...
+//	source.Stop()
+//	report := checker.Report()
+//
+// Gaps are only detected between the lowest and highest sequence numbers
+// received from a device, so losses at the end of its stream go unnoticed;
+// compare Last with the number of messages the device published. A
+// SequenceChecker is safe for concurrent use, but a source that delivers
+// concurrently may itself reorder messages.
+type SequenceChecker struct {
+	extract SequenceExtractor
+
+	mu           sync.Mutex
+	devices      map[string]*deviceSequence
+	unrecognised int
+}
+
+// deviceSequence is what a SequenceChecker has seen of a device.
+type deviceSequence struct {
+	seen       map[uint64]int
+	received   int
+	highest    uint64
+	outOfOrder int
+}
+
+// NewSequenceChecker creates a SequenceChecker that reads the device ID and
+// sequence number of each message with extract.
+func NewSequenceChecker(extract SequenceExtractor) *SequenceChecker {
+	return &SequenceChecker{extract: extract, devices: make(map[string]*deviceSequence)}
+}
+
+// Receive checks a received payload.
+func (c *SequenceChecker) Receive(payload []byte) {
+	id, seq, ok := c.extract(payload)
+	if !ok {
+		c.mu.Lock()
+		c.unrecognised++
+		c.mu.Unlock()
+		return
+	}
+	c.Observe(id, seq)
+}
+
+// Observe checks a message of a device that has already been decoded.
+func (c *SequenceChecker) Observe(deviceID string, seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.devices[deviceID]
+	if !ok {
+		d = &deviceSequence{seen: make(map[uint64]int)}
+		c.devices[deviceID] = d
+	}
+	d.seen[seq]++
+	if seq < d.highest && d.seen[seq] == 1 {
+		d.outOfOrder++
+	}
+	d.received++
+	d.highest = max(d.highest, seq)
+}
+
+// Report reports what has been received so far.
+func (c *SequenceChecker) Report() SequenceReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report := SequenceReport{Devices: make(map[string]DeviceSequenceReport, len(c.devices)), Unrecognised: c.unrecognised}
+	for id, d := range c.devices {
+		r := d.report()
+		report.Devices[id] = r
+		report.Received += r.Received
+		report.Missing += r.MissingCount()
+		report.Duplicates += len(r.Duplicates)
+		report.OutOfOrder += r.OutOfOrder
+	}
+	return report
+}
+
+// report summarises the sequence numbers seen of a device.
+func (d *deviceSequence) report() DeviceSequenceReport {
+	seqs := slices.Sorted(maps.Keys(d.seen))
+	r := DeviceSequenceReport{Received: d.received, First: seqs[0], Last: seqs[len(seqs)-1], OutOfOrder: d.outOfOrder}
+	for i, seq := range seqs {
+		for range d.seen[seq] - 1 {
+			r.Duplicates = append(r.Duplicates, seq)
+		}
+		if i > 0 && seq > seqs[i-1]+1 {
+			r.Missing = append(r.Missing, SequenceRange{From: seqs[i-1] + 1, To: seq - 1})
+		}
+	}
+	return r
+}
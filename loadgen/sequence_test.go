@@ -0,0 +1,90 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSequenceExtractor(t *testing.T) {
+	extract := loadgen.JSONSequenceExtractor("device", "seq")
+	for payload, want := range map[string]struct {
+		id  string
+		seq uint64
+		ok  bool
+	}{
+		`{"device":"d1","seq":7}`:   {"d1", 7, true},
+		`{"device":42,"seq":"8"}`:   {"42", 8, true},
+		`{"device":"d1"}`:           {},
+		`{"device":"d1","seq":-1}`:  {},
+		`{"device":"d1","seq":1.5}`: {},
+		`{"seq":1}`:                 {},
+		`not json`:                  {},
+	} {
+		id, seq, ok := extract([]byte(payload))
+		assert.Equal(t, want.ok, ok, payload)
+		assert.Equal(t, want.id, id, payload)
+		assert.Equal(t, want.seq, seq, payload)
+	}
+}
+
+func TestSequenceChecker(t *testing.T) {
+	checker := loadgen.NewSequenceChecker(loadgen.JSONSequenceExtractor("device", "seq"))
+	for _, seq := range []uint64{1, 2, 3, 4} {
+		checker.Observe("steady", seq)
+	}
+	for _, seq := range []uint64{1, 2, 5, 6, 9} {
+		checker.Observe("lossy", seq)
+	}
+	for _, seq := range []uint64{1, 3, 2, 3, 4, 4} {
+		checker.Observe("messy", seq)
+	}
+	checker.Receive([]byte(`{"device":"steady","seq":5}`))
+	checker.Receive([]byte(`{"temp":21}`))
+
+	report := checker.Report()
+	assert.False(t, report.OK())
+	assert.Equal(t, loadgen.DeviceSequenceReport{Received: 5, First: 1, Last: 5}, report.Devices["steady"])
+	assert.Equal(t, loadgen.DeviceSequenceReport{
+		Received: 5, First: 1, Last: 9,
+		Missing: []loadgen.SequenceRange{{From: 3, To: 4}, {From: 7, To: 8}},
+	}, report.Devices["lossy"])
+	assert.Equal(t, loadgen.DeviceSequenceReport{
+		Received: 6, First: 1, Last: 4, Duplicates: []uint64{3, 4}, OutOfOrder: 1,
+	}, report.Devices["messy"])
+	assert.Equal(t, 16, report.Received)
+	assert.Equal(t, 4, report.Missing)
+	assert.Equal(t, 2, report.Duplicates)
+	assert.Equal(t, 1, report.OutOfOrder)
+	assert.Equal(t, 1, report.Unrecognised)
+	assert.Equal(t, "3-4", report.Devices["lossy"].Missing[0].String())
+}
+
+func TestSequenceChecker_LoadTest(t *testing.T) {
+	// A ChaosClient that drops messages leaves gaps in the sequence.
+	checker := loadgen.NewSequenceChecker(loadgen.JSONSequenceExtractor("device", "seq"))
+	l := &loopback{sent: make(map[string]int), copies: func(*loadgen.Device, int) int { return 1 }}
+	require.NoError(t, l.Start(context.Background(), checker.Receive))
+	gen, err := loadgen.NewTemplatePayloadGenerator(`{"device":{{json .DeviceID}},"seq":{{.Seq}}}`)
+	require.NoError(t, err)
+	devices, err := loadgen.NewFleet().WithCount(3).WithRate(100).WithGenerator(func(string) loadgen.PayloadGenerator { return gen }).Build()
+	require.NoError(t, err)
+
+	t.Cleanup(loadgen.ClearSeed)
+	loadgen.SetSeed(1)
+	chaos := loadgen.NewChaosClient(l, loadgen.ChaosConfig{DropRate: 0.2})
+	_, err = loadgen.NewLoadGenerator(chaos, devices, zerolog.Nop()).Run(context.Background(), 200*time.Millisecond)
+	require.NoError(t, err)
+
+	report := checker.Report()
+	assert.Len(t, report.Devices, 3)
+	assert.Zero(t, report.Duplicates)
+	assert.Zero(t, report.OutOfOrder)
+	assert.Positive(t, report.Missing)
+	assert.LessOrEqual(t, report.Missing, chaos.Counts().Dropped, "drops after a device's last received message are not gaps")
+}
@@ -0,0 +1,125 @@
+// loadgen/stop.go
+
+package loadgen
+
+import (
+	"context"
+	"errors"
+)
+
+// StopReason says why a run stopped.
+type StopReason string
+
+const (
+	// StopDuration means the run's duration elapsed.
+	StopDuration StopReason = "duration"
+	// StopContext means the caller's context was cancelled.
+	StopContext StopReason = "context"
+	// StopMessageCount means StopAfterMessages was reached.
+	StopMessageCount StopReason = "message_count"
+	// StopErrorRate means the StopOnErrorRate budget was exceeded.
+	StopErrorRate StopReason = "error_rate"
+	// StopSignal means the StopOnSignal channel fired.
+	StopSignal StopReason = "signal"
+	// StopDevicesDone means every device stopped on its own, e.g. because
+	// none has a message rate.
+	StopDevicesDone StopReason = "devices_done"
+	// StopPayloadsExhausted means every device stopped on its own and at
+	// least one ran out of payloads, e.g. a ReplayPayloadGenerator reached
+	// the end of its messages.
+	StopPayloadsExhausted StopReason = "payloads_exhausted"
+)
+
+// RunResult is the outcome of RunUntil.
+type RunResult struct {
+	// Published is the number of successfully published messages.
+	Published int
+	// Failed is the number of publishes that failed or were not acknowledged.
+	Failed int
+	// Retries is the number of publishes retried under the RetryPolicy.
+	Retries int
+	// Shed is the number of messages dropped under BackpressureShed.
+	Shed int
+	// StoppedBy is the condition that ended the run.
+	StoppedBy StopReason
+	// WarmupPublished is the number of messages published during the
+	// warm-up, which the other counts leave out.
+	WarmupPublished int
+}
+
+// StopCondition ends a run early, before its duration elapses.
+type StopCondition func(*stopConditions)
+
+// stopConditions holds the settings collected from StopConditions.
+type stopConditions struct {
+	maxMessages  int64
+	maxErrorRate float64
+	minAttempts  int64
+	errorBudget  bool
+	signal       <-chan struct{}
+}
+
+// StopAfterMessages stops the run once n messages have been published.
+// Publishes already in flight still complete, so the total may exceed n by
+// up to one per device.
+func StopAfterMessages(n int) StopCondition {
+	return func(s *stopConditions) { s.maxMessages = int64(n) }
+}
+
+// StopOnErrorRate stops the run once more than maxRate (0 to 1) of the
+// publishes have failed, judged only after minAttempts publishes so a single
+// early failure does not stop the run.
+func StopOnErrorRate(maxRate float64, minAttempts int) StopCondition {
+	return func(s *stopConditions) {
+		s.errorBudget, s.maxErrorRate, s.minAttempts = true, maxRate, int64(minAttempts)
+	}
+}
+
+// StopOnSignal stops the run when signal is closed or receives a value,
+// e.g. when another component of the test has seen enough.
+func StopOnSignal(signal <-chan struct{}) StopCondition {
+	return func(s *stopConditions) { s.signal = signal }
+}
+
+// newStopConditions collects conditions.
+func newStopConditions(conditions []StopCondition) stopConditions {
+	var s stopConditions
+	for _, c := range conditions {
+		c(&s)
+	}
+	return s
+}
+
+// check returns the condition met after published successful and failed
+// publishes, or "" if none is.
+func (s stopConditions) check(published, failed int64) StopReason {
+	if s.maxMessages > 0 && published >= s.maxMessages {
+		return StopMessageCount
+	}
+	attempts := published + failed
+	if s.errorBudget && attempts > 0 && attempts >= s.minAttempts && float64(failed)/float64(attempts) > s.maxErrorRate {
+		return StopErrorRate
+	}
+	return ""
+}
+
+// stopError is the cancellation cause of a run that a condition stopped.
+type stopError struct {
+	reason StopReason
+}
+
+func (e stopError) Error() string {
+	return "load test stopped: " + string(e.reason)
+}
+
+// stopReason returns why runCtx, the context of a run, ended.
+func stopReason(runCtx context.Context) StopReason {
+	if runCtx.Err() == nil {
+		return StopDevicesDone
+	}
+	var se stopError
+	if errors.As(context.Cause(runCtx), &se) {
+		return se.reason
+	}
+	return StopContext
+}
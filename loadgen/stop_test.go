@@ -0,0 +1,96 @@
+package loadgen_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stopTestClient is a Client whose publishes succeed, or fail once fail
+// returns true.
+type stopTestClient struct {
+	fail func() bool
+}
+
+func newStopTestClient(fail func() bool) *stopTestClient {
+	return &stopTestClient{fail: fail}
+}
+
+func (c *stopTestClient) Connect() error { return nil }
+
+func (c *stopTestClient) Disconnect() {}
+
+func (c *stopTestClient) Publish(_ context.Context, _ *loadgen.Device) (bool, error) {
+	if c.fail != nil && c.fail() {
+		return false, errors.New("broker unavailable")
+	}
+	return true, nil
+}
+
+func TestLoadGenerator_RunUntil(t *testing.T) {
+	devices := func() []*loadgen.Device {
+		return []*loadgen.Device{{ID: "device-1", MessageRate: 100}, {ID: "device-2", MessageRate: 100}}
+	}
+
+	t.Run("duration", func(t *testing.T) {
+		lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices(), zerolog.Nop())
+		result, err := lg.RunUntil(context.Background(), 100*time.Millisecond, loadgen.StopAfterMessages(1000))
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+		assert.Positive(t, result.Published)
+		assert.Zero(t, result.Failed)
+	})
+
+	t.Run("message count", func(t *testing.T) {
+		lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices(), zerolog.Nop())
+		result, err := lg.RunUntil(context.Background(), 10*time.Second, loadgen.StopAfterMessages(20))
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopMessageCount, result.StoppedBy)
+		// At most one publish per device can be in flight when the count is reached.
+		assert.GreaterOrEqual(t, result.Published, 20)
+		assert.LessOrEqual(t, result.Published, 22)
+	})
+
+	t.Run("error rate", func(t *testing.T) {
+		var calls atomic.Int32
+		client := newStopTestClient(func() bool { return calls.Add(1) > 10 })
+		lg := loadgen.NewLoadGenerator(client, devices(), zerolog.Nop())
+		result, err := lg.RunUntil(context.Background(), 10*time.Second, loadgen.StopOnErrorRate(0.25, 10))
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopErrorRate, result.StoppedBy)
+		assert.Equal(t, 10, result.Published)
+		assert.Greater(t, float64(result.Failed)/float64(result.Published+result.Failed), 0.25)
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		signal := make(chan struct{})
+		time.AfterFunc(100*time.Millisecond, func() { close(signal) })
+		lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices(), zerolog.Nop())
+		result, err := lg.RunUntil(context.Background(), 0, loadgen.StopOnSignal(signal))
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopSignal, result.StoppedBy)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		t.Cleanup(cancel)
+		lg := loadgen.NewLoadGenerator(newStopTestClient(nil), devices(), zerolog.Nop())
+		result, err := lg.RunUntil(ctx, 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopContext, result.StoppedBy)
+	})
+
+	t.Run("devices done", func(t *testing.T) {
+		lg := loadgen.NewLoadGenerator(newStopTestClient(nil), []*loadgen.Device{{ID: "idle"}}, zerolog.Nop())
+		result, err := lg.RunUntil(context.Background(), 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopDevicesDone, result.StoppedBy)
+	})
+}
@@ -0,0 +1,128 @@
+// loadgen/summary.go
+
+package loadgen
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ungroupedName labels the devices without a group in Summary.
+const ungroupedName = "(ungrouped)"
+
+// summaryFailingDevices is the number of failing devices Summary lists.
+const summaryFailingDevices = 5
+
+// Summary renders r as human-readable text: a few lines on the run, then an
+// aligned table of the throughput, success rate and publish latency of each
+// group of devices and of the whole fleet, for tests to t.Log and CLIs to
+// print. The group rows are left out when the devices are not grouped. The
+// devices with the most failures follow, if any failed. Backpressure and
+// the load on the generator are summarised if SetMaxInFlight and
+// SetProfiling were used.
+func (r Results) Summary() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Started:\t%s\n", r.Start.Format(time.RFC3339))
+	elapsed := summaryDuration(r.Elapsed)
+	if r.Warmup > 0 {
+		elapsed += fmt.Sprintf(" (warm-up %s, %d published)", summaryDuration(r.Warmup), r.WarmupPublished)
+	}
+	fmt.Fprintf(tw, "Elapsed:\t%s\n", elapsed)
+	if r.StoppedBy != "" {
+		fmt.Fprintf(tw, "Stopped by:\t%s\n", r.StoppedBy)
+	}
+	fmt.Fprintf(tw, "Retries:\t%d\n", r.Retries)
+	if bp := r.Backpressure; bp != nil {
+		fmt.Fprintf(tw, "Backpressure:\t%s\n", summaryBackpressure(bp))
+	}
+	if p := r.Profile; p != nil {
+		fmt.Fprintf(tw, "Generator:\t%s\n", summaryProfile(p))
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Labels)) {
+		fmt.Fprintf(tw, "Label %s:\t%s\n", key, r.Labels[key])
+	}
+	_ = tw.Flush()
+	b.WriteString("\n")
+
+	fmt.Fprintln(tw, "GROUP\tDEVICES\tPUBLISHED\tFAILED\tSUCCESS\tRATE/S\tP50\tP95\tP99\tMAX")
+	if len(r.Groups) > 1 || len(r.Groups) == 1 && r.Groups[0].Name != "" {
+		for _, g := range r.Groups {
+			name := g.Name
+			if name == "" {
+				name = ungroupedName
+			}
+			summaryRow(tw, name, g.Devices, g.Published, g.Failed, g.Rate, g.Latency)
+		}
+	}
+	summaryRow(tw, "TOTAL", r.Devices, r.Published, r.Failed, r.Rate, r.Latency)
+	_ = tw.Flush()
+
+	if len(r.DeviceErrors) > 0 {
+		fmt.Fprintf(&b, "\nFailing devices: %d\n", len(r.DeviceErrors))
+		fmt.Fprintln(tw, "DEVICE\tFAILURES\tIN A ROW\tLAST ERROR")
+		for _, e := range r.DeviceErrors[:min(len(r.DeviceErrors), summaryFailingDevices)] {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", e.DeviceID, e.Failures, e.ConsecutiveFailures, errorMessage(e.LastError))
+		}
+		_ = tw.Flush()
+	}
+	return b.String()
+}
+
+// summaryRow writes a row of the Summary table.
+func summaryRow(tw *tabwriter.Writer, name string, devices, published, failed int, rate float64, latency LatencyStats) {
+	success := "-"
+	if total := published + failed; total > 0 {
+		success = fmt.Sprintf("%.1f%%", float64(published)*100/float64(total))
+	}
+	fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%.1f\t%s\t%s\t%s\t%s\n",
+		name, devices, published, failed, success, rate,
+		summaryDuration(latency.P50), summaryDuration(latency.P95), summaryDuration(latency.P99), summaryDuration(latency.Max))
+}
+
+// summaryBackpressure describes how often the in-flight limit was reached.
+func summaryBackpressure(bp *Backpressure) string {
+	s := fmt.Sprintf("%d incidents at %d in flight", bp.Incidents, bp.MaxInFlight)
+	if bp.Policy == BackpressureShed {
+		return fmt.Sprintf("%s; %d shed", s, bp.Shed)
+	}
+	return fmt.Sprintf("%s; %d blocked, %s in all", s, bp.Blocked, summaryDuration(bp.BlockedTime))
+}
+
+// summaryProfile describes the load the generator process was under.
+func summaryProfile(p *RunProfile) string {
+	cpu := "CPU unknown"
+	if p.MeanCPU >= 0 {
+		cpu = fmt.Sprintf("CPU %.0f%% mean, %.0f%% peak", p.MeanCPU*100, p.PeakCPU*100)
+	}
+	pause := summaryDuration(p.GCPause)
+	if p.GCPause == 0 {
+		pause = "0s"
+	}
+	return fmt.Sprintf("%s; %d goroutines peak; heap %.1fMiB peak; %d GCs, %s paused",
+		cpu, p.PeakGoroutines, float64(p.PeakHeapAlloc)/(1<<20), p.GCCycles, pause)
+}
+
+// summaryDuration formats d to about three significant figures, or "-" if
+// it is zero, e.g. when nothing was published.
+func summaryDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	case d >= 10*time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	case d >= time.Microsecond:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
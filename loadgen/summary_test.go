@@ -0,0 +1,126 @@
+package loadgen_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// groupTestClient is a Client whose gateway publishes take 5ms and whose
+// publishes of sensor-1 fail.
+type groupTestClient struct{}
+
+func (groupTestClient) Connect() error { return nil }
+
+func (groupTestClient) Disconnect() {}
+
+func (groupTestClient) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	if device.Metadata[loadgen.GroupMetadataKey] == "gateway" {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if device.ID == "sensor-1" {
+		return false, errors.New("rejected")
+	}
+	return true, nil
+}
+
+func TestResults_Groups(t *testing.T) {
+	devices, err := loadgen.NewFleet().
+		WithCount(2).WithIDPattern("sensor-%d").WithRate(50).WithMetadata(map[string]string{loadgen.GroupMetadataKey: "sensor"}).
+		AddGroup().WithCount(1).WithIDPattern("gateway-%d").WithMetadata(map[string]string{loadgen.GroupMetadataKey: "gateway"}).
+		AddGroup().WithCount(1).WithIDPattern("other-%d").WithMetadata(nil).
+		Build()
+	require.NoError(t, err)
+	lg := loadgen.NewLoadGenerator(groupTestClient{}, devices, zerolog.Nop())
+	result, err := lg.RunUntil(context.Background(), 200*time.Millisecond)
+	require.NoError(t, err)
+
+	results := lg.Results()
+	require.Len(t, results.Groups, 3)
+	sensors, gateways, others := results.Groups[0], results.Groups[1], results.Groups[2]
+	assert.Equal(t, "sensor", sensors.Name)
+	assert.Equal(t, 2, sensors.Devices)
+	assert.Positive(t, sensors.Published)
+	assert.Positive(t, sensors.Failed)
+	assert.Less(t, sensors.Latency.P99, 5*time.Millisecond)
+
+	assert.Equal(t, "gateway", gateways.Name)
+	assert.Zero(t, gateways.Failed)
+	assert.GreaterOrEqual(t, gateways.Latency.P50, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, gateways.Latency.Min, 5*time.Millisecond)
+	assert.LessOrEqual(t, gateways.Latency.P99, gateways.Latency.Max)
+
+	assert.Empty(t, others.Name)
+	assert.Equal(t, result.Published, sensors.Published+gateways.Published+others.Published)
+	assert.Equal(t, result.Failed, sensors.Failed)
+	assert.Equal(t, gateways.Latency.Max, results.Latency.Max)
+
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteJSON(&buf))
+	var decoded struct {
+		Groups []struct {
+			Name    string `json:"name"`
+			Latency struct {
+				P50Ms float64 `json:"p50_ms"`
+			} `json:"latency"`
+		} `json:"groups"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded.Groups, 3)
+	assert.Equal(t, "gateway", decoded.Groups[1].Name)
+	assert.GreaterOrEqual(t, decoded.Groups[1].Latency.P50Ms, 5.0)
+
+	summary := results.Summary()
+	t.Log("\n" + summary)
+	assert.Contains(t, summary, "(ungrouped)")
+}
+
+func TestResults_Summary(t *testing.T) {
+	results := loadgen.Results{
+		Start:           time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Elapsed:         12 * time.Second,
+		Warmup:          2 * time.Second,
+		Devices:         3,
+		Published:       950,
+		Failed:          50,
+		Retries:         7,
+		WarmupPublished: 100,
+		StoppedBy:       loadgen.StopDuration,
+		Rate:            95,
+		Labels:          map[string]string{"commit": "abc123"},
+		Latency:         loadgen.LatencyStats{P50: 1234 * time.Microsecond, P95: 12345 * time.Microsecond, P99: 123456 * time.Microsecond, Max: 2 * time.Second},
+		Groups: []loadgen.GroupResults{
+			{Name: "sensor", Devices: 2, Published: 900, Failed: 50, Rate: 90, Latency: loadgen.LatencyStats{P50: 456 * time.Microsecond, P95: time.Millisecond, P99: 2 * time.Millisecond, Max: 3 * time.Millisecond}},
+			{Devices: 1, Published: 50, Rate: 5, Latency: loadgen.LatencyStats{P50: time.Second, P95: time.Second, P99: 2 * time.Second, Max: 2 * time.Second}},
+		},
+	}
+
+	want := `Started:       2026-01-02T03:04:05Z
+Elapsed:       12s (warm-up 2s, 100 published)
+Stopped by:    duration
+Retries:       7
+Label commit:  abc123
+
+GROUP        DEVICES  PUBLISHED  FAILED  SUCCESS  RATE/S  P50     P95     P99    MAX
+sensor       2        900        50      94.7%    90.0    456µs   1ms     2ms    3ms
+(ungrouped)  1        50         0       100.0%   5.0     1s      1s      2s     2s
+TOTAL        3        950        50      95.0%    95.0    1.23ms  12.3ms  123ms  2s
+`
+	assert.Equal(t, want, results.Summary())
+
+	t.Run("ungrouped devices have no group rows", func(t *testing.T) {
+		results := loadgen.Results{Devices: 1, Groups: []loadgen.GroupResults{{Devices: 1}}}
+		lines := strings.Split(strings.TrimSpace(results.Summary()), "\n")
+		assert.True(t, strings.HasPrefix(lines[len(lines)-2], "GROUP"))
+		assert.Equal(t, "TOTAL  1        0          0       -        0.0     -    -    -    -", lines[len(lines)-1])
+	})
+}
@@ -0,0 +1,137 @@
+// loadgen/templategenerator.go
+
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TemplateData is the data a TemplatePayloadGenerator executes its template
+// with.
+type TemplateData struct {
+	// Device is the device publishing, e.g. {{.Device.Metadata.site}}.
+	Device *Device
+	// DeviceID is the ID of the device.
+	DeviceID string
+	// Seq is the device's message sequence number, starting at 1.
+	Seq uint64
+	// Now is the time the payload is generated.
+	Now time.Time
+	// Row is the dataset row of a DatasetPayloadGenerator, by column name.
+	Row map[string]any
+}
+
+// templateFuncs returns the functions available to payload templates, with
+// the random ones drawing from r.
+func templateFuncs(r func() *rand.Rand) template.FuncMap {
+	return template.FuncMap{
+		// randInt returns a random int in [min, max].
+		"randInt": func(min, max int) int { return min + r().IntN(max-min+1) },
+		// randFloat returns a random float64 in [min, max).
+		"randFloat": func(min, max float64) float64 { return min + r().Float64()*(max-min) },
+		// randBool returns true or false with equal probability.
+		"randBool": func() bool { return r().IntN(2) == 0 },
+		// randChoice returns one of its arguments at random.
+		"randChoice": func(choices ...any) any { return choices[r().IntN(len(choices))] },
+		// uuid returns a random UUID.
+		"uuid": func() (string, error) {
+			id, err := uuid.NewRandomFromReader(randReader{r()})
+			return id.String(), err
+		},
+		// rfc3339 formats a time as RFC 3339 with milliseconds.
+		"rfc3339": func(t time.Time) string { return t.UTC().Format("2006-01-02T15:04:05.000Z07:00") },
+		// json encodes a value as JSON, e.g. to quote a string.
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+}
+
+// TemplatePayloadGenerator renders payloads from a Go text/template, so a
+// device model's payload can be declared rather than coded. The template is
+// executed with a TemplateData and can use these functions:
+//
+//   - randInt min max, randFloat min max, randBool and randChoice a b c...
+//     for random values.
+//   - uuid for a random UUID.
+//   - rfc3339 .Now for a timestamp.
+//   - json v to encode a value as JSON.
+//
+// For example:
+//
+//	{"id":{{json .DeviceID}},"seq":{{.Seq}},"ts":"{{rfc3339 .Now}}","temp":{{printf "%.1f" (randFloat 18 24)}}}
+type TemplatePayloadGenerator struct {
+	tmpl *payloadTemplate
+
+	mu  sync.Mutex
+	seq map[string]uint64
+}
+
+// NewTemplatePayloadGenerator parses text as a payload template.
+func NewTemplatePayloadGenerator(text string) (*TemplatePayloadGenerator, error) {
+	tmpl, err := parsePayloadTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePayloadGenerator{tmpl: tmpl, seq: make(map[string]uint64)}, nil
+}
+
+// payloadTemplate is a parsed payload template. Each device renders its own
+// copy, whose random functions draw from the device's random source.
+type payloadTemplate struct {
+	tmpl *template.Template
+
+	mu      sync.Mutex
+	devices map[string]*template.Template
+}
+
+// parsePayloadTemplate parses text as a payload template with templateFuncs.
+func parsePayloadTemplate(text string) (*payloadTemplate, error) {
+	funcs := templateFuncs(func() *rand.Rand { return unseeded })
+	tmpl, err := template.New("payload").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %w", err)
+	}
+	return &payloadTemplate{tmpl: tmpl, devices: make(map[string]*template.Template)}, nil
+}
+
+// render executes the template of the device of data.
+func (p *payloadTemplate) render(data TemplateData) ([]byte, error) {
+	p.mu.Lock()
+	tmpl, ok := p.devices[data.DeviceID]
+	if !ok {
+		var err error
+		if tmpl, err = p.tmpl.Clone(); err != nil {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("failed to render payload template: %w", err)
+		}
+		tmpl.Funcs(templateFuncs(func() *rand.Rand { return DeviceRand(data.DeviceID) }))
+		p.devices[data.DeviceID] = tmpl
+	}
+	p.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render payload template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GeneratePayload renders the template for the next message of device.
+func (g *TemplatePayloadGenerator) GeneratePayload(device *Device) ([]byte, error) {
+	g.mu.Lock()
+	g.seq[device.ID]++
+	seq := g.seq[device.ID]
+	g.mu.Unlock()
+
+	return g.tmpl.render(TemplateData{Device: device, DeviceID: device.ID, Seq: seq, Now: time.Now()})
+}
@@ -0,0 +1,63 @@
+package loadgen_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatePayloadGenerator(t *testing.T) {
+	gen, err := loadgen.NewTemplatePayloadGenerator(`{"id":{{json .DeviceID}},"site":{{json .Device.Metadata.site}},"seq":{{.Seq}},` +
+		`"ts":"{{rfc3339 .Now}}","msg":"{{uuid}}","temp":{{randFloat 18 24}},"level":{{randInt 1 3}},` +
+		`"mode":{{json (randChoice "eco" "boost")}},"on":{{randBool}}}`)
+	require.NoError(t, err)
+
+	type payload struct {
+		ID    string
+		Site  string
+		Seq   uint64
+		TS    time.Time
+		Msg   string
+		Temp  float64
+		Level int
+		Mode  string
+		On    bool
+	}
+	devices := []*loadgen.Device{
+		{ID: `sensor "a"`, Metadata: map[string]string{"site": "north"}},
+		{ID: "sensor-b", Metadata: map[string]string{"site": "south"}},
+	}
+	for i := range 3 {
+		for _, device := range devices {
+			raw, err := gen.GeneratePayload(device)
+			require.NoError(t, err)
+			var p payload
+			require.NoError(t, json.Unmarshal(raw, &p), string(raw))
+
+			assert.Equal(t, device.ID, p.ID)
+			assert.Equal(t, device.Metadata["site"], p.Site)
+			assert.Equal(t, uint64(i+1), p.Seq)
+			assert.WithinDuration(t, time.Now(), p.TS, time.Minute)
+			assert.NoError(t, uuid.Validate(p.Msg))
+			assert.GreaterOrEqual(t, p.Temp, 18.0)
+			assert.Less(t, p.Temp, 24.0)
+			assert.Contains(t, []int{1, 2, 3}, p.Level)
+			assert.Contains(t, []string{"eco", "boost"}, p.Mode)
+		}
+	}
+}
+
+func TestTemplatePayloadGenerator_Errors(t *testing.T) {
+	_, err := loadgen.NewTemplatePayloadGenerator(`{"id":{{.DeviceID}`)
+	assert.ErrorContains(t, err, "invalid payload template")
+
+	gen, err := loadgen.NewTemplatePayloadGenerator(`{"site":{{json .Device.Metadata.site}}}`)
+	require.NoError(t, err)
+	_, err = gen.GeneratePayload(&loadgen.Device{ID: "d1"})
+	assert.ErrorContains(t, err, "failed to render payload template")
+}
@@ -0,0 +1,85 @@
+// loadgen/timing.go
+
+package loadgen
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Timing decides how long a device waits between messages. Real devices do
+// not publish on a metronome, and brokers behave differently under bursty
+// arrivals, so a Device can use a randomised Timing instead of a fixed one.
+type Timing interface {
+	// Next returns the wait before the next message of a device whose mean
+	// interval between messages is mean.
+	Next(mean time.Duration) time.Duration
+}
+
+// randTiming is implemented by the Timings of this package, which draw from
+// the random source of the device they time.
+type randTiming interface {
+	nextRand(r *rand.Rand, mean time.Duration) time.Duration
+}
+
+// nextWait returns the next wait of device under timing.
+func nextWait(timing Timing, device *Device, mean time.Duration) time.Duration {
+	if t, ok := timing.(randTiming); ok {
+		return t.nextRand(deviceRand(device), mean)
+	}
+	return timing.Next(mean)
+}
+
+// Burst makes a device send Size messages back-to-back every Interval,
+// like firmware that batches its readings.
+type Burst struct {
+	Size     int
+	Interval time.Duration
+}
+
+// FixedTiming waits exactly the mean interval. It is the default.
+func FixedTiming() Timing {
+	return fixedTiming{}
+}
+
+type fixedTiming struct{}
+
+func (fixedTiming) Next(mean time.Duration) time.Duration {
+	return mean
+}
+
+// JitteredTiming waits a uniformly random interval within ±jitter of the
+// mean, e.g. 0.2 for 800ms to 1.2s at a rate of 1Hz. jitter is clamped to
+// [0, 1].
+func JitteredTiming(jitter float64) Timing {
+	return jitteredTiming{jitter: min(max(jitter, 0), 1)}
+}
+
+type jitteredTiming struct {
+	jitter float64
+}
+
+func (j jitteredTiming) Next(mean time.Duration) time.Duration {
+	return j.nextRand(unseeded, mean)
+}
+
+func (j jitteredTiming) nextRand(r *rand.Rand, mean time.Duration) time.Duration {
+	return time.Duration(float64(mean) * (1 + j.jitter*(2*r.Float64()-1)))
+}
+
+// PoissonTiming waits exponentially distributed intervals, so messages
+// arrive as a Poisson process with the device's rate: mostly spread out,
+// sometimes in quick succession.
+func PoissonTiming() Timing {
+	return poissonTiming{}
+}
+
+type poissonTiming struct{}
+
+func (p poissonTiming) Next(mean time.Duration) time.Duration {
+	return p.nextRand(unseeded, mean)
+}
+
+func (poissonTiming) nextRand(r *rand.Rand, mean time.Duration) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(mean))
+}
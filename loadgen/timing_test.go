@@ -0,0 +1,88 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiming_Next(t *testing.T) {
+	const mean = 100 * time.Millisecond
+	const samples = 20000
+
+	testCases := []struct {
+		name     string
+		timing   loadgen.Timing
+		min, max time.Duration
+	}{
+		{"fixed", loadgen.FixedTiming(), mean, mean},
+		{"jittered", loadgen.JitteredTiming(0.2), 80 * time.Millisecond, 120 * time.Millisecond},
+		{"jitter clamped", loadgen.JitteredTiming(5), 0, 2 * mean},
+		{"poisson", loadgen.PoissonTiming(), 0, time.Hour},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var total time.Duration
+			for range samples {
+				d := tc.timing.Next(mean)
+				require.GreaterOrEqual(t, d, tc.min)
+				require.LessOrEqual(t, d, tc.max)
+				total += d
+			}
+			assert.InDelta(t, float64(mean), float64(total/samples), float64(mean)/20, "the mean interval should be kept")
+		})
+	}
+}
+
+func TestLoadGenerator_RunWithTiming(t *testing.T) {
+	mockClient := new(MockClient)
+	devices := []*loadgen.Device{
+		{ID: "jittered", MessageRate: 50, Timing: loadgen.JitteredTiming(0.5)},
+		{ID: "poisson", MessageRate: 50, Timing: loadgen.PoissonTiming()},
+	}
+	mockClient.On("Connect").Return(nil).Once()
+	mockClient.On("Disconnect").Return().Once()
+	mockClient.On("Publish", mock.Anything, mock.Anything).Return(true, nil)
+
+	lg := loadgen.NewLoadGenerator(mockClient, devices, zerolog.Nop())
+	count, err := lg.Run(context.Background(), time.Second)
+	require.NoError(t, err)
+
+	// Expected are 2 * (1 + 50) messages; allow for the randomness.
+	assert.InDelta(t, lg.ExpectedMessagesForDuration(time.Second), count, 40)
+	mockClient.AssertExpectations(t)
+}
+
+func TestLoadGenerator_RunWithBursts(t *testing.T) {
+	mockClient := new(MockClient)
+	devices := []*loadgen.Device{
+		// MessageRate is ignored for bursting devices.
+		{ID: "batching", MessageRate: 1000, Burst: &loadgen.Burst{Size: 5, Interval: 200 * time.Millisecond}},
+		{ID: "steady", MessageRate: 10},
+		{ID: "empty-burst", Burst: &loadgen.Burst{Size: 0, Interval: time.Millisecond}},
+	}
+	// Only the batching device's goroutine writes batched.
+	var batched int
+	mockClient.On("Connect").Return(nil).Once()
+	mockClient.On("Disconnect").Return().Once()
+	mockClient.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if d := args.Get(1).(*loadgen.Device); d.ID == "batching" {
+			batched++
+		}
+	}).Return(true, nil)
+
+	lg := loadgen.NewLoadGenerator(mockClient, devices, zerolog.Nop())
+	// Bursts at T=0, 200ms and 400ms; steady messages at T=0, 100ms, ..., 500ms.
+	assert.Equal(t, 3*5+6, lg.ExpectedMessagesForDuration(550*time.Millisecond))
+
+	count, err := lg.Run(context.Background(), 550*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 3*5+6, count)
+	assert.Equal(t, 15, batched)
+}
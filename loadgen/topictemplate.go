@@ -0,0 +1,173 @@
+// loadgen/topictemplate.go
+
+package loadgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TopicTemplate expands a topic pattern for each message of a device, so
+// multi-level production topic structures can be reproduced. A pattern is
+// literal text with placeholders in braces:
+//
+//   - {deviceID} is the device ID.
+//   - {seq} is the device's message sequence number, starting at 1.
+//   - {seq%N} is the sequence number modulo N, e.g. to rotate over N shards.
+//   - Any other {key} is the device's Metadata[key], e.g. {tenant} or {type}.
+//
+// For example "tenants/{tenant}/{type}/{deviceID}/shard-{seq%4}". For
+// compatibility with older MQTT topic patterns, a pattern without
+// placeholders has its first "+" replaced by the device ID.
+//
+// A TopicTemplate is safe for concurrent use.
+type TopicTemplate struct {
+	pattern string
+	parts   []templatePart
+	hasSeq  bool
+
+	mu  sync.Mutex
+	seq map[string]uint64
+}
+
+// templatePart is literal text or, if key is set, a placeholder.
+type templatePart struct {
+	literal string
+	key     string
+	// modulo is N of a {seq%N} placeholder.
+	modulo uint64
+}
+
+// ParseTopicTemplate parses pattern.
+func ParseTopicTemplate(pattern string) (*TopicTemplate, error) {
+	t := &TopicTemplate{pattern: pattern, seq: make(map[string]uint64)}
+	if !strings.ContainsAny(pattern, "{}") {
+		if before, after, ok := strings.Cut(pattern, "+"); ok {
+			t.parts = []templatePart{{literal: before}, {key: "deviceID"}, {literal: after}}
+		} else {
+			t.parts = []templatePart{{literal: pattern}}
+		}
+		return t, nil
+	}
+
+	rest := pattern
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("invalid topic pattern %q: unexpected '}'", pattern)
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] != '}' {
+			return nil, fmt.Errorf("invalid topic pattern %q: unclosed '{'", pattern)
+		}
+		part, err := parsePlaceholder(rest[open+1 : open+1+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic pattern %q: %w", pattern, err)
+		}
+		t.hasSeq = t.hasSeq || part.key == "seq"
+		t.parts = append(t.parts, part)
+		rest = rest[open+2+end:]
+	}
+	return t, nil
+}
+
+// parsePlaceholder parses the text between the braces of a placeholder.
+func parsePlaceholder(text string) (templatePart, error) {
+	if text == "" {
+		return templatePart{}, fmt.Errorf("empty placeholder")
+	}
+	if n, ok := strings.CutPrefix(text, "seq%"); ok {
+		modulo, err := strconv.ParseUint(n, 10, 64)
+		if err != nil || modulo == 0 {
+			return templatePart{}, fmt.Errorf("invalid sequence modulo in {%s}", text)
+		}
+		return templatePart{key: "seq", modulo: modulo}, nil
+	}
+	return templatePart{key: text}, nil
+}
+
+// String returns the pattern.
+func (t *TopicTemplate) String() string {
+	return t.pattern
+}
+
+// Expand returns the topic of device's next message. Each call advances the
+// device's sequence number if the pattern uses it. It fails if the pattern
+// refers to metadata the device does not have.
+func (t *TopicTemplate) Expand(device *Device) (string, error) {
+	var seq uint64
+	if t.hasSeq {
+		t.mu.Lock()
+		t.seq[device.ID]++
+		seq = t.seq[device.ID]
+		t.mu.Unlock()
+	}
+
+	var b strings.Builder
+	for _, part := range t.parts {
+		switch part.key {
+		case "":
+			b.WriteString(part.literal)
+		case "deviceID":
+			b.WriteString(device.ID)
+		case "seq":
+			if part.modulo > 0 {
+				b.WriteString(strconv.FormatUint(seq%part.modulo, 10))
+			} else {
+				b.WriteString(strconv.FormatUint(seq, 10))
+			}
+		default:
+			value, ok := device.Metadata[part.key]
+			if !ok {
+				return "", fmt.Errorf("topic pattern %q needs metadata %q, which device %s does not have", t.pattern, part.key, device.ID)
+			}
+			b.WriteString(value)
+		}
+	}
+	return b.String(), nil
+}
+
+// deviceTopics expands a client's topic template, or a device's own Topic.
+type deviceTopics struct {
+	template *TopicTemplate
+
+	mu        sync.Mutex
+	overrides map[string]*TopicTemplate
+}
+
+// newDeviceTopics parses a client's topic pattern.
+func newDeviceTopics(pattern string) (*deviceTopics, error) {
+	t, err := ParseTopicTemplate(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &deviceTopics{template: t, overrides: make(map[string]*TopicTemplate)}, nil
+}
+
+// expand returns the topic of device's next message.
+func (d *deviceTopics) expand(device *Device) (string, error) {
+	if device.Topic == "" {
+		return d.template.Expand(device)
+	}
+	d.mu.Lock()
+	t, ok := d.overrides[device.Topic]
+	if !ok {
+		var err error
+		if t, err = ParseTopicTemplate(device.Topic); err != nil {
+			d.mu.Unlock()
+			return "", err
+		}
+		d.overrides[device.Topic] = t
+	}
+	d.mu.Unlock()
+	return t.Expand(device)
+}
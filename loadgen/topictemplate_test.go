@@ -0,0 +1,74 @@
+package loadgen_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicTemplate_Expand(t *testing.T) {
+	device := &loadgen.Device{ID: "dev-1", Metadata: map[string]string{"tenant": "acme", "type": "thermostat"}}
+
+	testCases := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"legacy plus", "devices/+/data", []string{"devices/dev-1/data", "devices/dev-1/data"}},
+		{"literal", "devices/all", []string{"devices/all"}},
+		{"metadata", "tenants/{tenant}/{type}/{deviceID}", []string{"tenants/acme/thermostat/dev-1"}},
+		{"sequence", "devices/{deviceID}/msg/{seq}", []string{"devices/dev-1/msg/1", "devices/dev-1/msg/2"}},
+		{"sequence modulo", "shard-{seq%2}/{seq}", []string{"shard-1/1", "shard-0/2", "shard-1/3"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := loadgen.ParseTopicTemplate(tc.pattern)
+			require.NoError(t, err)
+			for _, want := range tc.want {
+				got, err := tmpl.Expand(device)
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestTopicTemplate_SequencePerDevice(t *testing.T) {
+	tmpl, err := loadgen.ParseTopicTemplate("{deviceID}/{seq}")
+	require.NoError(t, err)
+	devices := []*loadgen.Device{{ID: "a"}, {ID: "b"}}
+
+	var wg sync.WaitGroup
+	for _, d := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_, err := tmpl.Expand(d)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, d := range devices {
+		got, err := tmpl.Expand(d)
+		require.NoError(t, err)
+		assert.Equal(t, d.ID+"/101", got)
+	}
+}
+
+func TestTopicTemplate_Errors(t *testing.T) {
+	for _, pattern := range []string{"devices/{deviceID", "devices/}", "devices/{}", "shard-{seq%0}", "shard-{seq%x}", "a/{b{c}}"} {
+		_, err := loadgen.ParseTopicTemplate(pattern)
+		assert.Error(t, err, pattern)
+	}
+
+	tmpl, err := loadgen.ParseTopicTemplate("tenants/{tenant}/{deviceID}")
+	require.NoError(t, err)
+	_, err = tmpl.Expand(&loadgen.Device{ID: "dev-1"})
+	assert.ErrorContains(t, err, `needs metadata "tenant"`)
+}
@@ -0,0 +1,96 @@
+// loadgen/tracing.go
+
+package loadgen
+
+import (
+	"context"
+	"maps"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of a TracingClient.
+const tracerName = "github.com/illmade-knight/go-test/loadgen"
+
+// TracingOption configures a TracingClient.
+type TracingOption func(*tracingClient)
+
+// WithTracerProvider creates the spans with provider instead of the global
+// provider.
+func WithTracerProvider(provider trace.TracerProvider) TracingOption {
+	return func(c *tracingClient) { c.tracer = provider.Tracer(tracerName) }
+}
+
+// WithPropagator injects the trace context with propagator instead of the
+// global propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) TracingOption {
+	return func(c *tracingClient) { c.propagator = propagator }
+}
+
+// NewTracingClient returns a Client that publishes through client in an
+// OpenTelemetry producer span per message, so the pipeline under test can be
+// traced end to end. Each span has the device ID and, where the client sets
+// it, the destination (topic, routing key or URL) as attributes.
+//
+// The trace context is injected into the message's Device.Headers, which the
+// clients send as HTTP headers, gRPC metadata, AMQP headers, Pub/Sub
+// attributes or MQTT v5 user properties. MQTT 3.1.1 has no headers, so the
+// context is not propagated through an MqttClient or MqttDeviceClient.
+func NewTracingClient(client Client, opts ...TracingOption) Client {
+	c := &tracingClient{
+		Client:     client,
+		tracer:     otel.GetTracerProvider().Tracer(tracerName),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// tracingClient is the Client returned by NewTracingClient.
+type tracingClient struct {
+	Client
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// Publish publishes a copy of device whose Headers carry the trace context
+// of a new span.
+func (c *tracingClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	ctx, span := c.tracer.Start(ctx, "publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("loadgen.device.id", device.ID)),
+	)
+	defer span.End()
+
+	carrier := propagation.MapCarrier{}
+	c.propagator.Inject(ctx, carrier)
+	traced := *device
+	traced.Headers = make(map[string]string, len(device.Headers)+len(carrier))
+	maps.Copy(traced.Headers, device.Headers)
+	maps.Copy(traced.Headers, carrier)
+
+	ok, err := c.Client.Publish(ctx, &traced)
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case !ok:
+		span.SetStatus(codes.Error, "publish not acknowledged")
+	}
+	return ok, err
+}
+
+// setSpanDestination records the destination of a message on the span of
+// ctx, if a TracingClient started one.
+func setSpanDestination(ctx context.Context, destination string) {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(attribute.String("messaging.destination.name", destination))
+	}
+}
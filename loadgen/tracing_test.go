@@ -0,0 +1,124 @@
+package loadgen_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracing returns a TracingOption set that records spans and
+// propagates W3C trace context.
+func newTestTracing() (*tracetest.SpanRecorder, []loadgen.TracingOption) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder, []loadgen.TracingOption{
+		loadgen.WithTracerProvider(provider),
+		loadgen.WithPropagator(propagation.TraceContext{}),
+	}
+}
+
+// spanAttribute returns the value of the attribute key of span.
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
+
+func TestTracingClient_HTTPHeaders(t *testing.T) {
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	recorder, opts := newTestTracing()
+	client := loadgen.NewTracingClient(loadgen.NewHTTPClient(server.URL+"/devices/{deviceID}", nil, time.Second, zerolog.Nop()), opts...)
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	device := &loadgen.Device{ID: "d1", PayloadGenerator: staticPayloadGenerator(`{}`), Headers: map[string]string{"X-Tenant": "acme"}}
+	ok, err := client.Publish(context.Background(), device)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme"}, device.Headers, "the caller's device must not be modified")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, trace.SpanKindProducer, span.SpanKind())
+	assert.Equal(t, "d1", spanAttribute(span, "loadgen.device.id"))
+	assert.Equal(t, server.URL+"/devices/d1", spanAttribute(span, "messaging.destination.name"))
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	traceparent := <-traceparents
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
+}
+
+func TestTracingClient_MqttV5UserProperties(t *testing.T) {
+	brokerURL, published := startFakeMqttV5Broker(t, 0)
+	recorder, opts := newTestTracing()
+	client := loadgen.NewTracingClient(loadgen.NewMqttV5Client(loadgen.MqttV5ClientConfig{
+		BrokerURL:    brokerURL,
+		TopicPattern: "devices/{deviceID}/data",
+		QoS:          1,
+	}, zerolog.Nop()), opts...)
+	require.NoError(t, client.Connect())
+	t.Cleanup(client.Disconnect)
+
+	ok, err := client.Publish(context.Background(), &loadgen.Device{ID: "gw-1", PayloadGenerator: staticPayloadGenerator("hello")})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "devices/gw-1/data", spanAttribute(spans[0], "messaging.destination.name"))
+	select {
+	case p := <-published:
+		require.Len(t, p.Properties.User, 1)
+		assert.Equal(t, "traceparent", p.Properties.User[0].Key)
+		assert.Contains(t, p.Properties.User[0].Value, spans[0].SpanContext().TraceID().String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestTracingClient_RecordsErrors(t *testing.T) {
+	recorder, opts := newTestTracing()
+	failing := new(MockClient)
+	failing.On("Publish", mock.Anything, mock.Anything).Return(false, errors.New("broker down"))
+	unacked := new(MockClient)
+	unacked.On("Publish", mock.Anything, mock.Anything).Return(false, nil)
+	device := &loadgen.Device{ID: "d1", PayloadGenerator: staticPayloadGenerator(`{}`)}
+
+	_, err := loadgen.NewTracingClient(failing, opts...).Publish(context.Background(), device)
+	require.Error(t, err)
+	_, err = loadgen.NewTracingClient(unacked, opts...).Publish(context.Background(), device)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "broker down"}, spans[0].Status())
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "exception", spans[0].Events()[0].Name)
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "publish not acknowledged"}, spans[1].Status())
+}
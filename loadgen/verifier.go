@@ -0,0 +1,304 @@
+// loadgen/verifier.go
+
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// MessageSource receives the messages of a load test on the receiving side,
+// e.g. an MQTT topic or a Pub/Sub subscription.
+type MessageSource interface {
+	// Start subscribes and calls handle with the payload of every message
+	// received until Stop. It returns once the subscription is active, so
+	// no message published afterwards is missed. handle may be called
+	// concurrently.
+	Start(ctx context.Context, handle func(payload []byte)) error
+	// Stop ends the subscription.
+	Stop()
+}
+
+// Correlator injects a correlation ID into a payload and extracts it again,
+// so a Verifier can match received messages to published ones.
+type Correlator interface {
+	Inject(payload []byte, id string) ([]byte, error)
+	// Extract returns the ID of a payload, or false if it has none.
+	Extract(payload []byte) (string, bool)
+}
+
+// JSONFieldCorrelator carries the correlation ID in a top-level string field
+// of JSON object payloads. It is the Verifier's default, with field "loadgen_id".
+func JSONFieldCorrelator(field string) Correlator {
+	return jsonFieldCorrelator{field: field}
+}
+
+type jsonFieldCorrelator struct {
+	field string
+}
+
+func (c jsonFieldCorrelator) Inject(payload []byte, id string) ([]byte, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, fmt.Errorf("payload is not a JSON object: %w", err)
+	}
+	object[c.field], _ = json.Marshal(id)
+	return json.Marshal(object)
+}
+
+func (c jsonFieldCorrelator) Extract(payload []byte) (string, bool) {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(payload, &object) != nil {
+		return "", false
+	}
+	var id string
+	if json.Unmarshal(object[c.field], &id) != nil || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// VerifierConfig holds configuration for a Verifier.
+type VerifierConfig struct {
+	// Source receives the published messages.
+	Source MessageSource
+	// Correlator defaults to JSONFieldCorrelator("loadgen_id").
+	Correlator Correlator
+}
+
+// VerificationReport summarises the end-to-end delivery of a load test.
+type VerificationReport struct {
+	// Published is the number of messages the client published successfully.
+	Published int
+	// Delivered is the number of published messages received at least once.
+	Delivered int
+	// Missing is the number of published messages never received.
+	Missing int
+	// Duplicated is the number of extra copies received of delivered messages.
+	Duplicated int
+	// Unexpected is the number of received messages without a correlation ID
+	// of a successful publish, e.g. those of unconfirmed publishes that the
+	// broker did deliver, or traffic from other publishers.
+	Unexpected int
+	// MissingIDs are the correlation IDs of the missing messages.
+	MissingIDs []string
+	// Latency is the end-to-end latency of the delivered messages, from the
+	// start of the publish to the first receipt.
+	Latency LatencyStats
+}
+
+// LatencyStats summarises a set of latencies.
+type LatencyStats struct {
+	Min, Mean, P50, P95, P99, Max time.Duration
+}
+
+// Verifier checks the end-to-end delivery of a load test. It wraps the load
+// test's Client to inject a correlation ID into every payload and record the
+// successful publishes, receives the messages through a MessageSource, and
+// reports which were delivered, missing or duplicated, and how long they
+// took.
+//
+//	verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: source}, logger)
+//	require.NoError(t, verifier.Start(ctx))
+//	lg := loadgen.NewLoadGenerator(verifier.WrapClient(client), devices, logger)
+//	_, err := lg.Run(ctx, time.Minute)
+//	report := verifier.Finish(10 * time.Second)
+type Verifier struct {
+	cfg    VerifierConfig
+	logger zerolog.Logger
+	nextID atomic.Uint64
+
+	mu         sync.Mutex
+	published  map[string]time.Time
+	warmup     map[string]bool
+	received   map[string][]time.Time
+	unexpected int
+}
+
+// NewVerifier creates a new Verifier.
+func NewVerifier(cfg VerifierConfig, logger zerolog.Logger) *Verifier {
+	if cfg.Correlator == nil {
+		cfg.Correlator = JSONFieldCorrelator("loadgen_id")
+	}
+	return &Verifier{
+		cfg:       cfg,
+		logger:    logger.With().Str("component", "Verifier").Logger(),
+		published: make(map[string]time.Time),
+		warmup:    make(map[string]bool),
+		received:  make(map[string][]time.Time),
+	}
+}
+
+// Start starts receiving messages. Call it before the load test publishes.
+func (v *Verifier) Start(ctx context.Context) error {
+	if v.cfg.Source == nil {
+		return errors.New("verifier has no message source")
+	}
+	if err := v.cfg.Source.Start(ctx, v.receive); err != nil {
+		return fmt.Errorf("failed to start message source: %w", err)
+	}
+	return nil
+}
+
+// receive records the receipt of a message.
+func (v *Verifier) receive(payload []byte) {
+	now := time.Now()
+	id, ok := v.cfg.Correlator.Extract(payload)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !ok {
+		v.unexpected++
+		return
+	}
+	v.received[id] = append(v.received[id], now)
+}
+
+// WrapClient returns a Client that publishes through client, injecting a
+// correlation ID into every payload and recording successful publishes.
+func (v *Verifier) WrapClient(client Client) Client {
+	return &verifiedClient{Client: client, verifier: v}
+}
+
+// Finish waits up to timeout for every published message to arrive, stops
+// the message source and reports the result.
+func (v *Verifier) Finish(timeout time.Duration) VerificationReport {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && !v.allReceived() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	v.cfg.Source.Stop()
+
+	report := v.report()
+	v.logger.Info().Int("published", report.Published).Int("delivered", report.Delivered).
+		Int("missing", report.Missing).Int("duplicated", report.Duplicated).Int("unexpected", report.Unexpected).
+		Dur("latency_p50", report.Latency.P50).Dur("latency_p99", report.Latency.P99).Msg("Verification finished")
+	return report
+}
+
+// allReceived reports whether every published message has been received.
+func (v *Verifier) allReceived() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for id := range v.published {
+		if len(v.received[id]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// report correlates the published and received messages.
+func (v *Verifier) report() VerificationReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	report := VerificationReport{Published: len(v.published), Unexpected: v.unexpected}
+	var latencies []time.Duration
+	for id, sentAt := range v.published {
+		receipts := v.received[id]
+		if len(receipts) == 0 {
+			report.Missing++
+			report.MissingIDs = append(report.MissingIDs, id)
+			continue
+		}
+		report.Delivered++
+		report.Duplicated += len(receipts) - 1
+		latencies = append(latencies, slices.MinFunc(receipts, time.Time.Compare).Sub(sentAt))
+	}
+	for id, receipts := range v.received {
+		if _, ok := v.published[id]; !ok && !v.warmup[id] {
+			report.Unexpected += len(receipts)
+		}
+	}
+	slices.Sort(report.MissingIDs)
+	report.Latency = newLatencyStats(latencies)
+	return report
+}
+
+// newLatencyStats summarises latencies.
+func newLatencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	return LatencyStats{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(0.50),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// verifiedClient is the Client returned by Verifier.WrapClient.
+type verifiedClient struct {
+	Client
+	verifier *Verifier
+}
+
+// Publish publishes a copy of device whose PayloadGenerator injects a new
+// correlation ID, and records the ID if the publish succeeds. Warm-up
+// messages are recorded apart, so the report leaves them out.
+func (c *verifiedClient) Publish(ctx context.Context, device *Device) (bool, error) {
+	v := c.verifier
+	id := fmt.Sprintf("%s-%d", device.ID, v.nextID.Add(1))
+	correlated := *device
+	correlated.PayloadGenerator = correlatingGenerator{
+		generator:  device.PayloadGenerator,
+		correlator: v.cfg.Correlator,
+		id:         id,
+	}
+
+	sentAt := time.Now()
+	ok, err := c.Client.Publish(ctx, &correlated)
+	if ok {
+		v.mu.Lock()
+		if IsWarmup(ctx) {
+			v.warmup[id] = true
+		} else {
+			v.published[id] = sentAt
+		}
+		v.mu.Unlock()
+	}
+	return ok, err
+}
+
+// correlatingGenerator injects a correlation ID into the payloads of generator.
+type correlatingGenerator struct {
+	generator  PayloadGenerator
+	correlator Correlator
+	id         string
+}
+
+func (g correlatingGenerator) GeneratePayload(device *Device) ([]byte, error) {
+	payload, _, err := g.GenerateMessage(device)
+	return payload, err
+}
+
+func (g correlatingGenerator) GenerateMessage(device *Device) ([]byte, map[string]string, error) {
+	payload, attributes, err := generateMessage(g.generator, device)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err = g.correlator.Inject(payload, g.id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to inject correlation ID: %w", err)
+	}
+	return payload, attributes, nil
+}
@@ -0,0 +1,152 @@
+package loadgen_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// loopback is a Client and MessageSource pair: every payload published is
+// delivered to the source's handler copies times, as decided per device.
+type loopback struct {
+	copies func(device *loadgen.Device, n int) int
+
+	mu     sync.Mutex
+	handle func([]byte)
+	sent   map[string]int
+}
+
+func (l *loopback) Start(_ context.Context, handle func([]byte)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handle = handle
+	return nil
+}
+
+func (l *loopback) Stop() {}
+
+func (l *loopback) Connect() error { return nil }
+
+func (l *loopback) Disconnect() {}
+
+func (l *loopback) Publish(_ context.Context, device *loadgen.Device) (bool, error) {
+	payload, err := device.PayloadGenerator.GeneratePayload(device)
+	if err != nil {
+		return false, err
+	}
+	l.mu.Lock()
+	l.sent[device.ID]++
+	n := l.copies(device, l.sent[device.ID])
+	handle := l.handle
+	l.mu.Unlock()
+	for range n {
+		handle(payload)
+	}
+	return true, nil
+}
+
+func TestVerifier_Report(t *testing.T) {
+	l := &loopback{
+		sent: make(map[string]int),
+		copies: func(device *loadgen.Device, n int) int {
+			switch {
+			case device.ID == "lossy" && n%2 == 0:
+				return 0
+			case device.ID == "chatty":
+				return 2
+			}
+			return 1
+		},
+	}
+	verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: l}, zerolog.Nop())
+	require.NoError(t, verifier.Start(context.Background()))
+
+	client := verifier.WrapClient(l)
+	gen := staticPayloadGenerator(`{"temp":21}`)
+	for _, id := range []string{"steady", "lossy", "chatty"} {
+		device := &loadgen.Device{ID: id, PayloadGenerator: gen}
+		for range 4 {
+			ok, err := client.Publish(context.Background(), device)
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+	}
+	// A message from another publisher, without a correlation ID.
+	l.handle([]byte(`{"temp":30}`))
+
+	report := verifier.Finish(100 * time.Millisecond)
+	assert.Equal(t, 12, report.Published)
+	assert.Equal(t, 10, report.Delivered)
+	assert.Equal(t, 2, report.Missing)
+	assert.Len(t, report.MissingIDs, 2)
+	assert.Equal(t, 4, report.Duplicated)
+	assert.Equal(t, 1, report.Unexpected)
+	assert.LessOrEqual(t, report.Latency.Min, report.Latency.P50)
+	assert.LessOrEqual(t, report.Latency.P50, report.Latency.Max)
+}
+
+func TestVerifier_NonJSONPayload(t *testing.T) {
+	l := &loopback{sent: make(map[string]int), copies: func(*loadgen.Device, int) int { return 1 }}
+	verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: l}, zerolog.Nop())
+	require.NoError(t, verifier.Start(context.Background()))
+
+	ok, err := verifier.WrapClient(l).Publish(context.Background(), &loadgen.Device{ID: "d1", PayloadGenerator: staticPayloadGenerator("raw")})
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "failed to inject correlation ID")
+}
+
+func TestVerifier_PubsubSource(t *testing.T) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	ctx := context.Background()
+	_, err := srv.GServer.CreateTopic(ctx, &pubsubpb.Topic{Name: "projects/load-project/topics/telemetry"})
+	require.NoError(t, err)
+	_, err = srv.GServer.CreateSubscription(ctx, &pubsubpb.Subscription{
+		Name:  "projects/load-project/subscriptions/telemetry-verify",
+		Topic: "projects/load-project/topics/telemetry",
+	})
+	require.NoError(t, err)
+	clientOpts := []option.ClientOption{
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+
+	source := loadgen.NewPubsubSource(loadgen.PubsubSourceConfig{
+		ProjectID:      "load-project",
+		SubscriptionID: "telemetry-verify",
+		ClientOptions:  clientOpts,
+	}, zerolog.Nop())
+	verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: source}, zerolog.Nop())
+	require.NoError(t, verifier.Start(ctx))
+
+	client := loadgen.NewPubsubClient(loadgen.PubsubClientConfig{
+		ProjectID:     "load-project",
+		TopicID:       "telemetry",
+		ClientOptions: clientOpts,
+	}, zerolog.Nop())
+	devices := []*loadgen.Device{
+		{ID: "device-1", MessageRate: 20, PayloadGenerator: staticPayloadGenerator(`{"t":1}`)},
+		{ID: "device-2", MessageRate: 20, PayloadGenerator: staticPayloadGenerator(`{"t":2}`)},
+	}
+	lg := loadgen.NewLoadGenerator(verifier.WrapClient(client), devices, zerolog.Nop())
+	count, err := lg.Run(ctx, 200*time.Millisecond)
+	require.NoError(t, err)
+
+	report := verifier.Finish(5 * time.Second)
+	assert.Equal(t, count, report.Published)
+	assert.Equal(t, count, report.Delivered)
+	assert.Zero(t, report.Missing)
+	assert.Positive(t, report.Latency.Max)
+}
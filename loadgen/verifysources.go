@@ -0,0 +1,146 @@
+// loadgen/verifysources.go
+
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"google.golang.org/api/option"
+)
+
+// MqttSource is a MessageSource that subscribes to an MQTT topic filter.
+type MqttSource struct {
+	brokerURL   string
+	topicFilter string
+	qos         byte
+	settings    mqttSettings
+	client      mqtt.Client
+	logger      zerolog.Logger
+}
+
+// NewMqttSource creates a MessageSource for the messages matching
+// topicFilter, e.g. "devices/+/telemetry". opts configure TLS and
+// credentials, as for MqttClient.
+func NewMqttSource(brokerURL, topicFilter string, qos byte, logger zerolog.Logger, opts ...MqttOption) *MqttSource {
+	return &MqttSource{
+		brokerURL:   brokerURL,
+		topicFilter: topicFilter,
+		qos:         qos,
+		settings:    newMqttSettings(opts),
+		logger:      logger,
+	}
+}
+
+// Start connects to the broker and subscribes to the topic filter.
+func (s *MqttSource) Start(_ context.Context, handle func(payload []byte)) error {
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.brokerURL).
+		SetClientID(fmt.Sprintf("loadgen-verifier-%s", uuid.New().String())).
+		SetConnectTimeout(10 * time.Second).
+		SetOrderMatters(false)
+	if err := s.settings.apply(opts); err != nil {
+		return err
+	}
+	s.client = mqtt.NewClient(opts)
+	token := s.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out connecting to %s", s.brokerURL)
+	}
+	if token.Error() != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.brokerURL, token.Error())
+	}
+
+	token = s.client.Subscribe(s.topicFilter, s.qos, func(_ mqtt.Client, msg mqtt.Message) {
+		handle(msg.Payload())
+	})
+	if !token.WaitTimeout(10 * time.Second) {
+		s.client.Disconnect(250)
+		return fmt.Errorf("timed out subscribing to %s", s.topicFilter)
+	}
+	if token.Error() != nil {
+		s.client.Disconnect(250)
+		return fmt.Errorf("failed to subscribe to %s: %w", s.topicFilter, token.Error())
+	}
+	s.logger.Info().Str("topic_filter", s.topicFilter).Msg("MQTT source subscribed")
+	return nil
+}
+
+// Stop unsubscribes and disconnects.
+func (s *MqttSource) Stop() {
+	if s.client == nil {
+		return
+	}
+	s.client.Unsubscribe(s.topicFilter).WaitTimeout(time.Second)
+	s.client.Disconnect(250)
+	s.client = nil
+}
+
+// PubsubSourceConfig holds configuration for a PubsubSource.
+type PubsubSourceConfig struct {
+	// ProjectID and SubscriptionID identify the subscription to receive from.
+	// The subscription must exist.
+	ProjectID      string
+	SubscriptionID string
+	// ClientOptions are passed to the Pub/Sub client, as for PubsubClient.
+	ClientOptions []option.ClientOption
+}
+
+// PubsubSource is a MessageSource that receives from a Pub/Sub subscription
+// and acks every message.
+type PubsubSource struct {
+	cfg    PubsubSourceConfig
+	client *pubsub.Client
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+	logger zerolog.Logger
+}
+
+// NewPubsubSource creates a MessageSource for a Pub/Sub subscription.
+func NewPubsubSource(cfg PubsubSourceConfig, logger zerolog.Logger) *PubsubSource {
+	return &PubsubSource{cfg: cfg, logger: logger}
+}
+
+// Start starts receiving in the background. The subscription already
+// retains the messages published after it was created, so Start does not
+// wait for the first pull.
+func (s *PubsubSource) Start(ctx context.Context, handle func(payload []byte)) error {
+	client, err := pubsub.NewClient(ctx, s.cfg.ProjectID, s.cfg.ClientOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to create Pub/Sub client: %w", err)
+	}
+	s.client = client
+
+	receiveCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		err := client.Subscriber(s.cfg.SubscriptionID).Receive(receiveCtx, func(_ context.Context, msg *pubsub.Message) {
+			handle(msg.Data)
+			msg.Ack()
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Str("subscription_id", s.cfg.SubscriptionID).Msg("Pub/Sub receive failed")
+		}
+	}()
+	s.logger.Info().Str("subscription_id", s.cfg.SubscriptionID).Msg("Pub/Sub source receiving")
+	return nil
+}
+
+// Stop stops receiving and closes the client.
+func (s *PubsubSource) Stop() {
+	if s.client == nil {
+		return
+	}
+	s.cancel()
+	s.done.Wait()
+	_ = s.client.Close()
+	s.client = nil
+}
@@ -0,0 +1,42 @@
+// loadgen/warmup.go
+
+package loadgen
+
+import (
+	"context"
+	"time"
+)
+
+// SetWarmupDuration adds a warm-up of d before the measured part of each
+// run. Devices publish as usual during the warm-up, but its publishes are
+// left out of the run's counts, progress snapshots and stop conditions, so
+// connection establishment and other start-up effects do not skew a short
+// measurement window. RunResult.WarmupPublished counts them instead. The
+// run's duration is measured after the warm-up, which adds to it.
+//
+// The devices' schedules are not restarted after the warm-up, so the
+// measured window may hold one message per device less than
+// ExpectedMessagesForDuration. ExpectedRange allows for that.
+func (lg *LoadGenerator) SetWarmupDuration(d time.Duration) {
+	lg.warmup = d
+}
+
+// warmupKey is the context key that marks publishes made during a warm-up.
+type warmupKey struct{}
+
+// IsWarmup reports whether ctx is that of a publish made during the warm-up
+// set by SetWarmupDuration. Clients that gather statistics of their own, such
+// as the Verifier's, use it to leave warm-up messages out.
+func IsWarmup(ctx context.Context) bool {
+	warm, _ := ctx.Value(warmupKey{}).(bool)
+	return warm
+}
+
+// warmupContext marks ctx as that of a warm-up publish if the current run
+// is still warming up.
+func (lg *LoadGenerator) warmupContext(ctx context.Context) context.Context {
+	if lg.measureFrom.IsZero() || !time.Now().Before(lg.measureFrom) {
+		return ctx
+	}
+	return context.WithValue(ctx, warmupKey{}, true)
+}
@@ -0,0 +1,63 @@
+package loadgen_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/loadgen"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerator_SetWarmupDuration(t *testing.T) {
+	devices := func() []*loadgen.Device {
+		gen := staticPayloadGenerator(`{}`)
+		return []*loadgen.Device{
+			{ID: "device-1", MessageRate: 100, PayloadGenerator: gen},
+			{ID: "device-2", MessageRate: 100, PayloadGenerator: gen},
+		}
+	}
+
+	t.Run("duration", func(t *testing.T) {
+		client := &recordingClient{}
+		lg := loadgen.NewLoadGenerator(client, devices(), zerolog.Nop())
+		lg.SetWarmupDuration(100 * time.Millisecond)
+
+		start := time.Now()
+		result, err := lg.RunUntil(context.Background(), 200*time.Millisecond)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "the warm-up adds to the duration")
+		assert.Equal(t, loadgen.StopDuration, result.StoppedBy)
+		assert.InDelta(t, 20, result.WarmupPublished, 5)
+		assert.InDelta(t, lg.ExpectedMessagesForDuration(200*time.Millisecond), result.Published, 6)
+		assert.Len(t, client.payloads, result.Published+result.WarmupPublished)
+	})
+
+	t.Run("stop conditions", func(t *testing.T) {
+		lg := loadgen.NewLoadGenerator(&recordingClient{}, devices(), zerolog.Nop())
+		lg.SetWarmupDuration(50 * time.Millisecond)
+		result, err := lg.RunUntil(context.Background(), time.Minute, loadgen.StopAfterMessages(5))
+		require.NoError(t, err)
+		assert.Equal(t, loadgen.StopMessageCount, result.StoppedBy)
+		assert.Positive(t, result.WarmupPublished, "warm-up messages do not count towards the limit")
+		assert.GreaterOrEqual(t, result.Published, 5)
+	})
+
+	t.Run("verifier", func(t *testing.T) {
+		l := &loopback{sent: make(map[string]int), copies: func(*loadgen.Device, int) int { return 1 }}
+		verifier := loadgen.NewVerifier(loadgen.VerifierConfig{Source: l}, zerolog.Nop())
+		require.NoError(t, verifier.Start(context.Background()))
+		lg := loadgen.NewLoadGenerator(verifier.WrapClient(l), devices(), zerolog.Nop())
+		lg.SetWarmupDuration(50 * time.Millisecond)
+
+		result, err := lg.RunUntil(context.Background(), 100*time.Millisecond)
+		require.NoError(t, err)
+		report := verifier.Finish(time.Second)
+		assert.Positive(t, result.WarmupPublished)
+		assert.Equal(t, result.Published, report.Published)
+		assert.Equal(t, result.Published, report.Delivered)
+		assert.Zero(t, report.Unexpected, "warm-up messages are neither reported nor unexpected")
+	})
+}
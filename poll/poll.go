@@ -0,0 +1,135 @@
+// Package poll waits for a condition to hold, for tests that wait on
+// eventually consistent systems: a subscription being created, a document
+// being indexed, a metric being scraped. Unlike require.Eventually it
+// records why each attempt failed, so a timeout reports the errors of the
+// last attempts rather than only that the condition never held.
+package poll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportedAttempts is the number of most recent attempts a TimeoutError
+// keeps and reports.
+const reportedAttempts = 5
+
+// errNotDone is recorded for an attempt whose condition returned neither
+// done nor an error.
+var errNotDone = errors.New("condition not met")
+
+// Condition reports whether what is being waited for holds. An error means
+// it does not hold yet, and says why; polling goes on unless the error is
+// wrapped with Permanent.
+type Condition func() (done bool, err error)
+
+// Attempt is a failed call of a Condition.
+type Attempt struct {
+	// N is the attempt's number, from 1.
+	N int
+	// Elapsed is the time from the start of polling to the attempt.
+	Elapsed time.Duration
+	Err     error
+}
+
+// TimeoutError is returned by Wait when the condition did not hold in time.
+type TimeoutError struct {
+	// Attempts is the number of times the condition was called.
+	Attempts int
+	Elapsed  time.Duration
+	// Last are the most recent failed attempts, oldest first.
+	Last []Attempt
+	// Err is the context error that ended polling.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "condition not met after %d attempts in %s: %v", e.Attempts, e.Elapsed.Round(time.Millisecond), e.Err)
+	if len(e.Last) > 0 {
+		fmt.Fprintf(&b, "\nlast %d attempts:", len(e.Last))
+		for _, a := range e.Last {
+			fmt.Fprintf(&b, "\n  #%d at %s: %v", a.N, a.Elapsed.Round(time.Millisecond), a.Err)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the context error and the error of the last attempt.
+func (e *TimeoutError) Unwrap() []error {
+	errs := []error{e.Err}
+	if len(e.Last) > 0 {
+		errs = append(errs, e.Last[len(e.Last)-1].Err)
+	}
+	return errs
+}
+
+// permanentError marks an error that stops polling.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that a Condition returning it stops polling
+// straight away, for errors that waiting will not cure, e.g. PermissionDenied
+// while waiting for a resource to appear. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Wait calls condition straight away and then every interval until it
+// returns done, ctx is done or timeout passes. A timeout of zero or less
+// waits as long as ctx allows. It returns nil once the condition holds, a
+// *TimeoutError if it never did, or the error of an attempt that failed
+// with Permanent.
+func Wait(ctx context.Context, interval, timeout time.Duration, condition Condition) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	var last []Attempt
+	for n := 1; ; n++ {
+		done, err := condition()
+		if done {
+			return nil
+		}
+		var pe permanentError
+		if errors.As(err, &pe) {
+			return fmt.Errorf("poll stopped at attempt %d: %w", n, pe.err)
+		}
+		if err == nil {
+			err = errNotDone
+		}
+		if len(last) == reportedAttempts {
+			last = append(last[:0], last[1:]...)
+		}
+		last = append(last, Attempt{N: n, Elapsed: time.Since(start), Err: err})
+
+		select {
+		case <-ctx.Done():
+			return &TimeoutError{Attempts: n, Elapsed: time.Since(start), Last: last, Err: ctx.Err()}
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Until waits for condition as Wait does, and fails the test if it does not
+// hold, reporting the errors of the last attempts.
+func Until(t testing.TB, ctx context.Context, interval, timeout time.Duration, condition Condition) {
+	t.Helper()
+	if err := Wait(ctx, interval, timeout, condition); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
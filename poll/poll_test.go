@@ -0,0 +1,109 @@
+package poll_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/poll"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWait_Succeeds(t *testing.T) {
+	calls := 0
+	err := poll.Wait(context.Background(), time.Millisecond, 5*time.Second, func() (bool, error) {
+		calls++
+		if calls < 3 {
+			return false, errors.New("not found")
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWait_TimeoutReportsLastAttempts(t *testing.T) {
+	calls := 0
+	err := poll.Wait(context.Background(), time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+		calls++
+		if calls%2 == 0 {
+			return false, nil
+		}
+		return false, fmt.Errorf("attempt %d: not found", calls)
+	})
+
+	var timeout *poll.TimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, calls, timeout.Attempts)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, timeout.Last, 5)
+	assert.Equal(t, calls, timeout.Last[4].N)
+	assert.Equal(t, calls-4, timeout.Last[0].N)
+	assert.False(t, timeout.Last[4].Elapsed < timeout.Last[0].Elapsed)
+
+	// Attempts that return no error are reported as not met.
+	assert.Contains(t, err.Error(), "last 5 attempts:")
+	assert.Contains(t, err.Error(), fmt.Sprintf("#%d at", calls))
+	assert.Contains(t, err.Error(), "condition not met")
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestWait_Permanent(t *testing.T) {
+	denied := errors.New("permission denied")
+	calls := 0
+	err := poll.Wait(context.Background(), time.Millisecond, 5*time.Second, func() (bool, error) {
+		calls++
+		if calls < 2 {
+			return false, errors.New("not found")
+		}
+		return false, poll.Permanent(denied)
+	})
+	require.ErrorIs(t, err, denied)
+	assert.EqualError(t, err, "poll stopped at attempt 2: permission denied")
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, poll.Permanent(nil))
+}
+
+func TestWait_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := poll.Wait(ctx, time.Hour, 0, func() (bool, error) { return false, nil })
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestUntil_FailsTest(t *testing.T) {
+	recorder := &fatalRecorder{TB: t}
+	done := make(chan struct{})
+	// Fatalf ends the goroutine, as it would a test.
+	go func() {
+		defer close(done)
+		poll.Until(recorder, context.Background(), time.Millisecond, 20*time.Millisecond, func() (bool, error) {
+			return false, errors.New("subscription not found")
+		})
+	}()
+	<-done
+	require.True(t, recorder.failed)
+	assert.Contains(t, recorder.message, "subscription not found")
+
+	poll.Until(t, context.Background(), time.Millisecond, time.Second, func() (bool, error) { return true, nil })
+}
+
+// fatalRecorder is a testing.TB that records a fatal failure instead of
+// failing the test.
+type fatalRecorder struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (r *fatalRecorder) Helper() {}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.failed = true
+	r.message = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}